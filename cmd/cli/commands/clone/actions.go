@@ -20,6 +20,7 @@ import (
 
 	"gitlab.com/postgres-ai/database-lab/v3/cmd/cli/commands"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/observer"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/client/dblabapi"
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/client/dblabapi/types"
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
@@ -32,6 +33,10 @@ func list(cliCtx *cli.Context) error {
 		return err
 	}
 
+	if cliCtx.IsSet(cloneSelectorFlag) || cliCtx.IsSet("status") || cliCtx.IsSet("snapshot-id") {
+		return listByFilter(cliCtx, dblabClient)
+	}
+
 	body, err := dblabClient.ListClonesRaw(cliCtx.Context)
 	if err != nil {
 		return err
@@ -59,6 +64,58 @@ func list(cliCtx *cli.Context) error {
 	return err
 }
 
+// listByFilter lists all pages of clones matching the filter.
+func listByFilter(cliCtx *cli.Context, dblabClient *dblabapi.Client) error {
+	filter := types.ClonesFilterRequest{
+		Selector:   cliCtx.String(cloneSelectorFlag),
+		Status:     cliCtx.String("status"),
+		SnapshotID: cliCtx.String("snapshot-id"),
+	}
+
+	clones := make([]*models.CloneView, 0)
+
+	for {
+		page, err := listClonesPage(cliCtx, dblabClient, filter)
+		if err != nil {
+			return err
+		}
+
+		clones = append(clones, page.Clones...)
+
+		if len(page.Clones) == 0 || len(clones) >= page.Total {
+			break
+		}
+
+		filter.Offset = len(clones)
+	}
+
+	commandResponse, err := json.MarshalIndent(clones, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cliCtx.App.Writer, string(commandResponse))
+
+	return err
+}
+
+func listClonesPage(cliCtx *cli.Context, dblabClient *dblabapi.Client, filter types.ClonesFilterRequest) (*models.ClonesPageView, error) {
+	body, err := dblabClient.ListClonesByFilterRaw(cliCtx.Context, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = body.Close() }()
+
+	var page models.ClonesPageView
+
+	if err := json.NewDecoder(body).Decode(&page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
 // status runs a request to get clone info.
 func status(cliCtx *cli.Context) error {
 	dblabClient, err := commands.ClientByCLIContext(cliCtx)
@@ -99,6 +156,7 @@ func create(cliCtx *cli.Context) error {
 	cloneRequest := types.CloneCreateRequest{
 		ID:        cliCtx.String("id"),
 		Protected: cliCtx.Bool("protected"),
+		Pooler:    cliCtx.Bool("pooler"),
 		DB: &types.DatabaseRequest{
 			Username:   cliCtx.String("username"),
 			Password:   cliCtx.String("password"),
@@ -113,6 +171,21 @@ func create(cliCtx *cli.Context) error {
 
 	cloneRequest.ExtraConf = splitFlags(cliCtx.StringSlice("extra-config"))
 
+	if cliCtx.IsSet(cloneLabelFlag) {
+		cloneRequest.Labels = splitFlags(cliCtx.StringSlice(cloneLabelFlag))
+	}
+
+	if cliCtx.IsSet(cloneAllowedCIDRFlag) {
+		cloneRequest.AllowedCIDRs = allowedCIDRs(cliCtx.StringSlice(cloneAllowedCIDRFlag))
+	}
+
+	if cliCtx.IsSet("init-bundle") || cliCtx.IsSet("init-sql") {
+		cloneRequest.Init = &types.InitScriptsRequest{
+			Bundles: cliCtx.StringSlice("init-bundle"),
+			SQL:     cliCtx.String("init-sql"),
+		}
+	}
+
 	var clone *models.Clone
 
 	if cliCtx.Bool("async") {
@@ -147,8 +220,19 @@ func update(cliCtx *cli.Context) error {
 		return err
 	}
 
-	updateRequest := types.CloneUpdateRequest{
-		Protected: cliCtx.Bool("protected"),
+	updateRequest := types.CloneUpdateRequest{}
+
+	if cliCtx.IsSet("protected") {
+		protected := cliCtx.Bool("protected")
+		updateRequest.Protected = &protected
+	}
+
+	if cliCtx.IsSet(cloneLabelFlag) {
+		updateRequest.Labels = splitFlags(cliCtx.StringSlice(cloneLabelFlag))
+	}
+
+	if cliCtx.IsSet(cloneAllowedCIDRFlag) {
+		updateRequest.AllowedCIDRs = allowedCIDRs(cliCtx.StringSlice(cloneAllowedCIDRFlag))
 	}
 
 	cloneID := cliCtx.Args().First()
@@ -222,6 +306,10 @@ func destroy(cliCtx *cli.Context) error {
 		return err
 	}
 
+	if cliCtx.IsSet(cloneSelectorFlag) {
+		return destroyBySelector(cliCtx, dblabClient)
+	}
+
 	cloneID := cliCtx.Args().First()
 
 	if cliCtx.Bool("async") {
@@ -239,6 +327,25 @@ func destroy(cliCtx *cli.Context) error {
 	return err
 }
 
+// destroyBySelector destroys all clones matching the label selector.
+func destroyBySelector(cliCtx *cli.Context, dblabClient *dblabapi.Client) error {
+	result, err := dblabClient.DestroyClones(cliCtx.Context, types.ClonesFilterRequest{
+		Selector: cliCtx.String(cloneSelectorFlag),
+	})
+	if err != nil {
+		return err
+	}
+
+	commandResponse, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cliCtx.App.Writer, string(commandResponse))
+
+	return err
+}
+
 // startObservation runs a request to startObservation clone.
 func startObservation(cliCtx *cli.Context) error {
 	dblabClient, err := commands.ClientByCLIContext(cliCtx)
@@ -470,3 +577,16 @@ func splitFlags(flags []string) map[string]string {
 
 	return extraConfig
 }
+
+// allowedCIDRs skips empty values, so an empty flag value produces an empty list to restore the default allowlist.
+func allowedCIDRs(flags []string) []string {
+	cidrs := make([]string, 0, len(flags))
+
+	for _, cidr := range flags {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+
+	return cidrs
+}
@@ -13,6 +13,9 @@ import (
 const (
 	cloneResetLatestFlag     = "latest"
 	cloneResetSnapshotIDFlag = "snapshot-id"
+	cloneSelectorFlag        = "selector"
+	cloneLabelFlag           = "label"
+	cloneAllowedCIDRFlag     = "allowed-cidr"
 )
 
 // CommandList returns available commands for a clones management.
@@ -25,6 +28,20 @@ func CommandList() []*cli.Command {
 				Name:   "list",
 				Usage:  "list all existing clones",
 				Action: list,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  cloneSelectorFlag,
+						Usage: "list clones matching the label selector. An example: team=payments,env!=prod",
+					},
+					&cli.StringFlag{
+						Name:  "status",
+						Usage: "list clones with the status code. An example: OK",
+					},
+					&cli.StringFlag{
+						Name:  "snapshot-id",
+						Usage: "list clones of the snapshot",
+					},
+				},
 			},
 			{
 				Name:      "status",
@@ -78,6 +95,26 @@ func CommandList() []*cli.Command {
 						Name:  "extra-config",
 						Usage: "set an extra database configuration for the clone. An example: statement_timeout='1s'",
 					},
+					&cli.StringSliceFlag{
+						Name:  "init-bundle",
+						Usage: "run a named init bundle from the init scripts directory after the clone is created",
+					},
+					&cli.StringFlag{
+						Name:  "init-sql",
+						Usage: "run inline SQL as superuser after the clone is created",
+					},
+					&cli.StringSliceFlag{
+						Name:  cloneLabelFlag,
+						Usage: "set a clone label. An example: team=payments",
+					},
+					&cli.StringSliceFlag{
+						Name:  cloneAllowedCIDRFlag,
+						Usage: "allow connections to the clone only from the network. An example: 10.0.0.0/8",
+					},
+					&cli.BoolFlag{
+						Name:  "pooler",
+						Usage: "start a PgBouncer connection pooler in front of the clone",
+					},
 				},
 			},
 			{
@@ -92,6 +129,14 @@ func CommandList() []*cli.Command {
 						Usage:   "mark instance as protected from deletion",
 						Aliases: []string{"p"},
 					},
+					&cli.StringSliceFlag{
+						Name:  cloneLabelFlag,
+						Usage: "replace clone labels. An example: team=payments",
+					},
+					&cli.StringSliceFlag{
+						Name:  cloneAllowedCIDRFlag,
+						Usage: "replace networks allowed to connect to the clone. An empty value restores the default list",
+					},
 				},
 			},
 			{
@@ -120,14 +165,24 @@ func CommandList() []*cli.Command {
 				Name:      "destroy",
 				Usage:     "destroy clone",
 				ArgsUsage: "CLONE_ID",
-				Before:    checkCloneIDBefore,
-				Action:    destroy,
+				Before: func(ctxCli *cli.Context) error {
+					if ctxCli.IsSet(cloneSelectorFlag) {
+						return nil
+					}
+
+					return checkCloneIDBefore(ctxCli)
+				},
+				Action: destroy,
 				Flags: []cli.Flag{
 					&cli.BoolFlag{
 						Name:    "async",
 						Usage:   "run the command asynchronously",
 						Aliases: []string{"a"},
 					},
+					&cli.StringFlag{
+						Name:  cloneSelectorFlag,
+						Usage: "destroy all clones matching the label selector instead of CLONE_ID (requires the verification token)",
+					},
 				},
 			},
 			{
@@ -8,10 +8,13 @@ package instance
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/urfave/cli/v2"
 
 	"gitlab.com/postgres-ai/database-lab/v3/cmd/cli/commands"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/client/dblabapi/types"
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
 )
 
@@ -66,3 +69,66 @@ func health(cliCtx *cli.Context) error {
 
 	return err
 }
+
+// usage runs a request to get a clone usage report.
+func usage(cliCtx *cli.Context) error {
+	dblabClient, err := commands.ClientByCLIContext(cliCtx)
+	if err != nil {
+		return err
+	}
+
+	usageRequest := types.UsageRequest{
+		GroupBy: cliCtx.String("group-by"),
+		Label:   cliCtx.String("label"),
+	}
+
+	if usageRequest.From, err = parseUsageTime(cliCtx.String("from")); err != nil {
+		return err
+	}
+
+	if usageRequest.To, err = parseUsageTime(cliCtx.String("to")); err != nil {
+		return err
+	}
+
+	if cliCtx.Bool("csv") {
+		usageRequest.Format = "csv"
+
+		body, err := dblabClient.UsageRaw(cliCtx.Context, usageRequest)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = body.Close() }()
+
+		_, err = io.Copy(cliCtx.App.Writer, body)
+
+		return err
+	}
+
+	report, err := dblabClient.Usage(cliCtx.Context, usageRequest)
+	if err != nil {
+		return err
+	}
+
+	commandResponse, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cliCtx.App.Writer, string(commandResponse))
+
+	return err
+}
+
+func parseUsageTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: must be in the RFC 3339 format, e.g. 2022-05-01T00:00:00Z", value)
+	}
+
+	return parsed, nil
+}
@@ -25,6 +25,34 @@ func CommandList() []*cli.Command {
 					Usage:  "display instance's version",
 					Action: health,
 				},
+				{
+					Name:   "usage",
+					Usage:  "display clone usage for a period",
+					Action: usage,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "from",
+							Usage: "start of the period in the RFC 3339 format (default: 30 days before the end)",
+						},
+						&cli.StringFlag{
+							Name:  "to",
+							Usage: "end of the period in the RFC 3339 format (default: now)",
+						},
+						&cli.StringFlag{
+							Name:  "group-by",
+							Usage: "group usage by \"user\" or by \"label\"",
+							Value: "user",
+						},
+						&cli.StringFlag{
+							Name:  "label",
+							Usage: "label key to group usage by; required with --group-by label",
+						},
+						&cli.BoolFlag{
+							Name:  "csv",
+							Usage: "export the report in the CSV format",
+						},
+					},
+				},
 			},
 		},
 	}
@@ -21,8 +21,10 @@ import (
 	"github.com/pkg/errors"
 
 	"gitlab.com/postgres-ai/database-lab/v3/internal/cloning"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/diskpressure"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/embeddedui"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/estimator"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/freshness"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/observer"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/platform"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/provision"
@@ -121,6 +123,8 @@ func main() {
 		log.Errf(errors.WithMessage(err, `error in the "provision" section of the config`).Error())
 	}
 
+	provisioner.SetAccessHost(cfg.Cloning.AccessHost)
+
 	observingChan := make(chan string, 1)
 
 	emergencyShutdown := func() {
@@ -140,11 +144,26 @@ func main() {
 		return
 	}
 
+	retrievalSvc.SetWarmPool(cloningSvc)
+
 	obs := observer.NewObserver(docker, &cfg.Observer, pm)
 	est := estimator.NewEstimator(&cfg.Estimator)
+	if err = diskpressure.IsValidConfig(cfg.DiskPressure); err != nil {
+		log.Err("invalid disk pressure configuration:", err)
+		emergencyShutdown()
+
+		return
+	}
+
+	diskPressure := diskpressure.NewController(&cfg.DiskPressure, pm, cloningSvc, retrievalSvc)
+	dataFreshness := freshness.NewController(&cfg.DataFreshness, pm, cloningSvc, retrievalSvc)
 
 	go removeObservingClones(observingChan, obs)
 
+	go diskPressure.Run(ctx)
+
+	go dataFreshness.Run(ctx)
+
 	tm.SendEvent(ctx, telemetry.EngineStartedEvent, telemetry.EngineStarted{
 		EngineVersion: version.GetVersion(),
 		DBVersion:     provisioner.DetectDBVersion(),
@@ -153,10 +172,12 @@ func main() {
 	})
 
 	embeddedUI := embeddedui.New(cfg.EmbeddedUI, engProps, runner, docker)
-	server := srv.NewServer(&cfg.Server, &cfg.Global, engProps, docker, cloningSvc, provisioner, retrievalSvc, platformSvc, obs, est, pm, tm)
+	server := srv.NewServer(&cfg.Server, &cfg.Global, engProps, docker, cloningSvc, provisioner, retrievalSvc, platformSvc, obs, est,
+		diskPressure, dataFreshness, pm, tm)
 	shutdownCh := setShutdownListener()
 
-	go setReloadListener(ctx, provisioner, tm, retrievalSvc, pm, cloningSvc, platformSvc, est, embeddedUI, server)
+	go setReloadListener(ctx, provisioner, tm, retrievalSvc, pm, cloningSvc, platformSvc, est, diskPressure, dataFreshness, embeddedUI,
+		server)
 
 	server.InitHandlers()
 
@@ -223,8 +244,8 @@ func getEngineProperties(ctx context.Context, dockerCLI *client.Client, cfg *con
 }
 
 func reloadConfig(ctx context.Context, provisionSvc *provision.Provisioner, tm *telemetry.Agent, retrievalSvc *retrieval.Retrieval,
-	pm *pool.Manager, cloningSvc *cloning.Base, platformSvc *platform.Service, est *estimator.Estimator, embeddedUI *embeddedui.UIManager,
-	server *srv.Server) error {
+	pm *pool.Manager, cloningSvc *cloning.Base, platformSvc *platform.Service, est *estimator.Estimator,
+	diskPressure *diskpressure.Controller, dataFreshness *freshness.Controller, embeddedUI *embeddedui.UIManager, server *srv.Server) error {
 	cfg, err := config.LoadConfiguration()
 	if err != nil {
 		return err
@@ -238,6 +259,10 @@ func reloadConfig(ctx context.Context, provisionSvc *provision.Provisioner, tm *
 		return err
 	}
 
+	if err := diskpressure.IsValidConfig(cfg.DiskPressure); err != nil {
+		return err
+	}
+
 	newPlatformSvc, err := platform.New(ctx, cfg.Platform)
 	if err != nil {
 		return err
@@ -257,26 +282,30 @@ func reloadConfig(ctx context.Context, provisionSvc *provision.Provisioner, tm *
 	}
 
 	provisionSvc.Reload(cfg.Provision, dbCfg)
+	provisionSvc.SetAccessHost(cfg.Cloning.AccessHost)
 	tm.Reload(cfg.Global)
 	retrievalSvc.Reload(ctx, cfg)
 	cloningSvc.Reload(cfg.Cloning)
 	platformSvc.Reload(newPlatformSvc)
 	est.Reload(cfg.Estimator)
+	diskPressure.Reload(cfg.DiskPressure)
+	dataFreshness.Reload(cfg.DataFreshness)
 	server.Reload(cfg.Server)
 
 	return nil
 }
 
 func setReloadListener(ctx context.Context, provisionSvc *provision.Provisioner, tm *telemetry.Agent, retrievalSvc *retrieval.Retrieval,
-	pm *pool.Manager, cloningSvc *cloning.Base, platformSvc *platform.Service, est *estimator.Estimator, embeddedUI *embeddedui.UIManager,
-	server *srv.Server) {
+	pm *pool.Manager, cloningSvc *cloning.Base, platformSvc *platform.Service, est *estimator.Estimator,
+	diskPressure *diskpressure.Controller, dataFreshness *freshness.Controller, embeddedUI *embeddedui.UIManager, server *srv.Server) {
 	reloadCh := make(chan os.Signal, 1)
 	signal.Notify(reloadCh, syscall.SIGHUP)
 
 	for range reloadCh {
 		log.Msg("Reloading configuration")
 
-		if err := reloadConfig(ctx, provisionSvc, tm, retrievalSvc, pm, cloningSvc, platformSvc, est, embeddedUI, server); err != nil {
+		if err := reloadConfig(ctx, provisionSvc, tm, retrievalSvc, pm, cloningSvc, platformSvc, est, diskPressure, dataFreshness,
+			embeddedUI, server); err != nil {
 			log.Err("Failed to reload configuration", err)
 		}
 
@@ -34,12 +34,21 @@ const (
 	idleCheckDuration = 5 * time.Minute
 
 	defaultDatabaseName = "postgres"
+
+	// caCertFilename defines the suggested file name to keep the CA certificate on the client side.
+	caCertFilename = "dblab_ca.crt"
 )
 
 // Config contains a cloning configuration.
 type Config struct {
-	MaxIdleMinutes uint   `yaml:"maxIdleMinutes"`
-	AccessHost     string `yaml:"accessHost"`
+	MaxIdleMinutes uint     `yaml:"maxIdleMinutes"`
+	AccessHost     string   `yaml:"accessHost"`
+	WarmPool       WarmPool `yaml:"warmPool"`
+	Queue          Queue    `yaml:"queue"`
+
+	// AllowedCIDRs defines networks allowed to connect to clones if a clone request does not define its own list.
+	// An empty list allows connections from any address.
+	AllowedCIDRs []string `yaml:"allowedCIDRs"`
 }
 
 // Base provides cloning service.
@@ -51,6 +60,9 @@ type Base struct {
 	provision   *provision.Provisioner
 	tm          *telemetry.Agent
 	observingCh chan string
+	warmPool    warmPool
+	queue       operationQueue
+	usage       *usageLedger
 }
 
 // NewBase instances a new Base service.
@@ -64,12 +76,19 @@ func NewBase(cfg *Config, provision *provision.Provisioner, tm *telemetry.Agent,
 		snapshotBox: SnapshotBox{
 			items: make(map[string]*models.Snapshot),
 		},
+		warmPool: newWarmPool(),
+		queue:    newOperationQueue(),
+		usage:    &usageLedger{},
 	}
 }
 
 // Reload reloads base cloning configuration.
 func (c *Base) Reload(cfg Config) {
 	*c.config = cfg
+
+	c.warmPool.requestRefill()
+
+	c.dispatchQueue()
 }
 
 // Run initializes and runs cloning component.
@@ -98,8 +117,14 @@ func (c *Base) Run(ctx context.Context) error {
 		return fmt.Errorf("failed to revise port pool: %w", err)
 	}
 
+	c.restoreUsage()
+
 	go c.runIdleCheck(ctx)
 
+	go c.runUsageSampling(ctx)
+
+	go c.runWarmPool(ctx)
+
 	return nil
 }
 
@@ -154,11 +179,15 @@ func (c *Base) CreateClone(cloneRequest *types.CloneCreateRequest) (*models.Clon
 		}
 	}
 
+	allowedCIDRs := c.allowedCIDRs(cloneRequest.AllowedCIDRs)
+
 	clone := &models.Clone{
-		ID:        cloneRequest.ID,
-		Snapshot:  snapshot,
-		Protected: cloneRequest.Protected,
-		CreatedAt: util.FormatTime(createdAt),
+		ID:           cloneRequest.ID,
+		Snapshot:     snapshot,
+		Protected:    cloneRequest.Protected,
+		CreatedAt:    util.FormatTime(createdAt),
+		Labels:       cloneRequest.Labels,
+		AllowedCIDRs: allowedCIDRs,
 		Status: models.Status{
 			Code:    models.StatusCreating,
 			Message: models.CloneMessageCreating,
@@ -181,10 +210,19 @@ func (c *Base) CreateClone(cloneRequest *types.CloneCreateRequest) (*models.Clon
 		AvailableDB: cloneRequest.DB.DBName,
 	}
 
+	var initScripts resources.InitScripts
+
+	if cloneRequest.Init != nil {
+		initScripts = resources.InitScripts{
+			Bundles: cloneRequest.Init.Bundles,
+			SQL:     cloneRequest.Init.SQL,
+		}
+	}
+
 	c.incrementCloneNumber(clone.Snapshot.ID)
 
-	go func() {
-		session, err := c.provision.StartSession(clone.Snapshot.ID, ephemeralUser, cloneRequest.ExtraConf)
+	createClone := func() {
+		session, err := c.startSession(clone.Snapshot.ID, ephemeralUser, cloneRequest.ExtraConf, initScripts, allowedCIDRs)
 		if err != nil {
 			// TODO(anatoly): Empty room case.
 			log.Errf("Failed to start session: %v.", err)
@@ -199,9 +237,40 @@ func (c *Base) CreateClone(cloneRequest *types.CloneCreateRequest) (*models.Clon
 			return
 		}
 
+		if cloneRequest.Pooler {
+			if err := c.provision.StartPooler(context.Background(), session); err != nil {
+				log.Errf("Failed to start a connection pooler: %v.", err)
+
+				if stopErr := c.provision.StopSession(session); stopErr != nil {
+					log.Errf("Failed to stop session: %v.", stopErr)
+				}
+
+				if updateErr := c.UpdateCloneStatus(cloneID, models.Status{
+					Code:    models.StatusFatal,
+					Message: errors.Cause(err).Error(),
+				}); updateErr != nil {
+					log.Errf("Failed to update clone status: %v", updateErr)
+				}
+
+				return
+			}
+		}
+
 		c.fillCloneSession(cloneID, session)
 		c.SaveClonesState()
-	}()
+
+		c.usage.created(clone, w.TimeCreatedAt)
+		c.saveUsage()
+	}
+
+	// The clone is new, so it cannot have other operations.
+	_ = c.enqueue(&queueTask{
+		cloneID: cloneID,
+		owner:   clone.DB.Username,
+		kind:    createOperation,
+		status:  clone.Status,
+		run:     createClone,
+	})
 
 	return clone, nil
 }
@@ -235,6 +304,21 @@ func (c *Base) fillCloneSession(cloneID string, session *resources.Session) {
 	clone.DB.ConnStr = fmt.Sprintf("host=%s port=%s user=%s dbname=%s",
 		clone.DB.Host, clone.DB.Port, clone.DB.Username, dbName)
 
+	clone.DB.PooledConnStr = ""
+
+	if session.PoolerPort != 0 {
+		clone.DB.PooledConnStr = fmt.Sprintf("host=%s port=%d user=%s dbname=%s",
+			clone.DB.Host, session.PoolerPort, clone.DB.Username, dbName)
+	}
+
+	clone.DB.CACert = ""
+	clone.DB.VerifyFullConnStr = ""
+
+	if caCert := c.provision.TLSCACert(); caCert != nil {
+		clone.DB.CACert = string(caCert)
+		clone.DB.VerifyFullConnStr = clone.DB.ConnStr + " sslmode=verify-full sslrootcert=" + caCertFilename
+	}
+
 	clone.Metadata = models.CloneMetadata{
 		CloningTime:    w.TimeStartedAt.Sub(w.TimeCreatedAt).Seconds(),
 		MaxIdleMinutes: c.config.MaxIdleMinutes,
@@ -248,6 +332,10 @@ func (c *Base) ConnectToClone(ctx context.Context, cloneID string) (pgxtype.Quer
 		return nil, errors.New("not found")
 	}
 
+	if w.Session == nil {
+		return nil, errors.New("clone is not started yet")
+	}
+
 	connStr := connectionString(
 		w.Session.SocketHost, strconv.FormatUint(uint64(w.Session.Port), 10), w.Session.User, w.Clone.DB.DBName)
 
@@ -259,6 +347,38 @@ func (c *Base) ConnectToClone(ctx context.Context, cloneID string) (pgxtype.Quer
 	return db, nil
 }
 
+// ConnectToCloneAsUser connects to clone by cloneID as the ephemeral user of the clone.
+// Only restricted users are allowed because the ephemeral user of a non-restricted clone is a superuser.
+func (c *Base) ConnectToCloneAsUser(ctx context.Context, cloneID string) (*pgx.Conn, error) {
+	w, ok := c.findWrapper(cloneID)
+	if !ok {
+		return nil, models.New(models.ErrCodeNotFound, "clone not found")
+	}
+
+	if w.Session == nil || w.Clone.Status.Code != models.StatusOK {
+		return nil, models.New(models.ErrCodeBadRequest, "clone is not ready")
+	}
+
+	if !w.Session.EphemeralUser.Restricted {
+		return nil, models.New(models.ErrCodeBadRequest, "queries are available only for clones with a restricted user")
+	}
+
+	dbName := w.Clone.DB.DBName
+	if dbName == "" {
+		dbName = defaultDatabaseName
+	}
+
+	connStr := connectionString(w.Session.SocketHost, strconv.FormatUint(uint64(w.Session.Port), 10),
+		quoteConnValue(w.Session.EphemeralUser.Name), dbName)
+
+	return pgx.Connect(ctx, connStr)
+}
+
+// quoteConnValue quotes a value of a connection string.
+func quoteConnValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
 func connectionString(host, port, username, dbname string) string {
 	return fmt.Sprintf("host=%s port=%s user=%s database='%s'",
 		host, port, username, dbname)
@@ -275,14 +395,13 @@ func (c *Base) DestroyClone(cloneID string) error {
 		return models.New(models.ErrCodeBadRequest, "clone is protected")
 	}
 
-	if err := c.UpdateCloneStatus(cloneID, models.Status{
-		Code:    models.StatusDeleting,
-		Message: models.CloneMessageDeleting,
-	}); err != nil {
-		return errors.Wrap(err, "failed to update clone status")
-	}
-
 	if w.Session == nil {
+		if c.hasRunningOperation(cloneID) {
+			return models.New(models.ErrCodeBadRequest, "clone is being created")
+		}
+
+		// The clone may still wait for its creation in the queue.
+		c.dequeue(cloneID)
 		c.deleteClone(cloneID)
 
 		if w.Clone.Snapshot != nil {
@@ -292,7 +411,11 @@ func (c *Base) DestroyClone(cloneID string) error {
 		return nil
 	}
 
-	go func() {
+	if c.hasQueuedOperation(cloneID) {
+		return models.New(models.ErrCodeBadRequest, "clone has a queued or running operation")
+	}
+
+	destroyClone := func() {
 		if err := c.provision.StopSession(w.Session); err != nil {
 			log.Errf("Failed to delete a clone: %+v.", err)
 
@@ -314,7 +437,23 @@ func (c *Base) DestroyClone(cloneID string) error {
 		c.observingCh <- cloneID
 
 		c.SaveClonesState()
-	}()
+
+		c.usage.destroyed(cloneID, time.Now())
+		c.saveUsage()
+	}
+
+	if !c.enqueue(&queueTask{
+		cloneID: cloneID,
+		owner:   w.Clone.DB.Username,
+		kind:    destroyOperation,
+		status: models.Status{
+			Code:    models.StatusDeleting,
+			Message: models.CloneMessageDeleting,
+		},
+		run: destroyClone,
+	}) {
+		return models.New(models.ErrCodeBadRequest, "clone has a queued or running operation")
+	}
 
 	return nil
 }
@@ -356,11 +495,38 @@ func (c *Base) UpdateClone(id string, patch types.CloneUpdateRequest) (*models.C
 		return nil, models.New(models.ErrCodeNotFound, "clone not found")
 	}
 
-	var clone *models.Clone
+	var (
+		clone        *models.Clone
+		allowedCIDRs []string
+	)
+
+	if patch.AllowedCIDRs != nil {
+		if w.Session == nil {
+			return nil, models.New(models.ErrCodeBadRequest, "clone is not started yet")
+		}
+
+		allowedCIDRs = c.allowedCIDRs(patch.AllowedCIDRs)
+
+		if err := c.provision.UpdateSessionAccess(w.Session, allowedCIDRs); err != nil {
+			return nil, errors.Wrap(err, "failed to update access rules")
+		}
+	}
 
 	// Set fields.
 	c.cloneMutex.Lock()
-	w.Clone.Protected = patch.Protected
+	if patch.Protected != nil {
+		w.Clone.Protected = *patch.Protected
+	}
+
+	if patch.Labels != nil {
+		w.Clone.Labels = patch.Labels
+		c.usage.setLabels(id, patch.Labels)
+	}
+
+	if patch.AllowedCIDRs != nil {
+		w.Clone.AllowedCIDRs = allowedCIDRs
+	}
+
 	clone = w.Clone
 	c.cloneMutex.Unlock()
 
@@ -369,6 +535,22 @@ func (c *Base) UpdateClone(id string, patch types.CloneUpdateRequest) (*models.C
 	return clone, nil
 }
 
+// allowedCIDRs returns the requested list of allowed networks or the default list if the request does not define it.
+func (c *Base) allowedCIDRs(requested []string) []string {
+	if len(requested) > 0 {
+		return requested
+	}
+
+	if len(c.config.AllowedCIDRs) == 0 {
+		return nil
+	}
+
+	allowedCIDRs := make([]string, len(c.config.AllowedCIDRs))
+	copy(allowedCIDRs, c.config.AllowedCIDRs)
+
+	return allowedCIDRs
+}
+
 // UpdateCloneStatus updates the clone status.
 func (c *Base) UpdateCloneStatus(cloneID string, status models.Status) error {
 	c.cloneMutex.Lock()
@@ -384,6 +566,27 @@ func (c *Base) UpdateCloneStatus(cloneID string, status models.Status) error {
 	return nil
 }
 
+// MarkStaleClones sets warnings of clones created from the specified snapshots and clears warnings of other clones.
+// The snapshotWarnings map contains warning messages by snapshot IDs.
+func (c *Base) MarkStaleClones(snapshotWarnings map[string]string) {
+	c.cloneMutex.Lock()
+	defer c.cloneMutex.Unlock()
+
+	for _, w := range c.clones {
+		if w.Clone == nil {
+			continue
+		}
+
+		warning := ""
+
+		if w.Clone.Snapshot != nil {
+			warning = snapshotWarnings[w.Clone.Snapshot.ID]
+		}
+
+		w.Clone.Warning = warning
+	}
+}
+
 // ResetClone resets clone to chosen snapshot.
 func (c *Base) ResetClone(cloneID string, resetOptions types.ResetCloneRequest) error {
 	w, ok := c.findWrapper(cloneID)
@@ -411,14 +614,11 @@ func (c *Base) ResetClone(cloneID string, resetOptions types.ResetCloneRequest)
 		snapshotID = w.Clone.Snapshot.ID
 	}
 
-	if err := c.UpdateCloneStatus(cloneID, models.Status{
-		Code:    models.StatusResetting,
-		Message: models.CloneMessageResetting,
-	}); err != nil {
-		return errors.Wrap(err, "failed to update clone status")
+	if c.hasQueuedOperation(cloneID) {
+		return models.New(models.ErrCodeBadRequest, "clone has a queued or running operation")
 	}
 
-	go func() {
+	resetClone := func() {
 		var originalSnapshotID string
 
 		if w.Clone.Snapshot != nil {
@@ -454,12 +654,28 @@ func (c *Base) ResetClone(cloneID string, resetOptions types.ResetCloneRequest)
 
 		c.SaveClonesState()
 
+		c.usage.reset(cloneID, time.Now())
+		c.saveUsage()
+
 		c.tm.SendEvent(context.Background(), telemetry.CloneResetEvent, telemetry.CloneCreated{
 			ID:          util.HashID(w.Clone.ID),
 			CloningTime: w.Clone.Metadata.CloningTime,
 			DSADiff:     util.GetDataFreshness(snapshot.DataStateAt),
 		})
-	}()
+	}
+
+	if !c.enqueue(&queueTask{
+		cloneID: cloneID,
+		owner:   w.Clone.DB.Username,
+		kind:    resetOperation,
+		status: models.Status{
+			Code:    models.StatusResetting,
+			Message: models.CloneMessageResetting,
+		},
+		run: resetClone,
+	}) {
+		return models.New(models.ErrCodeBadRequest, "clone has a queued or running operation")
+	}
 
 	return nil
 }
@@ -471,6 +687,8 @@ func (c *Base) GetCloningState() models.Cloning {
 		ExpectedCloningTime: c.getExpectedCloningTime(),
 		Clones:              clones,
 		NumClones:           uint64(len(clones)),
+		NumWarmClones:       uint64(c.warmPool.len()),
+		NumQueued:           uint64(c.lenQueue()),
 	}
 
 	return cloning
@@ -593,7 +811,7 @@ func (c *Base) destroyIdleClones(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		default:
-			isIdleClone, err := c.isIdleClone(cloneWrapper)
+			isIdleClone, err := c.isIdleClone(cloneWrapper, time.Duration(c.config.MaxIdleMinutes)*time.Minute)
 			if err != nil {
 				log.Errf("Failed to check the idleness of clone %s: %v.", cloneWrapper.Clone.ID, err)
 				continue
@@ -611,11 +829,65 @@ func (c *Base) destroyIdleClones(ctx context.Context) {
 	}
 }
 
+// ReclaimIdleClones destroys the oldest idle unprotected clones of the pool until their total size reaches
+// the requested amount of space or the number of destroyed clones reaches the limit. It returns the destroyed clones.
+func (c *Base) ReclaimIdleClones(poolName string, idleDuration time.Duration, spaceToFree uint64, limit int) []*models.Clone {
+	candidates := make([]*CloneWrapper, 0)
+
+	c.cloneMutex.RLock()
+
+	for _, w := range c.clones {
+		if w.Session == nil || w.Session.Pool != poolName || w.Clone.Status.Code != models.StatusOK {
+			continue
+		}
+
+		candidates = append(candidates, w)
+	}
+
+	c.cloneMutex.RUnlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].TimeCreatedAt.Before(candidates[j].TimeCreatedAt)
+	})
+
+	var (
+		freed     uint64
+		destroyed []*models.Clone
+	)
+
+	for _, w := range candidates {
+		if freed >= spaceToFree || len(destroyed) >= limit {
+			break
+		}
+
+		isIdleClone, err := c.isIdleClone(w, idleDuration)
+		if err != nil {
+			log.Errf("Failed to check the idleness of clone %s: %v.", w.Clone.ID, err)
+			continue
+		}
+
+		if !isIdleClone {
+			continue
+		}
+
+		c.refreshCloneMetadata(w)
+
+		if err := c.DestroyClone(w.Clone.ID); err != nil {
+			log.Errf("Failed to destroy clone: %+v.", err)
+			continue
+		}
+
+		freed += w.Clone.Metadata.CloneDiffSize
+		destroyed = append(destroyed, w.Clone)
+	}
+
+	return destroyed
+}
+
 // isIdleClone checks if clone is idle.
-func (c *Base) isIdleClone(wrapper *CloneWrapper) (bool, error) {
+func (c *Base) isIdleClone(wrapper *CloneWrapper, idleDuration time.Duration) (bool, error) {
 	currentTime := time.Now()
 
-	idleDuration := time.Duration(c.config.MaxIdleMinutes) * time.Minute
 	minimumTime := currentTime.Add(-idleDuration)
 
 	if wrapper.Clone.Protected || wrapper.Clone.Status.Code == models.StatusExporting || wrapper.TimeStartedAt.After(minimumTime) {
@@ -632,6 +904,11 @@ func (c *Base) isIdleClone(wrapper *CloneWrapper) (bool, error) {
 		return false, errors.New("failed to get clone session")
 	}
 
+	return c.isSessionIdle(session, minimumTime)
+}
+
+// isSessionIdle checks if the session has no activity since the minimum time.
+func (c *Base) isSessionIdle(session *resources.Session, minimumTime time.Time) (bool, error) {
 	if _, err := c.provision.LastSessionActivity(session, minimumTime); err != nil {
 		if err == pglog.ErrNotFound {
 			log.Dbg(fmt.Sprintf("Not found recent activity for the session: %q. Clone name: %q",
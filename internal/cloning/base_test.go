@@ -5,12 +5,15 @@
 package cloning
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/client/dblabapi/types"
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
 )
 
@@ -117,6 +120,39 @@ func (s *BaseCloningSuite) TestDeleteClone() {
 	assert.Nil(s.T(), wrapper)
 }
 
+func (s *BaseCloningSuite) TestUpdateCloneKeepsProtection() {
+	s.cloning.usage = &usageLedger{}
+	s.cloning.setWrapper("testCloneID", &CloneWrapper{Clone: &models.Clone{ID: "testCloneID", Protected: true}})
+
+	clone, err := s.cloning.UpdateClone("testCloneID", types.CloneUpdateRequest{Labels: map[string]string{"team": "qa"}})
+	require.NoError(s.T(), err)
+	assert.True(s.T(), clone.Protected)
+	assert.Equal(s.T(), map[string]string{"team": "qa"}, clone.Labels)
+
+	protected := false
+
+	clone, err = s.cloning.UpdateClone("testCloneID", types.CloneUpdateRequest{Protected: &protected})
+	require.NoError(s.T(), err)
+	assert.False(s.T(), clone.Protected)
+	assert.Equal(s.T(), map[string]string{"team": "qa"}, clone.Labels)
+}
+
+func (s *BaseCloningSuite) TestConnectToCloneRejectsSuperuser() {
+	s.cloning.setWrapper("testCloneID", &CloneWrapper{
+		Clone:   &models.Clone{ID: "testCloneID", Status: models.Status{Code: models.StatusOK}},
+		Session: &resources.Session{EphemeralUser: resources.EphemeralUser{Name: "john", Restricted: false}},
+	})
+
+	conn, err := s.cloning.ConnectToCloneAsUser(context.Background(), "testCloneID")
+	require.Error(s.T(), err)
+	assert.Nil(s.T(), conn)
+
+	var modelErr *models.Error
+
+	require.ErrorAs(s.T(), err, &modelErr)
+	assert.Equal(s.T(), models.ErrCodeBadRequest, modelErr.Code)
+}
+
 func (s *BaseCloningSuite) TestLenClones() {
 	lenClones := s.cloning.lenClones()
 	assert.Equal(s.T(), 0, lenClones)
@@ -132,3 +168,20 @@ func (s *BaseCloningSuite) TestLenClones() {
 	lenClones = s.cloning.lenClones()
 	assert.Equal(s.T(), 1, lenClones)
 }
+
+func (s *BaseCloningSuite) TestMarkStaleClones() {
+	staleClone := &models.Clone{ID: "staleClone", Snapshot: &models.Snapshot{ID: "pool@snapshot_20220501000000"}}
+	freshClone := &models.Clone{ID: "freshClone", Snapshot: &models.Snapshot{ID: "pool@snapshot_20220509000000"}, Warning: "outdated"}
+
+	s.cloning.setWrapper(staleClone.ID, &CloneWrapper{Clone: staleClone})
+	s.cloning.setWrapper(freshClone.ID, &CloneWrapper{Clone: freshClone})
+
+	s.cloning.MarkStaleClones(map[string]string{"pool@snapshot_20220501000000": "Snapshot data is 9d 0h old (threshold: 7d 0h)"})
+
+	assert.Equal(s.T(), "Snapshot data is 9d 0h old (threshold: 7d 0h)", staleClone.Warning)
+	assert.Empty(s.T(), freshClone.Warning)
+
+	s.cloning.MarkStaleClones(nil)
+
+	assert.Empty(s.T(), staleClone.Warning)
+}
@@ -0,0 +1,169 @@
+/*
+2022 © Postgres.ai
+*/
+
+package cloning
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+// queryCanceledCode is the SQLSTATE code of a canceled query.
+const queryCanceledCode = "57014"
+
+// QueryOptions defines options of an ad-hoc query.
+type QueryOptions struct {
+	Query            string
+	StatementTimeout time.Duration
+	RowLimit         int
+}
+
+// RunQuery runs an ad-hoc query in the clone as the ephemeral user of the clone.
+// The query is canceled when the context is done or when the row limit is exceeded.
+func (c *Base) RunQuery(ctx context.Context, cloneID string, opts QueryOptions) (*models.QueryResult, error) {
+	conn, err := c.connectForQuery(ctx, cloneID, opts.StatementTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	defer closeConn(conn)
+
+	startedAt := time.Now()
+
+	// Values are requested in the text format, so they are returned as is.
+	resultReader := conn.PgConn().ExecParams(ctx, opts.Query, nil, nil, nil, nil)
+
+	result := &models.QueryResult{
+		Columns: []models.QueryColumn{},
+		Rows:    [][]*string{},
+	}
+
+	for _, field := range resultReader.FieldDescriptions() {
+		column := models.QueryColumn{Name: string(field.Name)}
+
+		if dataType, ok := conn.ConnInfo().DataTypeForOID(field.DataTypeOID); ok {
+			column.Type = dataType.Name
+		}
+
+		result.Columns = append(result.Columns, column)
+	}
+
+	for resultReader.NextRow() {
+		if len(result.Rows) >= opts.RowLimit {
+			result.Truncated = true
+			break
+		}
+
+		result.Rows = append(result.Rows, textValues(resultReader.Values()))
+	}
+
+	result.RowCount = len(result.Rows)
+
+	if result.Truncated {
+		// Closing the reader drains the rest of the result, so the query is canceled on the server first.
+		if err := conn.PgConn().CancelRequest(ctx); err != nil {
+			log.Err("Failed to cancel query:", err)
+		}
+	}
+
+	commandTag, err := resultReader.Close()
+	if err != nil {
+		if result.Truncated && isQueryCanceled(err) {
+			result.Duration = time.Since(startedAt).Seconds()
+
+			return result, nil
+		}
+
+		return nil, models.New(models.ErrCodeBadRequest, err.Error())
+	}
+
+	result.CommandTag = commandTag.String()
+	result.Duration = time.Since(startedAt).Seconds()
+
+	return result, nil
+}
+
+// ExplainQuery runs EXPLAIN (ANALYZE, BUFFERS) for the query in the clone as the ephemeral user of the clone.
+// The query runs inside a transaction which is rolled back, so data modifications are discarded.
+func (c *Base) ExplainQuery(ctx context.Context, cloneID string, opts QueryOptions) (*models.QueryPlan, error) {
+	conn, err := c.connectForQuery(ctx, cloneID, opts.StatementTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	defer closeConn(conn)
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to begin transaction")
+	}
+
+	defer func() {
+		if err := tx.Rollback(context.Background()); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			log.Err("Failed to rollback transaction:", err)
+		}
+	}()
+
+	var plan []byte
+
+	if err := tx.QueryRow(ctx, "explain (analyze, buffers, format json) "+opts.Query).Scan(&plan); err != nil {
+		return nil, models.New(models.ErrCodeBadRequest, err.Error())
+	}
+
+	return &models.QueryPlan{Plan: json.RawMessage(plan)}, nil
+}
+
+func (c *Base) connectForQuery(ctx context.Context, cloneID string, statementTimeout time.Duration) (*pgx.Conn, error) {
+	conn, err := c.ConnectToCloneAsUser(ctx, cloneID)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := fmt.Sprintf("%dms", statementTimeout.Milliseconds())
+
+	if _, err := conn.Exec(ctx, "select set_config('statement_timeout', $1, false)", timeout); err != nil {
+		closeConn(conn)
+
+		return nil, errors.Wrap(err, "failed to set statement timeout")
+	}
+
+	return conn, nil
+}
+
+func isQueryCanceled(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == queryCanceledCode
+}
+
+func closeConn(conn *pgx.Conn) {
+	if err := conn.Close(context.Background()); err != nil {
+		log.Err("Failed to close connection:", err)
+	}
+}
+
+func textValues(values [][]byte) []*string {
+	row := make([]*string, 0, len(values))
+
+	for _, value := range values {
+		if value == nil {
+			row = append(row, nil)
+			continue
+		}
+
+		text := string(value)
+		row = append(row, &text)
+	}
+
+	return row
+}
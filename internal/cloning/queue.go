@@ -0,0 +1,312 @@
+/*
+2022 © Postgres.ai
+*/
+
+package cloning
+
+import (
+	"fmt"
+	"sync"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+// Kinds of clone operations.
+const (
+	createOperation  = "create"
+	resetOperation   = "reset"
+	destroyOperation = "destroy"
+)
+
+// Queue defines options of the queue of clone operations.
+type Queue struct {
+	// Concurrency limits the number of clones that are being created, reset or destroyed at the same time.
+	// Zero means no limit.
+	Concurrency uint `yaml:"concurrency"`
+
+	// CreateConcurrency, ResetConcurrency and DestroyConcurrency limit operations of each kind. Zero means no limit.
+	CreateConcurrency  uint `yaml:"createConcurrency"`
+	ResetConcurrency   uint `yaml:"resetConcurrency"`
+	DestroyConcurrency uint `yaml:"destroyConcurrency"`
+}
+
+// kindConcurrency returns the limit of running operations of the kind.
+func (q Queue) kindConcurrency(kind string) uint {
+	switch kind {
+	case createOperation:
+		return q.CreateConcurrency
+
+	case resetOperation:
+		return q.ResetConcurrency
+
+	case destroyOperation:
+		return q.DestroyConcurrency
+	}
+
+	return 0
+}
+
+// queueTask describes a clone operation waiting for its turn.
+type queueTask struct {
+	cloneID string
+	owner   string
+	kind    string
+	status  models.Status
+	run     func()
+}
+
+// operationQueue keeps pending clone operations and serves them round-robin across owners,
+// so one user cannot starve the others.
+type operationQueue struct {
+	mu      sync.Mutex
+	running map[string]int
+	active  map[string]struct{}
+	owners  []string
+	tasks   map[string][]*queueTask
+}
+
+func newOperationQueue() operationQueue {
+	return operationQueue{
+		running: make(map[string]int),
+		active:  make(map[string]struct{}),
+		tasks:   make(map[string][]*queueTask),
+	}
+}
+
+// push adds a task to the end of the owner's queue.
+func (q *operationQueue) push(task *queueTask) {
+	if len(q.tasks[task.owner]) == 0 {
+		q.owners = append(q.owners, task.owner)
+	}
+
+	q.tasks[task.owner] = append(q.tasks[task.owner], task)
+}
+
+// pop takes the next task that can run and moves its owner to the end of the round.
+// Owners whose next task cannot run keep their turn. A nil canRun allows any task.
+func (q *operationQueue) pop(canRun func(task *queueTask) bool) *queueTask {
+	for i, owner := range q.owners {
+		tasks := q.tasks[owner]
+		task := tasks[0]
+
+		if canRun != nil && !canRun(task) {
+			continue
+		}
+
+		q.owners = append(q.owners[:i:i], q.owners[i+1:]...)
+
+		if len(tasks) > 1 {
+			q.tasks[owner] = tasks[1:]
+			q.owners = append(q.owners, owner)
+		} else {
+			delete(q.tasks, owner)
+		}
+
+		return task
+	}
+
+	return nil
+}
+
+// start marks the task as running.
+func (q *operationQueue) start(task *queueTask) {
+	q.running[task.kind]++
+	q.active[task.cloneID] = struct{}{}
+}
+
+// finish marks the task as completed.
+func (q *operationQueue) finish(task *queueTask) {
+	q.running[task.kind]--
+	delete(q.active, task.cloneID)
+}
+
+// totalRunning returns the number of running operations of all kinds.
+func (q *operationQueue) totalRunning() int {
+	total := 0
+
+	for _, count := range q.running {
+		total += count
+	}
+
+	return total
+}
+
+// isRunning checks if an operation of the clone is running.
+func (q *operationQueue) isRunning(cloneID string) bool {
+	_, ok := q.active[cloneID]
+
+	return ok
+}
+
+// remove removes a pending task of the clone.
+func (q *operationQueue) remove(cloneID string) bool {
+	for owner, tasks := range q.tasks {
+		for i, task := range tasks {
+			if task.cloneID != cloneID {
+				continue
+			}
+
+			q.tasks[owner] = append(tasks[:i:i], tasks[i+1:]...)
+
+			if len(q.tasks[owner]) == 0 {
+				delete(q.tasks, owner)
+				q.removeOwner(owner)
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q *operationQueue) removeOwner(owner string) {
+	for i, name := range q.owners {
+		if name == owner {
+			q.owners = append(q.owners[:i:i], q.owners[i+1:]...)
+			return
+		}
+	}
+}
+
+// contains checks if the clone has a pending task.
+func (q *operationQueue) contains(cloneID string) bool {
+	for _, tasks := range q.tasks {
+		for _, task := range tasks {
+			if task.cloneID == cloneID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// pending returns pending tasks in the order they will be served.
+func (q *operationQueue) pending() []*queueTask {
+	var list []*queueTask
+
+	for round := 0; ; round++ {
+		found := false
+
+		for _, owner := range q.owners {
+			if tasks := q.tasks[owner]; round < len(tasks) {
+				list = append(list, tasks[round])
+				found = true
+			}
+		}
+
+		if !found {
+			return list
+		}
+	}
+}
+
+// enqueue adds a clone operation to the queue and runs it as soon as there is a free slot.
+// It returns false if the clone already has a queued or running operation.
+func (c *Base) enqueue(task *queueTask) bool {
+	c.queue.mu.Lock()
+
+	if c.queue.contains(task.cloneID) || c.queue.isRunning(task.cloneID) {
+		c.queue.mu.Unlock()
+		return false
+	}
+
+	c.queue.push(task)
+	c.queue.mu.Unlock()
+
+	c.dispatchQueue()
+
+	return true
+}
+
+// dequeue removes a pending operation of the clone from the queue.
+func (c *Base) dequeue(cloneID string) bool {
+	c.queue.mu.Lock()
+	removed := c.queue.remove(cloneID)
+	c.queue.mu.Unlock()
+
+	if removed {
+		c.dispatchQueue()
+	}
+
+	return removed
+}
+
+// hasQueuedOperation checks if the clone has an operation waiting in the queue or running.
+func (c *Base) hasQueuedOperation(cloneID string) bool {
+	c.queue.mu.Lock()
+	defer c.queue.mu.Unlock()
+
+	return c.queue.contains(cloneID) || c.queue.isRunning(cloneID)
+}
+
+// hasRunningOperation checks if an operation of the clone is running.
+func (c *Base) hasRunningOperation(cloneID string) bool {
+	c.queue.mu.Lock()
+	defer c.queue.mu.Unlock()
+
+	return c.queue.isRunning(cloneID)
+}
+
+// dispatchQueue starts pending operations within the concurrency limit and updates positions of the rest.
+func (c *Base) dispatchQueue() {
+	c.queue.mu.Lock()
+	defer c.queue.mu.Unlock()
+
+	limit := int(c.config.Queue.Concurrency)
+
+	canRun := func(task *queueTask) bool {
+		kindLimit := int(c.config.Queue.kindConcurrency(task.kind))
+
+		return kindLimit == 0 || c.queue.running[task.kind] < kindLimit
+	}
+
+	for limit == 0 || c.queue.totalRunning() < limit {
+		task := c.queue.pop(canRun)
+		if task == nil {
+			break
+		}
+
+		c.queue.start(task)
+
+		c.setQueuedCloneStatus(task.cloneID, task.status)
+
+		go c.runQueueTask(task)
+	}
+
+	for i, task := range c.queue.pending() {
+		c.setQueuedCloneStatus(task.cloneID, models.Status{
+			Code:    models.StatusQueued,
+			Message: fmt.Sprintf(models.CloneMessageQueued, i+1),
+		})
+	}
+}
+
+func (c *Base) runQueueTask(task *queueTask) {
+	task.run()
+
+	c.queue.mu.Lock()
+	c.queue.finish(task)
+	c.queue.mu.Unlock()
+
+	c.dispatchQueue()
+}
+
+// setQueuedCloneStatus sets the clone status if the clone still exists.
+func (c *Base) setQueuedCloneStatus(cloneID string, status models.Status) {
+	c.cloneMutex.Lock()
+	defer c.cloneMutex.Unlock()
+
+	if w, ok := c.clones[cloneID]; ok {
+		w.Clone.Status = status
+	}
+}
+
+// lenQueue returns the number of pending operations.
+func (c *Base) lenQueue() int {
+	c.queue.mu.Lock()
+	defer c.queue.mu.Unlock()
+
+	return len(c.queue.pending())
+}
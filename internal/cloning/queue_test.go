@@ -0,0 +1,110 @@
+package cloning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationQueueRoundRobin(t *testing.T) {
+	q := newOperationQueue()
+
+	q.push(&queueTask{cloneID: "ci-1", owner: "ci"})
+	q.push(&queueTask{cloneID: "ci-2", owner: "ci"})
+	q.push(&queueTask{cloneID: "ci-3", owner: "ci"})
+	q.push(&queueTask{cloneID: "john-1", owner: "john"})
+	q.push(&queueTask{cloneID: "alice-1", owner: "alice"})
+	q.push(&queueTask{cloneID: "alice-2", owner: "alice"})
+
+	assert.Equal(t, []string{"ci-1", "john-1", "alice-1", "ci-2", "alice-2", "ci-3"}, cloneIDs(q.pending()))
+
+	assert.Equal(t, "ci-1", q.pop(nil).cloneID)
+	assert.Equal(t, "john-1", q.pop(nil).cloneID)
+
+	q.push(&queueTask{cloneID: "john-2", owner: "john"})
+
+	assert.Equal(t, []string{"alice-1", "ci-2", "john-2", "alice-2", "ci-3"}, cloneIDs(q.pending()))
+
+	var served []string
+
+	for task := q.pop(nil); task != nil; task = q.pop(nil) {
+		served = append(served, task.cloneID)
+	}
+
+	assert.Equal(t, []string{"alice-1", "ci-2", "john-2", "alice-2", "ci-3"}, served)
+	assert.Empty(t, q.pending())
+}
+
+func TestOperationQueueRemove(t *testing.T) {
+	q := newOperationQueue()
+
+	q.push(&queueTask{cloneID: "ci-1", owner: "ci"})
+	q.push(&queueTask{cloneID: "ci-2", owner: "ci"})
+	q.push(&queueTask{cloneID: "john-1", owner: "john"})
+
+	assert.True(t, q.contains("john-1"))
+	assert.True(t, q.remove("john-1"))
+	assert.False(t, q.contains("john-1"))
+	assert.False(t, q.remove("john-1"))
+
+	assert.True(t, q.remove("ci-1"))
+	assert.Equal(t, []string{"ci-2"}, cloneIDs(q.pending()))
+	assert.Equal(t, []string{"ci"}, q.owners)
+}
+
+func TestOperationQueueKindLimits(t *testing.T) {
+	q := newOperationQueue()
+
+	q.push(&queueTask{cloneID: "ci-1", owner: "ci", kind: destroyOperation})
+	q.push(&queueTask{cloneID: "john-1", owner: "john", kind: createOperation})
+
+	cfg := Queue{DestroyConcurrency: 1}
+	canRun := func(task *queueTask) bool {
+		limit := int(cfg.kindConcurrency(task.kind))
+
+		return limit == 0 || q.running[task.kind] < limit
+	}
+
+	q.start(&queueTask{cloneID: "alice-1", owner: "alice", kind: destroyOperation})
+
+	// The destroy limit is reached, so the owner keeps the turn for the next free slot.
+	assert.Equal(t, "john-1", q.pop(canRun).cloneID)
+	assert.Nil(t, q.pop(canRun))
+	assert.Equal(t, []string{"ci"}, q.owners)
+
+	q.finish(&queueTask{cloneID: "alice-1", owner: "alice", kind: destroyOperation})
+	assert.Equal(t, "ci-1", q.pop(canRun).cloneID)
+}
+
+func TestBaseEnqueueRejectsDuplicates(t *testing.T) {
+	c := &Base{config: &Config{Queue: Queue{Concurrency: 1}}, clones: make(map[string]*CloneWrapper), queue: newOperationQueue()}
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+
+	require.True(t, c.enqueue(&queueTask{cloneID: "clone1", kind: resetOperation, run: func() {
+		<-release
+		close(finished)
+	}}))
+
+	// The first reset is running, so another operation of the clone cannot be queued.
+	assert.True(t, c.hasRunningOperation("clone1"))
+	assert.False(t, c.enqueue(&queueTask{cloneID: "clone1", kind: destroyOperation, run: func() {}}))
+
+	require.True(t, c.enqueue(&queueTask{cloneID: "clone2", kind: destroyOperation, run: func() {}}))
+	assert.False(t, c.enqueue(&queueTask{cloneID: "clone2", kind: resetOperation, run: func() {}}))
+
+	close(release)
+	<-finished
+}
+
+func cloneIDs(tasks []*queueTask) []string {
+	ids := make([]string, 0, len(tasks))
+
+	for _, task := range tasks {
+		ids = append(ids, task.cloneID)
+	}
+
+	return ids
+}
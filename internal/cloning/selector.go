@@ -0,0 +1,164 @@
+/*
+2022 © Postgres.ai
+*/
+
+package cloning
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+// selectorOperator defines how a label requirement is checked.
+type selectorOperator string
+
+const (
+	selectorEquals    selectorOperator = "="
+	selectorNotEquals selectorOperator = "!="
+	selectorExists    selectorOperator = "exists"
+	selectorNotExists selectorOperator = "!exists"
+)
+
+// labelRequirement describes a single condition of a label selector.
+type labelRequirement struct {
+	key      string
+	operator selectorOperator
+	value    string
+}
+
+// Selector selects clones by their labels. All requirements must be satisfied.
+type Selector struct {
+	requirements []labelRequirement
+}
+
+// ParseSelector parses a comma-separated label selector.
+// Supported requirements: "key=value", "key==value", "key!=value", "key" (the label exists) and "!key" (the label is absent).
+func ParseSelector(selector string) (Selector, error) {
+	var s Selector
+
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		requirement, err := parseRequirement(part)
+		if err != nil {
+			return Selector{}, err
+		}
+
+		s.requirements = append(s.requirements, requirement)
+	}
+
+	return s, nil
+}
+
+func parseRequirement(part string) (labelRequirement, error) {
+	var requirement labelRequirement
+
+	switch {
+	case strings.Contains(part, "!="):
+		kv := strings.SplitN(part, "!=", 2)
+		requirement = labelRequirement{key: kv[0], operator: selectorNotEquals, value: kv[1]}
+
+	case strings.Contains(part, "=="):
+		kv := strings.SplitN(part, "==", 2)
+		requirement = labelRequirement{key: kv[0], operator: selectorEquals, value: kv[1]}
+
+	case strings.Contains(part, "="):
+		kv := strings.SplitN(part, "=", 2)
+		requirement = labelRequirement{key: kv[0], operator: selectorEquals, value: kv[1]}
+
+	case strings.HasPrefix(part, "!"):
+		requirement = labelRequirement{key: strings.TrimPrefix(part, "!"), operator: selectorNotExists}
+
+	default:
+		requirement = labelRequirement{key: part, operator: selectorExists}
+	}
+
+	requirement.key = strings.TrimSpace(requirement.key)
+	requirement.value = strings.TrimSpace(requirement.value)
+
+	if requirement.key == "" {
+		return labelRequirement{}, fmt.Errorf("invalid label selector %q: empty label key", part)
+	}
+
+	return requirement, nil
+}
+
+// Empty checks if the selector has no requirements.
+func (s Selector) Empty() bool {
+	return len(s.requirements) == 0
+}
+
+// Matches checks if labels satisfy the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, requirement := range s.requirements {
+		value, ok := labels[requirement.key]
+
+		switch requirement.operator {
+		case selectorEquals:
+			if !ok || value != requirement.value {
+				return false
+			}
+
+		case selectorNotEquals:
+			if ok && value == requirement.value {
+				return false
+			}
+
+		case selectorExists:
+			if !ok {
+				return false
+			}
+
+		case selectorNotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// CloneFilter defines criteria to select clones.
+type CloneFilter struct {
+	Selector   Selector
+	Status     models.StatusCode
+	SnapshotID string
+}
+
+// Empty checks if the filter selects all clones.
+func (f CloneFilter) Empty() bool {
+	return f.Selector.Empty() && f.Status == "" && f.SnapshotID == ""
+}
+
+// Matches checks if the clone satisfies the filter.
+func (f CloneFilter) Matches(clone *models.Clone) bool {
+	if f.Status != "" && clone.Status.Code != f.Status {
+		return false
+	}
+
+	if f.SnapshotID != "" && (clone.Snapshot == nil || clone.Snapshot.ID != f.SnapshotID) {
+		return false
+	}
+
+	return f.Selector.Matches(clone.Labels)
+}
+
+// FilterClones returns clones matching the filter descend ordered by creation time.
+func (c *Base) FilterClones(filter CloneFilter) []*models.Clone {
+	clones := c.GetClones()
+	filtered := make([]*models.Clone, 0, len(clones))
+
+	for _, clone := range clones {
+		if filter.Matches(clone) {
+			filtered = append(filtered, clone)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,61 @@
+package cloning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"team": "payments", "env": "ci"}
+
+	testCases := []struct {
+		selector string
+		matches  bool
+	}{
+		{selector: "", matches: true},
+		{selector: "team=payments", matches: true},
+		{selector: "team==payments,env=ci", matches: true},
+		{selector: "team=payments,env=staging", matches: false},
+		{selector: "team!=search", matches: true},
+		{selector: "team!=payments", matches: false},
+		{selector: "env", matches: true},
+		{selector: "owner", matches: false},
+		{selector: "!owner", matches: true},
+		{selector: "!env", matches: false},
+		{selector: " team = payments ", matches: true},
+	}
+
+	for _, tc := range testCases {
+		selector, err := ParseSelector(tc.selector)
+		require.NoError(t, err)
+
+		assert.Equal(t, tc.matches, selector.Matches(labels), tc.selector)
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, selector := range []string{"=payments", "!", "team=payments,!=ci"} {
+		_, err := ParseSelector(selector)
+		assert.Error(t, err, selector)
+	}
+}
+
+func TestCloneFilterMatches(t *testing.T) {
+	clone := &models.Clone{
+		Status:   models.Status{Code: models.StatusOK},
+		Snapshot: &models.Snapshot{ID: "snapshot1"},
+		Labels:   map[string]string{"team": "payments"},
+	}
+
+	selector, err := ParseSelector("team=payments")
+	require.NoError(t, err)
+
+	assert.True(t, CloneFilter{}.Empty())
+	assert.True(t, CloneFilter{Selector: selector, Status: models.StatusOK, SnapshotID: "snapshot1"}.Matches(clone))
+	assert.False(t, CloneFilter{Status: models.StatusFatal}.Matches(clone))
+	assert.False(t, CloneFilter{SnapshotID: "snapshot2"}.Matches(clone))
+}
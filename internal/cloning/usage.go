@@ -0,0 +1,452 @@
+/*
+2022 © Postgres.ai
+*/
+
+package cloning
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/util"
+)
+
+const (
+	usageFilename = "usage.json"
+
+	// usageSampleInterval defines how often clone sizes and activity are sampled.
+	usageSampleInterval = 15 * time.Minute
+
+	// usageRetention defines how long usage of destroyed clones is kept.
+	usageRetention = 400 * 24 * time.Hour
+)
+
+// usageEventType defines a clone lifecycle event.
+type usageEventType string
+
+const (
+	usageCreated   usageEventType = "created"
+	usageReset     usageEventType = "reset"
+	usageDestroyed usageEventType = "destroyed"
+)
+
+// usageEvent describes a clone lifecycle event.
+type usageEvent struct {
+	Time time.Time      `json:"time"`
+	Type usageEventType `json:"type"`
+}
+
+// usageSample describes the state of a clone at a point in time.
+// Idle reports whether the clone had no activity since the previous sample.
+type usageSample struct {
+	Time     time.Time `json:"time"`
+	DiffSize uint64    `json:"diffSize"`
+	Idle     bool      `json:"idle"`
+}
+
+// usageRecord keeps the lifecycle of a single clone.
+type usageRecord struct {
+	CloneID     string            `json:"cloneID"`
+	Username    string            `json:"username"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	DestroyedAt *time.Time        `json:"destroyedAt,omitempty"`
+	Events      []usageEvent      `json:"events"`
+	Samples     []usageSample     `json:"samples"`
+}
+
+// usageLedger records clone lifecycle intervals for usage reports.
+type usageLedger struct {
+	mu      sync.Mutex
+	records []*usageRecord
+}
+
+// activeRecord returns the record of a running clone. Clone IDs may be reused after a clone is destroyed.
+// It's not safe to invoke without the ledger mutex locking.
+func (l *usageLedger) activeRecord(cloneID string) *usageRecord {
+	for i := len(l.records) - 1; i >= 0; i-- {
+		if l.records[i].CloneID == cloneID && l.records[i].DestroyedAt == nil {
+			return l.records[i]
+		}
+	}
+
+	return nil
+}
+
+func (l *usageLedger) created(clone *models.Clone, createdAt time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, &usageRecord{
+		CloneID:   clone.ID,
+		Username:  clone.DB.Username,
+		Labels:    copyLabels(clone.Labels),
+		CreatedAt: createdAt,
+		Events:    []usageEvent{{Time: createdAt, Type: usageCreated}},
+	})
+}
+
+func (l *usageLedger) reset(cloneID string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record := l.activeRecord(cloneID); record != nil {
+		record.Events = append(record.Events, usageEvent{Time: at, Type: usageReset})
+	}
+}
+
+func (l *usageLedger) destroyed(cloneID string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record := l.activeRecord(cloneID); record != nil {
+		record.Events = append(record.Events, usageEvent{Time: at, Type: usageDestroyed})
+		record.DestroyedAt = &at
+	}
+}
+
+func (l *usageLedger) setLabels(cloneID string, labels map[string]string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record := l.activeRecord(cloneID); record != nil {
+		record.Labels = copyLabels(labels)
+	}
+}
+
+func (l *usageLedger) addSample(cloneID string, sample usageSample) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record := l.activeRecord(cloneID); record != nil {
+		record.Samples = append(record.Samples, sample)
+	}
+}
+
+// closeMissing marks records of clones that no longer exist as destroyed.
+func (l *usageLedger) closeMissing(existing map[string]struct{}, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, record := range l.records {
+		if _, ok := existing[record.CloneID]; ok || record.DestroyedAt != nil {
+			continue
+		}
+
+		destroyedAt := at
+		record.Events = append(record.Events, usageEvent{Time: at, Type: usageDestroyed})
+		record.DestroyedAt = &destroyedAt
+	}
+}
+
+// prune removes records and samples older than the retention period.
+func (l *usageLedger) prune(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	threshold := now.Add(-usageRetention)
+	records := l.records[:0]
+
+	for _, record := range l.records {
+		if record.DestroyedAt != nil && record.DestroyedAt.Before(threshold) {
+			continue
+		}
+
+		firstSample := sort.Search(len(record.Samples), func(i int) bool {
+			return !record.Samples[i].Time.Before(threshold)
+		})
+
+		record.Samples = record.Samples[firstSample:]
+		records = append(records, record)
+	}
+
+	l.records = records
+}
+
+func (l *usageLedger) load(filename string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to read usage data: %w", err)
+	}
+
+	return json.Unmarshal(data, &l.records)
+}
+
+func (l *usageLedger) save(filename string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := json.Marshal(l.records)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage data: %w", err)
+	}
+
+	return os.WriteFile(filename, data, 0600)
+}
+
+// report builds a usage report for the period.
+func (l *usageLedger) report(from, to time.Time, groupBy models.UsageGroupBy, label string) *models.UsageReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	groups := make(map[string]*usageAccumulator)
+
+	for _, record := range l.records {
+		end := to
+		if record.DestroyedAt != nil && record.DestroyedAt.Before(to) {
+			end = *record.DestroyedAt
+		}
+
+		if !record.CreatedAt.Before(to) || !end.After(from) {
+			continue
+		}
+
+		key := record.Username
+		if groupBy == models.UsageGroupByLabel {
+			key = record.Labels[label]
+		}
+
+		acc, ok := groups[key]
+		if !ok {
+			acc = &usageAccumulator{group: models.UsageGroup{Key: key}}
+			groups[key] = acc
+		}
+
+		acc.add(record, from, to, end)
+	}
+
+	report := &models.UsageReport{
+		From:    from,
+		To:      to,
+		GroupBy: groupBy,
+		Groups:  make([]models.UsageGroup, 0, len(groups)),
+	}
+
+	if groupBy == models.UsageGroupByLabel {
+		report.Label = label
+	}
+
+	for _, acc := range groups {
+		report.Groups = append(report.Groups, acc.result())
+	}
+
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if report.Groups[i].CloneHours != report.Groups[j].CloneHours {
+			return report.Groups[i].CloneHours > report.Groups[j].CloneHours
+		}
+
+		return report.Groups[i].Key < report.Groups[j].Key
+	})
+
+	return report
+}
+
+// usageAccumulator sums usage of the clones of a group.
+type usageAccumulator struct {
+	group       models.UsageGroup
+	cloneTime   time.Duration
+	idleTime    time.Duration
+	sizeSum     uint64
+	sizeSamples uint64
+}
+
+// add accounts the part of the clone lifecycle within [from, to). The clone exists until the end time.
+func (a *usageAccumulator) add(record *usageRecord, from, to, end time.Time) {
+	a.group.Clones++
+	a.cloneTime += overlap(record.CreatedAt, end, from, to)
+
+	for _, event := range record.Events {
+		if event.Time.Before(from) || !event.Time.Before(to) {
+			continue
+		}
+
+		switch event.Type {
+		case usageCreated:
+			a.group.Created++
+
+		case usageReset:
+			a.group.Resets++
+
+		case usageDestroyed:
+			a.group.Destroyed++
+		}
+	}
+
+	previous := record.CreatedAt
+
+	for _, sample := range record.Samples {
+		if sample.Idle {
+			a.idleTime += overlap(previous, sample.Time, from, to)
+		}
+
+		previous = sample.Time
+
+		if sample.Time.Before(from) || !sample.Time.Before(to) {
+			continue
+		}
+
+		a.sizeSum += sample.DiffSize
+		a.sizeSamples++
+
+		if sample.DiffSize > a.group.PeakDiffSize {
+			a.group.PeakDiffSize = sample.DiffSize
+		}
+	}
+}
+
+func (a *usageAccumulator) result() models.UsageGroup {
+	group := a.group
+	group.CloneHours = roundHours(a.cloneTime)
+	group.IdleHours = roundHours(a.idleTime)
+
+	if a.sizeSamples > 0 {
+		group.AvgDiffSize = a.sizeSum / a.sizeSamples
+	}
+
+	return group
+}
+
+// overlap returns the duration of the intersection of [start, end) and [from, to).
+func overlap(start, end, from, to time.Time) time.Duration {
+	if start.Before(from) {
+		start = from
+	}
+
+	if end.After(to) {
+		end = to
+	}
+
+	if !end.After(start) {
+		return 0
+	}
+
+	return end.Sub(start)
+}
+
+func roundHours(d time.Duration) float64 {
+	const precision = 100
+
+	return float64(int64(d.Hours()*precision+0.5)) / precision
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	copied := make(map[string]string, len(labels))
+	for key, value := range labels {
+		copied[key] = value
+	}
+
+	return copied
+}
+
+// UsageReport returns clone usage for the period grouped by users or by values of the label.
+func (c *Base) UsageReport(from, to time.Time, groupBy models.UsageGroupBy, label string) *models.UsageReport {
+	return c.usage.report(from, to, groupBy, label)
+}
+
+// restoreUsage loads usage records and closes records of clones that have disappeared while the engine was stopped.
+func (c *Base) restoreUsage() {
+	usagePath, err := util.GetMetaPath(usageFilename)
+	if err != nil {
+		log.Err("Failed to get path of a usage file", err)
+		return
+	}
+
+	if err := c.usage.load(usagePath); err != nil {
+		log.Err("Failed to load clone usage", err)
+	}
+
+	existing := make(map[string]struct{})
+
+	c.cloneMutex.RLock()
+	for cloneID := range c.clones {
+		existing[cloneID] = struct{}{}
+	}
+	c.cloneMutex.RUnlock()
+
+	c.usage.closeMissing(existing, time.Now())
+	c.saveUsage()
+}
+
+func (c *Base) saveUsage() {
+	usagePath, err := util.GetMetaPath(usageFilename)
+	if err != nil {
+		log.Err("Failed to get path of a usage file", err)
+		return
+	}
+
+	if err := c.usage.save(usagePath); err != nil {
+		log.Err("Failed to save clone usage", err)
+	}
+}
+
+func (c *Base) runUsageSampling(ctx context.Context) {
+	ticker := time.NewTicker(usageSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.sampleUsage(ctx)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sampleUsage records sizes and activity of running clones.
+func (c *Base) sampleUsage(ctx context.Context) {
+	wrappers := make([]*CloneWrapper, 0)
+
+	c.cloneMutex.RLock()
+
+	for _, w := range c.clones {
+		if w.Session != nil && w.Clone.Status.Code == models.StatusOK {
+			wrappers = append(wrappers, w)
+		}
+	}
+
+	c.cloneMutex.RUnlock()
+
+	now := time.Now()
+
+	for _, w := range wrappers {
+		if ctx.Err() != nil {
+			return
+		}
+
+		c.refreshCloneMetadata(w)
+
+		idle, err := c.isSessionIdle(w.Session, now.Add(-usageSampleInterval))
+		if err != nil {
+			log.Errf("Failed to check the activity of clone %s: %v.", w.Clone.ID, err)
+		}
+
+		c.usage.addSample(w.Clone.ID, usageSample{
+			Time:     now,
+			DiffSize: w.Clone.Metadata.CloneDiffSize,
+			Idle:     idle,
+		})
+	}
+
+	c.usage.prune(now)
+	c.saveUsage()
+}
@@ -0,0 +1,96 @@
+package cloning
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+func TestUsageReport(t *testing.T) {
+	start := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	ledger := &usageLedger{}
+
+	ledger.created(&models.Clone{
+		ID:     "c1",
+		DB:     models.Database{Username: "alice"},
+		Labels: map[string]string{"team": "payments"},
+	}, start)
+	ledger.addSample("c1", usageSample{Time: start.Add(time.Hour), DiffSize: 100})
+	ledger.addSample("c1", usageSample{Time: start.Add(2 * time.Hour), DiffSize: 300, Idle: true})
+	ledger.reset("c1", start.Add(3*time.Hour))
+	ledger.destroyed("c1", start.Add(4*time.Hour))
+
+	ledger.created(&models.Clone{ID: "c2", DB: models.Database{Username: "bob"}}, start.Add(2*time.Hour))
+	ledger.addSample("c2", usageSample{Time: start.Add(3 * time.Hour), DiffSize: 50})
+
+	// The clone ID is reused after the first clone is destroyed.
+	ledger.created(&models.Clone{
+		ID:     "c1",
+		DB:     models.Database{Username: "alice"},
+		Labels: map[string]string{"team": "payments"},
+	}, start.Add(5*time.Hour))
+
+	report := ledger.report(start, start.Add(6*time.Hour), models.UsageGroupByUser, "")
+
+	assert.Equal(t, []models.UsageGroup{
+		{
+			Key:          "alice",
+			Clones:       2,
+			Created:      2,
+			Resets:       1,
+			Destroyed:    1,
+			CloneHours:   5,
+			IdleHours:    1,
+			PeakDiffSize: 300,
+			AvgDiffSize:  200,
+		},
+		{
+			Key:          "bob",
+			Clones:       1,
+			Created:      1,
+			CloneHours:   4,
+			PeakDiffSize: 50,
+			AvgDiffSize:  50,
+		},
+	}, report.Groups)
+
+	report = ledger.report(start.Add(3*time.Hour), start.Add(4*time.Hour), models.UsageGroupByLabel, "team")
+
+	assert.Equal(t, "team", report.Label)
+	assert.Equal(t, []models.UsageGroup{
+		{Key: "", Clones: 1, CloneHours: 1, PeakDiffSize: 50, AvgDiffSize: 50},
+		{Key: "payments", Clones: 1, Resets: 1, CloneHours: 1},
+	}, report.Groups)
+}
+
+func TestUsageLedgerPersistence(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	destroyedAt := now.Add(-usageRetention - time.Hour)
+	ledger := &usageLedger{}
+
+	ledger.created(&models.Clone{ID: "old"}, destroyedAt.Add(-time.Hour))
+	ledger.destroyed("old", destroyedAt)
+	ledger.created(&models.Clone{ID: "running"}, now.Add(-time.Hour))
+	ledger.created(&models.Clone{ID: "missing"}, now.Add(-time.Hour))
+
+	ledger.prune(now)
+	ledger.closeMissing(map[string]struct{}{"running": {}}, now)
+
+	filename := path.Join(t.TempDir(), usageFilename)
+	require.NoError(t, ledger.save(filename))
+
+	loaded := &usageLedger{}
+	require.NoError(t, loaded.load(filename))
+	require.Len(t, loaded.records, 2)
+
+	assert.Equal(t, "running", loaded.records[0].CloneID)
+	assert.Nil(t, loaded.records[0].DestroyedAt)
+	assert.Equal(t, "missing", loaded.records[1].CloneID)
+	require.NotNil(t, loaded.records[1].DestroyedAt)
+	assert.True(t, now.Equal(*loaded.records[1].DestroyedAt))
+}
@@ -0,0 +1,234 @@
+/*
+2022 © Postgres.ai
+*/
+
+package cloning
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+)
+
+const warmPoolCheckInterval = time.Minute
+
+// WarmPool defines options of the pool of pre-started clones.
+type WarmPool struct {
+	Size uint `yaml:"size"`
+}
+
+// warmPool contains pre-started sessions of the latest snapshot which are not assigned to users yet.
+type warmPool struct {
+	mu         sync.Mutex
+	snapshotID string
+	sessions   []*resources.Session
+	refillCh   chan struct{}
+
+	// suspendedPool is the pool where pre-started clones must not be kept, e.g. because it is being refreshed.
+	suspendedPool string
+
+	// reviseMu serializes revisions, so a suspended pool is released once suspension returns.
+	reviseMu sync.Mutex
+}
+
+func newWarmPool() warmPool {
+	return warmPool{refillCh: make(chan struct{}, 1)}
+}
+
+// take takes a pre-started session of the snapshot.
+func (wp *warmPool) take(snapshotID string) *resources.Session {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if wp.snapshotID != snapshotID || len(wp.sessions) == 0 {
+		return nil
+	}
+
+	session := wp.sessions[0]
+	wp.sessions = wp.sessions[1:]
+
+	return session
+}
+
+// add adds a pre-started session to the pool if it still serves the same snapshot.
+func (wp *warmPool) add(snapshotID string, session *resources.Session) bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if wp.snapshotID != snapshotID || (wp.suspendedPool != "" && wp.suspendedPool == session.Pool) {
+		return false
+	}
+
+	wp.sessions = append(wp.sessions, session)
+
+	return true
+}
+
+// revise switches the pool to the snapshot and shrinks it to the size.
+// It returns the sessions that must be stopped.
+func (wp *warmPool) revise(snapshotID string, size int) []*resources.Session {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	var excess []*resources.Session
+
+	if wp.snapshotID != snapshotID {
+		excess = wp.sessions
+		wp.sessions = nil
+		wp.snapshotID = snapshotID
+	}
+
+	if len(wp.sessions) > size {
+		excess = append(excess, wp.sessions[size:]...)
+		wp.sessions = wp.sessions[:size]
+	}
+
+	return excess
+}
+
+// suspend keeps the pool free of pre-started sessions until the pool is resumed.
+func (wp *warmPool) suspend(poolName string) {
+	wp.mu.Lock()
+	wp.suspendedPool = poolName
+	wp.mu.Unlock()
+}
+
+// isSuspended reports whether pre-started sessions must not be kept in the pool.
+func (wp *warmPool) isSuspended(poolName string) bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	return wp.suspendedPool != "" && wp.suspendedPool == poolName
+}
+
+// len returns the number of pre-started sessions.
+func (wp *warmPool) len() int {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	return len(wp.sessions)
+}
+
+// requestRefill asks to refill the pool without waiting for the next check.
+func (wp *warmPool) requestRefill() {
+	select {
+	case wp.refillCh <- struct{}{}:
+	default:
+	}
+}
+
+// SuspendWarmPool stops pre-started clones of the pool and does not start new ones there until the warm pool is resumed.
+// Pre-started clones occupy the pool like user clones, so they have to be released before the pool is refreshed.
+func (c *Base) SuspendWarmPool(poolName string) {
+	c.warmPool.suspend(poolName)
+	c.reviseWarmPool(context.Background())
+}
+
+// ResumeWarmPool allows pre-started clones in all pools and refills the warm pool.
+func (c *Base) ResumeWarmPool() {
+	c.warmPool.suspend("")
+	c.warmPool.requestRefill()
+}
+
+// RefillWarmPool switches pre-started clones to the latest snapshot without waiting for the next check.
+func (c *Base) RefillWarmPool() {
+	c.warmPool.requestRefill()
+}
+
+// startSession takes a pre-started session from the warm pool if there is one, otherwise it starts a new session.
+func (c *Base) startSession(snapshotID string, user resources.EphemeralUser, extraConf map[string]string,
+	initScripts resources.InitScripts, allowedCIDRs []string) (*resources.Session, error) {
+	session := c.warmPool.take(snapshotID)
+	if session == nil {
+		return c.provision.StartSession(snapshotID, user, extraConf, initScripts, allowedCIDRs)
+	}
+
+	c.warmPool.requestRefill()
+
+	log.Dbg(fmt.Sprintf("Use a pre-started clone %s", session.ID))
+
+	if err := c.provision.ActivateSession(session, user, extraConf, initScripts, allowedCIDRs); err != nil {
+		if stopErr := c.provision.StopSession(session); stopErr != nil {
+			log.Err("Failed to stop a pre-started session:", stopErr)
+		}
+
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (c *Base) runWarmPool(ctx context.Context) {
+	ticker := time.NewTicker(warmPoolCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		c.reviseWarmPool(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-c.warmPool.refillCh:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reviseWarmPool keeps the configured number of pre-started clones of the latest snapshot.
+func (c *Base) reviseWarmPool(ctx context.Context) {
+	c.warmPool.reviseMu.Lock()
+	defer c.warmPool.reviseMu.Unlock()
+
+	size := int(c.config.WarmPool.Size)
+
+	if size == 0 && c.warmPool.len() == 0 {
+		return
+	}
+
+	if err := c.fetchSnapshots(); err != nil {
+		log.Err("Failed to fetch snapshots for the warm pool:", err)
+		return
+	}
+
+	var snapshotID string
+
+	if latestSnapshot, err := c.getLatestSnapshot(); err == nil && !c.warmPool.isSuspended(latestSnapshot.Pool) {
+		snapshotID = latestSnapshot.ID
+	}
+
+	if snapshotID == "" {
+		size = 0
+	}
+
+	for _, session := range c.warmPool.revise(snapshotID, size) {
+		log.Dbg(fmt.Sprintf("Stopping a pre-started clone %s", session.ID))
+
+		if err := c.provision.StopSession(session); err != nil {
+			log.Err("Failed to stop a pre-started session:", err)
+		}
+	}
+
+	for c.warmPool.len() < size {
+		if ctx.Err() != nil {
+			return
+		}
+
+		session, err := c.provision.StartWarmSession(snapshotID)
+		if err != nil {
+			log.Err("Failed to start a pre-started clone:", err)
+			return
+		}
+
+		if !c.warmPool.add(snapshotID, session) {
+			if err := c.provision.StopSession(session); err != nil {
+				log.Err("Failed to stop a pre-started session:", err)
+			}
+
+			return
+		}
+	}
+}
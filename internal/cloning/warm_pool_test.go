@@ -0,0 +1,47 @@
+package cloning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
+)
+
+func TestWarmPool(t *testing.T) {
+	wp := newWarmPool()
+
+	assert.Empty(t, wp.revise("snapshot1", 2))
+	assert.True(t, wp.add("snapshot1", &resources.Session{ID: "1"}))
+	assert.True(t, wp.add("snapshot1", &resources.Session{ID: "2"}))
+	assert.False(t, wp.add("snapshot0", &resources.Session{ID: "3"}))
+	assert.Equal(t, 2, wp.len())
+
+	assert.Nil(t, wp.take("snapshot0"))
+	assert.Equal(t, &resources.Session{ID: "1"}, wp.take("snapshot1"))
+	assert.Equal(t, 1, wp.len())
+
+	t.Run("pool must be shrunk to the configured size", func(t *testing.T) {
+		assert.True(t, wp.add("snapshot1", &resources.Session{ID: "4"}))
+		assert.Equal(t, []*resources.Session{{ID: "4"}}, wp.revise("snapshot1", 1))
+		assert.Equal(t, 1, wp.len())
+	})
+
+	t.Run("sessions of the previous snapshot must be released", func(t *testing.T) {
+		assert.Equal(t, []*resources.Session{{ID: "2"}}, wp.revise("snapshot2", 1))
+		assert.Equal(t, 0, wp.len())
+		assert.Nil(t, wp.take("snapshot1"))
+	})
+
+	t.Run("sessions of a suspended pool must be rejected", func(t *testing.T) {
+		wp.suspend("dblab_pool")
+		assert.True(t, wp.isSuspended("dblab_pool"))
+		assert.False(t, wp.isSuspended("dblab_pool_2"))
+		assert.False(t, wp.add("snapshot2", &resources.Session{ID: "5", Pool: "dblab_pool"}))
+		assert.True(t, wp.add("snapshot2", &resources.Session{ID: "6", Pool: "dblab_pool_2"}))
+
+		wp.suspend("")
+		assert.False(t, wp.isSuspended("dblab_pool"))
+		assert.True(t, wp.add("snapshot2", &resources.Session{ID: "7", Pool: "dblab_pool"}))
+	})
+}
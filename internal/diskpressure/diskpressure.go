@@ -0,0 +1,338 @@
+/*
+2022 © Postgres.ai
+*/
+
+// Package diskpressure provides a controller that reclaims space when storage pools run low.
+package diskpressure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/cloning"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/pool"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/telemetry"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+const (
+	checkInterval = time.Minute
+
+	// maxActions defines how many recent actions are kept to report in status.
+	maxActions = 50
+
+	defaultMinIdleMinutes     = 30
+	defaultKeepSnapshots      = 1
+	defaultMaxDestroyedClones = 1
+)
+
+// Config describes options of the disk pressure controller.
+type Config struct {
+	// WarningThreshold defines the pool usage (in percent) to raise a warning. Zero disables the controller.
+	WarningThreshold float64 `yaml:"warningThreshold"`
+
+	// CriticalThreshold defines the pool usage (in percent) to start reclaiming space.
+	// Zero means that space is never reclaimed automatically.
+	CriticalThreshold float64 `yaml:"criticalThreshold"`
+
+	// MinIdleMinutes defines how long a clone has to be idle to be destroyed under disk pressure.
+	MinIdleMinutes uint `yaml:"minIdleMinutes"`
+
+	// KeepSnapshots defines how many of the latest snapshots are never pruned under disk pressure. Zero means the default.
+	KeepSnapshots int `yaml:"keepSnapshots"`
+
+	// MaxDestroyedClones limits the number of idle clones destroyed per check. The space freed by a destroyed clone
+	// is known only after the next check because clone sizes do not include the data shared with snapshots.
+	MaxDestroyedClones uint `yaml:"maxDestroyedClones"`
+}
+
+// IsValidConfig checks options of the disk pressure controller.
+func IsValidConfig(cfg Config) error {
+	if cfg.KeepSnapshots < 0 {
+		return errors.New(`"diskPressure.keepSnapshots" must be non-negative`)
+	}
+
+	return nil
+}
+
+func (cfg Config) maxDestroyedClones() int {
+	if cfg.MaxDestroyedClones == 0 {
+		return defaultMaxDestroyedClones
+	}
+
+	return int(cfg.MaxDestroyedClones)
+}
+
+func (cfg Config) keepSnapshots() int {
+	if cfg.KeepSnapshots == 0 {
+		return defaultKeepSnapshots
+	}
+
+	return cfg.KeepSnapshots
+}
+
+// Alerter registers alerts of the instance.
+type Alerter interface {
+	ReportAlert(ctx context.Context, alert telemetry.Alert)
+	ResolveAlert(alertType models.AlertType)
+}
+
+// Controller watches storage pools and reclaims space when they run low.
+type Controller struct {
+	cfg     *Config
+	pm      *pool.Manager
+	cloning *cloning.Base
+	alerter Alerter
+
+	mu      sync.Mutex
+	levels  map[string]models.DiskPressureLevel
+	actions []models.DiskPressureAction
+}
+
+// NewController creates a new disk pressure controller.
+func NewController(cfg *Config, pm *pool.Manager, cloningSvc *cloning.Base, alerter Alerter) *Controller {
+	return &Controller{
+		cfg:     cfg,
+		pm:      pm,
+		cloning: cloningSvc,
+		alerter: alerter,
+		levels:  make(map[string]models.DiskPressureLevel),
+	}
+}
+
+// Reload reloads configuration of the disk pressure controller.
+func (c *Controller) Reload(cfg Config) {
+	*c.cfg = cfg
+}
+
+// Run starts watching storage pools.
+func (c *Controller) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		c.check(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Status returns the current state of the controller. It returns nil if the controller is disabled.
+func (c *Controller) Status() *models.DiskPressure {
+	if c.cfg.WarningThreshold == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	level := models.DiskPressureOK
+
+	for _, poolLevel := range c.levels {
+		if levelWeight(poolLevel) > levelWeight(level) {
+			level = poolLevel
+		}
+	}
+
+	actions := make([]models.DiskPressureAction, len(c.actions))
+	copy(actions, c.actions)
+
+	return &models.DiskPressure{
+		Level:   level,
+		Actions: actions,
+	}
+}
+
+func (c *Controller) check(ctx context.Context) {
+	if c.cfg.WarningThreshold == 0 {
+		c.alerter.ResolveAlert(models.LowDiskSpace)
+		return
+	}
+
+	var (
+		warnings  []string
+		escalated bool
+	)
+
+	for _, fsm := range c.pm.GetFSManagerList() {
+		if fsm.Pool() == nil {
+			continue
+		}
+
+		poolName := fsm.Pool().Name
+
+		fileSystem, err := fsm.GetFilesystemState()
+		if err != nil {
+			log.Err("Failed to get the filesystem state of the pool", poolName, err)
+			continue
+		}
+
+		usage := usagePercent(fileSystem)
+		level := pressureLevel(usage, c.cfg.WarningThreshold, c.cfg.CriticalThreshold)
+
+		if level != models.DiskPressureOK {
+			warnings = append(warnings, fmt.Sprintf("pool %s is %.1f%% full", poolName, usage))
+		}
+
+		if c.setLevel(poolName, level) {
+			escalated = true
+			c.addAction(poolName, models.DiskPressureActionWarn, fmt.Sprintf("Pool is %.1f%% full", usage))
+		}
+
+		if level == models.DiskPressureCritical {
+			c.reclaim(fsm, fileSystem)
+		}
+	}
+
+	if len(warnings) == 0 {
+		c.alerter.ResolveAlert(models.LowDiskSpace)
+		return
+	}
+
+	if !escalated {
+		return
+	}
+
+	c.alerter.ReportAlert(ctx, telemetry.Alert{
+		Level:   models.LowDiskSpace,
+		Message: "Low disk space: " + strings.Join(warnings, ", "),
+	})
+}
+
+// reclaim destroys idle clones and prunes snapshots without clones.
+// Snapshots released by the destroyed clones are pruned during the next checks.
+func (c *Controller) reclaim(fsm pool.FSManager, fileSystem models.FileSystem) {
+	poolName := fsm.Pool().Name
+
+	minIdleMinutes := c.cfg.MinIdleMinutes
+	if minIdleMinutes == 0 {
+		minIdleMinutes = defaultMinIdleMinutes
+	}
+
+	spaceToFree := spaceToFree(fileSystem, c.cfg.WarningThreshold)
+
+	idleClones := c.cloning.ReclaimIdleClones(poolName, time.Duration(minIdleMinutes)*time.Minute, spaceToFree, c.cfg.maxDestroyedClones())
+
+	for _, clone := range idleClones {
+		log.Msg(fmt.Sprintf("Disk pressure: idle clone %q is going to be removed", clone.ID))
+		c.addAction(poolName, models.DiskPressureActionDestroyClone,
+			fmt.Sprintf("Destroyed idle clone %s (%d bytes)", clone.ID, clone.Metadata.CloneDiffSize))
+	}
+
+	snapshotsBefore, err := fsm.GetSnapshots()
+	if err != nil {
+		log.Err("Failed to get snapshots of the pool", poolName, err)
+		return
+	}
+
+	if _, err := fsm.CleanupSnapshots(c.cfg.keepSnapshots()); err != nil {
+		log.Err("Failed to prune snapshots of the pool", poolName, err)
+		return
+	}
+
+	snapshotsAfter, err := fsm.GetSnapshots()
+	if err != nil {
+		log.Err("Failed to get snapshots of the pool", poolName, err)
+		return
+	}
+
+	remaining := make(map[string]struct{}, len(snapshotsAfter))
+	for _, snapshot := range snapshotsAfter {
+		remaining[snapshot.ID] = struct{}{}
+	}
+
+	var pruned []string
+
+	for _, snapshot := range snapshotsBefore {
+		if _, ok := remaining[snapshot.ID]; !ok {
+			pruned = append(pruned, snapshot.ID)
+		}
+	}
+
+	if len(pruned) > 0 {
+		log.Msg("Disk pressure: pruned snapshots", pruned)
+		c.addAction(poolName, models.DiskPressureActionPruneSnapshots, "Pruned snapshots: "+strings.Join(pruned, ", "))
+	}
+}
+
+// setLevel sets the pressure level of the pool and reports if it has grown.
+func (c *Controller) setLevel(poolName string, level models.DiskPressureLevel) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous := c.levels[poolName]
+	c.levels[poolName] = level
+
+	return levelWeight(level) > levelWeight(previous)
+}
+
+func (c *Controller) addAction(poolName string, actionType models.DiskPressureActionType, message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.actions = append(c.actions, models.DiskPressureAction{
+		Time:    time.Now().Truncate(time.Second),
+		Pool:    poolName,
+		Type:    actionType,
+		Message: message,
+	})
+
+	if len(c.actions) > maxActions {
+		c.actions = c.actions[len(c.actions)-maxActions:]
+	}
+}
+
+func usagePercent(fileSystem models.FileSystem) float64 {
+	if fileSystem.Size == 0 {
+		return 0
+	}
+
+	return float64(fileSystem.Used) / float64(fileSystem.Size) * 100
+}
+
+func pressureLevel(usage, warningThreshold, criticalThreshold float64) models.DiskPressureLevel {
+	switch {
+	case criticalThreshold > 0 && usage >= criticalThreshold:
+		return models.DiskPressureCritical
+
+	case warningThreshold > 0 && usage >= warningThreshold:
+		return models.DiskPressureWarning
+
+	default:
+		return models.DiskPressureOK
+	}
+}
+
+// spaceToFree calculates how much space has to be freed to get below the warning threshold.
+func spaceToFree(fileSystem models.FileSystem, warningThreshold float64) uint64 {
+	target := uint64(float64(fileSystem.Size) * warningThreshold / 100)
+
+	if fileSystem.Used <= target {
+		return 0
+	}
+
+	return fileSystem.Used - target
+}
+
+func levelWeight(level models.DiskPressureLevel) int {
+	switch level {
+	case models.DiskPressureWarning:
+		return 1
+
+	case models.DiskPressureCritical:
+		return 2
+
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,63 @@
+package diskpressure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+func TestPressureLevel(t *testing.T) {
+	testCases := []struct {
+		usage    float64
+		warning  float64
+		critical float64
+		level    models.DiskPressureLevel
+	}{
+		{usage: 50, warning: 80, critical: 90, level: models.DiskPressureOK},
+		{usage: 80, warning: 80, critical: 90, level: models.DiskPressureWarning},
+		{usage: 95, warning: 80, critical: 90, level: models.DiskPressureCritical},
+		{usage: 95, warning: 80, critical: 0, level: models.DiskPressureWarning},
+		{usage: 95, warning: 0, critical: 0, level: models.DiskPressureOK},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.level, pressureLevel(tc.usage, tc.warning, tc.critical))
+	}
+}
+
+func TestSpaceToFree(t *testing.T) {
+	testCases := []struct {
+		fileSystem  models.FileSystem
+		threshold   float64
+		spaceToFree uint64
+	}{
+		{fileSystem: models.FileSystem{Size: 1000, Used: 950}, threshold: 80, spaceToFree: 150},
+		{fileSystem: models.FileSystem{Size: 1000, Used: 700}, threshold: 80, spaceToFree: 0},
+		{fileSystem: models.FileSystem{Size: 1000, Used: 800}, threshold: 80, spaceToFree: 0},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.spaceToFree, spaceToFree(tc.fileSystem, tc.threshold))
+	}
+}
+
+func TestUsagePercent(t *testing.T) {
+	assert.Equal(t, 0.0, usagePercent(models.FileSystem{}))
+	assert.Equal(t, 25.0, usagePercent(models.FileSystem{Size: 400, Used: 100}))
+}
+
+func TestKeepSnapshots(t *testing.T) {
+	assert.Equal(t, 1, Config{}.keepSnapshots())
+	assert.Equal(t, 3, Config{KeepSnapshots: 3}.keepSnapshots())
+
+	assert.NoError(t, IsValidConfig(Config{}))
+	assert.NoError(t, IsValidConfig(Config{KeepSnapshots: 2}))
+	assert.EqualError(t, IsValidConfig(Config{KeepSnapshots: -1}), `"diskPressure.keepSnapshots" must be non-negative`)
+}
+
+func TestMaxDestroyedClones(t *testing.T) {
+	assert.Equal(t, 1, Config{}.maxDestroyedClones())
+	assert.Equal(t, 5, Config{MaxDestroyedClones: 5}.maxDestroyedClones())
+}
@@ -0,0 +1,273 @@
+/*
+2022 © Postgres.ai
+*/
+
+// Package freshness provides a controller that watches the age of data in storage pools and snapshots.
+package freshness
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/cloning"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/pool"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/telemetry"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+const (
+	checkInterval = time.Minute
+
+	hoursInDay = 24
+)
+
+// Config describes options of the data freshness controller.
+type Config struct {
+	// MaxPoolAgeHours defines the maximum data age (in hours) of active storage pools. Zero disables the check.
+	MaxPoolAgeHours uint `yaml:"maxPoolAgeHours"`
+
+	// Pools overrides the maximum data age (in hours) for storage pools by their names.
+	Pools map[string]uint `yaml:"pools"`
+
+	// MaxSnapshotAgeHours defines the maximum data age (in hours) of the latest snapshot. Zero disables the check.
+	MaxSnapshotAgeHours uint `yaml:"maxSnapshotAgeHours"`
+
+	// CloneWarningAgeHours defines the snapshot data age (in hours) to mark clones with a warning. Zero disables the marking.
+	CloneWarningAgeHours uint `yaml:"cloneWarningAgeHours"`
+}
+
+// Alerter registers alerts of the instance.
+type Alerter interface {
+	ReportAlert(ctx context.Context, alert telemetry.Alert)
+	ResolveAlert(alertType models.AlertType)
+}
+
+// Controller watches the age of data and raises alerts when data gets stale.
+type Controller struct {
+	cfg     *Config
+	pm      *pool.Manager
+	cloning *cloning.Base
+	alerter Alerter
+
+	mu          sync.Mutex
+	status      *models.DataFreshness
+	staleAlerts string
+}
+
+// NewController creates a new data freshness controller.
+func NewController(cfg *Config, pm *pool.Manager, cloningSvc *cloning.Base, alerter Alerter) *Controller {
+	return &Controller{
+		cfg:     cfg,
+		pm:      pm,
+		cloning: cloningSvc,
+		alerter: alerter,
+	}
+}
+
+// Reload reloads configuration of the data freshness controller.
+func (c *Controller) Reload(cfg Config) {
+	*c.cfg = cfg
+}
+
+// Run starts watching the data age.
+func (c *Controller) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		c.check(ctx, time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Status returns the current data freshness. It returns nil if the controller is disabled.
+func (c *Controller) Status() *models.DataFreshness {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.status == nil {
+		return nil
+	}
+
+	status := *c.status
+
+	return &status
+}
+
+func (c *Controller) isEnabled() bool {
+	return c.cfg.MaxPoolAgeHours > 0 || len(c.cfg.Pools) > 0 || c.cfg.MaxSnapshotAgeHours > 0 || c.cfg.CloneWarningAgeHours > 0
+}
+
+func (c *Controller) check(ctx context.Context, now time.Time) {
+	if !c.isEnabled() {
+		c.setStatus(nil, "")
+		c.alerter.ResolveAlert(models.DataStale)
+		c.cloning.MarkStaleClones(nil)
+
+		return
+	}
+
+	var (
+		status         = &models.DataFreshness{Pools: []models.DataAge{}}
+		latestSnapshot *resources.Snapshot
+		staleSnapshots = make(map[string]string)
+		cloneMaxAge    = hoursToDuration(c.cfg.CloneWarningAgeHours)
+	)
+
+	for _, fsm := range c.pm.GetFSManagerList() {
+		fsPool := fsm.Pool()
+		if fsPool == nil || fsPool.Status() != resources.ActivePool {
+			continue
+		}
+
+		status.Pools = append(status.Pools, dataAge(fsPool.Name, fsPool.DSA, c.poolMaxAge(fsPool.Name), now))
+
+		snapshots, err := fsm.GetSnapshots()
+		if err != nil {
+			log.Err("Failed to get snapshots of the pool", fsPool.Name, err)
+			continue
+		}
+
+		for i := range snapshots {
+			snapshot := snapshots[i]
+
+			if latestSnapshot == nil || snapshot.DataStateAt.After(latestSnapshot.DataStateAt) {
+				latestSnapshot = &snapshot
+			}
+
+			if cloneMaxAge > 0 && now.Sub(snapshot.DataStateAt) > cloneMaxAge {
+				staleSnapshots[snapshot.ID] = fmt.Sprintf("Snapshot data is %s old (threshold: %s)",
+					formatAge(now.Sub(snapshot.DataStateAt)), formatAge(cloneMaxAge))
+			}
+		}
+	}
+
+	sort.Slice(status.Pools, func(i, j int) bool {
+		return status.Pools[i].Name < status.Pools[j].Name
+	})
+
+	if latestSnapshot != nil {
+		snapshotAge := dataAge(latestSnapshot.ID, latestSnapshot.DataStateAt, hoursToDuration(c.cfg.MaxSnapshotAgeHours), now)
+		status.LatestSnapshot = &snapshotAge
+	}
+
+	c.cloning.MarkStaleClones(staleSnapshots)
+
+	problems := staleProblems(status)
+	status.Stale = len(problems) > 0
+
+	staleAlerts := strings.Join(staleNames(status), ",")
+	previousAlerts := c.setStatus(status, staleAlerts)
+
+	if !status.Stale {
+		c.alerter.ResolveAlert(models.DataStale)
+		return
+	}
+
+	// Report the alert again only when the set of stale pools and snapshots changes.
+	if staleAlerts == previousAlerts {
+		return
+	}
+
+	c.alerter.ReportAlert(ctx, telemetry.Alert{
+		Level:   models.DataStale,
+		Message: "Data is stale: " + strings.Join(problems, ", "),
+	})
+}
+
+// setStatus stores the status and returns previously stale pools and snapshots.
+func (c *Controller) setStatus(status *models.DataFreshness, staleAlerts string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previousAlerts := c.staleAlerts
+
+	c.status = status
+	c.staleAlerts = staleAlerts
+
+	return previousAlerts
+}
+
+func (c *Controller) poolMaxAge(poolName string) time.Duration {
+	if maxAgeHours, ok := c.cfg.Pools[poolName]; ok {
+		return hoursToDuration(maxAgeHours)
+	}
+
+	return hoursToDuration(c.cfg.MaxPoolAgeHours)
+}
+
+func dataAge(name string, dataStateAt time.Time, maxAge time.Duration, now time.Time) models.DataAge {
+	age := now.Sub(dataStateAt)
+	if age < 0 {
+		age = 0
+	}
+
+	return models.DataAge{
+		Name:        name,
+		DataStateAt: dataStateAt,
+		Age:         uint(age.Seconds()),
+		MaxAge:      uint(maxAge.Seconds()),
+		Stale:       maxAge > 0 && age > maxAge,
+	}
+}
+
+func staleProblems(status *models.DataFreshness) []string {
+	problems := []string{}
+
+	for _, poolAge := range status.Pools {
+		if poolAge.Stale {
+			problems = append(problems, fmt.Sprintf("pool %s contains data %s old (threshold: %s)",
+				poolAge.Name, formatAge(secondsToDuration(poolAge.Age)), formatAge(secondsToDuration(poolAge.MaxAge))))
+		}
+	}
+
+	if status.LatestSnapshot != nil && status.LatestSnapshot.Stale {
+		problems = append(problems, fmt.Sprintf("the latest snapshot %s contains data %s old (threshold: %s)",
+			status.LatestSnapshot.Name, formatAge(secondsToDuration(status.LatestSnapshot.Age)),
+			formatAge(secondsToDuration(status.LatestSnapshot.MaxAge))))
+	}
+
+	return problems
+}
+
+func staleNames(status *models.DataFreshness) []string {
+	names := []string{}
+
+	for _, poolAge := range status.Pools {
+		if poolAge.Stale {
+			names = append(names, poolAge.Name)
+		}
+	}
+
+	if status.LatestSnapshot != nil && status.LatestSnapshot.Stale {
+		names = append(names, status.LatestSnapshot.Name)
+	}
+
+	return names
+}
+
+func hoursToDuration(hours uint) time.Duration {
+	return time.Duration(hours) * time.Hour
+}
+
+func secondsToDuration(seconds uint) time.Duration {
+	return time.Duration(seconds) * time.Second
+}
+
+// formatAge formats the age in days and hours, for example, "9d 3h".
+func formatAge(age time.Duration) string {
+	hours := int(age.Hours())
+
+	return fmt.Sprintf("%dd %dh", hours/hoursInDay, hours%hoursInDay)
+}
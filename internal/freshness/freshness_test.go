@@ -0,0 +1,59 @@
+package freshness
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+func TestDataAge(t *testing.T) {
+	now := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	dataStateAt := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		maxAge time.Duration
+		stale  bool
+	}{
+		{maxAge: 0, stale: false},
+		{maxAge: 7 * 24 * time.Hour, stale: true},
+		{maxAge: 9 * 24 * time.Hour, stale: false},
+		{maxAge: 10 * 24 * time.Hour, stale: false},
+	}
+
+	for _, tc := range testCases {
+		age := dataAge("dblab_pool", dataStateAt, tc.maxAge, now)
+
+		assert.Equal(t, uint(9*24*60*60), age.Age)
+		assert.Equal(t, uint(tc.maxAge.Seconds()), age.MaxAge)
+		assert.Equal(t, tc.stale, age.Stale)
+	}
+
+	assert.Equal(t, uint(0), dataAge("dblab_pool", now.Add(time.Hour), time.Hour, now).Age)
+}
+
+func TestStaleProblems(t *testing.T) {
+	status := &models.DataFreshness{
+		LatestSnapshot: &models.DataAge{Name: "dblab_pool@snapshot_20220501120000", Age: 9 * 24 * 3600, MaxAge: 7 * 24 * 3600, Stale: true},
+		Pools: []models.DataAge{
+			{Name: "dblab_pool", Age: 9*24*3600 + 3*3600, MaxAge: 7 * 24 * 3600, Stale: true},
+			{Name: "dblab_pool2", Age: 3600},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"pool dblab_pool contains data 9d 3h old (threshold: 7d 0h)",
+		"the latest snapshot dblab_pool@snapshot_20220501120000 contains data 9d 0h old (threshold: 7d 0h)",
+	}, staleProblems(status))
+	assert.Equal(t, []string{"dblab_pool", "dblab_pool@snapshot_20220501120000"}, staleNames(status))
+
+	assert.Empty(t, staleProblems(&models.DataFreshness{Pools: []models.DataAge{{Name: "dblab_pool", Age: 3600}}}))
+}
+
+func TestFormatAge(t *testing.T) {
+	assert.Equal(t, "0d 0h", formatAge(30*time.Minute))
+	assert.Equal(t, "0d 5h", formatAge(5*time.Hour))
+	assert.Equal(t, "9d 3h", formatAge(9*24*time.Hour+3*time.Hour+15*time.Minute))
+}
@@ -0,0 +1,213 @@
+/*
+2022 © Postgres.ai
+*/
+
+// Package certs provides a local certificate authority to issue TLS certificates for clones.
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// CACertFilename defines the name of the CA certificate file.
+	CACertFilename = "ca.crt"
+
+	// CAKeyFilename defines the name of the CA private key file.
+	CAKeyFilename = "ca.key"
+
+	caValidity     = 10 * 365 * 24 * time.Hour
+	serverValidity = 365 * 24 * time.Hour
+
+	certificateBlockType = "CERTIFICATE"
+	privateKeyBlockType  = "PRIVATE KEY"
+
+	caCommonName = "Database Lab Engine CA"
+)
+
+// Authority issues server certificates signed by the local CA.
+type Authority struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+}
+
+// LoadOrCreateAuthority loads the CA from the directory or generates a new one if it does not exist.
+func LoadOrCreateAuthority(dir string) (*Authority, error) {
+	certPath := path.Join(dir, CACertFilename)
+	keyPath := path.Join(dir, CAKeyFilename)
+
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, "failed to read the CA certificate")
+		}
+
+		return createAuthority(certPath, keyPath)
+	}
+
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the CA private key")
+	}
+
+	return parseAuthority(certPEM, keyPEM)
+}
+
+func createAuthority(certPath, keyPath string) (*Authority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate the CA private key")
+	}
+
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: caCommonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the CA certificate")
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: certificateBlockType, Bytes: certDER})
+
+	if err := os.MkdirAll(path.Dir(certPath), 0700); err != nil {
+		return nil, errors.Wrap(err, "failed to create the CA directory")
+	}
+
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return nil, errors.Wrap(err, "failed to write the CA private key")
+	}
+
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return nil, errors.Wrap(err, "failed to write the CA certificate")
+	}
+
+	return parseAuthority(certPEM, keyPEM)
+}
+
+func parseAuthority(certPEM, keyPEM []byte) (*Authority, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil || certBlock.Type != certificateBlockType {
+		return nil, errors.New("invalid CA certificate")
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the CA certificate")
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil || keyBlock.Type != privateKeyBlockType {
+		return nil, errors.New("invalid CA private key")
+	}
+
+	parsedKey, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the CA private key")
+	}
+
+	key, ok := parsedKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("unsupported type of the CA private key")
+	}
+
+	return &Authority{cert: cert, key: key, certPEM: certPEM}, nil
+}
+
+// CertPEM returns the PEM-encoded CA certificate.
+func (a *Authority) CertPEM() []byte {
+	return a.certPEM
+}
+
+// IssueServerCert issues a server certificate for the host. The host may be either a DNS name or an IP address.
+// It returns the PEM-encoded certificate and private key.
+func (a *Authority) IssueServerCert(host string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate a private key")
+	}
+
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(serverValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create a server certificate")
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: certificateBlockType, Bytes: certDER}), keyPEM, nil
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode a private key")
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: privateKeyBlockType, Bytes: keyDER}), nil
+}
+
+func newSerialNumber() (*big.Int, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate a serial number")
+	}
+
+	return serialNumber, nil
+}
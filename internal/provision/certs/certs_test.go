@@ -0,0 +1,71 @@
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrCreateAuthority(t *testing.T) {
+	dir := path.Join(t.TempDir(), "tls")
+
+	authority, err := LoadOrCreateAuthority(dir)
+	require.NoError(t, err)
+
+	keyInfo, err := os.Stat(path.Join(dir, CAKeyFilename))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), keyInfo.Mode().Perm())
+
+	loaded, err := LoadOrCreateAuthority(dir)
+	require.NoError(t, err)
+	assert.Equal(t, authority.CertPEM(), loaded.CertPEM())
+}
+
+func TestIssueServerCert(t *testing.T) {
+	authority, err := LoadOrCreateAuthority(t.TempDir())
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(authority.CertPEM()))
+
+	testCases := []struct {
+		host      string
+		otherHost string
+	}{
+		{host: "dblab.example.com", otherHost: "example.com"},
+		{host: "10.0.0.5", otherHost: "10.0.0.6"},
+	}
+
+	for _, tc := range testCases {
+		certPEM, keyPEM, err := authority.IssueServerCert(tc.host)
+		require.NoError(t, err)
+
+		keyBlock, _ := pem.Decode(keyPEM)
+		require.NotNil(t, keyBlock)
+
+		certBlock, _ := pem.Decode(certPEM)
+		require.NotNil(t, certBlock)
+
+		cert, err := x509.ParseCertificate(certBlock.Bytes)
+		require.NoError(t, err)
+
+		opts := x509.VerifyOptions{
+			DNSName:   tc.host,
+			Roots:     roots,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+
+		_, err = cert.Verify(opts)
+		assert.NoError(t, err, tc.host)
+
+		opts.DNSName = tc.otherHost
+
+		_, err = cert.Verify(opts)
+		assert.Error(t, err, tc.otherHost)
+	}
+}
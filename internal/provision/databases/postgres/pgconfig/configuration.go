@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path"
 	"strings"
@@ -137,7 +138,7 @@ func (m *Manager) init() error {
 	}
 
 	// Correct PGDATA with Database Lab configs.
-	if err := m.adjustHBAConf(); err != nil {
+	if err := m.adjustHBAConf(nil, false); err != nil {
 		return errors.Wrap(err, "failed to adjust pg_hba PostgreSQL configs")
 	}
 
@@ -200,7 +201,9 @@ func (m *Manager) rewritePostgresConfig() error {
 }
 
 // adjustHBAConf corrects pg_hba.conf with Database Lab configs.
-func (m *Manager) adjustHBAConf() error {
+// If allowed CIDRs are provided, host rules open to any address are limited to these networks.
+// If SSL is required, host rules accept only SSL connections.
+func (m *Manager) adjustHBAConf(allowedCIDRs []string, requireSSL bool) error {
 	log.Dbg("Configuring pg_hba.conf...")
 
 	// Copy pg_hba.conf.
@@ -216,13 +219,80 @@ func (m *Manager) adjustHBAConf() error {
 		return errors.Wrapf(err, "cannot read %s from configs", pgHbaConfName)
 	}
 
-	if err := os.WriteFile(pgHbaDst, input, 0644); err != nil {
+	output, err := renderHBAConf(input, allowedCIDRs, requireSSL)
+	if err != nil {
+		return errors.Wrapf(err, "cannot render %s", pgHbaConfName)
+	}
+
+	if err := os.WriteFile(pgHbaDst, output, 0644); err != nil {
 		return errors.Wrapf(err, "cannot copy %s to PGDATA", pgHbaConfName)
 	}
 
 	return nil
 }
 
+// ApplyHBA rewrites pg_hba.conf to allow connections over the network only from the provided CIDRs.
+// An empty list allows connections from any address. If SSL is required, plaintext connections over the network are rejected.
+func (m *Manager) ApplyHBA(allowedCIDRs []string, requireSSL bool) error {
+	return m.adjustHBAConf(allowedCIDRs, requireSSL)
+}
+
+// renderHBAConf replaces host rules open to any address with rules for each of the allowed CIDRs
+// and turns host rules into hostssl ones if SSL is required.
+func renderHBAConf(input []byte, allowedCIDRs []string, requireSSL bool) ([]byte, error) {
+	if len(allowedCIDRs) == 0 && !requireSSL {
+		return input, nil
+	}
+
+	for _, cidr := range allowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return nil, errors.Errorf("invalid CIDR %q", cidr)
+		}
+	}
+
+	const addressField = 3
+
+	lines := strings.Split(string(input), "\n")
+	output := make([]string, 0, len(lines)+len(allowedCIDRs))
+	expanded := false
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+
+		if len(fields) <= addressField || !strings.HasPrefix(fields[0], "host") {
+			output = append(output, line)
+			continue
+		}
+
+		if requireSSL && fields[0] == "host" {
+			fields[0] = "hostssl"
+		}
+
+		if len(allowedCIDRs) == 0 || !isAnyAddress(fields[addressField]) {
+			output = append(output, strings.Join(fields, " "))
+			continue
+		}
+
+		// Rules for IPv4 and IPv6 wildcards are replaced with the same list, so it is rendered once.
+		if expanded {
+			continue
+		}
+
+		for _, cidr := range allowedCIDRs {
+			fields[addressField] = cidr
+			output = append(output, strings.Join(fields, " "))
+		}
+
+		expanded = true
+	}
+
+	return []byte(strings.Join(output, "\n")), nil
+}
+
+func isAnyAddress(address string) bool {
+	return address == "all" || address == "0.0.0.0/0" || address == "::/0"
+}
+
 // adjustGeneralConfigs corrects general PostgreSQL parameters with Database Lab configs.
 func (m Manager) adjustGeneralConfigs() error {
 	log.Dbg("Configuring Postgres...")
@@ -35,3 +35,61 @@ recovery_target_timeline = latest
 	assert.Equal(t, expected["standby_mode"], fileConfig["standby_mode"])
 	assert.Equal(t, expected["recovery_target_timeline"], fileConfig["recovery_target_timeline"])
 }
+
+func TestRenderHBAConf(t *testing.T) {
+	template := `## This file will replace any pg_hba.conf located in your PGDATA.
+
+local all all trust
+host all all 0.0.0.0/0 md5
+host all all ::/0 md5`
+
+	testCases := []struct {
+		name         string
+		allowedCIDRs []string
+		requireSSL   bool
+		expected     string
+	}{
+		{
+			name:     "no allowlist",
+			expected: template,
+		},
+		{
+			name:         "allowlist",
+			allowedCIDRs: []string{"10.0.0.0/8", "192.168.1.10/32"},
+			expected: `## This file will replace any pg_hba.conf located in your PGDATA.
+
+local all all trust
+host all all 10.0.0.0/8 md5
+host all all 192.168.1.10/32 md5`,
+		},
+		{
+			name:       "SSL required",
+			requireSSL: true,
+			expected: `## This file will replace any pg_hba.conf located in your PGDATA.
+
+local all all trust
+hostssl all all 0.0.0.0/0 md5
+hostssl all all ::/0 md5`,
+		},
+		{
+			name:         "allowlist with SSL required",
+			allowedCIDRs: []string{"10.0.0.0/8"},
+			requireSSL:   true,
+			expected: `## This file will replace any pg_hba.conf located in your PGDATA.
+
+local all all trust
+hostssl all all 10.0.0.0/8 md5`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := renderHBAConf([]byte(template), tc.allowedCIDRs, tc.requireSSL)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(output))
+		})
+	}
+
+	_, err := renderHBAConf([]byte(template), []string{"10.0.0.1"}, false)
+	assert.Error(t, err)
+}
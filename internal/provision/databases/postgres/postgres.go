@@ -8,11 +8,12 @@ package postgres
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq" // Register Postgres database driver.
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 
 	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/databases/postgres/pgconfig"
@@ -55,7 +56,77 @@ func Start(r runners.Runner, c *resources.AppConfig) error {
 		return errors.Wrap(err, "failed to run container")
 	}
 
-	// Waiting for server to become ready and promote if needed.
+	return waitReady(r, c)
+}
+
+// Reconfigure applies an extra configuration to a running Postgres instance.
+// The configuration is reloaded or, if any of the parameters cannot be changed without a restart, the instance is restarted.
+func Reconfigure(r runners.Runner, c *resources.AppConfig) error {
+	extraConf := c.ExtraConf()
+	if len(extraConf) == 0 {
+		return nil
+	}
+
+	configManager, err := pgconfig.NewCorrector(c.DataDir())
+	if err != nil {
+		return errors.Wrap(err, "failed to create a config manager")
+	}
+
+	if err := configManager.ApplyUserConfig(extraConf); err != nil {
+		return errors.Wrap(err, "cannot apply user configs")
+	}
+
+	connStr := getPgConnStr(c.Host, c.DB.DBName, c.DB.Username, c.Port)
+
+	out, err := runSimpleSQL(restartRequiredQuery(extraConf), connStr)
+	if err != nil {
+		return errors.Wrap(err, "failed to check configuration parameters")
+	}
+
+	if out == "t" || out == "true" {
+		log.Dbg("Postgres instance needs restart to apply the configuration.")
+
+		if _, err := docker.RestartContainer(r, c.CloneName); err != nil {
+			return errors.Wrap(err, "failed to restart container")
+		}
+
+		return waitReady(r, c)
+	}
+
+	if _, err := runSimpleSQL("select pg_reload_conf()", connStr); err != nil {
+		return errors.Wrap(err, "failed to reload configuration")
+	}
+
+	return nil
+}
+
+// ReloadConfig reloads the configuration of a running Postgres instance.
+func ReloadConfig(c *resources.AppConfig) error {
+	connStr := getPgConnStr(c.Host, c.DB.DBName, c.DB.Username, c.Port)
+
+	if _, err := runSimpleSQL("select pg_reload_conf()", connStr); err != nil {
+		return errors.Wrap(err, "failed to reload configuration")
+	}
+
+	return nil
+}
+
+// restartRequiredQuery builds a query to check whether any of the parameters can be changed only at server start.
+func restartRequiredQuery(extraConf map[string]string) string {
+	names := make([]string, 0, len(extraConf))
+
+	for name := range extraConf {
+		names = append(names, pq.QuoteLiteral(name))
+	}
+
+	sort.Strings(names)
+
+	return fmt.Sprintf("select exists (select 1 from pg_settings where name in (%s) and context = 'postmaster')",
+		strings.Join(names, ", "))
+}
+
+// waitReady waits for server to become ready and promotes it if needed.
+func waitReady(r runners.Runner, c *resources.AppConfig) error {
 	first := true
 	cnt := 0
 	waitPostgresTimeout := waitPostgresConnectionTimeout
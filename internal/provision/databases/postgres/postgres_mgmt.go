@@ -5,6 +5,7 @@
 package postgres
 
 import (
+	"database/sql"
 	"fmt"
 	"strings"
 
@@ -95,6 +96,66 @@ func CreateUser(c *resources.AppConfig, user resources.EphemeralUser) error {
 	return nil
 }
 
+// InitScriptError describes a failure of a clone init script.
+type InitScriptError struct {
+	Script string
+	Output string
+}
+
+// Error prints an error message.
+func (e *InitScriptError) Error() string {
+	return fmt.Sprintf("init script %q failed: %s", e.Script, e.Output)
+}
+
+// RunInitScript runs an SQL script as superuser in the specified database of a clone.
+func RunInitScript(c *resources.AppConfig, dbName, scriptName, query string) error {
+	db, err := sql.Open("postgres", getPgConnStr(c.Host, dbName, c.DB.Username, c.Port))
+	if err != nil {
+		return errors.Wrap(err, "cannot connect to database")
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Err("Cannot close database connection.")
+		}
+	}()
+
+	// Exec without arguments uses the simple query protocol, so a script may contain multiple statements.
+	if _, err := db.Exec(query); err != nil {
+		return &InitScriptError{Script: scriptName, Output: initScriptOutput(err)}
+	}
+
+	return nil
+}
+
+// initScriptOutput formats the error output of a failed script.
+func initScriptOutput(err error) string {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err.Error()
+	}
+
+	output := fmt.Sprintf("%s: %s", pqErr.Severity, pqErr.Message)
+
+	if pqErr.Detail != "" {
+		output += "; DETAIL: " + pqErr.Detail
+	}
+
+	if pqErr.Hint != "" {
+		output += "; HINT: " + pqErr.Hint
+	}
+
+	if pqErr.Where != "" {
+		output += "; CONTEXT: " + pqErr.Where
+	}
+
+	if pqErr.Position != "" {
+		output += "; POSITION: " + pqErr.Position
+	}
+
+	return output
+}
+
 func superuserQuery(username, password string) string {
 	return fmt.Sprintf(`create user %s with password %s login superuser;`, pq.QuoteIdentifier(username), pq.QuoteLiteral(password))
 }
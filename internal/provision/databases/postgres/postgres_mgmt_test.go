@@ -5,8 +5,10 @@
 package postgres
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -46,3 +48,19 @@ func TestRestrictedUserQuery(t *testing.T) {
 		assert.Contains(t, query, `new_owner := 'user.test"'`)
 	})
 }
+
+func TestInitScriptOutput(t *testing.T) {
+	t.Run("Postgres error details must be reported", func(t *testing.T) {
+		err := &pq.Error{
+			Severity: "ERROR",
+			Message:  `relation "flags" does not exist`,
+			Position: "13",
+		}
+
+		assert.Equal(t, `ERROR: relation "flags" does not exist; POSITION: 13`, initScriptOutput(err))
+	})
+
+	t.Run("other errors must be reported as is", func(t *testing.T) {
+		assert.Equal(t, "connection refused", initScriptOutput(errors.New("connection refused")))
+	})
+}
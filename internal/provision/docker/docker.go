@@ -179,6 +179,13 @@ func StopContainer(r runners.Runner, c *resources.AppConfig) (string, error) {
 	return r.Run(dockerStopCmd, false)
 }
 
+// RestartContainer restarts specified container.
+func RestartContainer(r runners.Runner, cloneName string) (string, error) {
+	dockerRestartCmd := "docker container restart " + cloneName
+
+	return r.Run(dockerRestartCmd, true)
+}
+
 // RemoveContainer removes specified container.
 func RemoveContainer(r runners.Runner, cloneName string) (string, error) {
 	dockerRemoveCmd := "docker container rm --force --volumes " + cloneName
@@ -0,0 +1,124 @@
+/*
+2022 © Postgres.ai
+*/
+
+package provision
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/databases/postgres"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+)
+
+const (
+	sqlFileExtension = ".sql"
+	inlineScriptName = "inline"
+)
+
+// initScript describes a single SQL script to run in a clone.
+type initScript struct {
+	name  string
+	query string
+}
+
+// runInitScripts runs requested init bundles and inline SQL as superuser in the database available to the ephemeral user.
+func (p *Provisioner) runInitScripts(pgConf *resources.AppConfig, user resources.EphemeralUser, initScripts resources.InitScripts) error {
+	if initScripts.IsEmpty() {
+		return nil
+	}
+
+	scripts, err := loadInitScripts(p.config.InitScriptsDir, initScripts)
+	if err != nil {
+		return err
+	}
+
+	dbName := pgConf.DB.DBName
+	if user.AvailableDB != "" {
+		dbName = user.AvailableDB
+	}
+
+	for _, script := range scripts {
+		log.Dbg(fmt.Sprintf("Running init script %q in clone %s", script.name, pgConf.CloneName))
+
+		if err := postgres.RunInitScript(pgConf, dbName, script.name, script.query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadInitScripts collects scripts of the requested bundles followed by the inline SQL.
+func loadInitScripts(scriptsDir string, initScripts resources.InitScripts) ([]initScript, error) {
+	scripts := make([]initScript, 0, len(initScripts.Bundles)+1)
+
+	for _, bundle := range initScripts.Bundles {
+		bundleScripts, err := loadBundle(scriptsDir, bundle)
+		if err != nil {
+			return nil, err
+		}
+
+		scripts = append(scripts, bundleScripts...)
+	}
+
+	if initScripts.SQL != "" {
+		scripts = append(scripts, initScript{name: inlineScriptName, query: initScripts.SQL})
+	}
+
+	return scripts, nil
+}
+
+// loadBundle reads a bundle, which is either an SQL file "<bundle>.sql" or a directory "<bundle>"
+// containing SQL files that are run in lexical order.
+func loadBundle(scriptsDir, bundle string) ([]initScript, error) {
+	if scriptsDir == "" {
+		return nil, errors.Errorf("cannot use init bundle %q: init scripts directory is not configured", bundle)
+	}
+
+	if bundle == "" || bundle != filepath.Base(bundle) || bundle == "." || bundle == ".." {
+		return nil, errors.Errorf("invalid init bundle name %q", bundle)
+	}
+
+	bundleDir := path.Join(scriptsDir, bundle)
+
+	if info, err := os.Stat(bundleDir); err == nil && info.IsDir() {
+		filenames, err := filepath.Glob(path.Join(bundleDir, "*"+sqlFileExtension))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list files of init bundle %q", bundle)
+		}
+
+		sort.Strings(filenames)
+
+		scripts := make([]initScript, 0, len(filenames))
+
+		for _, filename := range filenames {
+			query, err := os.ReadFile(filename)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read init bundle %q", bundle)
+			}
+
+			scripts = append(scripts, initScript{name: path.Join(bundle, filepath.Base(filename)), query: string(query)})
+		}
+
+		return scripts, nil
+	}
+
+	query, err := os.ReadFile(bundleDir + sqlFileExtension)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Errorf("init bundle %q not found", bundle)
+		}
+
+		return nil, errors.Wrapf(err, "failed to read init bundle %q", bundle)
+	}
+
+	return []initScript{{name: bundle, query: string(query)}}, nil
+}
@@ -0,0 +1,66 @@
+package provision
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
+)
+
+func TestLoadInitScripts(t *testing.T) {
+	scriptsDir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(path.Join(scriptsDir, "extensions.sql"), []byte("create extension pgcrypto;"), 0600))
+	require.NoError(t, os.Mkdir(path.Join(scriptsDir, "seed"), 0700))
+	require.NoError(t, os.WriteFile(path.Join(scriptsDir, "seed", "02_flags.sql"), []byte("insert into flags values (1);"), 0600))
+	require.NoError(t, os.WriteFile(path.Join(scriptsDir, "seed", "01_roles.sql"), []byte("create role reader;"), 0600))
+	require.NoError(t, os.WriteFile(path.Join(scriptsDir, "seed", "README.md"), []byte("skip me"), 0600))
+
+	scripts, err := loadInitScripts(scriptsDir, resources.InitScripts{
+		Bundles: []string{"extensions", "seed"},
+		SQL:     "select 1;",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []initScript{
+		{name: "extensions", query: "create extension pgcrypto;"},
+		{name: "seed/01_roles.sql", query: "create role reader;"},
+		{name: "seed/02_flags.sql", query: "insert into flags values (1);"},
+		{name: inlineScriptName, query: "select 1;"},
+	}, scripts)
+}
+
+func TestLoadInitScriptsErrors(t *testing.T) {
+	scriptsDir := t.TempDir()
+
+	testCases := []struct {
+		scriptsDir string
+		bundle     string
+		error      string
+	}{
+		{
+			scriptsDir: "",
+			bundle:     "extensions",
+			error:      `cannot use init bundle "extensions": init scripts directory is not configured`,
+		},
+		{
+			scriptsDir: scriptsDir,
+			bundle:     "missing",
+			error:      `init bundle "missing" not found`,
+		},
+		{
+			scriptsDir: scriptsDir,
+			bundle:     "../secret",
+			error:      `invalid init bundle name "../secret"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := loadInitScripts(tc.scriptsDir, resources.InitScripts{Bundles: []string{tc.bundle}})
+		assert.EqualError(t, err, tc.error)
+	}
+}
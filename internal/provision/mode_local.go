@@ -22,7 +22,9 @@ import (
 	"github.com/docker/docker/client"
 	"github.com/pkg/errors"
 
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/certs"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/databases/postgres"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/databases/postgres/pgconfig"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/docker"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/pool"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
@@ -55,6 +57,9 @@ type Config struct {
 	UseSudo           bool              `yaml:"useSudo"`
 	KeepUserPasswords bool              `yaml:"keepUserPasswords"`
 	ContainerConfig   map[string]string `yaml:"containerConfig"`
+	InitScriptsDir    string            `yaml:"initScriptsDir"`
+	TLS               TLS               `yaml:"tls"`
+	Pooler            Pooler            `yaml:"pooler"`
 }
 
 // Provisioner describes a struct for ports and clones management.
@@ -71,6 +76,9 @@ type Provisioner struct {
 	pm             *pool.Manager
 	networkID      string
 	instanceID     string
+	tlsMu          sync.Mutex
+	accessHost     string
+	authority      *certs.Authority
 }
 
 // New creates a new Provisioner instance.
@@ -149,22 +157,91 @@ func (p *Provisioner) ContainerOptions() models.ContainerOptions {
 
 // StartSession starts a new session.
 func (p *Provisioner) StartSession(snapshotID string, user resources.EphemeralUser,
-	extraConfig map[string]string) (*resources.Session, error) {
+	extraConfig map[string]string, initScripts resources.InitScripts, allowedCIDRs []string) (*resources.Session, error) {
+	session, appConfig, err := p.startSession(snapshotID, extraConfig, allowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.prepareUser(appConfig, user, initScripts); err != nil {
+		if stopErr := p.StopSession(session); stopErr != nil {
+			log.Err("Failed to stop session:", stopErr)
+		}
+
+		return nil, errors.Wrap(err, "failed to prepare a database")
+	}
+
+	session.EphemeralUser = user
+	session.InitScripts = initScripts
+
+	return session, nil
+}
+
+// StartWarmSession starts a new session without an ephemeral user to keep it in a pool of pre-started clones.
+func (p *Provisioner) StartWarmSession(snapshotID string) (*resources.Session, error) {
+	session, _, err := p.startSession(snapshotID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// ActivateSession assigns a pre-started session: applies an extra configuration and access rules, and creates an ephemeral user.
+func (p *Provisioner) ActivateSession(session *resources.Session, user resources.EphemeralUser,
+	extraConfig map[string]string, initScripts resources.InitScripts, allowedCIDRs []string) error {
+	fsm, err := p.pm.GetFSManager(session.Pool)
+	if err != nil {
+		return errors.Wrap(err, "failed to find a filesystem manager of this session")
+	}
+
+	appConfig := p.getAppConfig(fsm.Pool(), util.GetCloneName(session.Port), session.Port)
+	appConfig.SetExtraConf(extraConfig)
+
+	if err := postgres.Reconfigure(p.runner, appConfig); err != nil {
+		return errors.Wrap(err, "failed to apply an extra configuration")
+	}
+
+	if len(allowedCIDRs) > 0 {
+		if err := p.applyAccessRules(appConfig, allowedCIDRs); err != nil {
+			return err
+		}
+
+		if err := postgres.ReloadConfig(appConfig); err != nil {
+			return errors.Wrap(err, "failed to apply access rules")
+		}
+	}
+
+	if err := p.prepareUser(appConfig, user, initScripts); err != nil {
+		return errors.Wrap(err, "failed to prepare a database")
+	}
+
+	session.EphemeralUser = user
+	session.ExtraConfig = extraConfig
+	session.InitScripts = initScripts
+	session.AllowedCIDRs = allowedCIDRs
+
+	return nil
+}
+
+// startSession creates a clone of the snapshot and starts a Postgres container on a free port.
+func (p *Provisioner) startSession(snapshotID string, extraConfig map[string]string,
+	allowedCIDRs []string) (*resources.Session, *resources.AppConfig, error) {
 	snapshot, err := p.getSnapshot(snapshotID)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get snapshots")
+		return nil, nil, errors.Wrap(err, "failed to get snapshots")
 	}
 
 	port, err := p.allocatePort()
 	if err != nil {
-		return nil, errors.New("failed to get a free port")
+		return nil, nil, errors.New("failed to get a free port")
 	}
 
 	name := util.GetCloneName(port)
 
 	fsm, err := p.pm.GetFSManager(snapshot.Pool)
 	if err != nil {
-		return nil, fmt.Errorf("cannot work with pool %s: %w", snapshot.Pool, err)
+		return nil, nil, fmt.Errorf("cannot work with pool %s: %w", snapshot.Pool, err)
 	}
 
 	log.Dbg(fmt.Sprintf(`Starting session for port: %d.`, port))
@@ -180,33 +257,43 @@ func (p *Provisioner) StartSession(snapshotID string, user resources.EphemeralUs
 	}()
 
 	if err = fsm.CreateClone(name, snapshot.ID); err != nil {
-		return nil, errors.Wrap(err, "failed to create clone")
+		return nil, nil, errors.Wrap(err, "failed to create clone")
 	}
 
 	appConfig := p.getAppConfig(fsm.Pool(), name, port)
 	appConfig.SetExtraConf(extraConfig)
 
+	if err = p.setupTLS(appConfig); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to set up TLS")
+	}
+
+	if len(allowedCIDRs) > 0 || p.config.TLS.Enabled {
+		if err = p.applyAccessRules(appConfig, allowedCIDRs); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	if err = postgres.Start(p.runner, appConfig); err != nil {
-		return nil, errors.Wrap(err, "failed to start a container")
+		return nil, nil, errors.Wrap(err, "failed to start a container")
 	}
 
-	if err = p.prepareDB(appConfig, user); err != nil {
-		return nil, errors.Wrap(err, "failed to prepare a database")
+	if err = p.resetPasswords(appConfig); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to prepare a database")
 	}
 
 	atomic.AddUint32(&p.sessionCounter, 1)
 
 	session := &resources.Session{
-		ID:            strconv.FormatUint(uint64(p.sessionCounter), 10),
-		Pool:          fsm.Pool().Name,
-		Port:          port,
-		User:          appConfig.DB.Username,
-		SocketHost:    appConfig.Host,
-		EphemeralUser: user,
-		ExtraConfig:   extraConfig,
+		ID:           strconv.FormatUint(uint64(p.sessionCounter), 10),
+		Pool:         fsm.Pool().Name,
+		Port:         port,
+		User:         appConfig.DB.Username,
+		SocketHost:   appConfig.Host,
+		ExtraConfig:  extraConfig,
+		AllowedCIDRs: allowedCIDRs,
 	}
 
-	return session, nil
+	return session, appConfig, nil
 }
 
 // StopSession stops an existing session.
@@ -218,6 +305,10 @@ func (p *Provisioner) StopSession(session *resources.Session) error {
 
 	name := util.GetCloneName(session.Port)
 
+	if err := p.StopPooler(p.ctx, session); err != nil {
+		return errors.Wrap(err, "failed to stop a pooler")
+	}
+
 	if err := postgres.Stop(p.runner, fsm.Pool(), name); err != nil {
 		return errors.Wrap(err, "failed to stop a container")
 	}
@@ -282,11 +373,21 @@ func (p *Provisioner) ResetSession(session *resources.Session, snapshotID string
 	appConfig := p.getAppConfig(newFSManager.Pool(), name, session.Port)
 	appConfig.SetExtraConf(session.ExtraConfig)
 
+	if err = p.setupTLS(appConfig); err != nil {
+		return nil, errors.Wrap(err, "failed to set up TLS")
+	}
+
+	if len(session.AllowedCIDRs) > 0 || p.config.TLS.Enabled {
+		if err = p.applyAccessRules(appConfig, session.AllowedCIDRs); err != nil {
+			return nil, err
+		}
+	}
+
 	if err = postgres.Start(p.runner, appConfig); err != nil {
 		return nil, errors.Wrap(err, "failed to start container")
 	}
 
-	if err = p.prepareDB(appConfig, session.EphemeralUser); err != nil {
+	if err = p.prepareDB(appConfig, session.EphemeralUser, session.InitScripts); err != nil {
 		return nil, errors.Wrap(err, "failed to prepare database")
 	}
 
@@ -299,6 +400,80 @@ func (p *Provisioner) ResetSession(session *resources.Session, snapshotID string
 	return snapshotModel, nil
 }
 
+// UpdateSessionAccess replaces the list of networks allowed to connect to the session and reloads the rules
+// without a restart. An empty list allows connections from any address.
+func (p *Provisioner) UpdateSessionAccess(session *resources.Session, allowedCIDRs []string) error {
+	fsm, err := p.pm.GetFSManager(session.Pool)
+	if err != nil {
+		return errors.Wrap(err, "failed to find a filesystem manager of this session")
+	}
+
+	appConfig := p.getAppConfig(fsm.Pool(), util.GetCloneName(session.Port), session.Port)
+
+	if err := p.applyAccessRules(appConfig, allowedCIDRs); err != nil {
+		return err
+	}
+
+	if err := postgres.ReloadConfig(appConfig); err != nil {
+		return errors.Wrap(err, "failed to apply access rules")
+	}
+
+	if session.PoolerPort != 0 {
+		if err := p.updatePoolerAccess(p.ctx, session, allowedCIDRs); err != nil {
+			return errors.Wrap(err, "failed to apply access rules to the pooler")
+		}
+	}
+
+	session.AllowedCIDRs = allowedCIDRs
+
+	return nil
+}
+
+// applyAccessRules renders the allowed networks into pg_hba.conf of the clone.
+// The internal network of the instance is always allowed, so sidecars like a connection pooler can reach the clone.
+// If TLS is enabled, connections over the network must use SSL.
+func (p *Provisioner) applyAccessRules(appConfig *resources.AppConfig, allowedCIDRs []string) error {
+	configManager, err := pgconfig.NewCorrector(appConfig.DataDir())
+	if err != nil {
+		return errors.Wrap(err, "failed to create a config manager")
+	}
+
+	rules := allowedCIDRs
+
+	if len(allowedCIDRs) > 0 {
+		internalSubnets, err := p.internalSubnets()
+		if err != nil {
+			return errors.Wrap(err, "failed to get subnets of the internal network")
+		}
+
+		rules = append(append(make([]string, 0, len(allowedCIDRs)+len(internalSubnets)), allowedCIDRs...), internalSubnets...)
+	}
+
+	if err := configManager.ApplyHBA(rules, p.config.TLS.Enabled); err != nil {
+		return errors.Wrap(err, "failed to apply access rules")
+	}
+
+	return nil
+}
+
+// internalSubnets returns subnets of the internal Docker network of the instance.
+func (p *Provisioner) internalSubnets() ([]string, error) {
+	internalNetwork, err := p.dockerClient.NetworkInspect(p.ctx, p.networkID, types.NetworkInspectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	subnets := make([]string, 0, len(internalNetwork.IPAM.Config))
+
+	for _, ipamConfig := range internalNetwork.IPAM.Config {
+		if ipamConfig.Subnet != "" {
+			subnets = append(subnets, ipamConfig.Subnet)
+		}
+	}
+
+	return subnets, nil
+}
+
 // GetSnapshots provides a snapshot list from active pools.
 func (p *Provisioner) GetSnapshots() ([]resources.Snapshot, error) {
 	snapshots := []resources.Snapshot{}
@@ -562,6 +737,10 @@ func (p *Provisioner) stopPoolSessions(fsm pool.FSManager, exceptClones map[stri
 		if err = postgres.Stop(p.runner, fsPool, instance); err != nil {
 			return errors.Wrap(err, "failed to container")
 		}
+
+		if err := p.removeClonePooler(p.ctx, instance); err != nil {
+			log.Err("Failed to remove a pooler:", err)
+		}
 	}
 
 	clones, err := fsm.ListClonesNames()
@@ -686,7 +865,15 @@ func (p *Provisioner) scanCSVLogFile(ctx context.Context, filename string, avail
 	}
 }
 
-func (p *Provisioner) prepareDB(pgConf *resources.AppConfig, user resources.EphemeralUser) error {
+func (p *Provisioner) prepareDB(pgConf *resources.AppConfig, user resources.EphemeralUser, initScripts resources.InitScripts) error {
+	if err := p.resetPasswords(pgConf); err != nil {
+		return err
+	}
+
+	return p.prepareUser(pgConf, user, initScripts)
+}
+
+func (p *Provisioner) resetPasswords(pgConf *resources.AppConfig) error {
 	if !p.config.KeepUserPasswords {
 		whitelist := []string{p.dbCfg.Username}
 
@@ -695,10 +882,18 @@ func (p *Provisioner) prepareDB(pgConf *resources.AppConfig, user resources.Ephe
 		}
 	}
 
+	return nil
+}
+
+func (p *Provisioner) prepareUser(pgConf *resources.AppConfig, user resources.EphemeralUser, initScripts resources.InitScripts) error {
 	if err := postgres.CreateUser(pgConf, user); err != nil {
 		return errors.Wrap(err, "failed to create user")
 	}
 
+	if err := p.runInitScripts(pgConf, user, initScripts); err != nil {
+		return errors.Wrap(err, "failed to run init scripts")
+	}
+
 	return nil
 }
 
@@ -0,0 +1,294 @@
+/*
+2022 © Postgres.ai
+*/
+
+package provision
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/docker"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/util"
+)
+
+const (
+	poolerPrefix     = "dblab_pooler_"
+	labelPooler      = "dblab_pooler"
+	poolerConfigDir  = "/etc/pgbouncer"
+	poolerConfigName = "pgbouncer.ini"
+	poolerUsersName  = "userlist.txt"
+	poolerHBAName    = "hba.txt"
+
+	poolerStopTimeout = 10 * time.Second
+
+	defaultPoolerImage          = "edoburu/pgbouncer:1.18.0"
+	defaultPoolerMode           = "transaction"
+	defaultPoolerPoolSize       = 20
+	defaultPoolerMaxClientConns = 1000
+)
+
+// Pooler defines options of connection pooler sidecars of clones.
+type Pooler struct {
+	DockerImage     string `yaml:"dockerImage"`
+	PoolMode        string `yaml:"poolMode"`
+	DefaultPoolSize uint   `yaml:"defaultPoolSize"`
+	MaxClientConn   uint   `yaml:"maxClientConn"`
+}
+
+// StartPooler starts a PgBouncer sidecar for the session on a separate port.
+// The sidecar connects to the clone over the internal network and authenticates clients as the ephemeral user.
+func (p *Provisioner) StartPooler(ctx context.Context, session *resources.Session) (err error) {
+	if session.EphemeralUser.Name == "" {
+		return errors.New("the session has no ephemeral user")
+	}
+
+	poolerImage := p.config.Pooler.DockerImage
+	if poolerImage == "" {
+		poolerImage = defaultPoolerImage
+	}
+
+	if err := docker.PrepareImage(p.runner, poolerImage); err != nil {
+		return fmt.Errorf("cannot prepare docker image %s: %w", poolerImage, err)
+	}
+
+	port, err := p.allocatePort()
+	if err != nil {
+		return errors.Wrap(err, "failed to get a free port for a pooler")
+	}
+
+	name := getPoolerName(session.Port)
+
+	defer func() {
+		if err != nil {
+			tools.RemoveContainer(ctx, p.dockerClient, name, poolerStopTimeout)
+
+			if portErr := p.FreePort(port); portErr != nil {
+				log.Err(portErr)
+			}
+		}
+	}()
+
+	poolerPort := nat.Port(strconv.FormatUint(uint64(port), 10) + "/tcp")
+
+	poolerCont, err := p.dockerClient.ContainerCreate(ctx,
+		&container.Config{
+			Labels:       map[string]string{labelPooler: util.GetCloneName(session.Port)},
+			Image:        poolerImage,
+			ExposedPorts: nat.PortSet{poolerPort: struct{}{}},
+		},
+		&container.HostConfig{
+			PortBindings: nat.PortMap{
+				poolerPort: {{HostPort: poolerPort.Port()}},
+			},
+		},
+		&network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{p.networkID: {}},
+		},
+		nil,
+		name,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create a pooler container")
+	}
+
+	configArchive, err := p.buildPoolerConfigArchive(session, port)
+	if err != nil {
+		return err
+	}
+
+	if err := p.dockerClient.CopyToContainer(ctx, poolerCont.ID, poolerConfigDir, configArchive,
+		types.CopyToContainerOptions{AllowOverwriteDirWithFile: true}); err != nil {
+		return errors.Wrap(err, "failed to copy the pooler configuration")
+	}
+
+	if err := p.dockerClient.ContainerStart(ctx, poolerCont.ID, types.ContainerStartOptions{}); err != nil {
+		return errors.Wrap(err, "failed to start a pooler container")
+	}
+
+	session.PoolerPort = port
+
+	return nil
+}
+
+// StopPooler removes the connection pooler sidecar of the session.
+func (p *Provisioner) StopPooler(ctx context.Context, session *resources.Session) error {
+	if session.PoolerPort == 0 {
+		return nil
+	}
+
+	tools.RemoveContainer(ctx, p.dockerClient, getPoolerName(session.Port), poolerStopTimeout)
+
+	if err := p.FreePort(session.PoolerPort); err != nil {
+		return errors.Wrap(err, "failed to unbind a pooler port")
+	}
+
+	session.PoolerPort = 0
+
+	return nil
+}
+
+// removeClonePooler removes a pooler sidecar of the clone if there is one.
+func (p *Provisioner) removeClonePooler(ctx context.Context, cloneName string) error {
+	poolers, err := p.dockerClient.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", labelPooler+"="+cloneName)),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list pooler containers")
+	}
+
+	for _, pooler := range poolers {
+		tools.RemoveContainer(ctx, p.dockerClient, pooler.ID, poolerStopTimeout)
+	}
+
+	return nil
+}
+
+// buildPoolerConfigArchive builds a tar archive with the PgBouncer configuration, access rules and the generated user list.
+func (p *Provisioner) buildPoolerConfigArchive(session *resources.Session, port uint) (*bytes.Buffer, error) {
+	files := map[string]string{
+		poolerConfigName: buildPoolerConfig(p.config.Pooler, util.GetCloneName(session.Port), session.Port, port, p.config.TLS.Enabled),
+		poolerUsersName:  buildPoolerUserList(session.EphemeralUser),
+	}
+
+	return buildPoolerArchive(files, session.AllowedCIDRs)
+}
+
+// updatePoolerAccess replaces access rules of the pooler and makes PgBouncer reload them.
+func (p *Provisioner) updatePoolerAccess(ctx context.Context, session *resources.Session, allowedCIDRs []string) error {
+	configArchive, err := buildPoolerArchive(nil, allowedCIDRs)
+	if err != nil {
+		return err
+	}
+
+	name := getPoolerName(session.Port)
+
+	if err := p.dockerClient.CopyToContainer(ctx, name, poolerConfigDir, configArchive,
+		types.CopyToContainerOptions{AllowOverwriteDirWithFile: true}); err != nil {
+		return errors.Wrap(err, "failed to copy the pooler access rules")
+	}
+
+	if err := p.dockerClient.ContainerKill(ctx, name, "SIGHUP"); err != nil {
+		return errors.Wrap(err, "failed to reload the pooler configuration")
+	}
+
+	return nil
+}
+
+// buildPoolerArchive builds a tar archive with pooler configuration files and access rules.
+// Files are readable by everyone because PgBouncer does not run as root in the container.
+func buildPoolerArchive(files map[string]string, allowedCIDRs []string) (*bytes.Buffer, error) {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	contents := map[string]string{poolerHBAName: buildPoolerHBA(allowedCIDRs)}
+	for name, content := range files {
+		contents[name] = content
+	}
+
+	for name, content := range contents {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(content)),
+			ModTime: time.Now(),
+		}); err != nil {
+			return nil, errors.Wrap(err, "failed to write a pooler configuration header")
+		}
+
+		if _, err := tw.Write([]byte(content)); err != nil {
+			return nil, errors.Wrap(err, "failed to write a pooler configuration")
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to build a pooler configuration archive")
+	}
+
+	return buf, nil
+}
+
+func buildPoolerConfig(cfg Pooler, cloneHost string, clonePort, listenPort uint, serverTLS bool) string {
+	poolMode := cfg.PoolMode
+	if poolMode == "" {
+		poolMode = defaultPoolerMode
+	}
+
+	poolSize := cfg.DefaultPoolSize
+	if poolSize == 0 {
+		poolSize = defaultPoolerPoolSize
+	}
+
+	maxClientConn := cfg.MaxClientConn
+	if maxClientConn == 0 {
+		maxClientConn = defaultPoolerMaxClientConns
+	}
+
+	serverTLSMode := "disable"
+	if serverTLS {
+		// Clones accept only SSL connections over the network when TLS is enabled.
+		serverTLSMode = "require"
+	}
+
+	return strings.Join([]string{
+		"[databases]",
+		fmt.Sprintf("* = host=%s port=%d", cloneHost, clonePort),
+		"",
+		"[pgbouncer]",
+		"listen_addr = 0.0.0.0",
+		fmt.Sprintf("listen_port = %d", listenPort),
+		"auth_type = hba",
+		fmt.Sprintf("auth_file = %s/%s", poolerConfigDir, poolerUsersName),
+		fmt.Sprintf("auth_hba_file = %s/%s", poolerConfigDir, poolerHBAName),
+		"pool_mode = " + poolMode,
+		fmt.Sprintf("default_pool_size = %d", poolSize),
+		fmt.Sprintf("max_client_conn = %d", maxClientConn),
+		"ignore_startup_parameters = extra_float_digits",
+		"server_tls_sslmode = " + serverTLSMode,
+		"",
+	}, "\n")
+}
+
+// buildPoolerUserList generates a PgBouncer user list with credentials of the ephemeral user.
+func buildPoolerUserList(user resources.EphemeralUser) string {
+	return fmt.Sprintf("%s %s\n", quotePoolerValue(user.Name), quotePoolerValue(user.Password))
+}
+
+// buildPoolerHBA generates PgBouncer access rules: clients are allowed only from the listed networks if any.
+func buildPoolerHBA(allowedCIDRs []string) string {
+	if len(allowedCIDRs) == 0 {
+		allowedCIDRs = []string{"0.0.0.0/0", "::/0"}
+	}
+
+	rules := make([]string, 0, len(allowedCIDRs))
+
+	for _, cidr := range allowedCIDRs {
+		rules = append(rules, fmt.Sprintf("host all all %s md5", cidr))
+	}
+
+	return strings.Join(rules, "\n") + "\n"
+}
+
+func quotePoolerValue(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+func getPoolerName(clonePort uint) string {
+	return poolerPrefix + strconv.FormatUint(uint64(clonePort), 10)
+}
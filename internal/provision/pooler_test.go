@@ -0,0 +1,65 @@
+package provision
+
+import (
+	"archive/tar"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
+)
+
+func TestBuildPoolerConfig(t *testing.T) {
+	config := buildPoolerConfig(Pooler{PoolMode: "session"}, "dblab_clone_6000", 6000, 6001, false)
+
+	assert.Contains(t, config, "* = host=dblab_clone_6000 port=6000\n")
+	assert.Contains(t, config, "listen_port = 6001\n")
+	assert.Contains(t, config, "pool_mode = session\n")
+	assert.Contains(t, config, "default_pool_size = 20\n")
+	assert.Contains(t, config, "auth_hba_file = /etc/pgbouncer/hba.txt\n")
+	assert.Contains(t, config, "server_tls_sslmode = disable\n")
+
+	config = buildPoolerConfig(Pooler{}, "dblab_clone_6000", 6000, 6001, true)
+
+	assert.Contains(t, config, "server_tls_sslmode = require\n")
+}
+
+func TestBuildPoolerUserList(t *testing.T) {
+	userList := buildPoolerUserList(resources.EphemeralUser{Name: "john", Password: `se"cret`})
+
+	assert.Equal(t, "\"john\" \"se\"\"cret\"\n", userList)
+}
+
+func TestBuildPoolerHBA(t *testing.T) {
+	assert.Equal(t, "host all all 0.0.0.0/0 md5\nhost all all ::/0 md5\n", buildPoolerHBA(nil))
+	assert.Equal(t, "host all all 10.0.0.0/8 md5\n", buildPoolerHBA([]string{"10.0.0.0/8"}))
+}
+
+func TestBuildPoolerArchive(t *testing.T) {
+	buf, err := buildPoolerArchive(map[string]string{poolerUsersName: "\"john\" \"secret\"\n"}, []string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	tr := tar.NewReader(buf)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+
+		files[header.Name] = string(content)
+	}
+
+	assert.Equal(t, map[string]string{
+		poolerUsersName: "\"john\" \"secret\"\n",
+		poolerHBAName:   "host all all 10.0.0.0/8 md5\n",
+	}, files)
+}
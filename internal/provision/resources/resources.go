@@ -20,6 +20,9 @@ type Session struct {
 	SocketHost    string            `json:"socketHost"`
 	EphemeralUser EphemeralUser     `json:"ephemeralUser"`
 	ExtraConfig   map[string]string `json:"extraConfig"`
+	InitScripts   InitScripts       `json:"initScripts"`
+	AllowedCIDRs  []string          `json:"allowedCIDRs"`
+	PoolerPort    uint              `json:"poolerPort"`
 }
 
 // EphemeralUser describes an ephemeral database user defined by Database Lab users.
@@ -31,6 +34,17 @@ type EphemeralUser struct {
 	AvailableDB string `json:"availableDB"`
 }
 
+// InitScripts describes SQL scripts that are run as superuser after an ephemeral user is created.
+type InitScripts struct {
+	Bundles []string `json:"bundles"`
+	SQL     string   `json:"sql"`
+}
+
+// IsEmpty checks if there are no init scripts to run.
+func (s InitScripts) IsEmpty() bool {
+	return len(s.Bundles) == 0 && s.SQL == ""
+}
+
 // Snapshot defines snapshot of the data with related meta-information.
 type Snapshot struct {
 	ID                string
@@ -490,6 +490,20 @@ func (m *Manager) GetFilesystemState() (models.FileSystem, error) {
 
 // GetSnapshots returns a snapshot list.
 func (m *Manager) GetSnapshots() ([]resources.Snapshot, error) {
+	// Filter pre-snapshots, they will not be allowed to be used for cloning.
+	return m.getSnapshots(false)
+}
+
+// GetPreSnapshots returns a list of pre-snapshots, which keep data of the sync instance before promotion.
+func (m *Manager) GetPreSnapshots() ([]resources.Snapshot, error) {
+	if m.config.PreSnapshotSuffix == "" {
+		return nil, errors.New("pre-snapshot suffix is not configured")
+	}
+
+	return m.getSnapshots(true)
+}
+
+func (m *Manager) getSnapshots(preSnapshots bool) ([]resources.Snapshot, error) {
 	entries, err := m.listSnapshots(m.config.Pool.Name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list snapshots: %w", err)
@@ -498,8 +512,7 @@ func (m *Manager) GetSnapshots() ([]resources.Snapshot, error) {
 	snapshots := make([]resources.Snapshot, 0, len(entries))
 
 	for _, entry := range entries {
-		// Filter pre-snapshots, they will not be allowed to be used for cloning.
-		if strings.HasSuffix(entry.Name, m.config.PreSnapshotSuffix) {
+		if strings.HasSuffix(entry.Name, m.config.PreSnapshotSuffix) != preSnapshots {
 			continue
 		}
 
@@ -0,0 +1,155 @@
+/*
+2022 © Postgres.ai
+*/
+
+package provision
+
+import (
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/certs"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/databases/postgres/pgconfig"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/util"
+)
+
+const (
+	// tlsMetaDir defines the metadata directory to keep the local CA.
+	tlsMetaDir = "tls"
+
+	// serverCertFilename and serverKeyFilename define names of the clone certificate files placed into PGDATA.
+	serverCertFilename = "dblab_server.crt"
+	serverKeyFilename  = "dblab_server.key"
+
+	defaultTLSHost = "localhost"
+)
+
+// TLS defines options of TLS connections to clones.
+type TLS struct {
+	// Enabled turns on TLS in clones. Server certificates are issued by the local CA of the instance.
+	Enabled bool `yaml:"enabled"`
+}
+
+// SetAccessHost sets the host that clients use to connect to clones. Server certificates of clones are issued for this host.
+func (p *Provisioner) SetAccessHost(host string) {
+	p.tlsMu.Lock()
+	defer p.tlsMu.Unlock()
+
+	p.accessHost = host
+}
+
+// TLSCACert returns the PEM-encoded certificate of the local CA. It returns nil if TLS is disabled.
+func (p *Provisioner) TLSCACert() []byte {
+	if !p.config.TLS.Enabled {
+		return nil
+	}
+
+	authority, err := p.certAuthority()
+	if err != nil {
+		return nil
+	}
+
+	return authority.CertPEM()
+}
+
+// certAuthority loads the local CA on the first use.
+func (p *Provisioner) certAuthority() (*certs.Authority, error) {
+	p.tlsMu.Lock()
+	defer p.tlsMu.Unlock()
+
+	if p.authority != nil {
+		return p.authority, nil
+	}
+
+	caDir, err := util.GetMetaPath(tlsMetaDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the path of the CA directory")
+	}
+
+	authority, err := certs.LoadOrCreateAuthority(caDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the local CA")
+	}
+
+	p.authority = authority
+
+	return authority, nil
+}
+
+// setupTLS issues a server certificate for the clone, puts it into PGDATA which is mounted into the clone container,
+// and enables SSL in the clone configuration.
+func (p *Provisioner) setupTLS(appConfig *resources.AppConfig) error {
+	if !p.config.TLS.Enabled {
+		return nil
+	}
+
+	authority, err := p.certAuthority()
+	if err != nil {
+		return err
+	}
+
+	p.tlsMu.Lock()
+	host := p.accessHost
+	p.tlsMu.Unlock()
+
+	if host == "" {
+		host = defaultTLSHost
+	}
+
+	certPEM, keyPEM, err := authority.IssueServerCert(host)
+	if err != nil {
+		return errors.Wrap(err, "failed to issue a server certificate")
+	}
+
+	dataDir := appConfig.DataDir()
+
+	if err := writeOwnedFile(dataDir, serverCertFilename, certPEM); err != nil {
+		return errors.Wrap(err, "failed to write the server certificate")
+	}
+
+	if err := writeOwnedFile(dataDir, serverKeyFilename, keyPEM); err != nil {
+		return errors.Wrap(err, "failed to write the server private key")
+	}
+
+	configManager, err := pgconfig.NewCorrector(dataDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to create a config manager")
+	}
+
+	if err := configManager.AppendGeneralConfig(map[string]string{
+		"ssl":           "on",
+		"ssl_cert_file": serverCertFilename,
+		"ssl_key_file":  serverKeyFilename,
+	}); err != nil {
+		return errors.Wrap(err, "failed to enable SSL")
+	}
+
+	return nil
+}
+
+// writeOwnedFile writes a file readable only by the owner of the directory,
+// so Postgres running in the container accepts the private key.
+func writeOwnedFile(dir, name string, data []byte) error {
+	filename := path.Join(dir, name)
+
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return err
+	}
+
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+
+	if stat, ok := dirInfo.Sys().(*syscall.Stat_t); ok {
+		if err := os.Chown(filename, int(stat.Uid), int(stat.Gid)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"context"
 
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/config"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
 )
 
 // JobBuilder builds jobs.
@@ -28,3 +29,27 @@ type JobRunner interface {
 	// Run starts a job.
 	Run(ctx context.Context) error
 }
+
+// DataSizer is implemented by jobs that dump or restore data.
+type DataSizer interface {
+	// DataSize returns the number of bytes dumped or restored by the job.
+	DataSize() (uint64, error)
+}
+
+// ProgressReporter is implemented by jobs that report progress of processing databases.
+type ProgressReporter interface {
+	// Progress returns progress of processing databases by the last job run.
+	Progress() []models.DatabaseProgress
+}
+
+// RefreshRequester is implemented by jobs that detect when the retrieved data has to be fully refreshed.
+type RefreshRequester interface {
+	// RefreshRequests returns a channel receiving reasons to perform a full refresh.
+	RefreshRequests() <-chan string
+}
+
+// Resumer is implemented by jobs that keep working in the background after the retrieval.
+type Resumer interface {
+	// Resume restarts background work of the job when the engine starts without a data refresh.
+	Resume(ctx context.Context) error
+}
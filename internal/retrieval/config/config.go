@@ -6,17 +6,22 @@
 package config
 
 import (
+	"context"
+
 	"github.com/docker/docker/client"
 
 	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/dbmarker"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/telemetry"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
 )
 
 // Config describes of data retrieval jobs.
 type Config struct {
-	Refresh  Refresh            `yaml:"refresh"`
-	Jobs     []string           `yaml:"jobs,flow"`
-	JobsSpec map[string]JobSpec `yaml:"spec"`
+	Refresh        Refresh            `yaml:"refresh"`
+	SyncMonitoring SyncMonitoring     `yaml:"syncMonitoring"`
+	Jobs           []string           `yaml:"jobs,flow"`
+	JobsSpec       map[string]JobSpec `yaml:"spec"`
 }
 
 // Refresh describes full-refresh options.
@@ -24,6 +29,21 @@ type Refresh struct {
 	Timetable string `yaml:"timetable"`
 }
 
+// SyncMonitoring describes options of the sync instance monitoring in the physical mode.
+type SyncMonitoring struct {
+	// Interval defines how often (in seconds) the sync instance is checked. Zero disables the monitoring.
+	Interval int64 `yaml:"interval"`
+
+	// MaxReplayLag defines the replay lag (in seconds) to raise an alert. Zero disables the check.
+	MaxReplayLag int64 `yaml:"maxReplayLag"`
+
+	// MaxReplayLagBytes defines the amount of received but not replayed WAL (in bytes) to raise an alert. Zero disables the check.
+	MaxReplayLagBytes int64 `yaml:"maxReplayLagBytes"`
+
+	// RequireStreaming makes the monitoring raise an alert if the WAL receiver is not streaming.
+	RequireStreaming bool `yaml:"requireStreaming"`
+}
+
 // JobSpec contains details about a job.
 type JobSpec struct {
 	Name    string                 `yaml:"name"`
@@ -32,8 +52,23 @@ type JobSpec struct {
 
 // JobConfig describes a job configuration.
 type JobConfig struct {
-	Spec   JobSpec
-	Docker *client.Client
-	Marker *dbmarker.Marker
-	FSPool *resources.Pool
+	Spec    JobSpec
+	Docker  *client.Client
+	Marker  *dbmarker.Marker
+	FSPool  *resources.Pool
+	Alerter Alerter
+
+	// SnapshotListener is notified when a job creates a snapshot.
+	SnapshotListener SnapshotListener
+}
+
+// SnapshotListener handles snapshots created by jobs.
+type SnapshotListener interface {
+	SnapshotCreated()
+}
+
+// Alerter reports alerts of jobs to the retrieval state.
+type Alerter interface {
+	ReportAlert(ctx context.Context, alert telemetry.Alert)
+	ResolveAlert(alertType models.AlertType)
 }
@@ -51,6 +51,9 @@ func (s *JobBuilder) BuildJob(jobCfg config.JobConfig) (components.JobRunner, er
 	case logical.RestoreJobType:
 		return logical.NewJob(jobCfg, s.globalCfg, s.engineProps)
 
+	case logical.ReplicationJobType:
+		return logical.NewReplicationJob(jobCfg, s.globalCfg, s.engineProps)
+
 	case physical.RestoreJobType:
 		return physical.NewJob(jobCfg, s.globalCfg, s.engineProps)
 
@@ -5,7 +5,13 @@
 package logical
 
 import (
+	"fmt"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
 type compressionType string
@@ -14,8 +20,29 @@ const (
 	noCompression    compressionType = "no"
 	gzipCompression  compressionType = "gzip"
 	bzip2Compression compressionType = "bzip2"
+	zstdCompression  compressionType = "zstd"
+	lz4Compression   compressionType = "lz4"
+
+	// pgDumpCompressionMethodsVersion defines the first version of pg_dump supporting compression methods other than gzip.
+	pgDumpCompressionMethodsVersion = 16
+
+	// zstdMaxRegularLevel defines the highest zstd compression level available without the --ultra flag.
+	zstdMaxRegularLevel = 19
 )
 
+// pgDumpVersionRegexp extracts the major version from the pg_dump --version output.
+var pgDumpVersionRegexp = regexp.MustCompile(`\(PostgreSQL\) (\d+)`)
+
+// isValidCompression checks if the compression type is supported.
+func isValidCompression(compressionType compressionType) bool {
+	switch compressionType {
+	case "", noCompression, gzipCompression, bzip2Compression, zstdCompression, lz4Compression:
+		return true
+	}
+
+	return false
+}
+
 // getReadingArchiveCommand chooses command to read dump file.
 func getReadingArchiveCommand(compressionType compressionType) string {
 	switch compressionType {
@@ -25,11 +52,54 @@ func getReadingArchiveCommand(compressionType compressionType) string {
 	case bzip2Compression:
 		return "bunzip2 -c"
 
+	case zstdCompression:
+		return "zstd -dc"
+
+	case lz4Compression:
+		return "lz4 -dc"
+
 	default:
 		return "cat"
 	}
 }
 
+// getWritingArchiveCommand chooses command to compress a plain-text dump read from stdin.
+// The zero level means the default level of the tool. Only zstd compresses in multiple threads.
+func getWritingArchiveCommand(compressionType compressionType, level, threads int) string {
+	var command []string
+
+	switch compressionType {
+	case gzipCompression:
+		command = []string{"gzip", "-c"}
+
+	case bzip2Compression:
+		command = []string{"bzip2", "-c"}
+
+	case zstdCompression:
+		command = []string{"zstd", "-c", "-q"}
+
+		if level > zstdMaxRegularLevel {
+			command = append(command, "--ultra")
+		}
+
+		if threads > 0 {
+			command = append(command, "-T"+strconv.Itoa(threads))
+		}
+
+	case lz4Compression:
+		command = []string{"lz4", "-c", "-q"}
+
+	default:
+		return ""
+	}
+
+	if level > 0 {
+		command = append(command, "-"+strconv.Itoa(level))
+	}
+
+	return strings.Join(command, " ")
+}
+
 // getCompressionType returns archive type based on filename extension.
 func getCompressionType(filename string) compressionType {
 	switch filepath.Ext(filename) {
@@ -39,7 +109,79 @@ func getCompressionType(filename string) compressionType {
 	case ".bz2":
 		return bzip2Compression
 
+	case ".zst", ".zstd":
+		return zstdCompression
+
+	case ".lz4":
+		return lz4Compression
+
 	default:
 		return noCompression
 	}
 }
+
+// getArchiveExtension returns the filename extension of a plain-text dump compressed with the compression type.
+func getArchiveExtension(compressionType compressionType) string {
+	switch compressionType {
+	case gzipCompression:
+		return ".gz"
+
+	case bzip2Compression:
+		return ".bz2"
+
+	case zstdCompression:
+		return ".zst"
+
+	case lz4Compression:
+		return ".lz4"
+
+	default:
+		return ".sql"
+	}
+}
+
+// getPGDumpCompressOption builds a value of the pg_dump --compress option for custom and directory dumps.
+// It returns an empty string if pg_dump has to use its default compression.
+func getPGDumpCompressOption(compressionType compressionType, level, pgDumpVersion int) (string, error) {
+	switch compressionType {
+	case "":
+		return "", nil
+
+	case noCompression:
+		return "0", nil
+
+	case gzipCompression:
+		if pgDumpVersion < pgDumpCompressionMethodsVersion {
+			if level == 0 {
+				return "", nil
+			}
+
+			return strconv.Itoa(level), nil
+		}
+
+	case zstdCompression, lz4Compression:
+		if pgDumpVersion < pgDumpCompressionMethodsVersion {
+			return "", errors.Errorf("%s compression of custom and directory dumps requires pg_dump %d or newer, found %d",
+				compressionType, pgDumpCompressionMethodsVersion, pgDumpVersion)
+		}
+
+	default:
+		return "", errors.Errorf("%s compression is available only for plain-text dumps", compressionType)
+	}
+
+	if level == 0 {
+		return string(compressionType), nil
+	}
+
+	return fmt.Sprintf("%s:%d", compressionType, level), nil
+}
+
+// parsePGDumpVersion extracts the major version of pg_dump from its --version output.
+func parsePGDumpVersion(output string) (int, error) {
+	matches := pgDumpVersionRegexp.FindStringSubmatch(output)
+	if len(matches) < 2 {
+		return 0, errors.Errorf("failed to parse pg_dump version: %q", output)
+	}
+
+	return strconv.Atoi(matches[1])
+}
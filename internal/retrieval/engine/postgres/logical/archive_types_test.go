@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestReadingArchiveCommand(t *testing.T) {
@@ -23,6 +24,14 @@ func TestReadingArchiveCommand(t *testing.T) {
 			compressionType: bzip2Compression,
 			expectedCommand: "bunzip2 -c",
 		},
+		{
+			compressionType: zstdCompression,
+			expectedCommand: "zstd -dc",
+		},
+		{
+			compressionType: lz4Compression,
+			expectedCommand: "lz4 -dc",
+		},
 		{
 			compressionType: noCompression,
 			expectedCommand: "cat",
@@ -52,6 +61,14 @@ func TestArchiveType(t *testing.T) {
 			filename:                "dump.bz2",
 			expectedCompressionType: bzip2Compression,
 		},
+		{
+			filename:                "dump.sql.zst",
+			expectedCompressionType: zstdCompression,
+		},
+		{
+			filename:                "dump.lz4",
+			expectedCompressionType: lz4Compression,
+		},
 		{
 			filename:                "test.dmp",
 			expectedCompressionType: noCompression,
@@ -67,3 +84,68 @@ func TestArchiveType(t *testing.T) {
 		assert.Equal(t, tc.expectedCompressionType, compressionType)
 	}
 }
+
+func TestWritingArchiveCommand(t *testing.T) {
+	testCases := []struct {
+		compressionType compressionType
+		level           int
+		threads         int
+		expectedCommand string
+	}{
+		{compressionType: noCompression, expectedCommand: ""},
+		{compressionType: gzipCompression, level: 6, expectedCommand: "gzip -c -6"},
+		{compressionType: bzip2Compression, threads: 4, expectedCommand: "bzip2 -c"},
+		{compressionType: zstdCompression, level: 3, threads: 8, expectedCommand: "zstd -c -q -T8 -3"},
+		{compressionType: zstdCompression, level: 22, expectedCommand: "zstd -c -q --ultra -22"},
+		{compressionType: lz4Compression, level: 9, threads: 4, expectedCommand: "lz4 -c -q -9"},
+	}
+
+	for _, tc := range testCases {
+		command := getWritingArchiveCommand(tc.compressionType, tc.level, tc.threads)
+		assert.Equal(t, tc.expectedCommand, command)
+	}
+}
+
+func TestPGDumpCompressOption(t *testing.T) {
+	testCases := []struct {
+		compressionType compressionType
+		level           int
+		pgDumpVersion   int
+		expectedOption  string
+		expectedError   bool
+	}{
+		{compressionType: "", pgDumpVersion: 14, expectedOption: ""},
+		{compressionType: noCompression, pgDumpVersion: 16, expectedOption: "0"},
+		{compressionType: gzipCompression, pgDumpVersion: 14, expectedOption: ""},
+		{compressionType: gzipCompression, level: 5, pgDumpVersion: 14, expectedOption: "5"},
+		{compressionType: gzipCompression, level: 5, pgDumpVersion: 16, expectedOption: "gzip:5"},
+		{compressionType: zstdCompression, pgDumpVersion: 16, expectedOption: "zstd"},
+		{compressionType: lz4Compression, level: 3, pgDumpVersion: 17, expectedOption: "lz4:3"},
+		{compressionType: zstdCompression, pgDumpVersion: 15, expectedError: true},
+		{compressionType: bzip2Compression, pgDumpVersion: 16, expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		option, err := getPGDumpCompressOption(tc.compressionType, tc.level, tc.pgDumpVersion)
+		if tc.expectedError {
+			assert.Error(t, err)
+			continue
+		}
+
+		require.NoError(t, err)
+		assert.Equal(t, tc.expectedOption, option)
+	}
+}
+
+func TestParsePGDumpVersion(t *testing.T) {
+	version, err := parsePGDumpVersion("pg_dump (PostgreSQL) 16.2 (Debian 16.2-1.pgdg120+2)\n")
+	require.NoError(t, err)
+	assert.Equal(t, 16, version)
+
+	version, err = parsePGDumpVersion("pg_dump (PostgreSQL) 9.6.24")
+	require.NoError(t, err)
+	assert.Equal(t, 9, version)
+
+	_, err = parsePGDumpVersion("command not found")
+	assert.Error(t, err)
+}
@@ -28,11 +28,14 @@ import (
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/cont"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/db"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/defaults"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/fs"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/health"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/objstore"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/options"
 
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/config/global"
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
 )
 
 const (
@@ -74,6 +77,8 @@ type DumpJob struct {
 	dumper       dumper
 	dbMarker     *dbmarker.Marker
 	dbMark       *dbmarker.Config
+	progress     *progressTracker
+	storage      *objstore.Storage
 	DumpOptions
 }
 
@@ -87,6 +92,15 @@ type DumpOptions struct {
 	Databases       map[string]DumpDefinition `yaml:"databases"`
 	ParallelJobs    int                       `yaml:"parallelJobs"`
 	Restore         ImmediateRestore          `yaml:"immediateRestore"`
+
+	// CompressionThreads defines the number of threads to compress plain-text dumps with zstd.
+	CompressionThreads int `yaml:"compressionThreads"`
+
+	// ObjectStorage defines an object storage to upload dumps to instead of keeping them in the dump location only.
+	ObjectStorage *DumpStorage `yaml:"objectStorage"`
+
+	// Replication prepares the source to keep restored data in sync by the logicalReplication job.
+	Replication ReplicationSource `yaml:"replication"`
 }
 
 // Source describes source of data to dump.
@@ -98,10 +112,33 @@ type Source struct {
 
 // DumpDefinition describes a database for dumping.
 type DumpDefinition struct {
-	Tables      []string        `yaml:"tables"`
-	Format      string          `yaml:"format"`
-	Compression compressionType `yaml:"compression"`
-	dbName      string
+	Tables           []string        `yaml:"tables"`
+	Format           string          `yaml:"format"`
+	Compression      compressionType `yaml:"compression"`
+	CompressionLevel int             `yaml:"compressionLevel"`
+
+	// ExcludeTables lists patterns of tables to skip.
+	ExcludeTables []string `yaml:"excludeTables"`
+
+	// ExcludeTableData lists patterns of tables to keep without data, for example, huge audit tables.
+	ExcludeTableData []string `yaml:"excludeTableData"`
+
+	// Schemas lists patterns of schemas to keep. All schemas are kept if it is empty.
+	Schemas []string `yaml:"schemas"`
+
+	// ExcludeSchemas lists patterns of schemas to skip.
+	ExcludeSchemas []string `yaml:"excludeSchemas"`
+
+	// Sampling maps schema-qualified tables to WHERE conditions to keep only matching rows.
+	Sampling map[string]string `yaml:"sampling"`
+
+	// Subset keeps only rows related to rows of root tables by foreign keys.
+	Subset *Subset `yaml:"subset"`
+
+	dbName       string
+	snapshot     string
+	tocList      string
+	subsetTables []sourceTable
 }
 
 type dumpJobConfig struct {
@@ -148,6 +185,7 @@ func NewDumpJob(jobCfg config.JobConfig, global *global.Config, engineProps glob
 		dbMark: &dbmarker.Config{
 			DataType: dbmarker.LogicalDataType,
 		},
+		progress: newProgressTracker(jobCfg.Spec.Name),
 	}
 
 	if err := dumpJob.Reload(jobCfg.Spec.Options); err != nil {
@@ -167,6 +205,61 @@ func (d *DumpJob) validate() error {
 Either set 'numberOfJobs' equals to 1 or disable the restore section`)
 	}
 
+	if d.Restore.Enabled && d.ObjectStorage != nil {
+		return errors.New("the object storage cannot be used for the direct restore")
+	}
+
+	if d.ObjectStorage != nil && d.ObjectStorage.Generations < 0 {
+		return errors.New("the number of dump generations cannot be negative")
+	}
+
+	if d.CompressionThreads < 0 {
+		return errors.New("the number of compression threads cannot be negative")
+	}
+
+	if d.Replication.Enabled && d.ObjectStorage != nil {
+		return errors.New("replication cannot be prepared for dumps uploaded to the object storage")
+	}
+
+	for dbName, definition := range d.Databases {
+		switch definition.Format {
+		case "", plainFormat, customFormat, directoryFormat:
+		default:
+			return errors.Errorf("unknown dump format %q of the database %s", definition.Format, dbName)
+		}
+
+		if !isValidCompression(definition.Compression) {
+			return errors.Errorf("unknown compression %q of the database %s", definition.Compression, dbName)
+		}
+
+		if definition.Compression == bzip2Compression && definition.Format != plainFormat {
+			return errors.Errorf("bzip2 compression of the database %s is available only for plain-text dumps", dbName)
+		}
+
+		if definition.CompressionLevel < 0 {
+			return errors.Errorf("the compression level of the database %s cannot be negative", dbName)
+		}
+
+		if err := validateFilters(dbName, definition); err != nil {
+			return err
+		}
+
+		if err := validateSubset(dbName, definition.Subset); err != nil {
+			return err
+		}
+
+		hasCopiedRows := len(definition.Sampling) > 0 || definition.Subset != nil
+
+		if hasCopiedRows && !d.Restore.Enabled && definition.Format != "" && definition.Format != directoryFormat {
+			return errors.Errorf("sampling and subset of the database %s require the directory format or the immediate restore", dbName)
+		}
+
+		if d.Replication.Enabled && (definition.hasExclusions() || len(definition.Schemas) > 0 ||
+			len(definition.ExcludeSchemas) > 0 || hasCopiedRows) {
+			return errors.Errorf("only the tables filter of the database %s can be used with the replication", dbName)
+		}
+	}
+
 	return nil
 }
 
@@ -219,6 +312,20 @@ func (d *DumpJob) Name() string {
 	return d.name
 }
 
+// Progress returns progress of dumping databases.
+func (d *DumpJob) Progress() []models.DatabaseProgress {
+	return d.progress.Progress()
+}
+
+// DataSize returns the size of the dump or the size of restored data if the immediate restore is enabled.
+func (d *DumpJob) DataSize() (uint64, error) {
+	if d.DumpOptions.Restore.Enabled {
+		return fs.DirSize(d.fsPool.DataDir())
+	}
+
+	return fs.DirSize(d.DumpOptions.DumpLocation)
+}
+
 // Reload reloads job configuration.
 func (d *DumpJob) Reload(cfg map[string]interface{}) (err error) {
 	if err := options.Unmarshal(cfg, &d.DumpOptions); err != nil {
@@ -231,6 +338,14 @@ func (d *DumpJob) Reload(cfg map[string]interface{}) (err error) {
 
 	d.setDefaults()
 
+	d.storage = nil
+
+	if d.ObjectStorage != nil {
+		if d.storage, err = objstore.New(d.ObjectStorage.Config); err != nil {
+			return errors.Wrap(err, "failed to set up the object storage")
+		}
+	}
+
 	return nil
 }
 
@@ -238,6 +353,8 @@ func (d *DumpJob) Reload(cfg map[string]interface{}) (err error) {
 func (d *DumpJob) Run(ctx context.Context) (err error) {
 	log.Msg("Run job: ", d.Name())
 
+	d.progress.reset()
+
 	isEmpty, err := tools.IsEmptyDirectory(d.fsPool.DataDir())
 	if err != nil {
 		return errors.Wrap(err, "failed to explore the data directory")
@@ -335,16 +452,44 @@ func (d *DumpJob) Run(ctx context.Context) (err error) {
 		}
 	}
 
+	filteredList := make(map[string]DumpDefinition, len(dbList))
+
+	for dbName, dbDetails := range dbList {
+		if dbDetails.hasFilters() {
+			if err := d.prepareFilters(ctx, dbName, &dbDetails); err != nil {
+				return err
+			}
+		}
+
+		filteredList[dbName] = dbDetails
+	}
+
+	dbList = filteredList
+
 	if err := d.cleanupDumpLocation(ctx, dumpCont.ID, dbList); err != nil {
 		return err
 	}
 
+	pgDumpVersion, err := d.getPGDumpVersion(ctx, dumpCont.ID)
+	if err != nil {
+		return err
+	}
+
+	generation := dumpGeneration{createdAt: time.Now().UTC()}
+	generation.name = generationName(generation.createdAt)
+
 	for dbName, dbDetails := range dbList {
-		if err := d.dumpDatabase(ctx, dumpCont.ID, dbName, dbDetails); err != nil {
+		if err := d.dumpDatabase(ctx, dumpCont.ID, dbName, dbDetails, pgDumpVersion, generation.name); err != nil {
 			return errors.Wrapf(err, "failed to dump the database %s", dbName)
 		}
 	}
 
+	if d.storage != nil {
+		if err := d.completeUpload(ctx, generation, dbList); err != nil {
+			return errors.Wrap(err, "failed to complete the dump upload")
+		}
+	}
+
 	if d.DumpOptions.Restore.Enabled {
 		if err := d.markDatabaseData(); err != nil {
 			return errors.Wrap(err, "failed to mark the created dump")
@@ -396,6 +541,12 @@ func (d *DumpJob) getDBList(ctx context.Context) (map[string]DumpDefinition, err
 	return dbList, nil
 }
 
+// tablesCountQuery counts tables with data that pg_dump dumps.
+const tablesCountQuery = `select count(*)
+from pg_class c
+join pg_namespace n on n.oid = c.relnamespace
+where c.relkind = 'r' and n.nspname not in ('pg_catalog', 'information_schema') and n.nspname !~ '^pg_toast'`
+
 func (d *DumpJob) getPassword() string {
 	pwd := os.Getenv("PGPASSWORD")
 
@@ -417,8 +568,8 @@ func (d *DumpJob) cleanupDumpLocation(ctx context.Context, dumpContID string, db
 
 	cleanupCmd := []string{"rm", "-rf"}
 
-	for dbName := range dbList {
-		cleanupCmd = append(cleanupCmd, path.Join(d.DumpOptions.DumpLocation, dbName))
+	for dbName, dumpDefinition := range dbList {
+		cleanupCmd = append(cleanupCmd, d.getDumpPath(dbName, dumpDefinition))
 	}
 
 	log.Msg("Running cleanup command: ", cleanupCmd)
@@ -434,23 +585,80 @@ func (d *DumpJob) cleanupDumpLocation(ctx context.Context, dumpContID string, db
 	return nil
 }
 
-func (d *DumpJob) dumpDatabase(ctx context.Context, dumpContID, dbName string, dumpDefinition DumpDefinition) error {
-	dumpCommand := d.buildLogicalDumpCommand(dbName, dumpDefinition.Tables)
+func (d *DumpJob) dumpDatabase(ctx context.Context, dumpContID, dbName string, dumpDefinition DumpDefinition, pgDumpVersion int,
+	generation string) error {
+	if d.Replication.Enabled {
+		slotConn, err := d.prepareReplication(ctx, dbName, &dumpDefinition)
+		if err != nil {
+			return errors.Wrap(err, "failed to prepare the logical replication")
+		}
+
+		// The exported snapshot is valid until the replication connection is closed.
+		defer func() { _ = slotConn.Close(ctx) }()
+	}
+
+	dumpCommand, err := d.buildLogicalDumpCommand(dbName, dumpDefinition, pgDumpVersion)
+	if err != nil {
+		return errors.Wrap(err, "failed to build a dump command")
+	}
+
 	log.Msg("Running dump command: ", dumpCommand)
 
 	if len(dumpDefinition.Tables) > 0 {
 		log.Msg("Partial dump will be run. Tables for dumping: ", strings.Join(dumpDefinition.Tables, ", "))
 	}
 
-	if output, err := d.performDumpCommand(ctx, dumpContID, types.ExecConfig{
-		Tty: true,
+	dumpPath := d.getDumpPath(dbName, dumpDefinition)
+	isStreamed := d.isStreamedDump(dumpDefinition)
+
+	location := ""
+	if !d.DumpOptions.Restore.Enabled && !isStreamed {
+		location = dumpPath
+	}
+
+	dbProgress := d.progress.start(dbName, d.countTables(ctx, dbName, dumpDefinition.Tables), location)
+
+	commandCfg := types.ExecConfig{
 		Cmd: dumpCommand,
 		Env: d.getExecEnvironmentVariables(),
-	}); err != nil {
-		log.Dbg(output)
+	}
+
+	handler := func(line string) {
+		d.progress.handleLine(dbProgress, line)
+	}
+
+	if isStreamed {
+		err = d.streamDump(ctx, dumpContID, commandCfg, path.Join(generation, path.Base(dumpPath)), handler)
+	} else {
+		err = d.performDumpCommand(ctx, dumpContID, commandCfg, handler)
+	}
+
+	d.progress.finish(dbProgress, err)
+
+	if err != nil {
 		return errors.Wrap(err, "failed to dump a database")
 	}
 
+	if len(dumpDefinition.Sampling) > 0 {
+		if err := d.copySamples(ctx, dumpContID, dbName, dumpDefinition); err != nil {
+			return errors.Wrap(err, "failed to copy sampled rows")
+		}
+	}
+
+	if dumpDefinition.Subset != nil {
+		if err := d.dumpSubset(ctx, dumpContID, dbName, dumpDefinition); err != nil {
+			return errors.Wrap(err, "failed to extract the subset")
+		}
+	}
+
+	if d.storage != nil && !isStreamed {
+		log.Msg(fmt.Sprintf("Uploading the dump of the database %q to the object storage", dbName))
+
+		if err := d.storage.UploadDir(ctx, dumpPath, path.Join(generation, dbName)); err != nil {
+			return errors.Wrap(err, "failed to upload the dump")
+		}
+	}
+
 	log.Msg(fmt.Sprintf("Dumping job for the database %q has been finished", dbName))
 
 	return nil
@@ -527,12 +735,61 @@ func (d *DumpJob) setupConnectionOptions(ctx context.Context) error {
 	return nil
 }
 
-func (d *DumpJob) performDumpCommand(ctx context.Context, contID string, commandCfg types.ExecConfig) (string, error) {
+func (d *DumpJob) performDumpCommand(ctx context.Context, contID string, commandCfg types.ExecConfig, handler func(line string)) error {
 	if d.DumpOptions.Restore.Enabled {
 		d.dbMark.DataStateAt = time.Now().Format(tools.DataStateAtFormat)
 	}
 
-	return tools.ExecCommandWithOutput(ctx, d.dockerClient, contID, commandCfg)
+	return tools.ExecCommandWithLineHandler(ctx, d.dockerClient, contID, commandCfg, handler)
+}
+
+// getPGDumpVersion returns the major version of pg_dump in the dump container.
+func (d *DumpJob) getPGDumpVersion(ctx context.Context, contID string) (int, error) {
+	out, err := tools.ExecCommandWithOutput(ctx, d.dockerClient, contID, types.ExecConfig{
+		Cmd: []string{"pg_dump", "--version"},
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get pg_dump version")
+	}
+
+	return parsePGDumpVersion(out)
+}
+
+// getDumpPath returns the location of the database dump. Plain-text dumps are files with the extension of the compression type.
+func (d *DumpJob) getDumpPath(dbName string, definition DumpDefinition) string {
+	dumpPath := path.Join(d.DumpOptions.DumpLocation, dbName)
+
+	if definition.Format == plainFormat {
+		dumpPath += getArchiveExtension(definition.Compression)
+	}
+
+	return dumpPath
+}
+
+// countTables returns the number of tables to dump. It returns zero if the number is unknown.
+func (d *DumpJob) countTables(ctx context.Context, dbName string, tables []string) int {
+	if len(tables) > 0 {
+		return len(tables)
+	}
+
+	connStr := db.ConnectionString(d.config.db.Host, strconv.Itoa(d.config.db.Port), d.config.db.Username, dbName, d.getPassword())
+
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		log.Err("Failed to connect to the database to count tables:", err)
+		return 0
+	}
+
+	defer func() { _ = conn.Close(ctx) }()
+
+	var count int
+
+	if err := conn.QueryRow(ctx, tablesCountQuery).Scan(&count); err != nil {
+		log.Err("Failed to count tables to dump:", err)
+		return 0
+	}
+
+	return count
 }
 
 func (d *DumpJob) getEnvironmentVariables(password string) []string {
@@ -605,7 +862,7 @@ func (d *DumpJob) getExecEnvironmentVariables() []string {
 	return execEnvs
 }
 
-func (d *DumpJob) buildLogicalDumpCommand(dbName string, tables []string) []string {
+func (d *DumpJob) buildLogicalDumpCommand(dbName string, definition DumpDefinition, pgDumpVersion int) ([]string, error) {
 	optionalArgs := map[string]string{
 		"--host":     d.config.db.Host,
 		"--port":     strconv.Itoa(d.config.db.Port),
@@ -614,26 +871,89 @@ func (d *DumpJob) buildLogicalDumpCommand(dbName string, tables []string) []stri
 		"--jobs":     strconv.Itoa(d.DumpOptions.ParallelJobs),
 	}
 
-	dumpCmd := append([]string{"pg_dump", "--create"}, prepareCmdOptions(optionalArgs)...)
+	isPlainDump := definition.Format == plainFormat && !d.DumpOptions.Restore.Enabled
+
+	dumpCmd := []string{"pg_dump", "--verbose"}
+
+	if isPlainDump {
+		// Plain-text dumps are always single-threaded. The database is created during the restore.
+		delete(optionalArgs, "--jobs")
+	} else {
+		dumpCmd = append(dumpCmd, "--create")
+	}
 
-	for _, table := range tables {
+	dumpCmd = append(dumpCmd, prepareCmdOptions(optionalArgs)...)
+
+	for _, table := range definition.Tables {
 		dumpCmd = append(dumpCmd, "--table", table)
 	}
 
+	dumpCmd = append(dumpCmd, definition.dumpFilterArgs()...)
+
+	if definition.snapshot != "" {
+		dumpCmd = append(dumpCmd, "--snapshot", definition.snapshot)
+	}
+
 	// Define if restore directly or export to dump location.
 	if d.DumpOptions.Restore.Enabled {
 		dumpCmd = append(dumpCmd, "--format", customFormat)
-		dumpCmd = append(dumpCmd, d.buildLogicalRestoreCommand(dbName)...)
-		cmd := strings.Join(dumpCmd, " ")
+		cmd := shellJoin(dumpCmd) + " " + strings.Join(d.buildLogicalRestoreCommand(dbName), " ")
 
 		log.Dbg(cmd)
 
-		return []string{"sh", "-c", cmd}
+		return []string{"sh", "-c", cmd}, nil
 	}
 
-	dumpCmd = append(dumpCmd, "--format", directoryFormat, "--file", path.Join(d.DumpOptions.DumpLocation, dbName))
+	dumpPath := d.getDumpPath(dbName, definition)
+
+	// Streamed dumps are written to stdout.
+	isStreamed := d.isStreamedDump(definition)
+
+	if isPlainDump {
+		dumpCmd = append(dumpCmd, "--format", plainFormat)
+
+		archiveCmd := getWritingArchiveCommand(definition.Compression, definition.CompressionLevel, d.CompressionThreads)
+		if archiveCmd == "" {
+			if isStreamed {
+				return dumpCmd, nil
+			}
+
+			return append(dumpCmd, "--file", dumpPath), nil
+		}
+
+		// pg_dump writes to stdout, so the pipeline has to fail if pg_dump fails.
+		cmd := fmt.Sprintf("set -o pipefail; %s | %s", shellJoin(dumpCmd), archiveCmd)
+
+		if !isStreamed {
+			cmd += " > " + dumpPath
+		}
+
+		log.Dbg(cmd)
+
+		return []string{"bash", "-c", cmd}, nil
+	}
+
+	compressOption, err := getPGDumpCompressOption(definition.Compression, definition.CompressionLevel, pgDumpVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if compressOption != "" {
+		dumpCmd = append(dumpCmd, "--compress", compressOption)
+	}
+
+	dumpFormat := directoryFormat
+	if definition.Format == customFormat {
+		dumpFormat = customFormat
+	}
+
+	dumpCmd = append(dumpCmd, "--format", dumpFormat)
+
+	if !isStreamed {
+		dumpCmd = append(dumpCmd, "--file", dumpPath)
+	}
 
-	return dumpCmd
+	return dumpCmd, nil
 }
 
 func (d *DumpJob) buildLogicalRestoreCommand(dbName string) []string {
@@ -0,0 +1,113 @@
+package logical
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/objstore"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/config/global"
+)
+
+func TestBuildLogicalDumpCommand(t *testing.T) {
+	dumpJob := DumpJob{
+		DumpOptions: DumpOptions{
+			DumpLocation:       "/tmp/dump",
+			ParallelJobs:       2,
+			CompressionThreads: 4,
+		},
+	}
+
+	t.Run("directory dump", func(t *testing.T) {
+		command, err := dumpJob.buildLogicalDumpCommand("testDB", DumpDefinition{}, 14)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"pg_dump", "--verbose", "--create"}, command[:3])
+		assert.Contains(t, command, "--jobs")
+		assert.NotContains(t, command, "--compress")
+		assert.Equal(t, []string{"--format", "directory", "--file", "/tmp/dump/testDB"}, command[len(command)-4:])
+	})
+
+	t.Run("custom dump compressed with zstd", func(t *testing.T) {
+		definition := DumpDefinition{Format: customFormat, Compression: zstdCompression, CompressionLevel: 5}
+
+		command, err := dumpJob.buildLogicalDumpCommand("testDB", definition, 16)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"--compress", "zstd:5", "--format", "custom", "--file", "/tmp/dump/testDB"}, command[len(command)-6:])
+
+		_, err = dumpJob.buildLogicalDumpCommand("testDB", definition, 15)
+		assert.Error(t, err)
+	})
+
+	t.Run("dump with an exported snapshot", func(t *testing.T) {
+		command, err := dumpJob.buildLogicalDumpCommand("testDB", DumpDefinition{snapshot: "00000003-00000002-1"}, 14)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"--snapshot", "00000003-00000002-1", "--format", "directory"}, command[len(command)-6:len(command)-2])
+	})
+
+	t.Run("dump with filters", func(t *testing.T) {
+		definition := DumpDefinition{
+			ExcludeTableData: []string{"audit_*"},
+			Sampling:         map[string]string{"public.users": "id < 100"},
+		}
+
+		command, err := dumpJob.buildLogicalDumpCommand("testDB", definition, 14)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"--exclude-table-data", "audit_*", "--exclude-table-data", "public.users", "--format", "directory"},
+			command[len(command)-8:len(command)-2])
+
+		restoreJob := dumpJob
+		restoreJob.DumpOptions.Restore.Enabled = true
+		restoreJob.globalCfg = &global.Config{Database: global.Database{Username: "john"}}
+
+		command, err = restoreJob.buildLogicalDumpCommand("testDB", definition, 14)
+		require.NoError(t, err)
+		assert.Contains(t, command[2], "--exclude-table-data 'audit_*' --exclude-table-data public.users --format custom | pg_restore")
+	})
+
+	t.Run("plain dump", func(t *testing.T) {
+		command, err := dumpJob.buildLogicalDumpCommand("testDB", DumpDefinition{Format: plainFormat}, 14)
+		require.NoError(t, err)
+
+		assert.NotContains(t, command, "--create")
+		assert.NotContains(t, command, "--jobs")
+		assert.Equal(t, []string{"--format", "plain", "--file", "/tmp/dump/testDB.sql"}, command[len(command)-4:])
+	})
+
+	t.Run("plain dump compressed with zstd", func(t *testing.T) {
+		definition := DumpDefinition{Format: plainFormat, Compression: zstdCompression, CompressionLevel: 3}
+
+		command, err := dumpJob.buildLogicalDumpCommand("testDB", definition, 14)
+		require.NoError(t, err)
+		require.Len(t, command, 3)
+
+		assert.Equal(t, []string{"bash", "-c"}, command[:2])
+		assert.Regexp(t, `^set -o pipefail; pg_dump --verbose .* --format plain \| zstd -c -q -T4 -3 > /tmp/dump/testDB\.zst$`, command[2])
+		assert.NotContains(t, command[2], "--jobs")
+	})
+
+	t.Run("dumps streamed to object storage", func(t *testing.T) {
+		storage, err := objstore.New(objstore.Config{Bucket: "dumps"})
+		require.NoError(t, err)
+
+		storageJob := dumpJob
+		storageJob.storage = storage
+
+		command, err := storageJob.buildLogicalDumpCommand("testDB", DumpDefinition{Format: customFormat}, 14)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"--format", "custom"}, command[len(command)-2:])
+
+		definition := DumpDefinition{Format: plainFormat, Compression: lz4Compression}
+
+		command, err = storageJob.buildLogicalDumpCommand("testDB", definition, 14)
+		require.NoError(t, err)
+		assert.Regexp(t, `--format plain \| lz4 -c -q$`, command[2])
+
+		command, err = storageJob.buildLogicalDumpCommand("testDB", DumpDefinition{}, 14)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"--file", "/tmp/dump/testDB"}, command[len(command)-2:])
+	})
+}
@@ -0,0 +1,678 @@
+/*
+2022 © Postgres.ai
+*/
+
+package logical
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/db"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+)
+
+const (
+	// samplesDir is the directory of a directory dump that keeps rows of sampled tables.
+	samplesDir = "samples"
+
+	// sampleFileExt is the extension of files with rows of sampled tables in the COPY text format.
+	sampleFileExt = ".copy"
+
+	// Environment variables pass queries and file names to shell commands without quoting.
+	copyOutEnv    = "DBLAB_COPY_OUT"
+	copyInEnv     = "DBLAB_COPY_IN"
+	sampleFileEnv = "DBLAB_SAMPLE_FILE"
+
+	// replicaRoleOptions disables triggers and foreign key checks while sampled rows are loaded.
+	replicaRoleOptions = "PGOPTIONS=-c session_replication_role=replica"
+
+	// filterTablesQuery lists tables that can be filtered.
+	filterTablesQuery = `select n.nspname, c.relname
+from pg_class c
+join pg_namespace n on n.oid = c.relnamespace
+where c.relkind in ('r', 'p') and n.nspname not in ('pg_catalog', 'information_schema') and n.nspname !~ '^pg_toast'`
+
+	// filterSchemasQuery lists schemas that can be filtered.
+	filterSchemasQuery = `select nspname from pg_namespace
+where nspname not in ('pg_catalog', 'information_schema') and nspname !~ '^pg_toast' and nspname !~ '^pg_temp'`
+)
+
+var (
+	// tocTableItem matches table definitions and table data in the table of contents of a dump.
+	tocTableItem = regexp.MustCompile(`^\d+; \d+ \d+ (TABLE DATA|TABLE) (\S+) (\S+)(?: |$)`)
+
+	shellSafeArg = regexp.MustCompile(`^[\w@%+=:,./-]+$`)
+)
+
+// sourceTable describes a table of the source database.
+type sourceTable struct {
+	schema string
+	name   string
+}
+
+// hasFilters reports whether only a part of the database is dumped or restored.
+func (d DumpDefinition) hasFilters() bool {
+	return len(d.Tables) > 0 || len(d.ExcludeTables) > 0 || len(d.ExcludeTableData) > 0 ||
+		len(d.Schemas) > 0 || len(d.ExcludeSchemas) > 0 || len(d.Sampling) > 0 || d.Subset != nil
+}
+
+// hasExclusions reports whether the table of contents of a dump has to be filtered before the restore.
+func (d DumpDefinition) hasExclusions() bool {
+	return len(d.ExcludeTables) > 0 || len(d.ExcludeTableData) > 0
+}
+
+// sampledTables returns sampled tables in a stable order.
+func (d DumpDefinition) sampledTables() []string {
+	tables := make([]string, 0, len(d.Sampling))
+
+	for table := range d.Sampling {
+		tables = append(tables, table)
+	}
+
+	sort.Strings(tables)
+
+	return tables
+}
+
+// dumpFilterArgs returns pg_dump options to filter dumped objects. Data of sampled tables are copied separately.
+func (d DumpDefinition) dumpFilterArgs() []string {
+	args := []string{}
+
+	for _, schema := range d.Schemas {
+		args = append(args, "--schema", schema)
+	}
+
+	for _, schema := range d.ExcludeSchemas {
+		args = append(args, "--exclude-schema", schema)
+	}
+
+	for _, table := range d.ExcludeTables {
+		args = append(args, "--exclude-table", table)
+	}
+
+	for _, table := range d.ExcludeTableData {
+		args = append(args, "--exclude-table-data", table)
+	}
+
+	for _, table := range d.sampledTables() {
+		args = append(args, "--exclude-table-data", table)
+	}
+
+	for _, table := range d.subsetTables {
+		args = append(args, "--exclude-table-data", table.String())
+	}
+
+	return args
+}
+
+// validateFilters checks filters that do not depend on the database catalog.
+func validateFilters(dbName string, definition DumpDefinition) error {
+	for table, condition := range definition.Sampling {
+		if strings.ContainsAny(table, "*?") || !strings.Contains(table, ".") {
+			return errors.Errorf("sampled table %q of the database %s must be a schema-qualified name without wildcards", table, dbName)
+		}
+
+		if strings.TrimSpace(condition) == "" {
+			return errors.Errorf("empty sampling condition of the table %q of the database %s", table, dbName)
+		}
+	}
+
+	return nil
+}
+
+// validateRestoreFilters checks filters of the restore job. Unlike pg_dump, pg_restore matches schemas by exact names.
+func validateRestoreFilters(dbName string, definition DumpDefinition) error {
+	if err := validateFilters(dbName, definition); err != nil {
+		return err
+	}
+
+	for _, schema := range append(append([]string{}, definition.Schemas...), definition.ExcludeSchemas...) {
+		if strings.ContainsAny(schema, "*?") {
+			return errors.Errorf("schema %q of the database %s must be an exact name: pg_restore does not support patterns", schema, dbName)
+		}
+	}
+
+	return nil
+}
+
+// matchesPattern reports whether a pattern matches the object name.
+// Patterns follow pg_dump: unquoted patterns are case-insensitive, "*" and "?" are wildcards.
+func matchesPattern(pattern, name string) bool {
+	if strings.Contains(pattern, `"`) {
+		pattern = strings.ReplaceAll(pattern, `"`, "")
+	} else {
+		pattern = strings.ToLower(pattern)
+	}
+
+	matched, err := path.Match(pattern, name)
+
+	return err == nil && matched
+}
+
+// matchesTable reports whether a pattern matches the table. Unqualified patterns match tables of any schema.
+func matchesTable(pattern, schemaName, tableName string) bool {
+	if strings.Contains(pattern, ".") {
+		return matchesPattern(pattern, schemaName+"."+tableName)
+	}
+
+	return matchesPattern(pattern, tableName)
+}
+
+func matchesAnyTable(patterns []string, schemaName, tableName string) bool {
+	for _, pattern := range patterns {
+		if matchesTable(pattern, schemaName, tableName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isDataDumped reports whether data of the table are dumped with the filters of the definition.
+func (d DumpDefinition) isDataDumped(table sourceTable) bool {
+	if len(d.Tables) > 0 && !matchesAnyTable(d.Tables, table.schema, table.name) {
+		return false
+	}
+
+	if len(d.Schemas) > 0 && !hasMatchingSchemaPattern(d.Schemas, table.schema) {
+		return false
+	}
+
+	if hasMatchingSchemaPattern(d.ExcludeSchemas, table.schema) {
+		return false
+	}
+
+	return !matchesAnyTable(d.ExcludeTables, table.schema, table.name) && !matchesAnyTable(d.ExcludeTableData, table.schema, table.name)
+}
+
+func hasMatchingSchemaPattern(patterns []string, schema string) bool {
+	for _, pattern := range patterns {
+		if matchesPattern(pattern, schema) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findSubsetTables returns tables with data from the subset.
+func (d DumpDefinition) findSubsetTables(tables []sourceTable, foreignKeys []foreignKey) []sourceTable {
+	if d.Subset == nil {
+		return nil
+	}
+
+	roots := []sourceTable{}
+
+	for _, root := range d.Subset.subsetRoots() {
+		if table, ok := findTable(root, tables); ok {
+			roots = append(roots, table)
+		}
+	}
+
+	subsetTables := []sourceTable{}
+
+	for _, table := range linkedTables(roots, foreignKeys) {
+		if d.isDataDumped(table) {
+			subsetTables = append(subsetTables, table)
+		}
+	}
+
+	return subsetTables
+}
+
+// checkFilters lists filters that do not match objects of the source catalog.
+func checkFilters(definition DumpDefinition, tables []sourceTable, schemas []string, foreignKeys []foreignKey) []string {
+	problems := []string{}
+
+	tablePatterns := map[string][]string{
+		"tables":           definition.Tables,
+		"excludeTables":    definition.ExcludeTables,
+		"excludeTableData": definition.ExcludeTableData,
+	}
+
+	for _, option := range []string{"tables", "excludeTables", "excludeTableData"} {
+		for _, pattern := range tablePatterns[option] {
+			if !hasMatchingTable(pattern, tables) {
+				problems = append(problems, fmt.Sprintf("%s: no tables match %q", option, pattern))
+			}
+		}
+	}
+
+	schemaPatterns := map[string][]string{
+		"schemas":        definition.Schemas,
+		"excludeSchemas": definition.ExcludeSchemas,
+	}
+
+	for _, option := range []string{"schemas", "excludeSchemas"} {
+		for _, pattern := range schemaPatterns[option] {
+			if !hasMatchingSchema(pattern, schemas) {
+				problems = append(problems, fmt.Sprintf("%s: no schemas match %q", option, pattern))
+			}
+		}
+	}
+
+	excludedData := make([]string, 0, len(definition.ExcludeTables)+len(definition.ExcludeTableData))
+	excludedData = append(excludedData, definition.ExcludeTables...)
+	excludedData = append(excludedData, definition.ExcludeTableData...)
+
+	for _, table := range definition.sampledTables() {
+		if !hasMatchingTable(table, tables) {
+			problems = append(problems, fmt.Sprintf("sampling: table %q not found", table))
+			continue
+		}
+
+		for _, sourceTable := range tables {
+			if matchesTable(table, sourceTable.schema, sourceTable.name) &&
+				matchesAnyTable(excludedData, sourceTable.schema, sourceTable.name) {
+				problems = append(problems, fmt.Sprintf("sampling: data of the table %q are excluded", table))
+			}
+		}
+
+		// Sampled rows are loaded after foreign keys are created, so referencing rows would break them.
+		for _, fk := range foreignKeys {
+			if matchesTable(table, fk.parent.schema, fk.parent.name) && definition.isDataDumped(fk.child) {
+				problems = append(problems, fmt.Sprintf("sampling: rows of the table %q are referenced by the table %s, use subset instead",
+					table, fk.child))
+			}
+		}
+	}
+
+	if definition.Subset != nil {
+		for _, root := range definition.Subset.subsetRoots() {
+			if _, ok := findTable(root, tables); !ok {
+				problems = append(problems, fmt.Sprintf("subset: root table %q not found", root))
+			}
+		}
+
+		for _, table := range definition.findSubsetTables(tables, foreignKeys) {
+			for _, sampledTable := range definition.sampledTables() {
+				if matchesTable(sampledTable, table.schema, table.name) {
+					problems = append(problems, fmt.Sprintf("sampling: table %q is a part of the subset", sampledTable))
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+func hasMatchingTable(pattern string, tables []sourceTable) bool {
+	for _, table := range tables {
+		if matchesTable(pattern, table.schema, table.name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasMatchingSchema(pattern string, schemas []string) bool {
+	for _, schema := range schemas {
+		if matchesPattern(pattern, schema) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// prepareFilters validates filters of the database against the source catalog before dumping
+// and finds tables with data from the subset.
+func (d *DumpJob) prepareFilters(ctx context.Context, dbName string, definition *DumpDefinition) error {
+	connStr := db.ConnectionString(d.config.db.Host, strconv.Itoa(d.config.db.Port), d.config.db.Username, dbName, d.getPassword())
+
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the source database")
+	}
+
+	defer func() { _ = conn.Close(ctx) }()
+
+	tables, err := listSourceTables(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	schemas, err := listSourceSchemas(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	foreignKeys, err := listForeignKeys(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	problems := checkFilters(*definition, tables, schemas, foreignKeys)
+
+	for _, table := range definition.sampledTables() {
+		// EXPLAIN checks the condition without reading data.
+		if _, err := conn.Exec(ctx, fmt.Sprintf("explain select 1 from %s where %s", table, definition.Sampling[table])); err != nil {
+			problems = append(problems, fmt.Sprintf("sampling: invalid condition of the table %q: %v", table, err))
+		}
+	}
+
+	if definition.Subset != nil {
+		for _, root := range definition.Subset.subsetRoots() {
+			if _, err := conn.Exec(ctx, fmt.Sprintf("explain select 1 from %s where %s", root, definition.Subset.Roots[root])); err != nil {
+				problems = append(problems, fmt.Sprintf("subset: invalid condition of the root %q: %v", root, err))
+			}
+		}
+
+		inheritedTables, err := listInheritedTables(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		definition.subsetTables = definition.findSubsetTables(tables, foreignKeys)
+		problems = append(problems, checkSubsetTables(definition.subsetTables, inheritedTables)...)
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("invalid filters of the database %s: %s", dbName, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func listSourceTables(ctx context.Context, conn *pgx.Conn) ([]sourceTable, error) {
+	rows, err := conn.Query(ctx, filterTablesQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list tables of the source")
+	}
+
+	defer rows.Close()
+
+	tables := []sourceTable{}
+
+	for rows.Next() {
+		var table sourceTable
+		if err := rows.Scan(&table.schema, &table.name); err != nil {
+			return nil, errors.Wrap(err, "failed to scan tables of the source")
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
+
+func listSourceSchemas(ctx context.Context, conn *pgx.Conn) ([]string, error) {
+	rows, err := conn.Query(ctx, filterSchemasQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list schemas of the source")
+	}
+
+	defer rows.Close()
+
+	schemas := []string{}
+
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return nil, errors.Wrap(err, "failed to scan schemas of the source")
+		}
+
+		schemas = append(schemas, schema)
+	}
+
+	return schemas, rows.Err()
+}
+
+// copySamples copies rows of sampled tables that match sampling conditions.
+// Rows are loaded to the restored database immediately or stored in the directory dump.
+func (d *DumpJob) copySamples(ctx context.Context, contID, dbName string, definition DumpDefinition) error {
+	sampleDir := path.Join(d.getDumpPath(dbName, definition), samplesDir)
+
+	if !d.DumpOptions.Restore.Enabled {
+		if err := tools.MakeDir(ctx, d.dockerClient, contID, sampleDir); err != nil {
+			return err
+		}
+	}
+
+	sourceCmd := shellJoin([]string{"psql", "--host", d.config.db.Host, "--port", strconv.Itoa(d.config.db.Port),
+		"--username", d.config.db.Username, "--dbname", dbName, "--no-psqlrc", "--set", "ON_ERROR_STOP=1"})
+
+	for _, table := range definition.sampledTables() {
+		envs := append(d.getExecEnvironmentVariables(),
+			fmt.Sprintf("%s=copy (select * from %s where %s) to stdout", copyOutEnv, table, definition.Sampling[table]))
+
+		cmd := fmt.Sprintf(`%s --command "$%s"`, sourceCmd, copyOutEnv)
+
+		if d.DumpOptions.Restore.Enabled {
+			targetCmd := shellJoin([]string{"psql", "--username", d.globalCfg.Database.User(), "--dbname", dbName,
+				"--no-psqlrc", "--set", "ON_ERROR_STOP=1"})
+
+			envs = append(envs, fmt.Sprintf("%s=copy %s from stdin", copyInEnv, table))
+			cmd = fmt.Sprintf(`set -o pipefail; %s | %s %s --command "$%s"`, cmd, replicaRoleOptions, targetCmd, copyInEnv)
+		} else {
+			envs = append(envs, fmt.Sprintf("%s=%s", sampleFileEnv, path.Join(sampleDir, sampleFileName(table))))
+			cmd = fmt.Sprintf(`%s > "$%s"`, cmd, sampleFileEnv)
+		}
+
+		log.Msg(fmt.Sprintf("Copying sampled rows of the table %s of the database %s", table, dbName))
+
+		if out, err := tools.ExecCommandWithOutput(ctx, d.dockerClient, contID, types.ExecConfig{
+			Cmd: []string{"bash", "-c", cmd},
+			Env: envs,
+		}); err != nil {
+			log.Dbg(out)
+			return errors.Wrapf(err, "failed to copy sampled rows of the table %s", table)
+		}
+	}
+
+	return nil
+}
+
+// sampleFileName returns the name of the file with rows of the sampled table.
+func sampleFileName(table string) string {
+	return url.PathEscape(table) + sampleFileExt
+}
+
+// sampleTableName returns the name of the sampled table stored in the file.
+func sampleTableName(fileName string) (string, error) {
+	return url.PathUnescape(strings.TrimSuffix(fileName, sampleFileExt))
+}
+
+// shellJoin joins command arguments to a shell command quoting arguments with special characters.
+func shellJoin(args []string) string {
+	quoted := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		if !shellSafeArg.MatchString(arg) {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+
+		quoted = append(quoted, arg)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// filterTOC comments out data of excluded tables in the table of contents of a dump.
+// It returns the filtered list and definitions of excluded tables that have to be dropped after the restore.
+// Table definitions are kept because indexes and constraints of excluded tables depend on them.
+func filterTOC(toc string, definition DumpDefinition) (string, []string) {
+	lines := strings.Split(toc, "\n")
+	droppedTables := []string{}
+
+	for i, line := range lines {
+		item := tocTableItem.FindStringSubmatch(line)
+		if item == nil {
+			continue
+		}
+
+		itemType, schemaName, tableName := item[1], item[2], item[3]
+		isExcluded := matchesAnyTable(definition.ExcludeTables, schemaName, tableName)
+
+		if itemType == "TABLE" {
+			if isExcluded {
+				droppedTables = append(droppedTables, pgx.Identifier{schemaName, tableName}.Sanitize())
+			}
+
+			continue
+		}
+
+		if isExcluded || matchesAnyTable(definition.ExcludeTableData, schemaName, tableName) {
+			lines[i] = ";" + line
+		}
+	}
+
+	return strings.Join(lines, "\n"), droppedTables
+}
+
+// prepareTOC writes the filtered table of contents of the dump to the restore container.
+// It returns the path of the list in the container and tables to drop after the restore.
+func (r *RestoreJob) prepareTOC(ctx context.Context, contID, dumpName string, definition DumpDefinition) (string, []string, error) {
+	toc, err := r.execDumpCommandWithOutput(ctx, contID, dumpName, types.ExecConfig{
+		Cmd: []string{"pg_restore", "--list", r.getDumpLocation(definition.Format, dumpName)},
+	})
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to read the table of contents")
+	}
+
+	filteredTOC, droppedTables := filterTOC(toc, definition)
+
+	tempFile, err := os.CreateTemp("", "toc_*.list")
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer func() { _ = os.Remove(tempFile.Name()) }()
+	defer func() { _ = tempFile.Close() }()
+
+	if _, err := tempFile.WriteString(filteredTOC); err != nil {
+		return "", nil, err
+	}
+
+	dstPath := path.Join("/tmp", "toc_"+formatDBName(dumpName)+".list")
+
+	if err := r.prepareArchive(ctx, contID, tempFile, dstPath); err != nil {
+		return "", nil, errors.Wrap(err, "failed to copy the table of contents")
+	}
+
+	return dstPath, droppedTables, nil
+}
+
+// applyFilters finishes a partial restore: loads sampled rows, applies sampling conditions, and drops excluded tables.
+func (r *RestoreJob) applyFilters(ctx context.Context, contID, dumpName string, definition DumpDefinition,
+	droppedTables []string) error {
+	sampleDir := path.Join(r.getDumpLocation(definition.Format, dumpName), samplesDir)
+
+	_, errStat := os.Stat(sampleDir)
+	hasSamples := errStat == nil && definition.Format == directoryFormat
+
+	if !hasSamples && len(definition.Sampling) == 0 && len(droppedTables) == 0 {
+		return nil
+	}
+
+	dbName, err := r.restoredDBName(ctx, contID, dumpName, definition)
+	if err != nil {
+		return err
+	}
+
+	if hasSamples {
+		if err := loadSampleFiles(ctx, r.dockerClient, contID, r.globalCfg.Database.User(), dbName, sampleDir); err != nil {
+			return errors.Wrap(err, "failed to load sampled rows")
+		}
+	}
+
+	// Rows are deleted with foreign key checks enabled, so referencing rows are deleted by ON DELETE CASCADE
+	// or the restore fails instead of leaving the data referentially inconsistent.
+	for _, table := range definition.sampledTables() {
+		log.Msg(fmt.Sprintf("Deleting rows of the table %s that do not match the sampling condition", table))
+
+		query := fmt.Sprintf("delete from %s where not coalesce((%s), false)", table, definition.Sampling[table])
+
+		if err := r.execRestoredQuery(ctx, contID, dbName, query); err != nil {
+			return errors.Wrapf(err, "failed to sample the table %s", table)
+		}
+	}
+
+	for _, table := range droppedTables {
+		log.Msg("Dropping the excluded table ", table)
+
+		if err := r.execRestoredQuery(ctx, contID, dbName, fmt.Sprintf("drop table if exists %s cascade", table)); err != nil {
+			return errors.Wrapf(err, "failed to drop the excluded table %s", table)
+		}
+	}
+
+	return nil
+}
+
+// loadSampleFiles loads rows of sampled tables stored in the directory.
+func loadSampleFiles(ctx context.Context, dockerClient *client.Client, contID, username, dbName, sampleDir string) error {
+	entries, err := os.ReadDir(sampleDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the directory of sampled rows")
+	}
+
+	targetCmd := shellJoin([]string{"psql", "--username", username, "--dbname", dbName, "--no-psqlrc", "--set", "ON_ERROR_STOP=1"})
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), sampleFileExt) {
+			continue
+		}
+
+		table, err := sampleTableName(entry.Name())
+		if err != nil {
+			return errors.Wrapf(err, "invalid name of the file with sampled rows %q", entry.Name())
+		}
+
+		log.Msg(fmt.Sprintf("Loading sampled rows of the table %s", table))
+
+		if out, err := tools.ExecCommandWithOutput(ctx, dockerClient, contID, types.ExecConfig{
+			Cmd: []string{"bash", "-c", fmt.Sprintf(`%s %s --command "$%s" < "$%s"`, replicaRoleOptions, targetCmd, copyInEnv, sampleFileEnv)},
+			Env: []string{
+				fmt.Sprintf("%s=copy %s from stdin", copyInEnv, table),
+				fmt.Sprintf("%s=%s", sampleFileEnv, path.Join(sampleDir, entry.Name())),
+			},
+		}); err != nil {
+			log.Dbg(out)
+			return errors.Wrapf(err, "failed to load sampled rows of the table %s", table)
+		}
+	}
+
+	return nil
+}
+
+// restoredDBName returns the name of the database created by the restore.
+func (r *RestoreJob) restoredDBName(ctx context.Context, contID, dumpName string, definition DumpDefinition) (string, error) {
+	if definition.dbName != "" {
+		return definition.dbName, nil
+	}
+
+	dbName, err := r.extractDBNameFromDump(ctx, contID, dumpName, r.getDumpLocation(definition.Format, dumpName))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find the name of the restored database")
+	}
+
+	return dbName, nil
+}
+
+func (r *RestoreJob) execRestoredQuery(ctx context.Context, contID, dbName, query string) error {
+	out, err := tools.ExecCommandWithOutput(ctx, r.dockerClient, contID, types.ExecConfig{
+		Cmd: []string{"psql", "--username", r.globalCfg.Database.User(), "--dbname", dbName, "--no-psqlrc",
+			"--set", "ON_ERROR_STOP=1", "--command", query},
+	})
+	if err != nil {
+		log.Dbg(out)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package logical
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpFilterArgs(t *testing.T) {
+	definition := DumpDefinition{
+		ExcludeTables:    []string{"public.tmp_*"},
+		ExcludeTableData: []string{"audit_log"},
+		Schemas:          []string{"public", "billing"},
+		ExcludeSchemas:   []string{"archive"},
+		Sampling:         map[string]string{"public.users": "id % 10 = 0", "billing.invoices": "created_at > now() - interval '1 month'"},
+	}
+
+	assert.Equal(t, []string{
+		"--schema", "public", "--schema", "billing",
+		"--exclude-schema", "archive",
+		"--exclude-table", "public.tmp_*",
+		"--exclude-table-data", "audit_log",
+		"--exclude-table-data", "billing.invoices", "--exclude-table-data", "public.users",
+	}, definition.dumpFilterArgs())
+
+	assert.Empty(t, DumpDefinition{}.dumpFilterArgs())
+	assert.True(t, definition.hasFilters())
+	assert.False(t, DumpDefinition{Format: directoryFormat}.hasFilters())
+
+	subsetDefinition := DumpDefinition{subsetTables: []sourceTable{{schema: "public", name: "Users"}}}
+	assert.Equal(t, []string{"--exclude-table-data", `"public"."Users"`}, subsetDefinition.dumpFilterArgs())
+}
+
+func TestValidateFilters(t *testing.T) {
+	assert.NoError(t, validateFilters("db", DumpDefinition{Sampling: map[string]string{"public.users": "id < 100"}}))
+	assert.Error(t, validateFilters("db", DumpDefinition{Sampling: map[string]string{"users": "id < 100"}}))
+	assert.Error(t, validateFilters("db", DumpDefinition{Sampling: map[string]string{"public.user*": "id < 100"}}))
+	assert.Error(t, validateFilters("db", DumpDefinition{Sampling: map[string]string{"public.users": " "}}))
+
+	assert.NoError(t, validateRestoreFilters("db", DumpDefinition{Schemas: []string{"public"}, ExcludeSchemas: []string{"archive"}}))
+	assert.Error(t, validateRestoreFilters("db", DumpDefinition{Schemas: []string{"app_*"}}))
+	assert.Error(t, validateRestoreFilters("db", DumpDefinition{ExcludeSchemas: []string{"archive?"}}))
+	assert.Error(t, validateRestoreFilters("db", DumpDefinition{Sampling: map[string]string{"users": "id < 100"}}))
+}
+
+func TestMatchesTable(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		schema  string
+		table   string
+		matched bool
+	}{
+		{pattern: "users", schema: "public", table: "users", matched: true},
+		{pattern: "users", schema: "billing", table: "users", matched: true},
+		{pattern: "public.users", schema: "billing", table: "users", matched: false},
+		{pattern: "public.audit_*", schema: "public", table: "audit_2022", matched: true},
+		{pattern: "*.audit_?", schema: "billing", table: "audit_1", matched: true},
+		{pattern: "Users", schema: "public", table: "users", matched: true},
+		{pattern: `public."Users"`, schema: "public", table: "users", matched: false},
+		{pattern: `public."Users"`, schema: "public", table: "Users", matched: true},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.matched, matchesTable(tc.pattern, tc.schema, tc.table), tc.pattern)
+	}
+}
+
+func TestCheckFilters(t *testing.T) {
+	tables := []sourceTable{{schema: "public", name: "users"}, {schema: "public", name: "audit_log"}, {schema: "billing", name: "invoices"}}
+	schemas := []string{"public", "billing"}
+
+	t.Run("filters match the catalog", func(t *testing.T) {
+		definition := DumpDefinition{
+			Tables:           []string{"public.*"},
+			ExcludeTableData: []string{"audit_*"},
+			Schemas:          []string{"public"},
+			Sampling:         map[string]string{"public.users": "id < 100"},
+		}
+
+		assert.Empty(t, checkFilters(definition, tables, schemas, nil))
+	})
+
+	t.Run("filters do not match the catalog", func(t *testing.T) {
+		definition := DumpDefinition{
+			ExcludeTables:    []string{"public.usr"},
+			ExcludeTableData: []string{"users"},
+			ExcludeSchemas:   []string{"archive"},
+			Sampling:         map[string]string{"public.users": "id < 100", "public.orders": "id < 100"},
+		}
+
+		assert.Equal(t, []string{
+			`excludeTables: no tables match "public.usr"`,
+			`excludeSchemas: no schemas match "archive"`,
+			`sampling: table "public.orders" not found`,
+			`sampling: data of the table "public.users" are excluded`,
+		}, checkFilters(definition, tables, schemas, nil))
+	})
+}
+
+func TestCheckFiltersWithForeignKeys(t *testing.T) {
+	users := sourceTable{schema: "public", name: "users"}
+	orders := sourceTable{schema: "public", name: "orders"}
+	events := sourceTable{schema: "public", name: "events"}
+
+	tables := []sourceTable{users, orders, events}
+	foreignKeys := []foreignKey{{child: orders, parent: users, childColumns: []string{"user_id"}, parentColumns: []string{"id"}}}
+
+	assert.Equal(t, []string{`sampling: rows of the table "public.users" are referenced by the table "public"."orders", use subset instead`},
+		checkFilters(DumpDefinition{Sampling: map[string]string{"public.users": "id < 100"}}, tables, nil, foreignKeys))
+
+	assert.Empty(t, checkFilters(DumpDefinition{
+		ExcludeTableData: []string{"public.orders"},
+		Sampling:         map[string]string{"public.users": "id < 100"},
+	}, tables, nil, foreignKeys))
+
+	assert.Equal(t, []string{`subset: root table "public.accounts" not found`, `sampling: table "public.orders" is a part of the subset`},
+		checkFilters(DumpDefinition{
+			Sampling: map[string]string{"public.orders": "id < 100"},
+			Subset:   &Subset{Roots: map[string]string{"public.users": "id < 100", "public.accounts": "id < 100"}},
+		}, tables, nil, foreignKeys))
+}
+
+func TestFilterTOC(t *testing.T) {
+	const toc = `;
+; Archive created at 2022-06-01 10:00:00 UTC
+;
+215; 1259 16386 TABLE public users postgres
+216; 1259 16390 TABLE public audit_log postgres
+217; 1259 16395 TABLE public tmp_import postgres
+3245; 0 16386 TABLE DATA public users postgres
+3246; 0 16390 TABLE DATA public audit_log postgres
+3247; 0 16395 TABLE DATA public tmp_import postgres
+3100; 2606 16400 CONSTRAINT public users users_pkey postgres`
+
+	filteredTOC, droppedTables := filterTOC(toc, DumpDefinition{
+		ExcludeTables:    []string{"tmp_*"},
+		ExcludeTableData: []string{"public.audit_log"},
+	})
+
+	assert.Equal(t, `;
+; Archive created at 2022-06-01 10:00:00 UTC
+;
+215; 1259 16386 TABLE public users postgres
+216; 1259 16390 TABLE public audit_log postgres
+217; 1259 16395 TABLE public tmp_import postgres
+3245; 0 16386 TABLE DATA public users postgres
+;3246; 0 16390 TABLE DATA public audit_log postgres
+;3247; 0 16395 TABLE DATA public tmp_import postgres
+3100; 2606 16400 CONSTRAINT public users users_pkey postgres`, filteredTOC)
+	assert.Equal(t, []string{`"public"."tmp_import"`}, droppedTables)
+}
+
+func TestSampleFileName(t *testing.T) {
+	for _, table := range []string{"public.users", `public."Users/Archive"`} {
+		tableName, err := sampleTableName(sampleFileName(table))
+		require.NoError(t, err)
+		assert.Equal(t, table, tableName)
+	}
+
+	assert.Equal(t, "public.users.copy", sampleFileName("public.users"))
+}
+
+func TestShellJoin(t *testing.T) {
+	assert.Equal(t, "pg_dump --host db.example.com --port 5432", shellJoin([]string{"pg_dump", "--host", "db.example.com", "--port", "5432"}))
+	assert.Equal(t, `--exclude-table 'public.tmp_*' --table 'public."Users"' --schema 'it'\''s'`,
+		shellJoin([]string{"--exclude-table", "public.tmp_*", "--table", `public."Users"`, "--schema", "it's"}))
+}
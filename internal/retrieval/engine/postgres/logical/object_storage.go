@@ -0,0 +1,343 @@
+/*
+2022 © Postgres.ai
+*/
+
+package logical
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/objstore"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/util"
+)
+
+const (
+	// dumpManifestName defines the name of an object marking a completely uploaded dump.
+	dumpManifestName = "manifest.json"
+
+	// dumpDateLayout defines the layout of a date to select a dump.
+	dumpDateLayout = "2006-01-02"
+)
+
+// DumpStorage defines an object storage to upload dumps to.
+type DumpStorage struct {
+	objstore.Config `yaml:",inline"`
+
+	// Generations defines how many dumps are kept in the storage. Zero keeps all dumps.
+	Generations int `yaml:"generations"`
+}
+
+// RestoreStorage defines an object storage to restore dumps from.
+type RestoreStorage struct {
+	objstore.Config `yaml:",inline"`
+
+	// DumpDate selects the latest dump created not later than the date. The latest dump is restored if it's empty.
+	DumpDate string `yaml:"dumpDate"`
+}
+
+// dumpManifest describes a dump uploaded to the object storage.
+type dumpManifest struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Databases []string  `json:"databases"`
+}
+
+// dumpGeneration describes a dump kept in the object storage. Objects of a generation are stored under its name.
+type dumpGeneration struct {
+	name      string
+	createdAt time.Time
+	complete  bool
+}
+
+func generationName(createdAt time.Time) string {
+	return createdAt.UTC().Format(util.DataStateAtFormat)
+}
+
+// listGenerations returns dumps kept in the object storage, the newest first.
+func listGenerations(ctx context.Context, storage *objstore.Storage) ([]dumpGeneration, error) {
+	dirs, err := storage.ListDirs(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	generations := make([]dumpGeneration, 0, len(dirs))
+
+	for _, dir := range dirs {
+		createdAt, err := time.Parse(util.DataStateAtFormat, dir)
+		if err != nil {
+			log.Dbg(fmt.Sprintf("Skip %q because it is not a dump: %v", dir, err))
+			continue
+		}
+
+		complete, err := storage.Exists(ctx, path.Join(dir, dumpManifestName))
+		if err != nil {
+			return nil, err
+		}
+
+		generations = append(generations, dumpGeneration{name: dir, createdAt: createdAt, complete: complete})
+	}
+
+	sort.Slice(generations, func(i, j int) bool {
+		return generations[i].createdAt.After(generations[j].createdAt)
+	})
+
+	return generations, nil
+}
+
+// selectGeneration returns the latest complete dump created not later than the time.
+func selectGeneration(generations []dumpGeneration, until time.Time) (dumpGeneration, error) {
+	for _, generation := range generations {
+		if generation.complete && !generation.createdAt.After(until) {
+			return generation, nil
+		}
+	}
+
+	return dumpGeneration{}, errors.Errorf("no complete dump found created before %s", until.Format(time.RFC3339))
+}
+
+// expiredGenerations returns dumps to remove to keep the number of complete dumps.
+// Incomplete dumps older than the current one are left by failed runs, so they are removed as well.
+func expiredGenerations(generations []dumpGeneration, keep int, current dumpGeneration) []dumpGeneration {
+	expired := []dumpGeneration{}
+	kept := 0
+
+	for _, generation := range generations {
+		if generation.name == current.name || generation.createdAt.After(current.createdAt) {
+			if generation.complete {
+				kept++
+			}
+
+			continue
+		}
+
+		if generation.complete && (keep == 0 || kept < keep) {
+			kept++
+			continue
+		}
+
+		expired = append(expired, generation)
+	}
+
+	return expired
+}
+
+// parseDumpDate parses the date to select a dump. A date without time selects dumps created until the end of the day.
+func parseDumpDate(value string) (time.Time, error) {
+	if date, err := time.Parse(dumpDateLayout, value); err == nil {
+		return date.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid dump date %q: use the YYYY-MM-DD or RFC 3339 format", value)
+	}
+
+	return date, nil
+}
+
+// isStreamedDump checks if the dump is uploaded to the object storage right from the pg_dump output.
+// Directory dumps consist of several files, so they are uploaded after dumping.
+func (d *DumpJob) isStreamedDump(definition DumpDefinition) bool {
+	return d.storage != nil && (definition.Format == plainFormat || definition.Format == customFormat)
+}
+
+// streamDump runs the dump command and uploads its output to the object storage without saving it on disk.
+func (d *DumpJob) streamDump(ctx context.Context, contID string, commandCfg types.ExecConfig, key string, handler func(line string)) error {
+	pr, pw := io.Pipe()
+	uploadResult := make(chan error, 1)
+
+	go func() {
+		err := d.storage.Upload(ctx, key, pr)
+
+		// Unblock the dump command if the upload fails.
+		_ = pr.CloseWithError(err)
+		uploadResult <- err
+	}()
+
+	err := tools.ExecCommandWithStdout(ctx, d.dockerClient, contID, commandCfg, pw, handler)
+
+	// Complete the upload or abort it if the dump fails.
+	_ = pw.CloseWithError(err)
+
+	uploadErr := <-uploadResult
+
+	if err != nil {
+		return err
+	}
+
+	return uploadErr
+}
+
+// completeUpload marks the dump as complete and removes expired dumps from the object storage.
+func (d *DumpJob) completeUpload(ctx context.Context, current dumpGeneration, dbList map[string]DumpDefinition) error {
+	manifest := dumpManifest{CreatedAt: current.createdAt, Databases: make([]string, 0, len(dbList))}
+
+	for dbName := range dbList {
+		manifest.Databases = append(manifest.Databases, dbName)
+	}
+
+	sort.Strings(manifest.Databases)
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the dump manifest")
+	}
+
+	if err := d.storage.Upload(ctx, path.Join(current.name, dumpManifestName), bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	log.Msg(fmt.Sprintf("The dump %s has been uploaded to the object storage", current.name))
+
+	generations, err := listGenerations(ctx, d.storage)
+	if err != nil {
+		log.Err("Failed to list dumps in the object storage:", err)
+		return nil
+	}
+
+	current.complete = true
+
+	for _, generation := range expiredGenerations(generations, d.ObjectStorage.Generations, current) {
+		log.Msg(fmt.Sprintf("Removing the expired dump %s from the object storage", generation.name))
+
+		if err := d.storage.Remove(ctx, generation.name); err != nil {
+			log.Err("Failed to remove the expired dump:", err)
+		}
+	}
+
+	return nil
+}
+
+// prepareDump selects a dump in the object storage and returns the directory of dumps to restore.
+// Only directory dumps are downloaded there. Single-file dumps are streamed to restore commands, so they need no local space.
+func (r *RestoreJob) prepareDump(ctx context.Context) (string, error) {
+	until := time.Now()
+
+	if r.ObjectStorage.DumpDate != "" {
+		dumpDate, err := parseDumpDate(r.ObjectStorage.DumpDate)
+		if err != nil {
+			return "", err
+		}
+
+		until = dumpDate
+	}
+
+	generations, err := listGenerations(ctx, r.storage)
+	if err != nil {
+		return "", err
+	}
+
+	generation, err := selectGeneration(generations, until)
+	if err != nil {
+		return "", err
+	}
+
+	keys, err := r.storage.List(ctx, generation.name)
+	if err != nil {
+		return "", err
+	}
+
+	r.streamedDumps = streamedDumpKeys(generation.name, keys)
+	r.streamedDumpsCreatedAt = generation.createdAt
+
+	downloadDir := path.Join(r.RestoreOptions.DumpLocation, generation.name)
+
+	// Remove files left by a failed download.
+	if err := os.RemoveAll(downloadDir); err != nil {
+		return "", errors.Wrap(err, "failed to clean up the download directory")
+	}
+
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		return "", errors.Wrap(err, "failed to create the download directory")
+	}
+
+	log.Msg(fmt.Sprintf("Downloading directory dumps of %s from the object storage to %s", generation.name, downloadDir))
+
+	if err := r.storage.Download(ctx, generation.name, downloadDir, func(key string) bool {
+		return !isDirectoryDumpKey(key)
+	}); err != nil {
+		return "", err
+	}
+
+	return downloadDir, nil
+}
+
+// streamedDumpKeys maps names of single-file dumps of the generation to their keys in the object storage.
+func streamedDumpKeys(generationName string, keys []string) map[string]string {
+	dumps := make(map[string]string)
+
+	for _, key := range keys {
+		if key == dumpManifestName || isDirectoryDumpKey(key) {
+			continue
+		}
+
+		dumps[key] = path.Join(generationName, key)
+	}
+
+	return dumps
+}
+
+// isDirectoryDumpKey checks if the object belongs to a directory dump. Objects of directory dumps are kept under their names.
+func isDirectoryDumpKey(key string) bool {
+	return strings.Contains(key, "/")
+}
+
+// isStreamedDump checks if the dump is streamed from the object storage.
+func (r *RestoreJob) isStreamedDump(dumpName string) bool {
+	_, ok := r.streamedDumps[dumpName]
+	return ok
+}
+
+// execDumpCommand runs the command reading the dump. A dump kept in the object storage is streamed to stdin of the command.
+func (r *RestoreJob) execDumpCommand(ctx context.Context, contID, dumpName string, execCfg types.ExecConfig, stdout io.Writer,
+	handler func(line string)) error {
+	key, ok := r.streamedDumps[dumpName]
+	if !ok {
+		return tools.ExecCommandWithStdout(ctx, r.dockerClient, contID, execCfg, stdout, handler)
+	}
+
+	body, err := r.storage.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = body.Close() }()
+
+	return tools.ExecCommandWithStdin(ctx, r.dockerClient, contID, execCfg, body, stdout, handler)
+}
+
+// execDumpCommandWithOutput runs the command reading the dump and returns its output.
+// As tools.ExecCommandWithOutput does, it fails if the command writes to stderr.
+func (r *RestoreJob) execDumpCommandWithOutput(ctx context.Context, contID, dumpName string, execCfg types.ExecConfig) (string, error) {
+	if !r.isStreamedDump(dumpName) {
+		return tools.ExecCommandWithOutput(ctx, r.dockerClient, contID, execCfg)
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := []string{}
+
+	if err := r.execDumpCommand(ctx, contID, dumpName, execCfg, stdout, func(line string) {
+		stderr = append(stderr, line)
+	}); err != nil {
+		return "", err
+	}
+
+	if len(stderr) > 0 {
+		return "", errors.New(strings.Join(stderr, "\n"))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
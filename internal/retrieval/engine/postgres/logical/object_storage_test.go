@@ -0,0 +1,75 @@
+package logical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testGeneration(createdAt time.Time, complete bool) dumpGeneration {
+	return dumpGeneration{name: generationName(createdAt), createdAt: createdAt, complete: complete}
+}
+
+func TestSelectGeneration(t *testing.T) {
+	day := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	generations := []dumpGeneration{
+		testGeneration(day.Add(50*time.Hour), false),
+		testGeneration(day.Add(26*time.Hour), true),
+		testGeneration(day.Add(2*time.Hour), true),
+	}
+
+	generation, err := selectGeneration(generations, day.Add(72*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "20220602020000", generation.name)
+
+	generation, err = selectGeneration(generations, day.Add(25*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "20220601020000", generation.name)
+
+	_, err = selectGeneration(generations, day)
+	assert.Error(t, err)
+}
+
+func TestExpiredGenerations(t *testing.T) {
+	day := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	current := testGeneration(day.Add(72*time.Hour), true)
+
+	generations := []dumpGeneration{
+		current,
+		testGeneration(day.Add(50*time.Hour), false),
+		testGeneration(day.Add(48*time.Hour), true),
+		testGeneration(day.Add(24*time.Hour), true),
+		testGeneration(day, true),
+	}
+
+	expired := expiredGenerations(generations, 2, current)
+	assert.Equal(t, []dumpGeneration{generations[1], generations[3], generations[4]}, expired)
+
+	expired = expiredGenerations(generations, 0, current)
+	assert.Equal(t, []dumpGeneration{generations[1]}, expired)
+}
+
+func TestParseDumpDate(t *testing.T) {
+	date, err := parseDumpDate("2022-06-01")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 6, 1, 23, 59, 59, 999999999, time.UTC), date)
+
+	date, err = parseDumpDate("2022-06-01T10:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC), date)
+
+	_, err = parseDumpDate("01.06.2022")
+	assert.Error(t, err)
+}
+
+func TestStreamedDumpKeys(t *testing.T) {
+	keys := []string{"manifest.json", "test.dump", "plain.sql.gz", "dirdump/toc.dat", "dirdump/3310.dat.gz"}
+
+	assert.Equal(t, map[string]string{
+		"test.dump":    "20220601020000/test.dump",
+		"plain.sql.gz": "20220601020000/plain.sql.gz",
+	}, streamedDumpKeys("20220601020000", keys))
+}
@@ -0,0 +1,205 @@
+/*
+2022 © Postgres.ai
+*/
+
+package logical
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/fs"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+// Markers of table data processing in the verbose output of pg_dump and pg_restore.
+const (
+	dumpTableMarker     = "dumping contents of table "
+	restoreTableMarker  = "processing data for table "
+	finishedItemMarker  = "finished item "
+	tableDataItemMarker = " TABLE DATA "
+
+	// copyCommandTag starts psql output of a finished COPY command while restoring plain-text dumps.
+	copyCommandTag = "COPY "
+)
+
+// defaultSizeSampleInterval defines how often the size of a dump is measured.
+const defaultSizeSampleInterval = 10 * time.Second
+
+// progressTracker tracks progress of dumping or restoring databases of a job.
+type progressTracker struct {
+	mu                 sync.Mutex
+	job                string
+	databases          []*databaseProgress
+	sizeSampleInterval time.Duration
+}
+
+// databaseProgress tracks progress of a single database.
+type databaseProgress struct {
+	progress models.DatabaseProgress
+	started  int
+	finished int
+	// stopSampling stops measuring the size of the dump. It is nil if the size is not measured.
+	stopSampling chan struct{}
+	samplingDone chan struct{}
+}
+
+func newProgressTracker(job string) *progressTracker {
+	return &progressTracker{job: job, sizeSampleInterval: defaultSizeSampleInterval}
+}
+
+// reset clears the progress of the previous job run.
+func (t *progressTracker) reset() {
+	t.mu.Lock()
+	t.databases = nil
+	t.mu.Unlock()
+}
+
+// start registers a database. The total number of tables is zero if it is unknown.
+// If the location is not empty, the size of the dump in this location is measured in the background.
+func (t *progressTracker) start(dbName string, tablesTotal int, location string) *databaseProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	db := &databaseProgress{
+		progress: models.DatabaseProgress{
+			Job:         t.job,
+			Database:    dbName,
+			TablesTotal: tablesTotal,
+			StartedAt:   time.Now().Truncate(time.Second),
+		},
+	}
+
+	if location != "" {
+		db.stopSampling = make(chan struct{})
+		db.samplingDone = make(chan struct{})
+
+		go t.sampleSize(db, location)
+	}
+
+	t.databases = append(t.databases, db)
+
+	return db
+}
+
+// sampleSize periodically measures the size of the dump until sampling is stopped,
+// so progress requests do not walk a large dump directory.
+func (t *progressTracker) sampleSize(db *databaseProgress, location string) {
+	defer close(db.samplingDone)
+
+	ticker := time.NewTicker(t.sizeSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		t.measureSize(db, location)
+
+		select {
+		case <-db.stopSampling:
+			t.measureSize(db, location)
+			return
+
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *progressTracker) measureSize(db *databaseProgress, location string) {
+	size, err := fs.DirSize(location)
+	if err != nil {
+		log.Dbg("Failed to get the dump size:", err)
+		return
+	}
+
+	t.mu.Lock()
+	db.progress.BytesWritten = size
+	t.mu.Unlock()
+}
+
+// handleLine counts processed tables using a line of the verbose output.
+func (t *progressTracker) handleLine(db *databaseProgress, line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case strings.Contains(line, dumpTableMarker), strings.Contains(line, restoreTableMarker):
+		db.started++
+
+	case strings.Contains(line, finishedItemMarker) && strings.Contains(line, tableDataItemMarker),
+		strings.HasPrefix(line, copyCommandTag):
+		db.finished++
+
+	default:
+		return
+	}
+
+	db.progress.TablesDone = db.tablesDone()
+}
+
+// tablesDone returns the number of processed tables.
+// Parallel runs report finished items, otherwise a table is considered processed when the next one is started.
+func (db *databaseProgress) tablesDone() int {
+	done := db.finished
+	if done == 0 && db.started > 0 {
+		done = db.started - 1
+	}
+
+	if db.progress.TablesTotal > 0 && done > db.progress.TablesTotal {
+		done = db.progress.TablesTotal
+	}
+
+	return done
+}
+
+// finish completes tracking of the database.
+func (t *progressTracker) finish(db *databaseProgress, err error) {
+	if db.stopSampling != nil {
+		close(db.stopSampling)
+		<-db.samplingDone
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	finishedAt := time.Now().Truncate(time.Second)
+	db.progress.FinishedAt = &finishedAt
+
+	if err == nil && db.progress.TablesTotal > 0 {
+		db.progress.TablesDone = db.progress.TablesTotal
+	}
+}
+
+// Progress returns progress of databases of the last job run.
+func (t *progressTracker) Progress() []models.DatabaseProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	progress := make([]models.DatabaseProgress, 0, len(t.databases))
+
+	for _, db := range t.databases {
+		dbProgress := db.progress
+
+		if dbProgress.FinishedAt == nil {
+			dbProgress.ETA = estimateCompletion(dbProgress, now)
+		}
+
+		progress = append(progress, dbProgress)
+	}
+
+	return progress
+}
+
+// estimateCompletion estimates completion time assuming that the remaining tables are processed at the same pace.
+func estimateCompletion(progress models.DatabaseProgress, now time.Time) *time.Time {
+	if progress.TablesDone == 0 || progress.TablesTotal == 0 {
+		return nil
+	}
+
+	elapsed := now.Sub(progress.StartedAt)
+	remaining := time.Duration(float64(elapsed) / float64(progress.TablesDone) * float64(progress.TablesTotal-progress.TablesDone))
+	eta := now.Add(remaining).Truncate(time.Second)
+
+	return &eta
+}
@@ -0,0 +1,96 @@
+package logical
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+func TestProgressTrackerSerialDump(t *testing.T) {
+	dumpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dumpDir, "toc.dat"), make([]byte, 128), 0600))
+
+	tracker := newProgressTracker("logicalDump")
+	tracker.sizeSampleInterval = 10 * time.Millisecond
+	db := tracker.start("test", 3, dumpDir)
+
+	tracker.handleLine(db, `pg_dump: reading schemas`)
+	tracker.handleLine(db, `pg_dump: dumping contents of table "public.first"`)
+	tracker.handleLine(db, `pg_dump: dumping contents of table "public.second"`)
+
+	progress := tracker.Progress()
+	require.Len(t, progress, 1)
+	assert.Equal(t, "logicalDump", progress[0].Job)
+	assert.Equal(t, "test", progress[0].Database)
+	assert.Equal(t, 1, progress[0].TablesDone)
+	assert.Equal(t, 3, progress[0].TablesTotal)
+	assert.NotNil(t, progress[0].ETA)
+	assert.Nil(t, progress[0].FinishedAt)
+
+	assert.Eventually(t, func() bool {
+		return tracker.Progress()[0].BytesWritten == 128
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dumpDir, "3310.dat"), make([]byte, 64), 0600))
+
+	tracker.finish(db, nil)
+
+	progress = tracker.Progress()
+	assert.Equal(t, uint64(192), progress[0].BytesWritten)
+	assert.Equal(t, 3, progress[0].TablesDone)
+	assert.NotNil(t, progress[0].FinishedAt)
+	assert.Nil(t, progress[0].ETA)
+
+	tracker.reset()
+	assert.Empty(t, tracker.Progress())
+}
+
+func TestProgressTrackerParallelRestore(t *testing.T) {
+	tracker := newProgressTracker("logicalRestore")
+	db := tracker.start("test", 2, "")
+
+	tracker.handleLine(db, `pg_restore: processing data for table "public.first"`)
+	tracker.handleLine(db, `pg_restore: processing data for table "public.second"`)
+	tracker.handleLine(db, `pg_restore: finished item 3310 TABLE DATA first`)
+
+	progress := tracker.Progress()
+	assert.Equal(t, 1, progress[0].TablesDone)
+	assert.Zero(t, progress[0].BytesWritten)
+
+	tracker.finish(db, errors.New("exit code: 1"))
+
+	progress = tracker.Progress()
+	assert.Equal(t, 1, progress[0].TablesDone)
+	assert.NotNil(t, progress[0].FinishedAt)
+}
+
+func TestProgressTrackerPlainRestore(t *testing.T) {
+	tracker := newProgressTracker("logicalRestore")
+	db := tracker.start("test", 0, "")
+
+	tracker.handleLine(db, "SET")
+	tracker.handleLine(db, "COPY 1000")
+	tracker.handleLine(db, "COPY 20")
+
+	progress := tracker.Progress()
+	assert.Equal(t, 2, progress[0].TablesDone)
+	assert.Nil(t, progress[0].ETA)
+}
+
+func TestEstimateCompletion(t *testing.T) {
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, estimateCompletion(models.DatabaseProgress{TablesTotal: 10, StartedAt: now}, now))
+	assert.Nil(t, estimateCompletion(models.DatabaseProgress{TablesDone: 10, StartedAt: now}, now))
+
+	eta := estimateCompletion(models.DatabaseProgress{TablesDone: 2, TablesTotal: 10, StartedAt: now.Add(-time.Hour)}, now)
+	require.NotNil(t, eta)
+	assert.Equal(t, now.Add(4*time.Hour), *eta)
+}
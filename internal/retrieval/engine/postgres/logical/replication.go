@@ -0,0 +1,553 @@
+/*
+2022 © Postgres.ai
+*/
+
+package logical
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/databases/postgres/pgconfig"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/config"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/cont"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/db"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/defaults"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/health"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/options"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/config/global"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+)
+
+const (
+	// ReplicationJobType declares a job type for keeping restored data in sync with the source by the logical replication.
+	ReplicationJobType = "logicalReplication"
+
+	// replicationPrefix defines a prefix of publications, subscriptions and replication slots.
+	replicationPrefix = "dblab_"
+
+	// maxSlotNameLength defines the maximum length of a replication slot name.
+	maxSlotNameLength = 63
+
+	// replicationSlotSnapshotField defines the index of the exported snapshot in the output of CREATE_REPLICATION_SLOT.
+	replicationSlotSnapshotField = 2
+
+	// defaultDriftCheckInterval defines how often the schema of replicated databases is compared with the source by default.
+	defaultDriftCheckInterval = 5 * time.Minute
+)
+
+// ReplicationSource defines options to prepare the source for the logical replication.
+type ReplicationSource struct {
+	// Enabled creates a publication and a replication slot for every dumped database and dumps data as of the slot creation.
+	Enabled bool `yaml:"enabled"`
+}
+
+// ReplicationJob defines a job running a subscriber instance that keeps restored data in sync with the source.
+type ReplicationJob struct {
+	name            string
+	dockerClient    *client.Client
+	fsPool          *resources.Pool
+	globalCfg       *global.Config
+	engineProps     global.EngineProps
+	refreshRequests chan string
+	ReplicationOptions
+}
+
+// ReplicationOptions defines options of the logical replication job.
+type ReplicationOptions struct {
+	DockerImage     string                 `yaml:"dockerImage"`
+	ContainerConfig map[string]interface{} `yaml:"containerConfig"`
+	Configs         map[string]string      `yaml:"configs"`
+
+	// Connection defines how subscriptions connect to the source. The password is stored in subscriptions of the sync instance.
+	Connection Connection `yaml:"connection"`
+
+	// DriftCheckInterval defines how often (in seconds) the schema of replicated databases is compared with the source.
+	DriftCheckInterval uint `yaml:"driftCheckInterval"`
+}
+
+// NewReplicationJob creates a new logical replication job.
+func NewReplicationJob(cfg config.JobConfig, global *global.Config, engineProps global.EngineProps) (*ReplicationJob, error) {
+	replicationJob := &ReplicationJob{
+		name:            cfg.Spec.Name,
+		dockerClient:    cfg.Docker,
+		fsPool:          cfg.FSPool,
+		globalCfg:       global,
+		engineProps:     engineProps,
+		refreshRequests: make(chan string, 1),
+	}
+
+	if err := replicationJob.Reload(cfg.Spec.Options); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal configuration options")
+	}
+
+	return replicationJob, nil
+}
+
+// Name returns a name of the job.
+func (j *ReplicationJob) Name() string {
+	return j.name
+}
+
+// Reload reloads job configuration.
+func (j *ReplicationJob) Reload(cfg map[string]interface{}) error {
+	if err := options.Unmarshal(cfg, &j.ReplicationOptions); err != nil {
+		return errors.Wrap(err, "failed to unmarshal configuration options")
+	}
+
+	if j.Connection.Port == 0 {
+		j.Connection.Port = defaults.Port
+	}
+
+	if j.Connection.Username == "" {
+		j.Connection.Username = defaults.Username
+	}
+
+	return nil
+}
+
+// RefreshRequests returns a channel receiving reasons to perform a full refresh.
+func (j *ReplicationJob) RefreshRequests() <-chan string {
+	return j.refreshRequests
+}
+
+// Run starts the job.
+func (j *ReplicationJob) Run(ctx context.Context) error {
+	log.Msg("Run job: ", j.Name())
+
+	contID, err := j.runSyncInstance(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to run sync instance")
+	}
+
+	dbList, err := j.subscribe(ctx, contID)
+	if err != nil {
+		return errors.Wrap(err, "failed to subscribe to the source")
+	}
+
+	if len(dbList) == 0 {
+		return errors.New("no replication slots found for restored databases. Enable replication in the logicalDump job")
+	}
+
+	log.Msg("Logical replication has been started. Replicated databases: ", strings.Join(dbList, ", "))
+
+	go j.watchSchemaDrift(ctx, contID, dbList)
+
+	return nil
+}
+
+// Resume restarts the sync instance when the engine starts without a data refresh.
+func (j *ReplicationJob) Resume(ctx context.Context) error {
+	return j.Run(ctx)
+}
+
+func (j *ReplicationJob) syncInstanceName() string {
+	return cont.SyncInstanceContainerPrefix + j.engineProps.InstanceID
+}
+
+func (j *ReplicationJob) runSyncInstance(ctx context.Context) (string, error) {
+	syncContainer, err := j.dockerClient.ContainerInspect(ctx, j.syncInstanceName())
+	if err != nil && !client.IsErrNotFound(err) {
+		return "", errors.Wrap(err, "failed to inspect sync container")
+	}
+
+	if syncContainer.ContainerJSONBase != nil {
+		if syncContainer.State.Running {
+			log.Msg("Sync instance is already running")
+			return syncContainer.ID, nil
+		}
+
+		log.Msg("Removing non-running sync instance")
+
+		tools.RemoveContainer(ctx, j.dockerClient, syncContainer.ID, cont.StopTimeout)
+	}
+
+	if len(j.Configs) > 0 {
+		cfgManager, err := pgconfig.NewCorrector(j.fsPool.DataDir())
+		if err != nil {
+			return "", errors.Wrap(err, "failed to create a config manager")
+		}
+
+		if err := cfgManager.ApplySync(j.Configs); err != nil {
+			return "", errors.Wrap(err, "cannot update sync instance configs")
+		}
+	}
+
+	if err := tools.PullImage(ctx, j.dockerClient, j.DockerImage); err != nil {
+		return "", err
+	}
+
+	hostConfig, err := cont.BuildHostConfig(ctx, j.dockerClient, j.fsPool.DataDir(), j.ContainerConfig)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to build container host config")
+	}
+
+	pwd, err := tools.GeneratePassword()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate PostgreSQL password")
+	}
+
+	syncCont, err := j.dockerClient.ContainerCreate(ctx, j.buildContainerConfig(pwd), hostConfig, &network.NetworkingConfig{}, nil,
+		j.syncInstanceName())
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create container %s", j.syncInstanceName())
+	}
+
+	log.Msg("Starting sync instance: ", j.syncInstanceName())
+
+	if err := j.dockerClient.ContainerStart(ctx, syncCont.ID, types.ContainerStartOptions{}); err != nil {
+		return "", errors.Wrapf(err, "failed to start container %s", j.syncInstanceName())
+	}
+
+	log.Msg(fmt.Sprintf("View logs using the command: %s %s", tools.ViewLogsCmd, j.syncInstanceName()))
+
+	if err := tools.CheckContainerReadiness(ctx, j.dockerClient, syncCont.ID); err != nil {
+		tools.PrintContainerLogs(ctx, j.dockerClient, j.syncInstanceName())
+		tools.PrintLastPostgresLogs(ctx, j.dockerClient, j.syncInstanceName(), j.fsPool.DataDir())
+
+		return "", errors.Wrap(err, "failed to readiness check")
+	}
+
+	log.Msg("Sync instance has been running")
+
+	return syncCont.ID, nil
+}
+
+func (j *ReplicationJob) buildContainerConfig(password string) *container.Config {
+	return &container.Config{
+		Labels: map[string]string{
+			cont.DBLabControlLabel:    cont.DBLabSyncLabel,
+			cont.DBLabInstanceIDLabel: j.engineProps.InstanceID,
+			cont.DBLabEngineNameLabel: j.engineProps.ContainerName,
+		},
+		Env: []string{
+			"PGDATA=" + j.fsPool.DataDir(),
+			"POSTGRES_PASSWORD=" + password,
+		},
+		Image:       j.DockerImage,
+		Healthcheck: health.GetConfig(j.globalCfg.Database.User(), j.globalCfg.Database.Name()),
+	}
+}
+
+// subscribe creates missing subscriptions for restored databases which have replication slots on the source.
+// It returns the list of replicated databases.
+func (j *ReplicationJob) subscribe(ctx context.Context, contID string) ([]string, error) {
+	output, err := j.queryInstance(ctx, contID, defaults.DBName, "select datname from pg_database where not datistemplate order by 1")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list restored databases")
+	}
+
+	dbList := []string{}
+
+	for _, dbName := range strings.Split(strings.TrimSpace(output), "\n") {
+		if dbName == "" {
+			continue
+		}
+
+		slotName := replicationSlotName(j.engineProps.InstanceID, dbName)
+
+		hasSlot, err := j.hasReplicationSlot(ctx, dbName, slotName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check the replication slot of the database %s", dbName)
+		}
+
+		if !hasSlot {
+			log.Msg(fmt.Sprintf("Replication slot %q not found. Skip replication of the database %s", slotName, dbName))
+			continue
+		}
+
+		if err := j.createSubscription(ctx, contID, dbName, slotName); err != nil {
+			return nil, errors.Wrapf(err, "failed to create a subscription in the database %s", dbName)
+		}
+
+		dbList = append(dbList, dbName)
+	}
+
+	return dbList, nil
+}
+
+func (j *ReplicationJob) hasReplicationSlot(ctx context.Context, dbName, slotName string) (bool, error) {
+	conn, err := j.connectSource(ctx, dbName)
+	if err != nil {
+		return false, err
+	}
+
+	defer func() { _ = conn.Close(ctx) }()
+
+	var hasSlot bool
+
+	if err := conn.QueryRow(ctx, "select exists(select 1 from pg_replication_slots where slot_name = $1 and database = $2)",
+		slotName, dbName).Scan(&hasSlot); err != nil {
+		return false, errors.Wrap(err, "failed to query replication slots")
+	}
+
+	return hasSlot, nil
+}
+
+func (j *ReplicationJob) createSubscription(ctx context.Context, contID, dbName, slotName string) error {
+	subscriptionName := replicationName(j.engineProps.InstanceID)
+
+	output, err := j.queryInstance(ctx, contID, dbName,
+		fmt.Sprintf("select count(*) from pg_subscription where subname = %s and subdbid = "+
+			"(select oid from pg_database where datname = current_database())", quoteLiteral(subscriptionName)))
+	if err != nil {
+		return errors.Wrap(err, "failed to check existing subscriptions")
+	}
+
+	if strings.TrimSpace(output) != "0" {
+		log.Msg(fmt.Sprintf("Subscription %q already exists in the database %s", subscriptionName, dbName))
+		return nil
+	}
+
+	// The exported snapshot of the replication slot is already restored, so data must not be copied again.
+	query := fmt.Sprintf("create subscription %s connection %s publication %s with (create_slot = false, copy_data = false, slot_name = %s)",
+		pgx.Identifier{subscriptionName}.Sanitize(),
+		quoteLiteral(j.subscriptionConnInfo(dbName)),
+		pgx.Identifier{subscriptionName}.Sanitize(),
+		quoteLiteral(slotName),
+	)
+
+	// The query contains the password, so it is not logged.
+	if _, err := j.queryInstance(ctx, contID, dbName, query); err != nil {
+		return errors.Wrap(err, "failed to create a subscription")
+	}
+
+	log.Msg(fmt.Sprintf("Subscription %q has been created in the database %s", subscriptionName, dbName))
+
+	return nil
+}
+
+// subscriptionConnInfo builds a connection string used by the subscription to connect to the source.
+func (j *ReplicationJob) subscriptionConnInfo(dbName string) string {
+	connInfo := []string{
+		"host=" + quoteConnValue(j.Connection.Host),
+		"port=" + strconv.Itoa(j.Connection.Port),
+		"user=" + quoteConnValue(j.Connection.Username),
+		"dbname=" + quoteConnValue(dbName),
+	}
+
+	if password := j.getPassword(); password != "" {
+		connInfo = append(connInfo, "password="+quoteConnValue(password))
+	}
+
+	return strings.Join(connInfo, " ")
+}
+
+func (j *ReplicationJob) getPassword() string {
+	if j.Connection.Password != "" {
+		return j.Connection.Password
+	}
+
+	return os.Getenv("PGPASSWORD")
+}
+
+func (j *ReplicationJob) connectSource(ctx context.Context, dbName string) (*pgx.Conn, error) {
+	connStr := db.ConnectionString(j.Connection.Host, strconv.Itoa(j.Connection.Port), j.Connection.Username, dbName, j.getPassword())
+
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to the source")
+	}
+
+	return conn, nil
+}
+
+// queryInstance runs the query in the database of the sync instance and returns unaligned output.
+func (j *ReplicationJob) queryInstance(ctx context.Context, contID, dbName, query string) (string, error) {
+	return tools.ExecCommandWithOutput(ctx, j.dockerClient, contID, types.ExecConfig{
+		Cmd:  []string{"psql", "-U", j.globalCfg.Database.User(), "-d", dbName, "-XAt", "-F", columnSeparator, "-c", query},
+		User: defaults.Username,
+	})
+}
+
+// requestRefresh requests a full refresh unless it has been already requested.
+func (j *ReplicationJob) requestRefresh(reason string) {
+	select {
+	case j.refreshRequests <- reason:
+	default:
+	}
+}
+
+// prepareReplication creates a publication and a replication slot for the database and sets the exported snapshot
+// to dump data as of the slot creation. The returned replication connection must be kept open until the dump finishes.
+func (d *DumpJob) prepareReplication(ctx context.Context, dbName string, definition *DumpDefinition) (*pgx.Conn, error) {
+	connStr := db.ConnectionString(d.config.db.Host, strconv.Itoa(d.config.db.Port), d.config.db.Username, dbName, d.getPassword())
+
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to the source")
+	}
+
+	defer func() { _ = conn.Close(ctx) }()
+
+	publicationName := replicationName(d.engineProps.InstanceID)
+	slotName := replicationSlotName(d.engineProps.InstanceID, dbName)
+
+	if err := createPublication(ctx, conn, publicationName, definition.Tables); err != nil {
+		return nil, err
+	}
+
+	// A slot left by a previous refresh holds WAL on the source and cannot be reused.
+	if _, err := conn.Exec(ctx,
+		"select pg_drop_replication_slot(slot_name) from pg_replication_slots where slot_name = $1 and not active", slotName); err != nil {
+		return nil, errors.Wrapf(err, "failed to drop the previous replication slot %q", slotName)
+	}
+
+	slotConn, err := pgx.Connect(ctx, connStr+" replication=database")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open a replication connection")
+	}
+
+	results, err := slotConn.PgConn().Exec(ctx,
+		fmt.Sprintf("CREATE_REPLICATION_SLOT %s LOGICAL pgoutput EXPORT_SNAPSHOT", pgx.Identifier{slotName}.Sanitize())).ReadAll()
+	if err != nil {
+		_ = slotConn.Close(ctx)
+		return nil, errors.Wrapf(err, "failed to create the replication slot %q", slotName)
+	}
+
+	if len(results) == 0 || len(results[0].Rows) == 0 || len(results[0].Rows[0]) <= replicationSlotSnapshotField {
+		_ = slotConn.Close(ctx)
+		return nil, errors.Errorf("unexpected output of the replication slot %q creation", slotName)
+	}
+
+	definition.snapshot = string(results[0].Rows[0][replicationSlotSnapshotField])
+
+	log.Msg(fmt.Sprintf("Replication slot %q has been created. Dump the database %s using the snapshot %s",
+		slotName, dbName, definition.snapshot))
+
+	return slotConn, nil
+}
+
+// createPublication creates the publication or updates its tables if it exists.
+func createPublication(ctx context.Context, conn *pgx.Conn, publicationName string, tablePatterns []string) error {
+	tables := []string{}
+
+	if len(tablePatterns) > 0 {
+		sourceTables, err := listSourceTables(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		tables = resolvePublicationTables(sourceTables, tablePatterns)
+		if len(tables) == 0 {
+			return errors.Errorf("no tables match the patterns of the publication %q: %s", publicationName, strings.Join(tablePatterns, ", "))
+		}
+	}
+
+	var allTables bool
+
+	err := conn.QueryRow(ctx, "select puballtables from pg_publication where pubname = $1", publicationName).Scan(&allTables)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return errors.Wrap(err, "failed to check publications")
+	}
+
+	publicationExists := err == nil
+
+	switch {
+	case publicationExists && allTables && len(tables) == 0:
+		log.Msg(fmt.Sprintf("Publication %q already exists", publicationName))
+		return nil
+
+	case publicationExists && !allTables && len(tables) > 0:
+		if _, err := conn.Exec(ctx, buildAlterPublicationQuery(publicationName, tables)); err != nil {
+			return errors.Wrapf(err, "failed to update tables of the publication %q", publicationName)
+		}
+
+		log.Msg(fmt.Sprintf("Tables of the publication %q have been updated", publicationName))
+
+		return nil
+
+	case publicationExists:
+		// A publication cannot be switched between all tables and a list of tables, so it is recreated.
+		if _, err := conn.Exec(ctx, "drop publication "+pgx.Identifier{publicationName}.Sanitize()); err != nil {
+			return errors.Wrapf(err, "failed to drop the publication %q", publicationName)
+		}
+	}
+
+	if _, err := conn.Exec(ctx, buildPublicationQuery(publicationName, tables)); err != nil {
+		return errors.Wrapf(err, "failed to create the publication %q", publicationName)
+	}
+
+	log.Msg(fmt.Sprintf("Publication %q has been created", publicationName))
+
+	return nil
+}
+
+// resolvePublicationTables returns sanitized identifiers of source tables matching the patterns.
+func resolvePublicationTables(sourceTables []sourceTable, tablePatterns []string) []string {
+	tables := []string{}
+
+	for _, table := range sourceTables {
+		if matchesAnyTable(tablePatterns, table.schema, table.name) {
+			tables = append(tables, table.String())
+		}
+	}
+
+	sort.Strings(tables)
+
+	return tables
+}
+
+// buildPublicationQuery builds a query creating a publication of all tables, or of the dumped tables for partial dumps.
+func buildPublicationQuery(publicationName string, tables []string) string {
+	query := "create publication " + pgx.Identifier{publicationName}.Sanitize()
+
+	if len(tables) == 0 {
+		return query + " for all tables"
+	}
+
+	return query + " for table " + strings.Join(tables, ", ")
+}
+
+// buildAlterPublicationQuery builds a query replacing tables of the publication.
+func buildAlterPublicationQuery(publicationName string, tables []string) string {
+	return "alter publication " + pgx.Identifier{publicationName}.Sanitize() + " set table " + strings.Join(tables, ", ")
+}
+
+// replicationName returns the name of publications and subscriptions of the instance.
+func replicationName(instanceID string) string {
+	return replicationPrefix + instanceID
+}
+
+// replicationSlotName returns the name of the replication slot for the database.
+// Slot names may contain only lower case letters, numbers, and the underscore character.
+// Long names are truncated and get a hash suffix of the full name to stay unique.
+func replicationSlotName(instanceID, dbName string) string {
+	slotName := replicationName(instanceID) + "_" + strings.ToLower(filenameFormatter.ReplaceAllString(dbName, "_"))
+
+	if len(slotName) > maxSlotNameLength {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(instanceID + "/" + dbName))
+		suffix := fmt.Sprintf("_%08x", hash.Sum32())
+
+		slotName = slotName[:maxSlotNameLength-len(suffix)] + suffix
+	}
+
+	return slotName
+}
+
+// quoteLiteral quotes a string to be used as an SQL literal.
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// quoteConnValue quotes a value of a connection string.
+func quoteConnValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value) + "'"
+}
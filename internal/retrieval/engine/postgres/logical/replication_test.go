@@ -0,0 +1,55 @@
+package logical
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicationSlotName(t *testing.T) {
+	assert.Equal(t, "dblab_c6nvq5ck2a9u8pcr2a4g_test_db", replicationSlotName("c6nvq5ck2a9u8pcr2a4g", "Test-DB"))
+
+	slotName := replicationSlotName("c6nvq5ck2a9u8pcr2a4g", strings.Repeat("database", 10))
+	assert.Len(t, slotName, maxSlotNameLength)
+
+	// Long names sharing the same prefix must not collide.
+	anotherSlotName := replicationSlotName("c6nvq5ck2a9u8pcr2a4g", strings.Repeat("database", 10)+"_2")
+	assert.Len(t, anotherSlotName, maxSlotNameLength)
+	assert.NotEqual(t, slotName, anotherSlotName)
+	assert.Equal(t, slotName, replicationSlotName("c6nvq5ck2a9u8pcr2a4g", strings.Repeat("database", 10)))
+}
+
+func TestResolvePublicationTables(t *testing.T) {
+	sourceTables := []sourceTable{
+		{schema: "public", name: "users"},
+		{schema: "public", name: "Orders"},
+		{schema: "public", name: "audit_2022"},
+		{schema: "billing", name: "invoices"},
+	}
+
+	assert.Equal(t, []string{`"billing"."invoices"`, `"public"."Orders"`, `"public"."users"`},
+		resolvePublicationTables(sourceTables, []string{"users", `public."Orders"`, "billing.*"}))
+	assert.Empty(t, resolvePublicationTables(sourceTables, []string{"public.missing"}))
+}
+
+func TestBuildPublicationQuery(t *testing.T) {
+	assert.Equal(t, `create publication "dblab_test" for all tables`, buildPublicationQuery("dblab_test", nil))
+	assert.Equal(t, `create publication "dblab_test" for table "public"."users", "public"."orders"`,
+		buildPublicationQuery("dblab_test", []string{`"public"."users"`, `"public"."orders"`}))
+	assert.Equal(t, `alter publication "dblab_test" set table "public"."users"`,
+		buildAlterPublicationQuery("dblab_test", []string{`"public"."users"`}))
+}
+
+func TestSubscriptionConnInfo(t *testing.T) {
+	job := ReplicationJob{
+		ReplicationOptions: ReplicationOptions{
+			Connection: Connection{Host: "source.example.com", Port: 5432, Username: "replicator", Password: `pa'ss\word`},
+		},
+	}
+
+	connInfo := job.subscriptionConnInfo("test")
+
+	assert.Equal(t, `host='source.example.com' port=5432 user='replicator' dbname='test' password='pa\'ss\\word'`, connInfo)
+	assert.Equal(t, `'host=''example'''`, quoteLiteral(`host='example'`))
+}
@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
@@ -31,11 +32,14 @@ import (
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/cont"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/defaults"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/fs"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/health"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/objstore"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/options"
 
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/config/global"
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/util"
 )
 
@@ -52,6 +56,12 @@ const (
 	// dumpMetafile defines metafile name of a directory dump.
 	dumpMetafile = "toc.dat"
 
+	// stdinDumpLocation defines the location of a dump streamed to stdin of restore commands.
+	stdinDumpLocation = "/dev/stdin"
+
+	// dumpLocationEnv passes the dump location to shell commands, so the path is not interpolated into them.
+	dumpLocationEnv = "DBLAB_DUMP_LOCATION"
+
 	// prefixDBName defines a prefix of database name inside of a custom dump metafile.
 	prefixDBName = "dbname:"
 
@@ -89,6 +99,12 @@ type RestoreJob struct {
 	dbMarker          *dbmarker.Marker
 	dbMark            *dbmarker.Config
 	isDumpLocationDir bool
+	progress          *progressTracker
+	storage           *objstore.Storage
+	downloadDir       string
+	// streamedDumps maps names of single-file dumps kept in the object storage to their keys.
+	streamedDumps          map[string]string
+	streamedDumpsCreatedAt time.Time
 	RestoreOptions
 }
 
@@ -101,6 +117,9 @@ type RestoreOptions struct {
 	ForceInit       bool                      `yaml:"forceInit"`
 	ParallelJobs    int                       `yaml:"parallelJobs"`
 	Configs         map[string]string         `yaml:"configs"`
+
+	// ObjectStorage defines an object storage to download dumps from. Dumps are downloaded to the dump location.
+	ObjectStorage *RestoreStorage `yaml:"objectStorage"`
 }
 
 // Partial defines tables and rules for a partial logical restore.
@@ -118,6 +137,7 @@ func NewJob(cfg config.JobConfig, global *global.Config, engineProps global.Engi
 		engineProps:  engineProps,
 		dbMarker:     cfg.Marker,
 		dbMark:       &dbmarker.Config{DataType: dbmarker.LogicalDataType},
+		progress:     newProgressTracker(cfg.Spec.Name),
 	}
 
 	if err := restoreJob.Reload(cfg.Spec.Options); err != nil {
@@ -145,14 +165,57 @@ func (r *RestoreJob) Name() string {
 	return r.name
 }
 
+// DataSize returns the size of restored data.
+func (r *RestoreJob) DataSize() (uint64, error) {
+	return fs.DirSize(r.fsPool.DataDir())
+}
+
+// Progress returns progress of restoring databases.
+func (r *RestoreJob) Progress() []models.DatabaseProgress {
+	return r.progress.Progress()
+}
+
 // Reload reloads job configuration.
 func (r *RestoreJob) Reload(cfg map[string]interface{}) (err error) {
 	if err := options.Unmarshal(cfg, &r.RestoreOptions); err != nil {
 		return errors.Wrap(err, "failed to unmarshal configuration options")
 	}
 
+	for dbName, definition := range r.Databases {
+		if err := validateRestoreFilters(dbName, definition); err != nil {
+			return err
+		}
+
+		if definition.Subset != nil {
+			return errors.Errorf("subset of the database %s can be extracted only by the logicalDump job", dbName)
+		}
+	}
+
 	r.setDefaults()
 
+	r.storage = nil
+
+	if r.ObjectStorage != nil {
+		if r.storage, err = objstore.New(r.ObjectStorage.Config); err != nil {
+			return errors.Wrap(err, "failed to set up the object storage")
+		}
+
+		if r.ObjectStorage.DumpDate != "" {
+			if _, err := parseDumpDate(r.ObjectStorage.DumpDate); err != nil {
+				return err
+			}
+		}
+
+		if err := os.MkdirAll(r.RestoreOptions.DumpLocation, 0755); err != nil {
+			return errors.Wrap(err, "failed to create a download directory")
+		}
+
+		// Downloaded dumps are always kept in a directory.
+		r.isDumpLocationDir = true
+
+		return nil
+	}
+
 	stat, err := os.Stat(r.RestoreOptions.DumpLocation)
 	if err != nil {
 		return errors.Wrap(err, "dumpLocation not found")
@@ -167,6 +230,8 @@ func (r *RestoreJob) Reload(cfg map[string]interface{}) (err error) {
 func (r *RestoreJob) Run(ctx context.Context) (err error) {
 	log.Msg("Run job: ", r.Name())
 
+	r.progress.reset()
+
 	isEmpty, err := tools.IsEmptyDirectory(r.fsPool.DataDir())
 	if err != nil {
 		return errors.Wrapf(err, "failed to explore the data directory %q", r.fsPool.DataDir())
@@ -181,6 +246,24 @@ func (r *RestoreJob) Run(ctx context.Context) (err error) {
 		log.Msg(fmt.Sprintf("The data directory %q is not empty. Existing data may be overwritten.", r.fsPool.DataDir()))
 	}
 
+	if r.storage != nil {
+		downloadDir, err := r.prepareDump(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to prepare the dump from the object storage")
+		}
+
+		r.downloadDir = downloadDir
+
+		defer func() {
+			r.downloadDir = ""
+			r.streamedDumps = nil
+
+			if err := os.RemoveAll(downloadDir); err != nil {
+				log.Err("Failed to remove the downloaded dump:", err)
+			}
+		}()
+	}
+
 	if err := tools.PullImage(ctx, r.dockerClient, r.RestoreOptions.DockerImage); err != nil {
 		return errors.Wrap(err, "failed to scan image pulling response")
 	}
@@ -280,7 +363,7 @@ func (r *RestoreJob) getDBList(ctx context.Context, contID string) (map[string]D
 	}
 
 	if !r.isDumpLocationDir {
-		dbDefinition, err := r.exploreDumpFile(ctx, contID, r.RestoreOptions.DumpLocation)
+		dbDefinition, err := r.exploreDumpFile(ctx, contID, filepath.Base(r.getDumpRoot()), r.getDumpRoot())
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to find a database to restore in dump files")
 		}
@@ -290,17 +373,17 @@ func (r *RestoreJob) getDBList(ctx context.Context, contID string) (map[string]D
 		}
 
 		return map[string]DumpDefinition{
-			filepath.Base(r.RestoreOptions.DumpLocation): *dbDefinition,
+			filepath.Base(r.getDumpRoot()): *dbDefinition,
 		}, nil
 	}
 
 	return r.discoverDumpLocation(ctx, contID)
 }
 
-// discoverDumpLocation explores dump file to identify its type.
-func (r *RestoreJob) exploreDumpFile(ctx context.Context, contID, dumpPath string) (*DumpDefinition, error) {
+// exploreDumpFile explores dump file to identify its type.
+func (r *RestoreJob) exploreDumpFile(ctx context.Context, contID, dumpName, dumpPath string) (*DumpDefinition, error) {
 	// Detect if dump is custom.
-	dbName, err := r.extractDBNameFromDump(ctx, contID, dumpPath)
+	dbName, err := r.extractDBNameFromDump(ctx, contID, dumpName, dumpPath)
 	if err != nil {
 		if !errors.Is(err, errInvalidDump) {
 			return nil, err
@@ -319,7 +402,7 @@ func (r *RestoreJob) exploreDumpFile(ctx context.Context, contID, dumpPath strin
 	// Identify type of compression if plain-text dump is archived.
 	dbDefinition := &DumpDefinition{
 		Format:      plainFormat,
-		Compression: getCompressionType(dumpPath),
+		Compression: getCompressionType(dumpName),
 	}
 
 	if dbDefinition.Compression != noCompression {
@@ -327,7 +410,7 @@ func (r *RestoreJob) exploreDumpFile(ctx context.Context, contID, dumpPath strin
 	}
 
 	// Extract database name from plain-text dump.
-	dbName, err = r.parsePlainFile(dumpPath)
+	dbName, err = r.parsePlainFile(ctx, dumpName, dumpPath)
 	if err != nil {
 		if errors.Is(err, errDBNameNotFound) {
 			return dbDefinition, nil
@@ -342,12 +425,13 @@ func (r *RestoreJob) exploreDumpFile(ctx context.Context, contID, dumpPath strin
 }
 
 // extractDBNameFromDump discovers dump to extract the database name.
-func (r *RestoreJob) extractDBNameFromDump(ctx context.Context, contID, dumpPath string) (string, error) {
-	extractDBNameCmd := fmt.Sprintf("pg_restore --list %s | grep %s | tr -d '[;]'", dumpPath, prefixDBName)
-	log.Msg("Extract database name: ", extractDBNameCmd)
+func (r *RestoreJob) extractDBNameFromDump(ctx context.Context, contID, dumpName, dumpPath string) (string, error) {
+	extractDBNameCmd := fmt.Sprintf(`pg_restore --list "$%s" | grep %s | tr -d '[;]'`, dumpLocationEnv, prefixDBName)
+	log.Msg(fmt.Sprintf("Extract database name from %s: %s", dumpPath, extractDBNameCmd))
 
-	outputLine, err := tools.ExecCommandWithOutput(ctx, r.dockerClient, contID, types.ExecConfig{
+	outputLine, err := r.execDumpCommandWithOutput(ctx, contID, dumpName, types.ExecConfig{
 		Cmd: []string{"bash", "-c", extractDBNameCmd},
+		Env: []string{dumpLocationEnv + "=" + dumpPath},
 	})
 	if err != nil {
 		return "", errInvalidDump
@@ -362,10 +446,23 @@ func (r *RestoreJob) extractDBNameFromDump(ctx context.Context, contID, dumpPath
 	return dbName, nil
 }
 
-func (r *RestoreJob) parsePlainFile(dumpPath string) (string, error) {
-	f, err := os.Open(dumpPath)
-	if err != nil {
-		return "", errors.Wrap(err, "failed to open dump file")
+func (r *RestoreJob) parsePlainFile(ctx context.Context, dumpName, dumpPath string) (string, error) {
+	var f io.ReadCloser
+
+	if key, ok := r.streamedDumps[dumpName]; ok {
+		body, err := r.storage.Open(ctx, key)
+		if err != nil {
+			return "", err
+		}
+
+		f = body
+	} else {
+		file, err := os.Open(dumpPath)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to open dump file")
+		}
+
+		f = file
 	}
 
 	defer func() { _ = f.Close() }()
@@ -394,7 +491,7 @@ func (r *RestoreJob) parsePlainFile(dumpPath string) (string, error) {
 		}
 	}
 
-	return "", errors.Errorf("unknown format of the dump file: %v", dumpPath)
+	return "", errors.Errorf("unknown format of the dump file: %v", dumpName)
 }
 
 // discoverDumpLocation discovers dump location to find databases ready to restore.
@@ -402,15 +499,15 @@ func (r *RestoreJob) discoverDumpLocation(ctx context.Context, contID string) (m
 	dbList := make(map[string]DumpDefinition)
 
 	// Check the dumpLocation directory.
-	if dumpDefinition, err := r.getDirectoryDumpDefinition(ctx, contID, r.RestoreOptions.DumpLocation); err == nil {
+	if dumpDefinition, err := r.getDirectoryDumpDefinition(ctx, contID, r.getDumpRoot()); err == nil {
 		dbList[""] = dumpDefinition // empty string because of the root directory.
 
 		return dbList, nil
 	}
 
-	log.Msg(fmt.Sprintf("Directory dump not found in %q", r.RestoreOptions.DumpLocation))
+	log.Msg(fmt.Sprintf("Directory dump not found in %q", r.getDumpRoot()))
 
-	fileInfos, err := os.ReadDir(r.RestoreOptions.DumpLocation)
+	fileInfos, err := os.ReadDir(r.getDumpRoot())
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to discover dump location")
 	}
@@ -419,7 +516,7 @@ func (r *RestoreJob) discoverDumpLocation(ctx context.Context, contID string) (m
 		log.Dbg("Explore: ", info.Name())
 
 		if info.IsDir() {
-			dumpDirectory := path.Join(r.RestoreOptions.DumpLocation, info.Name())
+			dumpDirectory := path.Join(r.getDumpRoot(), info.Name())
 
 			dumpDefinition, err := r.getDirectoryDumpDefinition(ctx, contID, dumpDirectory)
 			if err != nil {
@@ -434,23 +531,32 @@ func (r *RestoreJob) discoverDumpLocation(ctx context.Context, contID string) (m
 			continue
 		}
 
-		dumpDefinition, err := r.exploreDumpFile(ctx, contID, path.Join(r.RestoreOptions.DumpLocation, info.Name()))
-		if err != nil {
-			log.Dbg(fmt.Sprintf("Skip file %q due to failure to find a database to restore: %v", info.Name(), err))
-			continue
-		}
+		r.addDumpFile(ctx, contID, dbList, info.Name(), path.Join(r.getDumpRoot(), info.Name()))
+	}
 
-		if dumpDefinition == nil {
-			log.Dbg(fmt.Sprintf("Skip file %q because the database definition is empty", info.Name()))
-			continue
-		}
+	for dumpName := range r.streamedDumps {
+		r.addDumpFile(ctx, contID, dbList, dumpName, stdinDumpLocation)
+	}
 
-		dbList[info.Name()] = *dumpDefinition
+	return dbList, nil
+}
 
-		log.Msg(fmt.Sprintf("Found the %s dump file: %s", dumpDefinition.Format, info.Name()))
+// addDumpFile adds the dump file to the list of databases to restore if the file contains a database.
+func (r *RestoreJob) addDumpFile(ctx context.Context, contID string, dbList map[string]DumpDefinition, dumpName, dumpPath string) {
+	dumpDefinition, err := r.exploreDumpFile(ctx, contID, dumpName, dumpPath)
+	if err != nil {
+		log.Dbg(fmt.Sprintf("Skip file %q due to failure to find a database to restore: %v", dumpName, err))
+		return
 	}
 
-	return dbList, nil
+	if dumpDefinition == nil {
+		log.Dbg(fmt.Sprintf("Skip file %q because the database definition is empty", dumpName))
+		return
+	}
+
+	dbList[dumpName] = *dumpDefinition
+
+	log.Msg(fmt.Sprintf("Found the %s dump file: %s", dumpDefinition.Format, dumpName))
 }
 
 func (r *RestoreJob) getDirectoryDumpDefinition(ctx context.Context, contID, dumpDir string) (DumpDefinition, error) {
@@ -463,7 +569,7 @@ func (r *RestoreJob) getDirectoryDumpDefinition(ctx context.Context, contID, dum
 
 	log.Msg(fmt.Sprintf("TOC file has been found: %q", dumpMetafilePath))
 
-	dbName, err := r.extractDBNameFromDump(ctx, contID, dumpDir)
+	dbName, err := r.extractDBNameFromDump(ctx, contID, filepath.Base(dumpDir), dumpDir)
 	if err != nil {
 		log.Err("Invalid dump: ", err)
 		return DumpDefinition{}, errors.Wrap(err, "invalid database name")
@@ -483,21 +589,39 @@ func (r *RestoreJob) restoreDB(ctx context.Context, contID, dbName string, dbDef
 		}
 	}
 
+	droppedTables := []string{}
+
+	if dbDefinition.Format != plainFormat && dbDefinition.hasExclusions() {
+		tocList, tables, err := r.prepareTOC(ctx, contID, dbName, dbDefinition)
+		if err != nil {
+			return errors.Wrap(err, "failed to filter the table of contents")
+		}
+
+		dbDefinition.tocList = tocList
+		droppedTables = tables
+	}
+
 	restoreCommand := r.buildLogicalRestoreCommand(dbName, dbDefinition)
 	log.Msg("Running restore command for "+dbName, restoreCommand)
 
-	output, err := tools.ExecCommandWithOutput(ctx, r.dockerClient, contID, types.ExecConfig{
-		Tty: true, Cmd: restoreCommand,
+	dbProgress := r.progress.start(dbName, r.countTables(ctx, contID, dbName, dbDefinition), "")
+
+	err := r.execDumpCommand(ctx, contID, dbName, types.ExecConfig{Cmd: restoreCommand}, nil, func(line string) {
+		r.progress.handleLine(dbProgress, line)
 	})
 
-	if output != "" {
-		log.Dbg("Output of the restore command: ", output)
-	}
+	r.progress.finish(dbProgress, err)
 
 	if err != nil {
 		return errors.Wrap(err, "failed to exec restore command")
 	}
 
+	if dbDefinition.Format != plainFormat {
+		if err := r.applyFilters(ctx, contID, dbName, dbDefinition, droppedTables); err != nil {
+			return errors.Wrap(err, "failed to apply filters of the partial restore")
+		}
+	}
+
 	if err := r.defineDSA(ctx, dbDefinition, contID, dbName); err != nil {
 		log.Err("Failed to define DataStateAt: ", err)
 	}
@@ -509,6 +633,37 @@ func (r *RestoreJob) restoreDB(ctx context.Context, contID, dbName string, dbDef
 	return nil
 }
 
+// countTables returns the number of tables to restore using the table of contents of the dump.
+// It returns zero if the number is unknown, for example, for plain-text dumps.
+func (r *RestoreJob) countTables(ctx context.Context, contID, dbName string, dbDefinition DumpDefinition) int {
+	if dbDefinition.Format == plainFormat {
+		return 0
+	}
+
+	if len(dbDefinition.Tables) > 0 {
+		return len(dbDefinition.Tables)
+	}
+
+	countCmd := fmt.Sprintf(`pg_restore --list "$%s" | grep -c '%s' || true`, dumpLocationEnv, tableDataItemMarker)
+
+	output, err := r.execDumpCommandWithOutput(ctx, contID, dbName, types.ExecConfig{
+		Cmd: []string{"sh", "-c", countCmd},
+		Env: []string{dumpLocationEnv + "=" + r.getDumpLocation(dbDefinition.Format, dbName)},
+	})
+	if err != nil {
+		log.Err("Failed to count tables in the table of contents:", err)
+		return 0
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		log.Err("Failed to parse the number of tables in the table of contents:", err)
+		return 0
+	}
+
+	return count
+}
+
 // prepareDB creates a new database if it does not exist in the dump file.
 func (r *RestoreJob) prepareDB(ctx context.Context, contID, dbName string) error {
 	log.Dbg("The dump has a plain-text format with an empty database name. Creating a database for the dump:", dbName)
@@ -608,6 +763,12 @@ func (r *RestoreJob) defineDSA(ctx context.Context, dbDefinition DumpDefinition,
 		return nil
 	}
 
+	if r.isStreamedDump(dbName) {
+		// Reading the dump once more only to get its creation time is expensive, so the time of the dump generation is used.
+		r.dbMark.DataStateAt = r.streamedDumpsCreatedAt.Format(util.DataStateAtFormat)
+		return nil
+	}
+
 	dumpLocation := r.getDumpLocation(dbDefinition.Format, dbName)
 
 	dataStateAt, err := r.retrieveDataStateAt(ctx, contID, dumpLocation)
@@ -693,7 +854,7 @@ func (r *RestoreJob) buildPlainTextCommand(dumpName string, definition DumpDefin
 		dbName = formatDBName(dumpName)
 	}
 
-	if len(definition.Tables) > 0 {
+	if definition.hasFilters() {
 		log.Msg("Partial restore is not available for plain-text dump")
 	}
 
@@ -709,7 +870,7 @@ func (r *RestoreJob) buildPlainTextCommand(dumpName string, definition DumpDefin
 
 func (r *RestoreJob) buildPGRestoreCommand(dumpName string, definition DumpDefinition) []string {
 	restoreCmd := []string{"pg_restore", "--username", r.globalCfg.Database.User(), "--dbname", defaults.DBName,
-		"--no-privileges", "--no-owner", "--exit-on-error"}
+		"--no-privileges", "--no-owner", "--exit-on-error", "--verbose"}
 
 	if definition.dbName != defaults.DBName {
 		// To avoid recreating of the default database.
@@ -720,7 +881,15 @@ func (r *RestoreJob) buildPGRestoreCommand(dumpName string, definition DumpDefin
 		restoreCmd = append(restoreCmd, "--clean", "--if-exists")
 	}
 
-	restoreCmd = append(restoreCmd, "--jobs", strconv.Itoa(r.ParallelJobs))
+	parallelJobs := r.ParallelJobs
+
+	if r.isStreamedDump(dumpName) && parallelJobs > 1 {
+		log.Msg("Parallel restore is not available for dumps streamed from the object storage. It is always single-threaded")
+
+		parallelJobs = 1
+	}
+
+	restoreCmd = append(restoreCmd, "--jobs", strconv.Itoa(parallelJobs))
 
 	if len(definition.Tables) > 0 {
 		log.Msg("Partial restore will be run. Tables for restoring: ", strings.Join(definition.Tables, ", "))
@@ -730,21 +899,46 @@ func (r *RestoreJob) buildPGRestoreCommand(dumpName string, definition DumpDefin
 		}
 	}
 
+	for _, schema := range definition.Schemas {
+		restoreCmd = append(restoreCmd, "--schema", schema)
+	}
+
+	for _, schema := range definition.ExcludeSchemas {
+		restoreCmd = append(restoreCmd, "--exclude-schema", schema)
+	}
+
+	if definition.tocList != "" {
+		restoreCmd = append(restoreCmd, "--use-list", definition.tocList)
+	}
+
 	restoreCmd = append(restoreCmd, r.getDumpLocation(definition.Format, dumpName))
 
 	return restoreCmd
 }
 
+// getDumpRoot returns the location of dumps to restore. It's the directory of a downloaded dump if the object storage is used.
+func (r *RestoreJob) getDumpRoot() string {
+	if r.downloadDir != "" {
+		return r.downloadDir
+	}
+
+	return r.RestoreOptions.DumpLocation
+}
+
 func (r *RestoreJob) getDumpLocation(dumpFormat, dbName string) string {
 	switch dumpFormat {
 	case customFormat, plainFormat:
+		if r.isStreamedDump(dbName) {
+			return stdinDumpLocation
+		}
+
 		if r.isDumpLocationDir {
-			return path.Join(r.RestoreOptions.DumpLocation, dbName)
+			return path.Join(r.getDumpRoot(), dbName)
 		}
 
-		return r.RestoreOptions.DumpLocation
+		return r.getDumpRoot()
 
 	default:
-		return path.Join(r.RestoreOptions.DumpLocation, dbName)
+		return path.Join(r.getDumpRoot(), dbName)
 	}
 }
@@ -42,14 +42,14 @@ func TestRestoreCommandBuilding(t *testing.T) {
 				},
 				DumpLocation: "/tmp/db.dump",
 			},
-			command: []string{"pg_restore", "--username", "john", "--dbname", "postgres", "--no-privileges", "--no-owner", "--exit-on-error", "--create", "--jobs", "1", "/tmp/db.dump"},
+			command: []string{"pg_restore", "--username", "john", "--dbname", "postgres", "--no-privileges", "--no-owner", "--exit-on-error", "--verbose", "--create", "--jobs", "1", "/tmp/db.dump"},
 		},
 		{
 			copyOptions: RestoreOptions{
 				ParallelJobs: 4,
 				ForceInit:    true,
 			},
-			command: []string{"pg_restore", "--username", "john", "--dbname", "postgres", "--no-privileges", "--no-owner", "--exit-on-error", "--create", "--clean", "--if-exists", "--jobs", "4", ""},
+			command: []string{"pg_restore", "--username", "john", "--dbname", "postgres", "--no-privileges", "--no-owner", "--exit-on-error", "--verbose", "--create", "--clean", "--if-exists", "--jobs", "4", ""},
 		},
 		{
 			copyOptions: RestoreOptions{
@@ -58,7 +58,7 @@ func TestRestoreCommandBuilding(t *testing.T) {
 				Databases:    map[string]DumpDefinition{"testDB": {}},
 				DumpLocation: "/tmp/db.dump",
 			},
-			command: []string{"pg_restore", "--username", "john", "--dbname", "postgres", "--no-privileges", "--no-owner", "--exit-on-error", "--create", "--jobs", "2", "/tmp/db.dump/testDB"},
+			command: []string{"pg_restore", "--username", "john", "--dbname", "postgres", "--no-privileges", "--no-owner", "--exit-on-error", "--verbose", "--create", "--jobs", "2", "/tmp/db.dump/testDB"},
 		},
 		{
 			copyOptions: RestoreOptions{
@@ -71,7 +71,22 @@ func TestRestoreCommandBuilding(t *testing.T) {
 				},
 				DumpLocation: "/tmp/db.dump",
 			},
-			command: []string{"pg_restore", "--username", "john", "--dbname", "postgres", "--no-privileges", "--no-owner", "--exit-on-error", "--create", "--jobs", "1", "--table", "test", "--table", "users", "/tmp/db.dump/testDB"},
+			command: []string{"pg_restore", "--username", "john", "--dbname", "postgres", "--no-privileges", "--no-owner", "--exit-on-error", "--verbose", "--create", "--jobs", "1", "--table", "test", "--table", "users", "/tmp/db.dump/testDB"},
+		},
+		{
+			copyOptions: RestoreOptions{
+				ParallelJobs: 1,
+				Databases: map[string]DumpDefinition{
+					"testDB": {
+						Schemas:        []string{"public"},
+						ExcludeSchemas: []string{"archive"},
+						Format:         directoryFormat,
+						tocList:        "/tmp/toc_testDB.list",
+					},
+				},
+				DumpLocation: "/tmp/db.dump",
+			},
+			command: []string{"pg_restore", "--username", "john", "--dbname", "postgres", "--no-privileges", "--no-owner", "--exit-on-error", "--verbose", "--create", "--jobs", "1", "--schema", "public", "--exclude-schema", "archive", "--use-list", "/tmp/toc_testDB.list", "/tmp/db.dump/testDB"},
 		},
 		{
 			copyOptions: RestoreOptions{
@@ -200,6 +215,26 @@ func TestDumpLocation(t *testing.T) {
 	}
 }
 
+func TestStreamedDumpRestoreCommand(t *testing.T) {
+	r := &RestoreJob{
+		globalCfg:     &global.Config{Database: global.Database{Username: "john", DBName: "testdb"}},
+		streamedDumps: map[string]string{"testdb": "20220601020000/testdb"},
+	}
+	r.RestoreOptions.DumpLocation = "/tmp/dblab_test"
+	r.RestoreOptions.ParallelJobs = 4
+	r.isDumpLocationDir = true
+
+	assert.Equal(t, stdinDumpLocation, r.getDumpLocation(customFormat, "testdb"))
+	assert.Equal(t, "/tmp/dblab_test/otherdb", r.getDumpLocation(customFormat, "otherdb"))
+
+	assert.Equal(t, []string{"pg_restore", "--username", "john", "--dbname", "postgres", "--no-privileges", "--no-owner",
+		"--exit-on-error", "--verbose", "--create", "--jobs", "1", "/dev/stdin"},
+		r.buildLogicalRestoreCommand("testdb", DumpDefinition{Format: customFormat, dbName: "testdb"}))
+
+	assert.Equal(t, []string{"sh", "-c", "cat /dev/stdin | psql --username john --dbname postgres"},
+		r.buildLogicalRestoreCommand("testdb", DumpDefinition{Format: plainFormat, dbName: "testdb"}))
+}
+
 const (
 	contentPlain = `
 -- PostgreSQL database dump
@@ -305,7 +340,7 @@ func TestParsingPlainFile(t *testing.T) {
 
 		r := &RestoreJob{}
 
-		dbName, err := r.parsePlainFile(f.Name())
+		dbName, err := r.parsePlainFile(context.Background(), path.Base(f.Name()), f.Name())
 		assert.Equal(t, tc.err, err)
 		assert.Equal(t, tc.dbname, dbName)
 	}
@@ -341,7 +376,7 @@ func TestParsingInvalidPlainFile(t *testing.T) {
 
 		r := &RestoreJob{}
 
-		dbName, err := r.parsePlainFile(f.Name())
+		dbName, err := r.parsePlainFile(context.Background(), path.Base(f.Name()), f.Name())
 		assert.Error(t, err)
 		assert.Equal(t, tc.dbname, dbName)
 	}
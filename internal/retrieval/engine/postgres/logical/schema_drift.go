@@ -0,0 +1,225 @@
+/*
+2022 © Postgres.ai
+*/
+
+package logical
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+)
+
+const (
+	// columnSeparator separates fields of psql output. It cannot be a part of object names.
+	columnSeparator = "\x1f"
+
+	columnDefinitionFields = 4
+
+	// columnsQuery lists columns of user tables.
+	columnsQuery = `select n.nspname, c.relname, a.attname, format_type(a.atttypid, a.atttypmod)
+from pg_attribute a
+join pg_class c on c.oid = a.attrelid
+join pg_namespace n on n.oid = c.relnamespace
+where c.relkind in ('r', 'p') and a.attnum > 0 and not a.attisdropped
+  and n.nspname not in ('pg_catalog', 'information_schema') and n.nspname !~ '^pg_toast'`
+)
+
+// tableColumns maps qualified table names to column types by column names.
+type tableColumns map[string]map[string]string
+
+func (tc tableColumns) add(schemaName, tableName, columnName, columnType string) {
+	table := schemaName + "." + tableName
+
+	if _, ok := tc[table]; !ok {
+		tc[table] = make(map[string]string)
+	}
+
+	tc[table][columnName] = columnType
+}
+
+// watchSchemaDrift periodically compares the schema of replicated databases with the source.
+// The logical replication does not replicate DDL, so a full refresh is requested once the schema diverges.
+func (j *ReplicationJob) watchSchemaDrift(ctx context.Context, contID string, dbList []string) {
+	ticker := time.NewTicker(j.driftCheckInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		for _, dbName := range dbList {
+			drift, err := j.checkSchemaDrift(ctx, contID, dbName)
+			if err != nil {
+				log.Err("Failed to check the schema drift of the database", dbName, err)
+				continue
+			}
+
+			if len(drift) == 0 {
+				continue
+			}
+
+			reason := fmt.Sprintf("schema of the database %s diverged from the source: %s", dbName, strings.Join(drift, "; "))
+			log.Warn("Replicated data require a full refresh:", reason)
+
+			j.requestRefresh(reason)
+
+			return
+		}
+	}
+}
+
+func (j *ReplicationJob) driftCheckInterval() time.Duration {
+	if j.DriftCheckInterval == 0 {
+		return defaultDriftCheckInterval
+	}
+
+	return time.Duration(j.DriftCheckInterval) * time.Second
+}
+
+// checkSchemaDrift lists differences of published tables between the source and the sync instance.
+func (j *ReplicationJob) checkSchemaDrift(ctx context.Context, contID, dbName string) ([]string, error) {
+	conn, err := j.connectSource(ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = conn.Close(ctx) }()
+
+	rows, err := conn.Query(ctx, "select schemaname || '.' || tablename from pg_publication_tables where pubname = $1",
+		replicationName(j.engineProps.InstanceID))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list published tables")
+	}
+
+	published := []string{}
+
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, errors.Wrap(err, "failed to scan published tables")
+		}
+
+		published = append(published, table)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to list published tables")
+	}
+
+	sourceColumns := make(tableColumns)
+
+	rows, err = conn.Query(ctx, columnsQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list columns of the source")
+	}
+
+	for rows.Next() {
+		var schemaName, tableName, columnName, columnType string
+		if err := rows.Scan(&schemaName, &tableName, &columnName, &columnType); err != nil {
+			return nil, errors.Wrap(err, "failed to scan columns of the source")
+		}
+
+		sourceColumns.add(schemaName, tableName, columnName, columnType)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to list columns of the source")
+	}
+
+	output, err := j.queryInstance(ctx, contID, dbName, columnsQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list columns of the sync instance")
+	}
+
+	replicaColumns, err := parseColumns(output)
+	if err != nil {
+		return nil, err
+	}
+
+	return schemaDrift(published, sourceColumns, replicaColumns), nil
+}
+
+// parseColumns parses psql output of the columns query.
+func parseColumns(output string) (tableColumns, error) {
+	columns := make(tableColumns)
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Split(line, columnSeparator)
+		if len(fields) != columnDefinitionFields {
+			return nil, errors.Errorf("unexpected column definition: %q", line)
+		}
+
+		columns.add(fields[0], fields[1], fields[2], fields[3])
+	}
+
+	return columns, nil
+}
+
+// schemaDrift describes differences of the published tables between the source and the replica.
+func schemaDrift(published []string, source, replica tableColumns) []string {
+	drift := []string{}
+
+	sort.Strings(published)
+
+	for _, table := range published {
+		replicaTable, ok := replica[table]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("table %s is missing", table))
+			continue
+		}
+
+		sourceTable := source[table]
+
+		for _, column := range sortedColumns(sourceTable, replicaTable) {
+			sourceType, inSource := sourceTable[column]
+			replicaType, inReplica := replicaTable[column]
+
+			switch {
+			case !inReplica:
+				drift = append(drift, fmt.Sprintf("column %s.%s is missing", table, column))
+
+			case !inSource:
+				drift = append(drift, fmt.Sprintf("column %s.%s has been dropped on the source", table, column))
+
+			case sourceType != replicaType:
+				drift = append(drift, fmt.Sprintf("column %s.%s has type %s instead of %s", table, column, replicaType, sourceType))
+			}
+		}
+	}
+
+	return drift
+}
+
+func sortedColumns(tables ...map[string]string) []string {
+	columnSet := make(map[string]struct{})
+
+	for _, table := range tables {
+		for column := range table {
+			columnSet[column] = struct{}{}
+		}
+	}
+
+	columns := make([]string, 0, len(columnSet))
+
+	for column := range columnSet {
+		columns = append(columns, column)
+	}
+
+	sort.Strings(columns)
+
+	return columns
+}
@@ -0,0 +1,50 @@
+package logical
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColumns(t *testing.T) {
+	output := "public\x1fusers\x1fid\x1fbigint\npublic\x1fusers\x1fname\x1ftext\n\n"
+
+	columns, err := parseColumns(output)
+	require.NoError(t, err)
+	assert.Equal(t, tableColumns{"public.users": {"id": "bigint", "name": "text"}}, columns)
+
+	_, err = parseColumns("public|users|id|bigint")
+	assert.Error(t, err)
+}
+
+func TestSchemaDrift(t *testing.T) {
+	source := tableColumns{
+		"public.users":  {"id": "bigint", "email": "text", "age": "integer"},
+		"public.orders": {"id": "bigint"},
+		"public.logs":   {"id": "bigint"},
+	}
+
+	t.Run("no drift", func(t *testing.T) {
+		replica := tableColumns{
+			"public.users":  {"id": "bigint", "email": "text", "age": "integer"},
+			"public.orders": {"id": "bigint"},
+		}
+
+		assert.Empty(t, schemaDrift([]string{"public.users", "public.orders"}, source, replica))
+	})
+
+	t.Run("diverged schema", func(t *testing.T) {
+		replica := tableColumns{
+			"public.users":  {"id": "bigint", "age": "smallint", "name": "text"},
+			"public.orders": {"id": "bigint"},
+		}
+
+		assert.Equal(t, []string{
+			"table public.logs is missing",
+			"column public.users.age has type smallint instead of integer",
+			"column public.users.email is missing",
+			"column public.users.name has been dropped on the source",
+		}, schemaDrift([]string{"public.users", "public.orders", "public.logs"}, source, replica))
+	})
+}
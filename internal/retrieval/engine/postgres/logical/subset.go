@@ -0,0 +1,569 @@
+/*
+2022 © Postgres.ai
+*/
+
+package logical
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/db"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+)
+
+const (
+	defaultSubsetDepth = 1
+
+	// foreignKeysQuery lists foreign keys between regular and partitioned tables with columns in the order of the key.
+	foreignKeysQuery = `select cn.nspname, cc.relname, pn.nspname, pc.relname,
+  array(select a.attname::text from unnest(c.conkey) with ordinality k(attnum, n)
+    join pg_attribute a on a.attrelid = c.conrelid and a.attnum = k.attnum order by k.n),
+  array(select format_type(a.atttypid, a.atttypmod) from unnest(c.conkey) with ordinality k(attnum, n)
+    join pg_attribute a on a.attrelid = c.conrelid and a.attnum = k.attnum order by k.n),
+  array(select a.attname::text from unnest(c.confkey) with ordinality k(attnum, n)
+    join pg_attribute a on a.attrelid = c.confrelid and a.attnum = k.attnum order by k.n),
+  array(select format_type(a.atttypid, a.atttypmod) from unnest(c.confkey) with ordinality k(attnum, n)
+    join pg_attribute a on a.attrelid = c.confrelid and a.attnum = k.attnum order by k.n)
+from pg_constraint c
+join pg_class cc on cc.oid = c.conrelid
+join pg_namespace cn on cn.oid = cc.relnamespace
+join pg_class pc on pc.oid = c.confrelid
+join pg_namespace pn on pn.oid = pc.relnamespace
+where c.contype = 'f' and cc.relkind in ('r', 'p') and pc.relkind in ('r', 'p')`
+
+	// inheritedTablesQuery lists partitioned tables, partitions, and tables of inheritance trees.
+	// A query of such a table reads rows of several relations, so row identifiers do not identify rows.
+	inheritedTablesQuery = `select n.nspname, c.relname
+from pg_class c
+join pg_namespace n on n.oid = c.relnamespace
+where c.relkind = 'p' or exists (select 1 from pg_inherits i where i.inhrelid = c.oid or i.inhparent = c.oid)`
+)
+
+// Subset defines a referentially consistent subset of the database.
+type Subset struct {
+	// Roots maps schema-qualified root tables to WHERE conditions of rows to start from.
+	Roots map[string]string `yaml:"roots"`
+
+	// Depth limits the number of foreign keys followed from referenced rows to referencing ones. Default: 1.
+	// Referenced rows are always followed, so the subset stays consistent.
+	Depth int `yaml:"depth"`
+}
+
+func (s *Subset) depth() int {
+	if s.Depth == 0 {
+		return defaultSubsetDepth
+	}
+
+	return s.Depth
+}
+
+// foreignKey describes a foreign key of the child table referencing the parent table.
+type foreignKey struct {
+	child         sourceTable
+	parent        sourceTable
+	childColumns  []string
+	childTypes    []string
+	parentColumns []string
+	parentTypes   []string
+}
+
+// subsetBatch describes rows of the table added to the subset.
+type subsetBatch struct {
+	table sourceTable
+	ctids []string
+	depth int
+
+	// referencing reports whether referencing rows of the batch have to be followed.
+	referencing bool
+}
+
+func (t sourceTable) String() string {
+	return pgx.Identifier{t.schema, t.name}.Sanitize()
+}
+
+// validateSubset checks subset options that do not depend on the database catalog.
+func validateSubset(dbName string, subset *Subset) error {
+	if subset == nil {
+		return nil
+	}
+
+	if len(subset.Roots) == 0 {
+		return errors.Errorf("subset of the database %s has no root tables", dbName)
+	}
+
+	if subset.Depth < 0 {
+		return errors.Errorf("subset depth of the database %s cannot be negative", dbName)
+	}
+
+	for table, condition := range subset.Roots {
+		if strings.ContainsAny(table, "*?") || !strings.Contains(table, ".") {
+			return errors.Errorf("subset root %q of the database %s must be a schema-qualified name without wildcards", table, dbName)
+		}
+
+		if strings.TrimSpace(condition) == "" {
+			return errors.Errorf("empty condition of the subset root %q of the database %s", table, dbName)
+		}
+	}
+
+	return nil
+}
+
+// subsetRoots returns root tables of the subset in a stable order.
+func (s *Subset) subsetRoots() []string {
+	roots := make([]string, 0, len(s.Roots))
+
+	for table := range s.Roots {
+		roots = append(roots, table)
+	}
+
+	sort.Strings(roots)
+
+	return roots
+}
+
+// findTable returns the source table matching the name.
+func findTable(name string, tables []sourceTable) (sourceTable, bool) {
+	for _, table := range tables {
+		if matchesTable(name, table.schema, table.name) {
+			return table, true
+		}
+	}
+
+	return sourceTable{}, false
+}
+
+// linkedTables returns tables connected to root tables by foreign keys in any direction.
+// Data of all these tables come from the subset: dumping any of them fully would break foreign keys.
+func linkedTables(roots []sourceTable, foreignKeys []foreignKey) []sourceTable {
+	linked := make(map[sourceTable]struct{})
+	queue := append([]sourceTable{}, roots...)
+
+	for len(queue) > 0 {
+		table := queue[0]
+		queue = queue[1:]
+
+		if _, ok := linked[table]; ok {
+			continue
+		}
+
+		linked[table] = struct{}{}
+
+		for _, fk := range foreignKeys {
+			switch table {
+			case fk.child:
+				queue = append(queue, fk.parent)
+
+			case fk.parent:
+				queue = append(queue, fk.child)
+			}
+		}
+	}
+
+	tables := make([]sourceTable, 0, len(linked))
+
+	for table := range linked {
+		tables = append(tables, table)
+	}
+
+	sort.Slice(tables, func(i, j int) bool { return tables[i].String() < tables[j].String() })
+
+	return tables
+}
+
+func listForeignKeys(ctx context.Context, conn *pgx.Conn) ([]foreignKey, error) {
+	rows, err := conn.Query(ctx, foreignKeysQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list foreign keys of the source")
+	}
+
+	defer rows.Close()
+
+	foreignKeys := []foreignKey{}
+
+	for rows.Next() {
+		var fk foreignKey
+		if err := rows.Scan(&fk.child.schema, &fk.child.name, &fk.parent.schema, &fk.parent.name,
+			&fk.childColumns, &fk.childTypes, &fk.parentColumns, &fk.parentTypes); err != nil {
+			return nil, errors.Wrap(err, "failed to scan foreign keys of the source")
+		}
+
+		foreignKeys = append(foreignKeys, fk)
+	}
+
+	return foreignKeys, rows.Err()
+}
+
+func listInheritedTables(ctx context.Context, conn *pgx.Conn) ([]sourceTable, error) {
+	rows, err := conn.Query(ctx, inheritedTablesQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list inherited tables of the source")
+	}
+
+	defer rows.Close()
+
+	tables := []sourceTable{}
+
+	for rows.Next() {
+		var table sourceTable
+		if err := rows.Scan(&table.schema, &table.name); err != nil {
+			return nil, errors.Wrap(err, "failed to scan inherited tables of the source")
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
+
+// checkSubsetTables lists tables of the subset that are partitioned or take part in inheritance.
+func checkSubsetTables(subsetTables, inheritedTables []sourceTable) []string {
+	inherited := make(map[sourceTable]struct{}, len(inheritedTables))
+
+	for _, table := range inheritedTables {
+		inherited[table] = struct{}{}
+	}
+
+	problems := []string{}
+
+	for _, table := range subsetTables {
+		if _, ok := inherited[table]; ok {
+			problems = append(problems, fmt.Sprintf("subset: partitioned and inherited tables are not supported, found %s", table))
+		}
+	}
+
+	return problems
+}
+
+// tidArray returns the SQL literal of the array of row identifiers.
+func tidArray(ctids []string) string {
+	return "'{" + strings.Join(ctids, ",") + "}'::tid[]"
+}
+
+// buildKeysQuery builds the query to get distinct non-null keys of the rows as JSON arrays.
+func buildKeysQuery(table sourceTable, columns []string) string {
+	conditions := make([]string, 0, len(columns))
+
+	for _, column := range columns {
+		conditions = append(conditions, pgx.Identifier{column}.Sanitize()+" is not null")
+	}
+
+	return fmt.Sprintf("select distinct jsonb_build_array(%s)::text from %s where ctid = any($1::text[]::tid[]) and %s",
+		joinIdentifiers(columns), table, strings.Join(conditions, " and "))
+}
+
+// buildLookupQuery builds the query to find rows by keys passed as a JSON array of key arrays.
+func buildLookupQuery(table sourceTable, columns, types []string) string {
+	keyFields := make([]string, 0, len(columns))
+
+	for i := range columns {
+		keyFields = append(keyFields, fmt.Sprintf("(k->>%d)::%s", i, types[i]))
+	}
+
+	return fmt.Sprintf("select ctid::text from %s where (%s) in (select %s from jsonb_array_elements($1::jsonb) k)",
+		table, joinIdentifiers(columns), strings.Join(keyFields, ", "))
+}
+
+func joinIdentifiers(columns []string) string {
+	identifiers := make([]string, 0, len(columns))
+
+	for _, column := range columns {
+		identifiers = append(identifiers, pgx.Identifier{column}.Sanitize())
+	}
+
+	return strings.Join(identifiers, ", ")
+}
+
+// subsetExtractor collects rows of the subset in a single snapshot of the source.
+type subsetExtractor struct {
+	conn        *pgx.Conn
+	foreignKeys []foreignKey
+	maxDepth    int
+	rows        map[sourceTable]map[string]bool
+	queue       []subsetBatch
+}
+
+// extractSubset copies rows of the subset to the directory of sampled rows and returns row counts by tables.
+func (d *DumpJob) extractSubset(ctx context.Context, dbName string, definition DumpDefinition, sampleDir string) (map[string]int64, error) {
+	connStr := db.ConnectionString(d.config.db.Host, strconv.Itoa(d.config.db.Port), d.config.db.Username, dbName, d.getPassword())
+
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to the source database")
+	}
+
+	defer func() { _ = conn.Close(ctx) }()
+
+	// Row identifiers are stable only in the same snapshot.
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to begin a transaction")
+	}
+
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// The subset is consistent with the dump if the snapshot exported by the replication slot is available.
+	if definition.snapshot != "" {
+		if _, err := tx.Exec(ctx, "set transaction snapshot "+quoteLiteral(definition.snapshot)); err != nil {
+			return nil, errors.Wrap(err, "failed to import the snapshot of the dump")
+		}
+	}
+
+	tables, err := listSourceTables(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	foreignKeys, err := listForeignKeys(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	extractor := &subsetExtractor{
+		conn:        conn,
+		foreignKeys: foreignKeys,
+		maxDepth:    definition.Subset.depth(),
+		rows:        make(map[sourceTable]map[string]bool),
+	}
+
+	for _, root := range definition.Subset.subsetRoots() {
+		table, ok := findTable(root, tables)
+		if !ok {
+			return nil, errors.Errorf("subset root %q not found", root)
+		}
+
+		ctids, err := extractor.queryCTIDs(ctx,
+			fmt.Sprintf("select ctid::text from %s where %s", table, definition.Subset.Roots[root]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to select rows of the subset root %s", root)
+		}
+
+		extractor.add(subsetBatch{table: table, ctids: ctids, referencing: true})
+	}
+
+	if err := extractor.run(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(sampleDir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create the directory of sampled rows")
+	}
+
+	rowCounts := make(map[string]int64)
+
+	for _, table := range definition.subsetTables {
+		count, err := extractor.copyRows(ctx, table, sampleDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to copy rows of the table %s", table)
+		}
+
+		rowCounts[table.String()] = count
+	}
+
+	return rowCounts, nil
+}
+
+// dumpSubset extracts the subset next to the directory dump or loads it to the restored database immediately.
+func (d *DumpJob) dumpSubset(ctx context.Context, contID, dbName string, definition DumpDefinition) error {
+	sampleDir := path.Join(d.getDumpPath(dbName, definition), samplesDir)
+
+	if d.DumpOptions.Restore.Enabled {
+		// There is no dump to keep extracted rows, so they are staged in the dump location until loaded.
+		sampleDir = path.Join(d.DumpOptions.DumpLocation, samplesDir+"_"+filenameFormatter.ReplaceAllString(dbName, "_"))
+
+		defer func() {
+			if err := os.RemoveAll(sampleDir); err != nil {
+				log.Err("Failed to remove extracted rows of the subset:", err)
+			}
+		}()
+	}
+
+	rowCounts, err := d.extractSubset(ctx, dbName, definition, sampleDir)
+	if err != nil {
+		return err
+	}
+
+	logSubsetReport(dbName, rowCounts)
+
+	if !d.DumpOptions.Restore.Enabled {
+		return nil
+	}
+
+	return loadSampleFiles(ctx, d.dockerClient, contID, d.globalCfg.Database.User(), dbName, sampleDir)
+}
+
+// add adds rows of the batch to the subset and queues rows that have not been followed yet.
+func (e *subsetExtractor) add(batch subsetBatch) {
+	tableRows, ok := e.rows[batch.table]
+	if !ok {
+		tableRows = make(map[string]bool)
+		e.rows[batch.table] = tableRows
+	}
+
+	newRows := []string{}
+
+	for _, ctid := range batch.ctids {
+		isReferencingFollowed, ok := tableRows[ctid]
+
+		// Rows already added as referenced ones have to be followed again if they have been reached by referencing rows.
+		if ok && (isReferencingFollowed || !batch.referencing) {
+			continue
+		}
+
+		tableRows[ctid] = batch.referencing
+		newRows = append(newRows, ctid)
+	}
+
+	if len(newRows) > 0 {
+		e.queue = append(e.queue, subsetBatch{table: batch.table, ctids: newRows, depth: batch.depth, referencing: batch.referencing})
+	}
+}
+
+// run follows foreign keys of queued rows until the subset is complete.
+func (e *subsetExtractor) run(ctx context.Context) error {
+	for len(e.queue) > 0 {
+		batch := e.queue[0]
+		e.queue = e.queue[1:]
+
+		for _, fk := range e.foreignKeys {
+			// Referenced rows keep the subset consistent, so they are always followed.
+			if fk.child == batch.table {
+				ctids, err := e.followKey(ctx, batch, fk.child, fk.childColumns, fk.parent, fk.parentColumns, fk.parentTypes)
+				if err != nil {
+					return err
+				}
+
+				e.add(subsetBatch{table: fk.parent, ctids: ctids, depth: batch.depth})
+			}
+
+			if fk.parent == batch.table && batch.referencing && batch.depth < e.maxDepth {
+				ctids, err := e.followKey(ctx, batch, fk.parent, fk.parentColumns, fk.child, fk.childColumns, fk.childTypes)
+				if err != nil {
+					return err
+				}
+
+				e.add(subsetBatch{table: fk.child, ctids: ctids, depth: batch.depth + 1, referencing: true})
+			}
+		}
+	}
+
+	return nil
+}
+
+// followKey returns rows of the target table matching keys of the batch rows.
+func (e *subsetExtractor) followKey(ctx context.Context, batch subsetBatch, source sourceTable, sourceColumns []string,
+	target sourceTable, targetColumns, targetTypes []string) ([]string, error) {
+	rows, err := e.conn.Query(ctx, buildKeysQuery(source, sourceColumns), batch.ctids)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get keys of the table %s", source)
+	}
+
+	keys := []string{}
+
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, errors.Wrapf(err, "failed to scan keys of the table %s", source)
+		}
+
+		keys = append(keys, key)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to get keys of the table %s", source)
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	ctids, err := e.queryCTIDs(ctx, buildLookupQuery(target, targetColumns, targetTypes), "["+strings.Join(keys, ",")+"]")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find rows of the table %s", target)
+	}
+
+	return ctids, nil
+}
+
+func (e *subsetExtractor) queryCTIDs(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := e.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ctids := []string{}
+
+	for rows.Next() {
+		var ctid string
+		if err := rows.Scan(&ctid); err != nil {
+			return nil, err
+		}
+
+		ctids = append(ctids, ctid)
+	}
+
+	return ctids, rows.Err()
+}
+
+// copyRows writes rows of the table in the subset to the file in the COPY text format.
+func (e *subsetExtractor) copyRows(ctx context.Context, table sourceTable, sampleDir string) (int64, error) {
+	tableRows := e.rows[table]
+	if len(tableRows) == 0 {
+		return 0, nil
+	}
+
+	ctids := make([]string, 0, len(tableRows))
+
+	for ctid := range tableRows {
+		ctids = append(ctids, ctid)
+	}
+
+	sort.Strings(ctids)
+
+	sampleFile, err := os.Create(path.Join(sampleDir, sampleFileName(table.String())))
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() { _ = sampleFile.Close() }()
+
+	commandTag, err := e.conn.PgConn().CopyTo(ctx, sampleFile,
+		fmt.Sprintf("copy (select * from %s where ctid = any(%s)) to stdout", table, tidArray(ctids)))
+	if err != nil {
+		return 0, err
+	}
+
+	return commandTag.RowsAffected(), nil
+}
+
+// logSubsetReport logs the number of rows of tables in the subset.
+func logSubsetReport(dbName string, rowCounts map[string]int64) {
+	tables := make([]string, 0, len(rowCounts))
+
+	for table := range rowCounts {
+		tables = append(tables, table)
+	}
+
+	sort.Strings(tables)
+
+	report := make([]string, 0, len(tables))
+
+	for _, table := range tables {
+		report = append(report, fmt.Sprintf("%s: %d", table, rowCounts[table]))
+	}
+
+	log.Msg(fmt.Sprintf("Subset of the database %s has been extracted. Rows by tables: %s", dbName, strings.Join(report, ", ")))
+}
@@ -0,0 +1,89 @@
+package logical
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSubset(t *testing.T) {
+	assert.NoError(t, validateSubset("db", nil))
+	assert.NoError(t, validateSubset("db", &Subset{Roots: map[string]string{"public.users": "id < 100"}, Depth: 2}))
+	assert.Error(t, validateSubset("db", &Subset{}))
+	assert.Error(t, validateSubset("db", &Subset{Roots: map[string]string{"public.users": "id < 100"}, Depth: -1}))
+	assert.Error(t, validateSubset("db", &Subset{Roots: map[string]string{"users": "id < 100"}}))
+	assert.Error(t, validateSubset("db", &Subset{Roots: map[string]string{"public.users": ""}}))
+
+	assert.Equal(t, 1, (&Subset{}).depth())
+	assert.Equal(t, 3, (&Subset{Depth: 3}).depth())
+}
+
+func TestSubsetTables(t *testing.T) {
+	users := sourceTable{schema: "public", name: "users"}
+	orders := sourceTable{schema: "public", name: "orders"}
+	items := sourceTable{schema: "public", name: "order_items"}
+	countries := sourceTable{schema: "public", name: "countries"}
+	logs := sourceTable{schema: "public", name: "logs"}
+
+	tables := []sourceTable{users, orders, items, countries, logs}
+	foreignKeys := []foreignKey{
+		{child: orders, parent: users},
+		{child: items, parent: orders},
+		{child: users, parent: countries},
+	}
+
+	assert.Equal(t, []sourceTable{countries, items, orders, users}, linkedTables([]sourceTable{orders}, foreignKeys))
+
+	definition := DumpDefinition{
+		ExcludeTableData: []string{"countries"},
+		Subset:           &Subset{Roots: map[string]string{"public.users": "id < 100"}},
+	}
+
+	assert.Equal(t, []sourceTable{items, orders, users}, definition.findSubsetTables(tables, foreignKeys))
+	assert.Empty(t, DumpDefinition{}.findSubsetTables(tables, foreignKeys))
+}
+
+func TestCheckSubsetTables(t *testing.T) {
+	users := sourceTable{schema: "public", name: "users"}
+	events := sourceTable{schema: "public", name: "events"}
+	events2022 := sourceTable{schema: "public", name: "events_2022"}
+
+	assert.Empty(t, checkSubsetTables([]sourceTable{users}, []sourceTable{events, events2022}))
+	assert.Equal(t, []string{`subset: partitioned and inherited tables are not supported, found "public"."events_2022"`},
+		checkSubsetTables([]sourceTable{events2022, users}, []sourceTable{events, events2022}))
+}
+
+func TestSubsetQueries(t *testing.T) {
+	orders := sourceTable{schema: "public", name: "orders"}
+
+	assert.Equal(t, `select distinct jsonb_build_array("user_id", "shop_id")::text from "public"."orders" `+
+		`where ctid = any($1::text[]::tid[]) and "user_id" is not null and "shop_id" is not null`,
+		buildKeysQuery(orders, []string{"user_id", "shop_id"}))
+
+	assert.Equal(t, `select ctid::text from "public"."orders" where ("user_id", "shop_id") in `+
+		`(select (k->>0)::bigint, (k->>1)::integer from jsonb_array_elements($1::jsonb) k)`,
+		buildLookupQuery(orders, []string{"user_id", "shop_id"}, []string{"bigint", "integer"}))
+
+	assert.Equal(t, `'{(0,1),(12,7)}'::tid[]`, tidArray([]string{"(0,1)", "(12,7)"}))
+}
+
+func TestSubsetExtractorAdd(t *testing.T) {
+	users := sourceTable{schema: "public", name: "users"}
+	extractor := &subsetExtractor{rows: make(map[sourceTable]map[string]bool)}
+
+	extractor.add(subsetBatch{table: users, ctids: []string{"(0,1)", "(0,2)"}})
+	assert.Len(t, extractor.queue, 1)
+
+	// Rows added as referenced ones are not followed again.
+	extractor.add(subsetBatch{table: users, ctids: []string{"(0,1)"}})
+	assert.Len(t, extractor.queue, 1)
+
+	// Referencing rows of rows reached from the referencing side have to be followed.
+	extractor.add(subsetBatch{table: users, ctids: []string{"(0,1)", "(0,3)"}, depth: 1, referencing: true})
+	assert.Len(t, extractor.queue, 2)
+	assert.Equal(t, subsetBatch{table: users, ctids: []string{"(0,1)", "(0,3)"}, depth: 1, referencing: true}, extractor.queue[1])
+
+	extractor.add(subsetBatch{table: users, ctids: []string{"(0,1)", "(0,2)"}})
+	assert.Len(t, extractor.queue, 2)
+	assert.Len(t, extractor.rows[users], 3)
+}
@@ -0,0 +1,169 @@
+/*
+2022 © Postgres.ai
+*/
+
+package physical
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/defaults"
+)
+
+const (
+	pgbackrestTool = "pgbackrest"
+
+	// pgbackrestStatusOK defines the status code of a stanza without errors.
+	pgbackrestStatusOK = 0
+)
+
+// pgbackrestRepoTypes lists repository types supported by pgBackRest.
+var pgbackrestRepoTypes = map[string]struct{}{
+	"posix": {},
+	"cifs":  {},
+	"s3":    {},
+	"gcs":   {},
+	"azure": {},
+	"sftp":  {},
+}
+
+// pgbackrest defines pgBackRest as an archival restoration tool.
+type pgbackrest struct {
+	pgDataDir string
+	options   pgbackrestOptions
+}
+
+// pgbackrestOptions defines options of pgBackRest.
+// Other repository options, such as credentials of cloud storages, can be set with PGBACKREST_* environment variables in "envs".
+type pgbackrestOptions struct {
+	Stanza   string `yaml:"stanza"`
+	RepoType string `yaml:"repoType"`
+	RepoPath string `yaml:"repoPath"`
+
+	// BackupSet defines the label of a backup to restore. The latest backup is restored if it's empty.
+	BackupSet string `yaml:"backupSet"`
+	Delta     bool   `yaml:"delta"`
+}
+
+// pgbackrestStanzaInfo describes a stanza in the output of the pgBackRest info command.
+type pgbackrestStanzaInfo struct {
+	Name   string `json:"name"`
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+	Backup []struct {
+		Label string `json:"label"`
+	} `json:"backup"`
+}
+
+func newPGBackRest(pgDataDir string, options pgbackrestOptions) (*pgbackrest, error) {
+	if options.Stanza == "" {
+		return nil, errors.New("stanza is not specified")
+	}
+
+	if options.RepoType != "" {
+		if _, ok := pgbackrestRepoTypes[options.RepoType]; !ok {
+			return nil, errors.Errorf("unknown repository type: %q", options.RepoType)
+		}
+	}
+
+	return &pgbackrest{
+		pgDataDir: pgDataDir,
+		options:   options,
+	}, nil
+}
+
+// GetRestoreCommand returns a command to restore data.
+// Recovery settings are written by the engine, so pgBackRest does not generate them.
+func (p *pgbackrest) GetRestoreCommand() string {
+	restoreCmd := append([]string{"pgbackrest"}, p.repoArgs()...)
+	restoreCmd = append(restoreCmd, "--pg1-path="+p.pgDataDir, "--type=preserve", "--log-level-console=info")
+
+	if p.options.BackupSet != "" {
+		restoreCmd = append(restoreCmd, "--set="+p.options.BackupSet)
+	}
+
+	if p.options.Delta {
+		restoreCmd = append(restoreCmd, "--delta")
+	}
+
+	return strings.Join(append(restoreCmd, "restore"), " ")
+}
+
+// GetRecoveryConfig returns a recovery config to restore data.
+func (p *pgbackrest) GetRecoveryConfig(pgVersion float64) map[string]string {
+	restoreCmd := append([]string{"pgbackrest"}, p.repoArgs()...)
+
+	recoveryCfg := map[string]string{
+		"restore_command": strings.Join(append(restoreCmd, "archive-get", "%f", `"%p"`), " "),
+	}
+
+	if pgVersion < defaults.PGVersion12 {
+		recoveryCfg["standby_mode"] = "on"
+		recoveryCfg["recovery_target_timeline"] = "latest"
+	}
+
+	return recoveryCfg
+}
+
+// GetValidationCommand returns a command to get information about backups of the stanza.
+func (p *pgbackrest) GetValidationCommand() string {
+	infoCmd := append([]string{"pgbackrest"}, p.repoArgs()...)
+
+	return strings.Join(append(infoCmd, "--output=json", "info"), " ")
+}
+
+// ValidateOutput checks that the stanza is healthy and contains the backup to restore.
+func (p *pgbackrest) ValidateOutput(output string) error {
+	stanzas := []pgbackrestStanzaInfo{}
+
+	if err := json.Unmarshal([]byte(output), &stanzas); err != nil {
+		return errors.Wrap(err, "failed to parse pgBackRest info")
+	}
+
+	for _, stanza := range stanzas {
+		if stanza.Name != p.options.Stanza {
+			continue
+		}
+
+		if stanza.Status.Code != pgbackrestStatusOK {
+			return errors.Errorf("stanza %q is not ready: %s", stanza.Name, stanza.Status.Message)
+		}
+
+		if len(stanza.Backup) == 0 {
+			return errors.Errorf("no backups found in stanza %q", stanza.Name)
+		}
+
+		if p.options.BackupSet == "" {
+			return nil
+		}
+
+		for _, backup := range stanza.Backup {
+			if backup.Label == p.options.BackupSet {
+				return nil
+			}
+		}
+
+		return errors.Errorf("backup set %q not found in stanza %q", p.options.BackupSet, stanza.Name)
+	}
+
+	return errors.Errorf("stanza %q not found", p.options.Stanza)
+}
+
+func (p *pgbackrest) repoArgs() []string {
+	args := []string{"--stanza=" + p.options.Stanza}
+
+	if p.options.RepoType != "" {
+		args = append(args, "--repo1-type="+p.options.RepoType)
+	}
+
+	if p.options.RepoPath != "" {
+		args = append(args, "--repo1-path="+p.options.RepoPath)
+	}
+
+	return args
+}
@@ -0,0 +1,85 @@
+package physical
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPGBackRestInfo = `[{"name":"main","status":{"code":0,"message":"ok"},
+"backup":[{"label":"20220601-010000F","type":"full"},{"label":"20220602-010000F_20220602-120000I","type":"incr"}]}]`
+
+func TestPGBackRestCommands(t *testing.T) {
+	pgbackrest, err := newPGBackRest("/var/lib/dblab/data", pgbackrestOptions{
+		Stanza:    "main",
+		RepoType:  "s3",
+		RepoPath:  "/pgbackrest",
+		BackupSet: "20220601-010000F",
+		Delta:     true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "pgbackrest --stanza=main --repo1-type=s3 --repo1-path=/pgbackrest --pg1-path=/var/lib/dblab/data "+
+		"--type=preserve --log-level-console=info --set=20220601-010000F --delta restore", pgbackrest.GetRestoreCommand())
+
+	assert.Equal(t, "pgbackrest --stanza=main --repo1-type=s3 --repo1-path=/pgbackrest --output=json info",
+		pgbackrest.GetValidationCommand())
+}
+
+func TestPGBackRestRecoveryConfig(t *testing.T) {
+	pgbackrest, err := newPGBackRest("dataDir", pgbackrestOptions{Stanza: "main"})
+	require.NoError(t, err)
+
+	recoveryConfig := pgbackrest.GetRecoveryConfig(11.7)
+	expectedResponse11 := map[string]string{
+		"restore_command":          `pgbackrest --stanza=main archive-get %f "%p"`,
+		"standby_mode":             "on",
+		"recovery_target_timeline": "latest",
+	}
+	assert.Equal(t, expectedResponse11, recoveryConfig)
+
+	recoveryConfig = pgbackrest.GetRecoveryConfig(12.3)
+	expectedResponse12 := map[string]string{
+		"restore_command": `pgbackrest --stanza=main archive-get %f "%p"`,
+	}
+	assert.Equal(t, expectedResponse12, recoveryConfig)
+}
+
+func TestPGBackRestValidation(t *testing.T) {
+	_, err := newPGBackRest("dataDir", pgbackrestOptions{})
+	assert.Error(t, err)
+
+	_, err = newPGBackRest("dataDir", pgbackrestOptions{Stanza: "main", RepoType: "ftp"})
+	assert.Error(t, err)
+
+	testCases := []struct {
+		options       pgbackrestOptions
+		output        string
+		expectedError bool
+	}{
+		{options: pgbackrestOptions{Stanza: "main"}, output: testPGBackRestInfo},
+		{options: pgbackrestOptions{Stanza: "main", BackupSet: "20220602-010000F_20220602-120000I"}, output: testPGBackRestInfo},
+		{options: pgbackrestOptions{Stanza: "main", BackupSet: "20220501-010000F"}, output: testPGBackRestInfo, expectedError: true},
+		{options: pgbackrestOptions{Stanza: "other"}, output: testPGBackRestInfo, expectedError: true},
+		{options: pgbackrestOptions{Stanza: "main"}, output: "not json", expectedError: true},
+		{
+			options:       pgbackrestOptions{Stanza: "main"},
+			output:        `[{"name":"main","status":{"code":2,"message":"no valid backups"},"backup":[]}]`,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		pgbackrest, err := newPGBackRest("dataDir", tc.options)
+		require.NoError(t, err)
+
+		err = pgbackrest.ValidateOutput(tc.output)
+		if tc.expectedError {
+			assert.Error(t, err)
+			continue
+		}
+
+		assert.NoError(t, err)
+	}
+}
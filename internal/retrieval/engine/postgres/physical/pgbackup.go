@@ -0,0 +1,217 @@
+/*
+2022 © Postgres.ai
+*/
+
+package physical
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/defaults"
+)
+
+const (
+	pgbackupTool = "pgbackup"
+
+	// Checkpoint modes of pg_basebackup.
+	checkpointFast   = "fast"
+	checkpointSpread = "spread"
+)
+
+// pgbackup defines pg_basebackup as a tool to stream a base backup from a running instance.
+type pgbackup struct {
+	pgDataDir string
+	options   pgbackupOptions
+}
+
+type pgbackupOptions struct {
+	Connection pgbackupConnection `yaml:"connection"`
+
+	// ReplicationSlot defines a physical replication slot created by pg_basebackup.
+	// The sync instance streams WAL through the slot, so no WAL is lost between the backup and the start of the sync instance.
+	ReplicationSlot string `yaml:"replicationSlot"`
+
+	// TablespaceMapping relocates tablespaces: keys are directories on the source, values are directories on the engine.
+	// The target directories must be located in the data directory, so the tablespaces are included in snapshots and clones.
+	TablespaceMapping map[string]string `yaml:"tablespaceMapping"`
+
+	Checkpoint string `yaml:"checkpoint"`
+	MaxRate    string `yaml:"maxRate"`
+}
+
+// pgbackupConnection defines a replication connection to the source.
+type pgbackupConnection struct {
+	Host            string `yaml:"host"`
+	Port            int    `yaml:"port"`
+	Username        string `yaml:"username"`
+	Password        string `yaml:"password"`
+	ApplicationName string `yaml:"applicationName"`
+}
+
+func newPGBackup(pgDataDir string, options pgbackupOptions, syncEnabled bool) (*pgbackup, error) {
+	if options.Connection.Host == "" {
+		return nil, errors.New("host of the source is not specified")
+	}
+
+	if options.ReplicationSlot != "" && !syncEnabled {
+		return nil, errors.New("the replication slot requires the sync instance to stream WAL")
+	}
+
+	switch options.Checkpoint {
+	case "":
+		options.Checkpoint = checkpointFast
+
+	case checkpointFast, checkpointSpread:
+
+	default:
+		return nil, errors.Errorf("unknown checkpoint mode: %q", options.Checkpoint)
+	}
+
+	for oldDir, newDir := range options.TablespaceMapping {
+		if !isSubdirectory(pgDataDir, newDir) {
+			return nil, errors.Errorf("the tablespace %q must be relocated into the data directory %q, got %q", oldDir, pgDataDir, newDir)
+		}
+	}
+
+	if options.Connection.Port == 0 {
+		options.Connection.Port = defaults.Port
+	}
+
+	if options.Connection.Username == "" {
+		options.Connection.Username = defaults.Username
+	}
+
+	return &pgbackup{
+		pgDataDir: pgDataDir,
+		options:   options,
+	}, nil
+}
+
+// isSubdirectory checks if the directory is located inside the parent directory.
+func isSubdirectory(parent, dir string) bool {
+	relPath, err := filepath.Rel(filepath.Clean(parent), filepath.Clean(dir))
+	if err != nil {
+		return false
+	}
+
+	return filepath.IsAbs(dir) && relPath != "." && relPath != ".." && !strings.HasPrefix(relPath, "../")
+}
+
+// GetRestoreCommand returns a command to restore data.
+func (p *pgbackup) GetRestoreCommand() string {
+	restoreCmd := []string{"pg_basebackup",
+		"--pgdata", p.pgDataDir,
+		"--wal-method", "stream",
+		"--checkpoint", p.options.Checkpoint,
+		"--no-password",
+		"--progress",
+		"--verbose",
+	}
+
+	restoreCmd = append(restoreCmd, p.connectionArgs()...)
+
+	if p.options.ReplicationSlot != "" {
+		restoreCmd = append(restoreCmd, "--slot", p.options.ReplicationSlot, "--create-slot")
+	}
+
+	if p.options.MaxRate != "" {
+		restoreCmd = append(restoreCmd, "--max-rate", p.options.MaxRate)
+	}
+
+	oldDirs := make([]string, 0, len(p.options.TablespaceMapping))
+	for oldDir := range p.options.TablespaceMapping {
+		oldDirs = append(oldDirs, oldDir)
+	}
+
+	sort.Strings(oldDirs)
+
+	for _, oldDir := range oldDirs {
+		restoreCmd = append(restoreCmd, "--tablespace-mapping", oldDir+"="+p.options.TablespaceMapping[oldDir])
+	}
+
+	return strings.Join(restoreCmd, " ")
+}
+
+// GetCleanupCommand returns a command to drop the replication slot if the restore fails.
+func (p *pgbackup) GetCleanupCommand() string {
+	if p.options.ReplicationSlot == "" {
+		return ""
+	}
+
+	cleanupCmd := append([]string{"pg_receivewal", "--drop-slot", "--slot", p.options.ReplicationSlot, "--no-password"},
+		p.connectionArgs()...)
+
+	return strings.Join(cleanupCmd, " ")
+}
+
+// GetEnvVariables returns environment variables of the restore command.
+func (p *pgbackup) GetEnvVariables() []string {
+	if p.options.Connection.Password == "" {
+		return nil
+	}
+
+	return []string{"PGPASSWORD=" + p.options.Connection.Password}
+}
+
+// GetRecoveryConfig returns a recovery config to stream WAL from the source.
+func (p *pgbackup) GetRecoveryConfig(pgVersion float64) map[string]string {
+	recoveryCfg := map[string]string{
+		// Backslashes and single quotes are escaped because recovery parameters are written in single quotes.
+		"primary_conninfo": strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(p.connInfo()),
+	}
+
+	if p.options.ReplicationSlot != "" {
+		recoveryCfg["primary_slot_name"] = p.options.ReplicationSlot
+	}
+
+	if pgVersion < defaults.PGVersion12 {
+		recoveryCfg["standby_mode"] = "on"
+		recoveryCfg["recovery_target_timeline"] = "latest"
+	}
+
+	return recoveryCfg
+}
+
+func (p *pgbackup) connectionArgs() []string {
+	return []string{
+		"--host", p.options.Connection.Host,
+		"--port", strconv.Itoa(p.options.Connection.Port),
+		"--username", p.options.Connection.Username,
+	}
+}
+
+// connInfo builds a connection string to the source.
+func (p *pgbackup) connInfo() string {
+	params := []string{
+		"host=" + quoteConnValue(p.options.Connection.Host),
+		"port=" + strconv.Itoa(p.options.Connection.Port),
+		"user=" + quoteConnValue(p.options.Connection.Username),
+	}
+
+	if p.options.Connection.Password != "" {
+		params = append(params, "password="+quoteConnValue(p.options.Connection.Password))
+	}
+
+	if p.options.Connection.ApplicationName != "" {
+		params = append(params, "application_name="+quoteConnValue(p.options.Connection.ApplicationName))
+	}
+
+	return strings.Join(params, " ")
+}
+
+// quoteConnValue quotes a value of a connection string if it's required.
+func quoteConnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+	return fmt.Sprintf("'%s'", replacer.Replace(value))
+}
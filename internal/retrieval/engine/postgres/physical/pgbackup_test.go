@@ -0,0 +1,182 @@
+package physical
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPGBackupRestoreCommand(t *testing.T) {
+	pgbackup, err := newPGBackup("/var/lib/dblab/data", pgbackupOptions{
+		Connection:      pgbackupConnection{Host: "replica.example.com", Username: "replicator"},
+		ReplicationSlot: "dblab_slot",
+		TablespaceMapping: map[string]string{
+			"/mnt/ts2": "/var/lib/dblab/data/ts2",
+			"/mnt/ts1": "/var/lib/dblab/data/ts1",
+		},
+	}, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, "pg_basebackup --pgdata /var/lib/dblab/data --wal-method stream --checkpoint fast --no-password --progress --verbose "+
+		"--host replica.example.com --port 5432 --username replicator --slot dblab_slot --create-slot "+
+		"--tablespace-mapping /mnt/ts1=/var/lib/dblab/data/ts1 --tablespace-mapping /mnt/ts2=/var/lib/dblab/data/ts2",
+		pgbackup.GetRestoreCommand())
+
+	assert.Equal(t, "pg_receivewal --drop-slot --slot dblab_slot --no-password --host replica.example.com --port 5432 --username replicator",
+		pgbackup.GetCleanupCommand())
+}
+
+func TestPGBackupRecoveryConfig(t *testing.T) {
+	pgbackup, err := newPGBackup("dataDir", pgbackupOptions{
+		Connection: pgbackupConnection{
+			Host:            "replica",
+			Port:            6432,
+			Username:        "replicator",
+			Password:        "it's secret",
+			ApplicationName: "dblab",
+		},
+		ReplicationSlot: "dblab_slot",
+	}, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"PGPASSWORD=it's secret"}, pgbackup.GetEnvVariables())
+
+	recoveryConfig := pgbackup.GetRecoveryConfig(11.7)
+	expectedResponse11 := map[string]string{
+		"primary_conninfo":         `host=replica port=6432 user=replicator password=''it\\''s secret'' application_name=dblab`,
+		"primary_slot_name":        "dblab_slot",
+		"standby_mode":             "on",
+		"recovery_target_timeline": "latest",
+	}
+	assert.Equal(t, expectedResponse11, recoveryConfig)
+
+	recoveryConfig = pgbackup.GetRecoveryConfig(12.3)
+	expectedResponse12 := map[string]string{
+		"primary_conninfo":  `host=replica port=6432 user=replicator password=''it\\''s secret'' application_name=dblab`,
+		"primary_slot_name": "dblab_slot",
+	}
+	assert.Equal(t, expectedResponse12, recoveryConfig)
+}
+
+func TestPGBackupConnInfoRoundTrip(t *testing.T) {
+	for _, password := range []string{"secret", "it's secret", `back\slash`, `\'mixed'\\`, "with space"} {
+		pgbackup, err := newPGBackup("dataDir", pgbackupOptions{
+			Connection: pgbackupConnection{Host: "replica", Username: "replicator", Password: password},
+		}, true)
+		require.NoError(t, err)
+
+		connInfo, err := unquoteConfigValue("'" + pgbackup.GetRecoveryConfig(12.3)["primary_conninfo"] + "'")
+		require.NoError(t, err)
+
+		assert.Equal(t, password, parseConnInfo(t, connInfo)["password"], password)
+	}
+}
+
+// unquoteConfigValue unquotes a string value as the PostgreSQL configuration file parser does.
+func unquoteConfigValue(value string) (string, error) {
+	if len(value) < 2 || value[0] != '\'' || value[len(value)-1] != '\'' {
+		return "", errors.Errorf("value is not quoted: %s", value)
+	}
+
+	var sb strings.Builder
+
+	runes := []rune(value[1 : len(value)-1])
+
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && i+1 < len(runes):
+			i++
+			sb.WriteRune(runes[i])
+
+		case runes[i] == '\'' && i+1 < len(runes) && runes[i+1] == '\'':
+			i++
+			sb.WriteRune('\'')
+
+		case runes[i] == '\'':
+			return "", errors.Errorf("unescaped quote in the value: %s", value)
+
+		default:
+			sb.WriteRune(runes[i])
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// parseConnInfo parses a libpq connection string of key/value pairs.
+func parseConnInfo(t *testing.T, connInfo string) map[string]string {
+	params := map[string]string{}
+	runes := []rune(connInfo)
+
+	for i := 0; i < len(runes); {
+		for i < len(runes) && runes[i] == ' ' {
+			i++
+		}
+
+		start := i
+		for i < len(runes) && runes[i] != '=' {
+			i++
+		}
+
+		require.Less(t, i, len(runes), connInfo)
+
+		key := string(runes[start:i])
+		i++
+
+		var sb strings.Builder
+
+		if i < len(runes) && runes[i] == '\'' {
+			for i++; i < len(runes) && runes[i] != '\''; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+
+				sb.WriteRune(runes[i])
+			}
+
+			require.Less(t, i, len(runes), "unterminated quoted value: %s", connInfo)
+			i++
+		} else {
+			for ; i < len(runes) && runes[i] != ' '; i++ {
+				sb.WriteRune(runes[i])
+			}
+		}
+
+		params[key] = sb.String()
+	}
+
+	return params
+}
+
+func TestPGBackupValidation(t *testing.T) {
+	_, err := newPGBackup("dataDir", pgbackupOptions{}, true)
+	assert.Error(t, err)
+
+	_, err = newPGBackup("dataDir", pgbackupOptions{
+		Connection:      pgbackupConnection{Host: "replica"},
+		ReplicationSlot: "dblab_slot",
+	}, false)
+	assert.Error(t, err)
+
+	_, err = newPGBackup("dataDir", pgbackupOptions{
+		Connection: pgbackupConnection{Host: "replica"},
+		Checkpoint: "slow",
+	}, false)
+	assert.Error(t, err)
+
+	for _, target := range []string{"/var/lib/dblab/data_ts1", "/var/lib/dblab/data", "/var/lib/dblab/data/../ts1", "data/ts1"} {
+		_, err = newPGBackup("/var/lib/dblab/data", pgbackupOptions{
+			Connection:        pgbackupConnection{Host: "replica"},
+			TablespaceMapping: map[string]string{"/mnt/ts1": target},
+		}, false)
+		assert.Error(t, err, target)
+	}
+
+	pgbackup, err := newPGBackup("dataDir", pgbackupOptions{Connection: pgbackupConnection{Host: "replica"}}, false)
+	require.NoError(t, err)
+	assert.Empty(t, pgbackup.GetCleanupCommand())
+	assert.Empty(t, pgbackup.GetEnvVariables())
+}
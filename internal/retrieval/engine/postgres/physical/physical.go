@@ -7,6 +7,7 @@ package physical
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -26,6 +27,7 @@ import (
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/dbmarker"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/cont"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/fs"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/health"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/pgtool"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/options"
@@ -73,6 +75,8 @@ type CopyOptions struct {
 	Envs            map[string]string      `yaml:"envs"`
 	WALG            walgOptions            `yaml:"walg"`
 	CustomTool      customOptions          `yaml:"customTool"`
+	PGBackup        pgbackupOptions        `yaml:"pgbackup"`
+	PGBackRest      pgbackrestOptions      `yaml:"pgbackrest"`
 	Sync            Sync                   `yaml:"sync"`
 }
 
@@ -99,6 +103,27 @@ type restorer interface {
 	GetRecoveryConfig(version float64) map[string]string
 }
 
+// restoreCleaner describes tools that change the source, so they have to clean up if the restore fails.
+type restoreCleaner interface {
+	// GetCleanupCommand returns a command to run if the restore fails.
+	GetCleanupCommand() string
+}
+
+// backupValidator describes tools that can check a backup before restoring it.
+type backupValidator interface {
+	// GetValidationCommand returns a command which output describes the backup.
+	GetValidationCommand() string
+
+	// ValidateOutput checks the output of the validation command.
+	ValidateOutput(output string) error
+}
+
+// envProvider describes tools that require additional environment variables.
+type envProvider interface {
+	// GetEnvVariables returns environment variables of the restore command.
+	GetEnvVariables() []string
+}
+
 // NewJob creates a new physical restore job.
 func NewJob(cfg config.JobConfig, global *global.Config, engineProps global.EngineProps) (*RestoreJob, error) {
 	physicalJob := &RestoreJob{
@@ -132,6 +157,12 @@ func (r *RestoreJob) getRestorer(tool string) (restorer, error) {
 
 	case customTool:
 		return newCustomTool(r.CustomTool), nil
+
+	case pgbackupTool:
+		return newPGBackup(r.fsPool.DataDir(), r.PGBackup, r.Sync.Enabled)
+
+	case pgbackrestTool:
+		return newPGBackRest(r.fsPool.DataDir(), r.PGBackRest)
 	}
 
 	return nil, errors.Errorf("unknown restore tool given: %v", tool)
@@ -146,6 +177,11 @@ func (r *RestoreJob) Name() string {
 	return r.name
 }
 
+// DataSize returns the size of restored data.
+func (r *RestoreJob) DataSize() (uint64, error) {
+	return fs.DirSize(r.fsPool.DataDir())
+}
+
 // Reload reloads job configuration.
 func (r *RestoreJob) Reload(cfg map[string]interface{}) (err error) {
 	return options.Unmarshal(cfg, &r.CopyOptions)
@@ -211,6 +247,16 @@ func (r *RestoreJob) Run(ctx context.Context) (err error) {
 		return errors.Wrapf(err, "failed to start container: %v", contID)
 	}
 
+	if err := r.validateBackup(ctx, contID); err != nil {
+		return errors.Wrap(err, "failed to validate the backup")
+	}
+
+	defer func() {
+		if err != nil {
+			r.cleanupRestore(ctx, contID)
+		}
+	}()
+
 	log.Msg("Running restore command: ", r.restorer.GetRestoreCommand())
 	log.Msg(fmt.Sprintf("View logs using the command: %s %s", tools.ViewLogsCmd, r.restoreContainerName()))
 
@@ -269,6 +315,45 @@ func (r *RestoreJob) Run(ctx context.Context) (err error) {
 	return nil
 }
 
+// validateBackup checks the backup if the restore tool supports it.
+func (r *RestoreJob) validateBackup(ctx context.Context, contID string) error {
+	validator, ok := r.restorer.(backupValidator)
+	if !ok {
+		return nil
+	}
+
+	log.Msg("Running validation command: ", validator.GetValidationCommand())
+
+	var output bytes.Buffer
+
+	// The result is decided by the exit code only because tools report routine warnings to stderr.
+	if err := tools.ExecCommandWithStdout(ctx, r.dockerClient, contID, types.ExecConfig{
+		Cmd: []string{"bash", "-c", validator.GetValidationCommand()},
+	}, &output, func(line string) {
+		log.Msg("Validation command:", line)
+	}); err != nil {
+		return errors.Wrap(err, "failed to get information about the backup")
+	}
+
+	return validator.ValidateOutput(output.String())
+}
+
+// cleanupRestore runs the cleanup command of the restore tool if it has one.
+func (r *RestoreJob) cleanupRestore(ctx context.Context, contID string) {
+	cleaner, ok := r.restorer.(restoreCleaner)
+	if !ok || cleaner.GetCleanupCommand() == "" {
+		return
+	}
+
+	log.Msg("Running cleanup command: ", cleaner.GetCleanupCommand())
+
+	if err := tools.ExecCommand(ctx, r.dockerClient, contID, types.ExecConfig{
+		Cmd: []string{"bash", "-c", cleaner.GetCleanupCommand() + " >& /proc/1/fd/1"},
+	}); err != nil {
+		log.Err("Failed to clean up after the failed restore: ", err)
+	}
+}
+
 func (r *RestoreJob) startContainer(ctx context.Context, containerName string, containerConfig *container.Config) (string, error) {
 	hostConfig, err := cont.BuildHostConfig(ctx, r.dockerClient, r.fsPool.DataDir(), r.CopyOptions.ContainerConfig)
 	if err != nil {
@@ -391,6 +476,10 @@ func (r *RestoreJob) getEnvironmentVariables(password string) []string {
 		"PGDATA=" + r.fsPool.DataDir(),
 	}...)
 
+	if provider, ok := r.restorer.(envProvider); ok {
+		envVariables = append(envVariables, provider.GetEnvVariables()...)
+	}
+
 	// Add user-defined environment variables.
 	for env, value := range r.Envs {
 		envVariables = append(envVariables, fmt.Sprintf("%s=%s", env, value))
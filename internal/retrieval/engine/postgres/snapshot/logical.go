@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types"
@@ -16,6 +17,7 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
 
 	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/databases/postgres/pgconfig"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/pool"
@@ -52,6 +54,13 @@ type LogicalInitial struct {
 	engineProps    global.EngineProps
 	dbMarker       *dbmarker.Marker
 	queryProcessor *queryProcessor
+	validator      *dataValidator
+	alerter        config.Alerter
+	listener       config.SnapshotListener
+	scheduler      *cron.Cron
+	schedulerCtx   context.Context
+	schedulerMu    sync.Mutex
+	snapshotMutex  sync.Mutex
 }
 
 // LogicalOptions describes options for a logical initialization job.
@@ -59,13 +68,16 @@ type LogicalOptions struct {
 	DataPatching        DataPatching      `yaml:"dataPatching"`
 	PreprocessingScript string            `yaml:"preprocessingScript"`
 	Configs             map[string]string `yaml:"configs"`
-	Schedule            Scheduler         `yaml:"schedule"`
+
+	// Schedule defines timetables of snapshots of data kept in sync by the logicalReplication job.
+	Schedule Scheduler `yaml:"schedule"`
 }
 
 // DataPatching allows executing queries to transform data before snapshot taking.
 type DataPatching struct {
 	DockerImage        string                 `yaml:"dockerImage"`
 	QueryPreprocessing QueryPreprocessing     `yaml:"queryPreprocessing"`
+	Validation         Validation             `yaml:"validation"`
 	ContainerConfig    map[string]interface{} `yaml:"containerConfig"`
 }
 
@@ -81,18 +93,14 @@ func NewLogicalInitialJob(cfg config.JobConfig, global *global.Config, enginePro
 		engineProps:  engineProps,
 		dbMarker:     cfg.Marker,
 		tm:           tm,
+		alerter:      cfg.Alerter,
+		listener:     cfg.SnapshotListener,
 	}
 
 	if err := li.Reload(cfg.Spec.Options); err != nil {
 		return nil, errors.Wrap(err, "failed to unmarshal configuration options")
 	}
 
-	if li.options.DataPatching.QueryPreprocessing.QueryPath != "" {
-		li.queryProcessor = newQueryProcessor(cfg.Docker, global.Database.Name(), global.Database.User(),
-			li.options.DataPatching.QueryPreprocessing.QueryPath,
-			li.options.DataPatching.QueryPreprocessing.MaxParallelWorkers)
-	}
-
 	return li, nil
 }
 
@@ -108,11 +116,58 @@ func (s *LogicalInitial) patchContainerName() string {
 
 // Reload reloads job configuration.
 func (s *LogicalInitial) Reload(cfg map[string]interface{}) (err error) {
-	return options.Unmarshal(cfg, &s.options)
+	// Options are unmarshaled from scratch, so options removed from the configuration do not survive reloading.
+	logicalOptions := LogicalOptions{}
+
+	if err := options.Unmarshal(cfg, &logicalOptions); err != nil {
+		return err
+	}
+
+	if err := validateTimetables(&logicalOptions.Schedule); err != nil {
+		return errors.Wrap(err, "invalid logicalSnapshot configuration")
+	}
+
+	if err := logicalOptions.DataPatching.QueryPreprocessing.Masking.validate(); err != nil {
+		return errors.Wrap(err, "invalid logicalSnapshot configuration")
+	}
+
+	if err := logicalOptions.DataPatching.Validation.validate(); err != nil {
+		return errors.Wrap(err, "invalid logicalSnapshot configuration")
+	}
+
+	s.options = logicalOptions
+
+	s.setupDataPatching()
+	s.reloadScheduler()
+
+	return nil
+}
+
+// setupDataPatching prepares query preprocessing and validation of data according to the current options.
+func (s *LogicalInitial) setupDataPatching() {
+	s.queryProcessor = nil
+	s.validator = nil
+
+	if s.options.DataPatching.QueryPreprocessing.isEnabled() {
+		s.queryProcessor = newQueryProcessor(s.dockerClient, s.globalCfg.Database.Name(), s.globalCfg.Database.User(),
+			s.options.DataPatching.QueryPreprocessing)
+	}
+
+	if s.options.DataPatching.Validation.isEnabled() {
+		s.validator = newDataValidator(s.dockerClient, s.globalCfg.Database.Name(), s.globalCfg.Database.User(),
+			s.options.DataPatching.Validation)
+	}
 }
 
 // Run starts the job.
 func (s *LogicalInitial) Run(ctx context.Context) error {
+	// Start scheduling after the initial snapshot.
+	defer s.startScheduling(ctx)
+
+	if syncContainerID, ok := s.getSyncInstance(ctx); ok {
+		return s.snapshotReplica(ctx, syncContainerID)
+	}
+
 	if s.options.PreprocessingScript != "" {
 		if err := runPreprocessingScript(s.options.PreprocessingScript); err != nil {
 			return err
@@ -136,9 +191,9 @@ func (s *LogicalInitial) Run(ctx context.Context) error {
 		return errors.Wrap(err, "failed to store PostgreSQL configs for the snapshot")
 	}
 
-	if s.queryProcessor != nil {
-		if err := s.runPreprocessingQueries(ctx, dataDir); err != nil {
-			return errors.Wrap(err, "failed to run preprocessing queries")
+	if s.queryProcessor != nil || s.validator != nil {
+		if err := s.patchData(ctx, dataDir, s.prepareData); err != nil {
+			return errors.Wrap(err, "failed to prepare data")
 		}
 	}
 
@@ -159,6 +214,7 @@ func (s *LogicalInitial) Run(ctx context.Context) error {
 	}
 
 	s.tm.SendEvent(ctx, telemetry.SnapshotCreatedEvent, telemetry.SnapshotCreated{})
+	notifySnapshotCreated(s.listener)
 
 	return nil
 }
@@ -188,7 +244,24 @@ func (s *LogicalInitial) touchConfigFiles() error {
 	return tools.TouchFile(path.Join(dataDir, "pg_hba.conf"))
 }
 
-func (s *LogicalInitial) runPreprocessingQueries(ctx context.Context, dataDir string) error {
+// prepareData runs preprocessing queries and validates the prepared data.
+func (s *LogicalInitial) prepareData(ctx context.Context, containerID string) error {
+	if s.queryProcessor != nil {
+		if err := s.queryProcessor.applyPreprocessingQueries(ctx, containerID); err != nil {
+			return errors.Wrap(err, "failed to run preprocessing queries")
+		}
+	}
+
+	if s.validator != nil {
+		return s.validator.validateData(ctx, containerID)
+	}
+
+	return nil
+}
+
+// patchData runs a patch container on the data directory and applies the patch to the running instance.
+func (s *LogicalInitial) patchData(ctx context.Context, dataDir string, patch func(ctx context.Context, containerID string) error) (
+	err error) {
 	pgVersion, err := tools.DetectPGVersion(dataDir)
 	if err != nil {
 		return errors.Wrap(err, "failed to detect the Postgres version")
@@ -208,7 +281,7 @@ func (s *LogicalInitial) runPreprocessingQueries(ctx context.Context, dataDir st
 		return errors.Wrap(err, "failed to generate PostgreSQL password")
 	}
 
-	hostConfig, err := cont.BuildHostConfig(ctx, s.dockerClient, s.fsPool.DataDir(), s.options.DataPatching.ContainerConfig)
+	hostConfig, err := cont.BuildHostConfig(ctx, s.dockerClient, dataDir, s.options.DataPatching.ContainerConfig)
 	if err != nil {
 		return errors.Wrap(err, "failed to build container host config")
 	}
@@ -247,11 +320,7 @@ func (s *LogicalInitial) runPreprocessingQueries(ctx context.Context, dataDir st
 		return errors.Wrap(err, "failed to readiness check")
 	}
 
-	if err := s.queryProcessor.applyPreprocessingQueries(ctx, patchCont.ID); err != nil {
-		return errors.Wrap(err, "failed to run preprocessing queries")
-	}
-
-	return nil
+	return patch(ctx, patchCont.ID)
 }
 
 func (s *LogicalInitial) buildContainerConfig(clonePath, patchImage, password string) *container.Config {
@@ -0,0 +1,348 @@
+/*
+2022 © Postgres.ai
+*/
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/databases/postgres/pgconfig"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/cont"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/defaults"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/telemetry"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+)
+
+const (
+	// fieldSeparator separates fields of psql output. It cannot be a part of object names.
+	fieldSeparator = "\x1f"
+
+	subscriptionFields = 2
+
+	subscriptionsQuery = "select s.subname, d.datname from pg_subscription s join pg_database d on d.oid = s.subdbid order by 2, 1"
+)
+
+// subscription describes a subscription of the sync instance.
+type subscription struct {
+	name     string
+	database string
+}
+
+// Resume starts scheduled snapshots when the engine starts without a data refresh.
+func (s *LogicalInitial) Resume(ctx context.Context) error {
+	s.startScheduling(ctx)
+
+	return nil
+}
+
+func (s *LogicalInitial) hasSchedulingOptions() bool {
+	return s.options.Schedule.Snapshot.Timetable != "" || s.options.Schedule.Retention.Timetable != ""
+}
+
+// startScheduling binds scheduled snapshots to the context of the current run and stops them when the context is done.
+func (s *LogicalInitial) startScheduling(ctx context.Context) {
+	s.schedulerMu.Lock()
+	s.schedulerCtx = ctx
+	s.schedulerMu.Unlock()
+
+	s.reloadScheduler()
+
+	go func() {
+		<-ctx.Done()
+
+		s.schedulerMu.Lock()
+		defer s.schedulerMu.Unlock()
+
+		// A newer run has taken over the scheduler.
+		if s.schedulerCtx != ctx || s.scheduler == nil {
+			return
+		}
+
+		log.Msg("Stop snapshot scheduler")
+		s.scheduler.Stop()
+	}()
+}
+
+// reloadScheduler replaces scheduled entries according to the current options.
+func (s *LogicalInitial) reloadScheduler() {
+	s.schedulerMu.Lock()
+	defer s.schedulerMu.Unlock()
+
+	if s.schedulerCtx == nil || s.schedulerCtx.Err() != nil {
+		return
+	}
+
+	if s.scheduler == nil {
+		if !s.hasSchedulingOptions() {
+			return
+		}
+
+		s.scheduler = cron.New()
+	}
+
+	s.scheduler.Stop()
+
+	for _, ent := range s.scheduler.Entries() {
+		s.scheduler.Remove(ent.ID)
+	}
+
+	s.startScheduler(s.schedulerCtx)
+}
+
+func (s *LogicalInitial) startScheduler(ctx context.Context) {
+	if s.scheduler == nil || !s.hasSchedulingOptions() {
+		return
+	}
+
+	if s.options.Schedule.Snapshot.Timetable != "" {
+		if _, err := s.scheduler.AddFunc(s.options.Schedule.Snapshot.Timetable, s.runAutoSnapshot(ctx)); err != nil {
+			log.Err(errors.Wrap(err, "failed to schedule a new snapshot job"))
+			return
+		}
+	}
+
+	if s.options.Schedule.Retention.Timetable != "" {
+		if _, err := s.scheduler.AddFunc(s.options.Schedule.Retention.Timetable,
+			s.runAutoCleanup(ctx, s.options.Schedule.Retention.Limit)); err != nil {
+			log.Err(errors.Wrap(err, "failed to schedule a new cleanup job"))
+			return
+		}
+	}
+
+	s.scheduler.Start()
+
+	log.Msg("Snapshot scheduler has been started")
+}
+
+func (s *LogicalInitial) runAutoSnapshot(ctx context.Context) func() {
+	return func() {
+		syncContainerID, ok := s.getSyncInstance(ctx)
+		if !ok {
+			log.Msg("Skip taking a snapshot automatically: sync instance is not running")
+			return
+		}
+
+		if err := s.snapshotReplica(ctx, syncContainerID); err != nil {
+			log.Err(errors.Wrap(err, "failed to take a snapshot automatically"))
+			reportSnapshotFailure(ctx, s.alerter, err)
+		}
+	}
+}
+
+func (s *LogicalInitial) runAutoCleanup(ctx context.Context, retentionLimit int) func() {
+	return func() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if _, err := s.cloneManager.CleanupSnapshots(retentionLimit); err != nil {
+			log.Err(errors.Wrap(err, "failed to clean up snapshots automatically"))
+		}
+	}
+}
+
+func (s *LogicalInitial) syncInstanceName() string {
+	return cont.SyncInstanceContainerPrefix + s.engineProps.InstanceID
+}
+
+// getSyncInstance returns the ID of the sync instance if it keeps data in sync with the source by the logical replication.
+func (s *LogicalInitial) getSyncInstance(ctx context.Context) (string, bool) {
+	syncContainer, err := s.dockerClient.ContainerInspect(ctx, s.syncInstanceName())
+	if err != nil || syncContainer.State == nil || !syncContainer.State.Running {
+		return "", false
+	}
+
+	return syncContainer.ID, true
+}
+
+// snapshotReplica takes a snapshot of data kept in sync by the logical replication.
+//
+// Subscriptions are paused to take a pre-snapshot of the sync instance. Then subscriptions are dropped in a clone
+// of the pre-snapshot, so clones never connect to the source, and the prepared clone is snapshotted.
+func (s *LogicalInitial) snapshotReplica(ctx context.Context, syncContainerID string) (err error) {
+	s.snapshotMutex.Lock()
+	defer s.snapshotMutex.Unlock()
+
+	subscriptions, err := s.listSubscriptions(ctx, syncContainerID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list subscriptions of the sync instance")
+	}
+
+	if len(subscriptions) == 0 {
+		return errors.New("no subscriptions found in the sync instance")
+	}
+
+	preSnapshot, dataStateAt, err := s.takePreSnapshot(ctx, syncContainerID, subscriptions)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if errDestroy := s.cloneManager.DestroySnapshot(preSnapshot); errDestroy != nil {
+				log.Err(fmt.Sprintf("Failed to destroy the %q snapshot: %v", preSnapshot, errDestroy))
+			}
+		}
+	}()
+
+	cloneName := fmt.Sprintf("clone%s_%s", pre, dataStateAt)
+
+	if err := s.cloneManager.CreateClone(cloneName, preSnapshot); err != nil {
+		return errors.Wrapf(err, "failed to create \"pre\" clone %s", cloneName)
+	}
+
+	defer func() {
+		if err != nil {
+			if errDestroy := s.cloneManager.DestroyClone(cloneName); errDestroy != nil {
+				log.Err(fmt.Sprintf("Failed to destroy clone %q: %v", cloneName, errDestroy))
+			}
+		}
+	}()
+
+	clonePath := path.Join(s.fsPool.ClonesDir(), cloneName, s.fsPool.DataSubDir)
+
+	// The preprocessing script runs before data patching as in the initial run, so the validation checks transformed data.
+	if s.options.PreprocessingScript != "" {
+		if err := runPreprocessingScript(s.options.PreprocessingScript); err != nil {
+			return err
+		}
+	}
+
+	cfgManager, err := pgconfig.NewCorrector(clonePath)
+	if err != nil {
+		return errors.Wrap(err, "failed to create a config manager")
+	}
+
+	if err := cfgManager.ApplySnapshot(s.options.Configs); err != nil {
+		return errors.Wrap(err, "failed to store PostgreSQL configs for the snapshot")
+	}
+
+	if err := s.patchData(ctx, clonePath, func(ctx context.Context, containerID string) error {
+		if err := s.dropSubscriptions(ctx, containerID, subscriptions); err != nil {
+			return errors.Wrap(err, "failed to drop subscriptions")
+		}
+
+		return s.prepareData(ctx, containerID)
+	}); err != nil {
+		return errors.Wrap(err, "failed to prepare the clone")
+	}
+
+	snapshotName, err := s.cloneManager.CreateSnapshot(cloneName, dataStateAt)
+	if err != nil {
+		return errors.Wrap(err, "failed to create a snapshot")
+	}
+
+	s.tm.SendEvent(ctx, telemetry.SnapshotCreatedEvent, telemetry.SnapshotCreated{})
+	notifySnapshotCreated(s.listener)
+
+	log.Msg("Snapshot of replicated data has been created: ", snapshotName)
+
+	return nil
+}
+
+// takePreSnapshot pauses subscriptions of the sync instance and snapshots its data.
+func (s *LogicalInitial) takePreSnapshot(ctx context.Context, syncContainerID string, subscriptions []subscription) (
+	preSnapshot, dataStateAt string, err error) {
+	if err := s.alterSubscriptions(ctx, syncContainerID, subscriptions, "disable"); err != nil {
+		return "", "", errors.Wrap(err, "failed to pause subscriptions")
+	}
+
+	defer func() {
+		if errEnable := s.alterSubscriptions(ctx, syncContainerID, subscriptions, "enable"); errEnable != nil {
+			log.Err("Failed to resume subscriptions of the sync instance:", errEnable)
+		}
+	}()
+
+	if _, err := s.querySubscriber(ctx, syncContainerID, s.globalCfg.Database.Name(), "checkpoint"); err != nil {
+		return "", "", errors.Wrap(err, "failed to make a checkpoint for sync instance")
+	}
+
+	// Changes are applied continuously, so data are as of the moment when subscriptions have been paused.
+	dataStateAt = time.Now().Format(tools.DataStateAtFormat)
+
+	preSnapshot, err = s.cloneManager.CreateSnapshot("", dataStateAt+pre)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to create a snapshot")
+	}
+
+	return preSnapshot, dataStateAt, nil
+}
+
+func (s *LogicalInitial) listSubscriptions(ctx context.Context, containerID string) ([]subscription, error) {
+	output, err := s.querySubscriber(ctx, containerID, s.globalCfg.Database.Name(), subscriptionsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSubscriptions(output)
+}
+
+func (s *LogicalInitial) alterSubscriptions(ctx context.Context, containerID string, subscriptions []subscription, action string) error {
+	for _, sub := range subscriptions {
+		if _, err := s.querySubscriber(ctx, containerID, sub.database,
+			fmt.Sprintf("alter subscription %s %s", pgx.Identifier{sub.name}.Sanitize(), action)); err != nil {
+			return errors.Wrapf(err, "failed to %s the subscription %q", action, sub.name)
+		}
+	}
+
+	return nil
+}
+
+// dropSubscriptions drops subscriptions without dropping their replication slots on the source.
+func (s *LogicalInitial) dropSubscriptions(ctx context.Context, containerID string, subscriptions []subscription) error {
+	for _, sub := range subscriptions {
+		subscriptionName := pgx.Identifier{sub.name}.Sanitize()
+
+		// DROP SUBSCRIPTION cannot run in a transaction block with ALTER SUBSCRIPTION, so the queries are sent separately.
+		for _, query := range []string{
+			fmt.Sprintf("alter subscription %s set (slot_name = none)", subscriptionName),
+			fmt.Sprintf("drop subscription %s", subscriptionName),
+		} {
+			if _, err := s.querySubscriber(ctx, containerID, sub.database, query); err != nil {
+				return errors.Wrapf(err, "failed to drop the subscription %q", sub.name)
+			}
+		}
+
+		log.Dbg(fmt.Sprintf("Subscription %q has been dropped in the database %s", sub.name, sub.database))
+	}
+
+	return nil
+}
+
+func (s *LogicalInitial) querySubscriber(ctx context.Context, containerID, dbName, query string) (string, error) {
+	return tools.ExecCommandWithOutput(ctx, s.dockerClient, containerID, types.ExecConfig{
+		Cmd:  []string{"psql", "-U", s.globalCfg.Database.User(), "-d", dbName, "-XAt", "-F", fieldSeparator, "-c", query},
+		User: defaults.Username,
+	})
+}
+
+// parseSubscriptions parses psql output of the subscriptions query.
+func parseSubscriptions(output string) ([]subscription, error) {
+	subscriptions := []subscription{}
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Split(line, fieldSeparator)
+		if len(fields) != subscriptionFields {
+			return nil, errors.Errorf("unexpected subscription definition: %q", line)
+		}
+
+		subscriptions = append(subscriptions, subscription{name: fields[0], database: fields[1]})
+	}
+
+	return subscriptions, nil
+}
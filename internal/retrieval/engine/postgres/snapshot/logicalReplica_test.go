@@ -0,0 +1,76 @@
+package snapshot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/config/global"
+)
+
+func TestParseSubscriptions(t *testing.T) {
+	subscriptions, err := parseSubscriptions("dblab_test\x1fapp\ndblab_test\x1fanalytics\n")
+	require.NoError(t, err)
+
+	assert.Equal(t, []subscription{{name: "dblab_test", database: "app"}, {name: "dblab_test", database: "analytics"}}, subscriptions)
+
+	subscriptions, err = parseSubscriptions("")
+	require.NoError(t, err)
+	assert.Empty(t, subscriptions)
+
+	_, err = parseSubscriptions("dblab_test")
+	assert.Error(t, err)
+}
+
+func TestLogicalScheduler(t *testing.T) {
+	s := &LogicalInitial{}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	defer cancelFirst()
+
+	s.startScheduling(firstCtx)
+	assert.Nil(t, s.scheduler)
+
+	// A schedule added by reloading the configuration has to be started.
+	require.NoError(t, s.Reload(map[string]interface{}{
+		"schedule": map[string]interface{}{"snapshot": map[string]interface{}{"timetable": "0 */6 * * *"}},
+	}))
+	require.NotNil(t, s.scheduler)
+	assert.Len(t, s.scheduler.Entries(), 1)
+
+	// Each run replaces entries of the previous one.
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	defer cancelSecond()
+
+	cancelFirst()
+	s.startScheduling(secondCtx)
+	assert.Len(t, s.scheduler.Entries(), 1)
+
+	s.reloadScheduler()
+	assert.Len(t, s.scheduler.Entries(), 1)
+}
+
+func TestLogicalReloadDataPatching(t *testing.T) {
+	s := &LogicalInitial{globalCfg: &global.Config{Database: global.Database{Username: "postgres", DBName: "test"}}}
+
+	require.NoError(t, s.Reload(map[string]interface{}{
+		"dataPatching": map[string]interface{}{
+			"queryPreprocessing": map[string]interface{}{
+				"masking": map[string]interface{}{
+					"rules": []interface{}{map[string]interface{}{"table": "public.users", "column": "notes", "method": "nullify"}},
+				},
+			},
+			"validation": map[string]interface{}{"amcheck": map[string]interface{}{"enabled": true}},
+		},
+	}))
+	require.NotNil(t, s.queryProcessor)
+	require.NotNil(t, s.validator)
+	assert.Len(t, s.queryProcessor.masking.Rules, 1)
+
+	// Reloaded rules replace the previous ones.
+	require.NoError(t, s.Reload(map[string]interface{}{}))
+	assert.Nil(t, s.queryProcessor)
+	assert.Nil(t, s.validator)
+}
@@ -0,0 +1,512 @@
+/*
+2022 © Postgres.ai
+*/
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+)
+
+// Masking methods.
+const (
+	fakerMethod       = "faker"
+	hashMethod        = "hash"
+	nullifyMethod     = "nullify"
+	shuffleMethod     = "shuffle"
+	partialMaskMethod = "partialMask"
+)
+
+// Kinds of fake values.
+const (
+	fakeFirstName = "firstName"
+	fakeLastName  = "lastName"
+	fakeFullName  = "fullName"
+	fakeEmail     = "email"
+	fakePhone     = "phone"
+	fakeText      = "text"
+)
+
+const (
+	piiColumnFields = 3
+
+	// piiColumnsQuery lists columns of user tables.
+	piiColumnsQuery = `select n.nspname, c.relname, a.attname
+from pg_attribute a
+join pg_class c on c.oid = a.attrelid
+join pg_namespace n on n.oid = c.relnamespace
+where c.relkind in ('r', 'p') and a.attnum > 0 and not a.attisdropped
+  and n.nspname not in ('pg_catalog', 'information_schema') and n.nspname !~ '^pg_toast'
+order by 1, 2, a.attnum`
+
+	defaultMaskChar = "*"
+)
+
+var (
+	// defaultPIIPatterns match names of columns that likely contain personally identifiable information.
+	defaultPIIPatterns = []string{
+		`e_?mail`,
+		`phone|mobile`,
+		`(first|last|middle|full|sur)_?name`,
+		`address|street|zip_?code|postal`,
+		`birth|^dob$`,
+		`ssn|social_?security|passport|tax_?id|national_?id`,
+		`card_?number|credit_?card|iban|account_?number`,
+		`password|passwd|secret|token`,
+		`ip_?addr`,
+	}
+
+	firstNames = []string{"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda", "David", "Elizabeth"}
+	lastNames  = []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez"}
+)
+
+// Masking defines declarative rules to mask sensitive data before taking a snapshot.
+type Masking struct {
+	Rules     []MaskingRule `yaml:"rules"`
+	PIIReport PIIReport     `yaml:"piiReport"`
+}
+
+// MaskingRule defines how to mask a column.
+type MaskingRule struct {
+	// Database contains the table. Default: the database of the global section.
+	Database string `yaml:"database"`
+
+	// Table is a schema-qualified table name.
+	Table  string `yaml:"table"`
+	Column string `yaml:"column"`
+
+	// Method is one of "faker", "hash", "nullify", "shuffle", "partialMask".
+	Method string `yaml:"method"`
+
+	// Faker defines the kind of fake values: "firstName", "lastName", "fullName", "email", "phone", "text".
+	Faker string `yaml:"faker"`
+
+	// Salt is added to values before hashing. It is required by the hash method and by fake emails and texts,
+	// which are derived from hashes of original values. Other fake values also depend on it if set.
+	// Without a secret salt, hashed values can be matched against hashes of guessed values.
+	Salt string `yaml:"salt"`
+
+	// KeepFirst and KeepLast define the number of characters kept by the partial mask.
+	KeepFirst int    `yaml:"keepFirst"`
+	KeepLast  int    `yaml:"keepLast"`
+	MaskChar  string `yaml:"maskChar"`
+}
+
+// PIIReport defines the discovery of unmasked columns that likely contain personally identifiable information.
+type PIIReport struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Patterns are additional regular expressions matching column names. They are case-insensitive.
+	Patterns []string `yaml:"patterns"`
+
+	// Ignore lists columns that are known to be safe in the "schema.table.column" format.
+	Ignore []string `yaml:"ignore"`
+
+	// FailOnUnmasked fails the snapshot creation when unmasked columns are found.
+	FailOnUnmasked bool `yaml:"failOnUnmasked"`
+}
+
+// piiColumn describes a column that likely contains personally identifiable information.
+type piiColumn struct {
+	schema string
+	table  string
+	column string
+}
+
+func (c piiColumn) String() string {
+	return c.schema + "." + c.table + "." + c.column
+}
+
+// isEnabled reports whether masking has to be applied.
+func (m Masking) isEnabled() bool {
+	return len(m.Rules) > 0 || m.PIIReport.Enabled
+}
+
+func (m Masking) validate() error {
+	for i, rule := range m.Rules {
+		if err := rule.validate(); err != nil {
+			return errors.Wrapf(err, "invalid masking rule #%d", i+1)
+		}
+	}
+
+	for _, pattern := range m.PIIReport.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return errors.Wrapf(err, "invalid PII pattern %q", pattern)
+		}
+	}
+
+	return nil
+}
+
+func (r MaskingRule) validate() error {
+	if _, _, ok := splitTableName(r.Table); !ok {
+		return errors.Errorf("table %q must be schema-qualified", r.Table)
+	}
+
+	if r.Column == "" {
+		return errors.New("column is required")
+	}
+
+	switch r.Method {
+	case fakerMethod:
+		switch r.Faker {
+		case fakeFirstName, fakeLastName, fakeFullName, fakePhone:
+		case fakeEmail, fakeText:
+			if r.Salt == "" {
+				return errors.Errorf("salt is required by fake values of the %q kind", r.Faker)
+			}
+
+		default:
+			return errors.Errorf("unknown kind of fake values %q", r.Faker)
+		}
+
+	case partialMaskMethod:
+		if r.KeepFirst < 0 || r.KeepLast < 0 {
+			return errors.New("the number of kept characters cannot be negative")
+		}
+
+		if len([]rune(r.MaskChar)) > 1 {
+			return errors.Errorf("mask character %q must be a single character", r.MaskChar)
+		}
+
+	case hashMethod:
+		if r.Salt == "" {
+			return errors.New("salt is required by the hash method")
+		}
+
+	case nullifyMethod, shuffleMethod:
+
+	default:
+		return errors.Errorf("unknown masking method %q", r.Method)
+	}
+
+	return nil
+}
+
+// splitTableName splits the schema-qualified table name.
+func splitTableName(table string) (string, string, bool) {
+	parts := strings.SplitN(table, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+func (r MaskingRule) tableIdentifier() string {
+	schemaName, tableName, _ := splitTableName(r.Table)
+
+	return pgx.Identifier{schemaName, tableName}.Sanitize()
+}
+
+// buildQuery builds the query that masks the column.
+func (r MaskingRule) buildQuery() string {
+	table := r.tableIdentifier()
+	column := pgx.Identifier{r.Column}.Sanitize()
+
+	switch r.Method {
+	case nullifyMethod:
+		return fmt.Sprintf("update %s set %s = null where %s is not null", table, column, column)
+
+	case hashMethod:
+		return fmt.Sprintf("update %s set %s = md5(%s) where %s is not null", table, column, saltedValue(column, r.Salt), column)
+
+	case shuffleMethod:
+		// Values are permuted between rows, so the distribution of values is kept.
+		return fmt.Sprintf(`with shuffled as (
+  select ctid as row_id, row_number() over (order by random()) as n from %[1]s
+), original as (
+  select %[2]s as value, row_number() over () as n from %[1]s
+)
+update %[1]s t set %[2]s = o.value from shuffled s join original o using (n) where t.ctid = s.row_id`, table, column)
+
+	case partialMaskMethod:
+		maskChar := r.MaskChar
+		if maskChar == "" {
+			maskChar = defaultMaskChar
+		}
+
+		return fmt.Sprintf(`update %[1]s set %[2]s = case
+  when length(%[2]s::text) <= %[3]d then repeat(%[5]s, length(%[2]s::text))
+  else left(%[2]s::text, %[6]d) || repeat(%[5]s, length(%[2]s::text) - %[3]d) || right(%[2]s::text, %[4]d)
+end where %[2]s is not null`, table, column, r.KeepFirst+r.KeepLast, r.KeepLast, quoteLiteral(maskChar), r.KeepFirst)
+
+	default:
+		return fmt.Sprintf("update %s set %s = %s where %s is not null", table, column, fakeValue(r.Faker, saltedValue(column, r.Salt)), column)
+	}
+}
+
+// saltedValue returns the SQL expression of the text value of the column prefixed with the salt.
+func saltedValue(column, salt string) string {
+	if salt == "" {
+		return column + "::text"
+	}
+
+	return quoteLiteral(salt) + " || " + column + "::text"
+}
+
+// fakeValue returns the SQL expression of a fake value of the text value. Values are deterministic,
+// so equal values stay equal after masking.
+func fakeValue(kind, value string) string {
+	hash := fmt.Sprintf("abs(hashtext(%s)::bigint)", value)
+
+	switch kind {
+	case fakeFirstName:
+		return pickValue(firstNames, hash)
+
+	case fakeLastName:
+		return pickValue(lastNames, hash)
+
+	case fakeFullName:
+		return pickValue(firstNames, hash) + " || ' ' || " + pickValue(lastNames, fmt.Sprintf("(%s / %d)", hash, len(firstNames)))
+
+	case fakeEmail:
+		return fmt.Sprintf("'user_' || left(md5(%s), 12) || '@example.com'", value)
+
+	case fakePhone:
+		return fmt.Sprintf("'+1555' || lpad((%s %% 10000000)::text, 7, '0')", hash)
+
+	default:
+		return fmt.Sprintf("'Lorem ipsum ' || left(md5(%s), 8)", value)
+	}
+}
+
+func pickValue(values []string, hash string) string {
+	literals := make([]string, 0, len(values))
+
+	for _, value := range values {
+		literals = append(literals, quoteLiteral(value))
+	}
+
+	return fmt.Sprintf("(array[%s])[1 + %s %% %d]", strings.Join(literals, ", "), hash, len(values))
+}
+
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// applyMasking masks data by rules and reports unmasked columns that likely contain personally identifiable information.
+func (q *queryProcessor) applyMasking(ctx context.Context, containerID string) error {
+	for _, rule := range q.masking.Rules {
+		dbName := q.ruleDatabase(rule)
+
+		log.Msg(fmt.Sprintf("Masking the column %s.%s in the database %s by the %s method", rule.Table, rule.Column, dbName, rule.Method))
+
+		if _, err := q.runQuery(ctx, containerID, dbName, rule.buildQuery()); err != nil {
+			return errors.Wrapf(err, "failed to mask the column %s.%s", rule.Table, rule.Column)
+		}
+	}
+
+	if err := q.rewriteMaskedTables(ctx, containerID); err != nil {
+		return err
+	}
+
+	if !q.masking.PIIReport.Enabled {
+		return nil
+	}
+
+	return q.reportPII(ctx, containerID)
+}
+
+// maskedTable describes a table masked by rules.
+type maskedTable struct {
+	dbName string
+	table  string
+}
+
+// maskedTables returns tables masked by the rules in the order of the rules.
+func (q *queryProcessor) maskedTables() []maskedTable {
+	tables := []maskedTable{}
+	seen := make(map[maskedTable]struct{})
+
+	for _, rule := range q.masking.Rules {
+		table := maskedTable{dbName: q.ruleDatabase(rule), table: rule.tableIdentifier()}
+
+		if _, ok := seen[table]; ok {
+			continue
+		}
+
+		seen[table] = struct{}{}
+		tables = append(tables, table)
+	}
+
+	return tables
+}
+
+// rewriteMaskedTables rewrites masked tables and makes a checkpoint.
+// Masking updates leave original values in dead tuples, and the snapshot must not keep them.
+func (q *queryProcessor) rewriteMaskedTables(ctx context.Context, containerID string) error {
+	tables := q.maskedTables()
+	if len(tables) == 0 {
+		return nil
+	}
+
+	for _, table := range tables {
+		log.Msg(fmt.Sprintf("Rewriting the masked table %s in the database %s", table.table, table.dbName))
+
+		if _, err := q.runQuery(ctx, containerID, table.dbName, "vacuum full "+table.table); err != nil {
+			return errors.Wrapf(err, "failed to rewrite the masked table %s", table.table)
+		}
+	}
+
+	// The checkpoint lets Postgres recycle WAL containing original values.
+	if _, err := q.runQuery(ctx, containerID, q.dbName, "checkpoint"); err != nil {
+		return errors.Wrap(err, "failed to make a checkpoint after masking")
+	}
+
+	return nil
+}
+
+func (q *queryProcessor) ruleDatabase(rule MaskingRule) string {
+	if rule.Database == "" {
+		return q.dbName
+	}
+
+	return rule.Database
+}
+
+// reportPII logs unmasked columns that likely contain personally identifiable information.
+func (q *queryProcessor) reportPII(ctx context.Context, containerID string) error {
+	patterns, err := compilePIIPatterns(q.masking.PIIReport.Patterns)
+	if err != nil {
+		return err
+	}
+
+	databases := []string{q.dbName}
+	masked := make(map[string]map[string]struct{})
+
+	for _, rule := range q.masking.Rules {
+		dbName := q.ruleDatabase(rule)
+
+		if _, ok := masked[dbName]; !ok {
+			masked[dbName] = make(map[string]struct{})
+
+			if dbName != q.dbName {
+				databases = append(databases, dbName)
+			}
+		}
+
+		masked[dbName][rule.Table+"."+rule.Column] = struct{}{}
+	}
+
+	unmasked := []string{}
+
+	for _, dbName := range databases {
+		output, err := q.runQuery(ctx, containerID, dbName, piiColumnsQuery)
+		if err != nil {
+			return errors.Wrapf(err, "failed to list columns of the database %s", dbName)
+		}
+
+		columns, err := parsePIIColumns(output)
+		if err != nil {
+			return err
+		}
+
+		for _, column := range findUnmaskedColumns(columns, patterns, masked[dbName], q.masking.PIIReport.Ignore) {
+			unmasked = append(unmasked, dbName+": "+column.String())
+		}
+	}
+
+	if len(unmasked) == 0 {
+		log.Msg("PII report: no unmasked columns found")
+		return nil
+	}
+
+	sort.Strings(unmasked)
+
+	report := "unmasked columns likely containing personally identifiable information: " + strings.Join(unmasked, ", ")
+
+	if q.masking.PIIReport.FailOnUnmasked {
+		return errors.New(report)
+	}
+
+	log.Warn("PII report:", report)
+
+	return nil
+}
+
+func compilePIIPatterns(customPatterns []string) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(defaultPIIPatterns)+len(customPatterns))
+
+	allPatterns := make([]string, 0, len(defaultPIIPatterns)+len(customPatterns))
+	allPatterns = append(allPatterns, defaultPIIPatterns...)
+	allPatterns = append(allPatterns, customPatterns...)
+
+	for _, pattern := range allPatterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid PII pattern %q", pattern)
+		}
+
+		patterns = append(patterns, re)
+	}
+
+	return patterns, nil
+}
+
+// findUnmaskedColumns returns columns matching PII patterns that are neither masked nor ignored.
+func findUnmaskedColumns(columns []piiColumn, patterns []*regexp.Regexp, masked map[string]struct{}, ignore []string) []piiColumn {
+	ignored := make(map[string]struct{}, len(ignore))
+
+	for _, column := range ignore {
+		ignored[column] = struct{}{}
+	}
+
+	unmasked := []piiColumn{}
+
+	for _, column := range columns {
+		if _, ok := masked[column.String()]; ok {
+			continue
+		}
+
+		if _, ok := ignored[column.String()]; ok {
+			continue
+		}
+
+		for _, pattern := range patterns {
+			if pattern.MatchString(column.column) {
+				unmasked = append(unmasked, column)
+				break
+			}
+		}
+	}
+
+	return unmasked
+}
+
+// parsePIIColumns parses psql output of the PII columns query.
+func parsePIIColumns(output string) ([]piiColumn, error) {
+	columns := []piiColumn{}
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Split(line, fieldSeparator)
+		if len(fields) != piiColumnFields {
+			return nil, errors.Errorf("unexpected column definition: %q", line)
+		}
+
+		columns = append(columns, piiColumn{schema: fields[0], table: fields[1], column: fields[2]})
+	}
+
+	return columns, nil
+}
+
+func (q *queryProcessor) runQuery(ctx context.Context, containerID, dbName, query string) (string, error) {
+	return tools.ExecCommandWithOutput(ctx, q.docker, containerID, types.ExecConfig{
+		Cmd: []string{"psql", "-U", q.username, "-d", dbName, "-XAt", "-v", "ON_ERROR_STOP=1", "-F", fieldSeparator, "-c", query},
+	})
+}
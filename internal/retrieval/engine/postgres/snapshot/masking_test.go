@@ -0,0 +1,124 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskingValidation(t *testing.T) {
+	valid := Masking{
+		Rules: []MaskingRule{
+			{Table: "public.users", Column: "email", Method: fakerMethod, Faker: fakeEmail, Salt: "secret"},
+			{Table: "public.users", Column: "name", Method: fakerMethod, Faker: fakeFullName},
+			{Table: "public.users", Column: "ssn", Method: partialMaskMethod, KeepLast: 4, MaskChar: "#"},
+			{Table: "public.users", Column: "notes", Method: nullifyMethod},
+			{Table: "public.users", Column: "login", Method: hashMethod, Salt: "secret"},
+		},
+		PIIReport: PIIReport{Enabled: true, Patterns: []string{"^salary$"}},
+	}
+
+	require.NoError(t, valid.validate())
+	assert.True(t, valid.isEnabled())
+	assert.False(t, Masking{}.isEnabled())
+
+	invalidMaskings := []Masking{
+		{Rules: []MaskingRule{{Table: "users", Column: "email", Method: hashMethod, Salt: "secret"}}},
+		{Rules: []MaskingRule{{Table: "public.users", Method: hashMethod, Salt: "secret"}}},
+		{Rules: []MaskingRule{{Table: "public.users", Column: "login", Method: hashMethod}}},
+		{Rules: []MaskingRule{{Table: "public.users", Column: "email", Method: "encrypt"}}},
+		{Rules: []MaskingRule{{Table: "public.users", Column: "email", Method: fakerMethod, Faker: "company"}}},
+		{Rules: []MaskingRule{{Table: "public.users", Column: "email", Method: fakerMethod, Faker: fakeEmail}}},
+		{Rules: []MaskingRule{{Table: "public.users", Column: "notes", Method: fakerMethod, Faker: fakeText}}},
+		{Rules: []MaskingRule{{Table: "public.users", Column: "ssn", Method: partialMaskMethod, KeepFirst: -1}}},
+		{Rules: []MaskingRule{{Table: "public.users", Column: "ssn", Method: partialMaskMethod, MaskChar: "**"}}},
+		{PIIReport: PIIReport{Patterns: []string{"("}}},
+	}
+
+	for _, masking := range invalidMaskings {
+		assert.Error(t, masking.validate())
+	}
+}
+
+func TestMaskingQueries(t *testing.T) {
+	testCases := []struct {
+		rule  MaskingRule
+		query string
+	}{
+		{
+			rule:  MaskingRule{Table: "public.users", Column: "notes", Method: nullifyMethod},
+			query: `update "public"."users" set "notes" = null where "notes" is not null`,
+		},
+		{
+			rule:  MaskingRule{Table: "public.users", Column: "login", Method: hashMethod, Salt: "it's"},
+			query: `update "public"."users" set "login" = md5('it''s' || "login"::text) where "login" is not null`,
+		},
+		{
+			rule: MaskingRule{Table: "public.users", Column: "email", Method: fakerMethod, Faker: fakeEmail, Salt: "secret"},
+			query: `update "public"."users" set "email" = 'user_' || left(md5('secret' || "email"::text), 12) || '@example.com' ` +
+				`where "email" is not null`,
+		},
+		{
+			rule: MaskingRule{Table: "public.users", Column: "card", Method: partialMaskMethod, KeepFirst: 2, KeepLast: 4},
+			query: `update "public"."users" set "card" = case
+  when length("card"::text) <= 6 then repeat('*', length("card"::text))
+  else left("card"::text, 2) || repeat('*', length("card"::text) - 6) || right("card"::text, 4)
+end where "card" is not null`,
+		},
+		{
+			rule: MaskingRule{Table: "billing.Invoices", Column: "amount", Method: shuffleMethod},
+			query: `with shuffled as (
+  select ctid as row_id, row_number() over (order by random()) as n from "billing"."Invoices"
+), original as (
+  select "amount" as value, row_number() over () as n from "billing"."Invoices"
+)
+update "billing"."Invoices" t set "amount" = o.value from shuffled s join original o using (n) where t.ctid = s.row_id`,
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.query, tc.rule.buildQuery())
+	}
+
+	assert.Equal(t, `(array['Smith', 'Johnson', 'Williams', 'Brown', 'Jones', 'Garcia', 'Miller', 'Davis', 'Rodriguez', 'Martinez'])`+
+		`[1 + abs(hashtext("name"::text)::bigint) % 10]`, fakeValue(fakeLastName, `"name"::text`))
+	assert.Contains(t, fakeValue(fakeFullName, `"name"::text`), `(abs(hashtext("name"::text)::bigint) / 10) % 10]`)
+	assert.Equal(t, `'Lorem ipsum ' || left(md5('it''s' || "notes"::text), 8)`, fakeValue(fakeText, saltedValue(`"notes"`, "it's")))
+}
+
+func TestPIIReport(t *testing.T) {
+	columns, err := parsePIIColumns("public\x1fusers\x1fid\npublic\x1fusers\x1fEmail\npublic\x1fusers\x1fphone_number\n" +
+		"public\x1fusers\x1fsalary\npublic\x1forders\x1fshipping_address\npublic\x1forders\x1ftotal\n")
+	require.NoError(t, err)
+	require.Len(t, columns, 6)
+
+	patterns, err := compilePIIPatterns([]string{"^salary$"})
+	require.NoError(t, err)
+
+	masked := map[string]struct{}{"public.users.Email": {}}
+
+	unmasked := findUnmaskedColumns(columns, patterns, masked, []string{"public.orders.shipping_address"})
+	assert.Equal(t, []piiColumn{
+		{schema: "public", table: "users", column: "phone_number"},
+		{schema: "public", table: "users", column: "salary"},
+	}, unmasked)
+
+	_, err = parsePIIColumns("public\x1fusers\n")
+	assert.Error(t, err)
+}
+
+func TestMaskedTables(t *testing.T) {
+	q := &queryProcessor{dbName: "postgres", masking: Masking{Rules: []MaskingRule{
+		{Table: "public.users", Column: "email", Method: fakerMethod, Faker: fakeEmail, Salt: "secret"},
+		{Table: "billing.Invoices", Column: "amount", Method: shuffleMethod},
+		{Table: "public.users", Column: "notes", Method: nullifyMethod},
+		{Database: "crm", Table: "public.users", Column: "phone", Method: fakerMethod, Faker: fakePhone},
+	}}}
+
+	assert.Equal(t, []maskedTable{
+		{dbName: "postgres", table: `"public"."users"`},
+		{dbName: "postgres", table: `"billing"."Invoices"`},
+		{dbName: "crm", table: `"public"."users"`},
+	}, q.maskedTables())
+}
@@ -57,6 +57,10 @@ const (
 	targetActionOption   = "recovery_target_action"
 	promoteTargetAction  = "promote"
 
+	// Options of streaming replication from the source. They are removed, so the promote instance does not use the source.
+	primaryConnInfoOption = "primary_conninfo"
+	primarySlotNameOption = "primary_slot_name"
+
 	// WAL parsing constants.
 	walNameLen  = 24
 	pgVersion10 = 10
@@ -94,6 +98,9 @@ type PhysicalInitial struct {
 	schedulerCtx   context.Context
 	promotionMutex sync.Mutex
 	queryProcessor *queryProcessor
+	validator      *dataValidator
+	alerter        config.Alerter
+	listener       config.SnapshotListener
 	tm             *telemetry.Agent
 }
 
@@ -115,6 +122,7 @@ type Promotion struct {
 	ContainerConfig    map[string]interface{} `yaml:"containerConfig"`
 	HealthCheck        HealthCheck            `yaml:"healthCheck"`
 	QueryPreprocessing QueryPreprocessing     `yaml:"queryPreprocessing"`
+	Validation         Validation             `yaml:"validation"`
 	Configs            map[string]string      `yaml:"configs"`
 	Recovery           map[string]string      `yaml:"recovery"`
 }
@@ -141,6 +149,13 @@ type ScheduleSpec struct {
 type QueryPreprocessing struct {
 	QueryPath          string `yaml:"queryPath"`
 	MaxParallelWorkers int    `yaml:"maxParallelWorkers"`
+
+	// Masking masks data by declarative rules after preprocessing queries.
+	Masking Masking `yaml:"masking"`
+}
+
+func (q QueryPreprocessing) isEnabled() bool {
+	return q.QueryPath != "" || q.Masking.isEnabled()
 }
 
 // syncState defines state of a sync instance.
@@ -162,6 +177,8 @@ func NewPhysicalInitialJob(cfg config.JobConfig, global *global.Config, enginePr
 		dbMark:       &dbmarker.Config{DataType: dbmarker.PhysicalDataType},
 		dockerClient: cfg.Docker,
 		tm:           tm,
+		alerter:      cfg.Alerter,
+		listener:     cfg.SnapshotListener,
 	}
 
 	if err := p.loadConfig(cfg.Spec.Options); err != nil {
@@ -172,12 +189,7 @@ func NewPhysicalInitialJob(cfg config.JobConfig, global *global.Config, enginePr
 		return nil, errors.Wrap(err, "invalid physicalSnapshot configuration")
 	}
 
-	if p.options.Promotion.QueryPreprocessing.QueryPath != "" {
-		p.queryProcessor = newQueryProcessor(cfg.Docker, global.Database.Name(), global.Database.User(),
-			p.options.Promotion.QueryPreprocessing.QueryPath,
-			p.options.Promotion.QueryPreprocessing.MaxParallelWorkers)
-	}
-
+	p.setupPromotionProcessing()
 	p.setupScheduler()
 
 	return p, nil
@@ -191,6 +203,22 @@ func (p *PhysicalInitial) setupScheduler() {
 	p.scheduler = cron.New()
 }
 
+// setupPromotionProcessing prepares query preprocessing and validation of the promoted data according to the current options.
+func (p *PhysicalInitial) setupPromotionProcessing() {
+	p.queryProcessor = nil
+	p.validator = nil
+
+	if p.options.Promotion.QueryPreprocessing.isEnabled() {
+		p.queryProcessor = newQueryProcessor(p.dockerClient, p.globalCfg.Database.Name(), p.globalCfg.Database.User(),
+			p.options.Promotion.QueryPreprocessing)
+	}
+
+	if p.options.Promotion.Validation.isEnabled() {
+		p.validator = newDataValidator(p.dockerClient, p.globalCfg.Database.Name(), p.globalCfg.Database.User(),
+			p.options.Promotion.Validation)
+	}
+}
+
 func (p *PhysicalInitial) validateConfig() error {
 	notSupportedSysctls := []string{}
 
@@ -209,6 +237,18 @@ func (p *PhysicalInitial) validateConfig() error {
 		return err
 	}
 
+	if err := p.options.Promotion.QueryPreprocessing.Masking.validate(); err != nil {
+		return err
+	}
+
+	if err := p.options.Promotion.Validation.validate(); err != nil {
+		return err
+	}
+
+	if p.options.Promotion.Validation.isEnabled() && !p.options.Promotion.Enabled {
+		return errors.New("snapshot validation requires promotion to be enabled")
+	}
+
 	return nil
 }
 
@@ -222,14 +262,19 @@ func (p *PhysicalInitial) validateScheduler() error {
 		return nil
 	}
 
+	return validateTimetables(p.options.Scheduler)
+}
+
+// validateTimetables checks timetables of the snapshot scheduler.
+func validateTimetables(scheduler *Scheduler) error {
 	specParser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
 
-	if _, err := specParser.Parse(p.options.Scheduler.Snapshot.Timetable); p.options.Scheduler.Snapshot.Timetable != "" && err != nil {
-		return errors.Wrapf(err, "failed to parse schedule timetable %q", p.options.Scheduler.Snapshot.Timetable)
+	if _, err := specParser.Parse(scheduler.Snapshot.Timetable); scheduler.Snapshot.Timetable != "" && err != nil {
+		return errors.Wrapf(err, "failed to parse schedule timetable %q", scheduler.Snapshot.Timetable)
 	}
 
-	if _, err := specParser.Parse(p.options.Scheduler.Retention.Timetable); p.options.Scheduler.Retention.Timetable != "" && err != nil {
-		return errors.Wrapf(err, "failed to parse retention timetable %q", p.options.Scheduler.Retention.Timetable)
+	if _, err := specParser.Parse(scheduler.Retention.Timetable); scheduler.Retention.Timetable != "" && err != nil {
+		return errors.Wrapf(err, "failed to parse retention timetable %q", scheduler.Retention.Timetable)
 	}
 
 	return nil
@@ -246,16 +291,26 @@ func (p *PhysicalInitial) Reload(cfg map[string]interface{}) (err error) {
 		return errors.Wrap(err, "failed to load job config")
 	}
 
+	if err := p.validateConfig(); err != nil {
+		return errors.Wrap(err, "invalid physicalSnapshot configuration")
+	}
+
+	p.setupPromotionProcessing()
 	p.reloadScheduler()
 
 	return nil
 }
 
 func (p *PhysicalInitial) loadConfig(cfg map[string]interface{}) (err error) {
-	if err := options.Unmarshal(cfg, &p.options); err != nil {
+	// Options are unmarshaled from scratch, so options removed from the configuration do not survive reloading.
+	physicalOptions := PhysicalOptions{}
+
+	if err := options.Unmarshal(cfg, &physicalOptions); err != nil {
 		return errors.Wrap(err, "failed to unmarshal configuration options")
 	}
 
+	p.options = physicalOptions
+
 	return nil
 }
 
@@ -353,9 +408,11 @@ func (p *PhysicalInitial) run(ctx context.Context) (err error) {
 		}
 	}()
 
+	clonePath := path.Join(p.fsPool.ClonesDir(), cloneName, p.fsPool.DataSubDir)
+
 	// Promotion.
 	if p.options.Promotion.Enabled {
-		if err := p.promoteInstance(ctx, path.Join(p.fsPool.ClonesDir(), cloneName, p.fsPool.DataSubDir), syState); err != nil {
+		if err := p.promoteInstance(ctx, clonePath, syState, time.Time{}); err != nil {
 			return errors.Wrap(err, "failed to promote instance")
 		}
 	}
@@ -372,6 +429,13 @@ func (p *PhysicalInitial) run(ctx context.Context) (err error) {
 		return errors.Wrap(err, "failed to mark the prepared data")
 	}
 
+	// Validate the prepared data after all transformations.
+	if p.validator != nil {
+		if err := p.validateClone(ctx, clonePath); err != nil {
+			return err
+		}
+	}
+
 	// Create a snapshot.
 	if _, err := p.cloneManager.CreateSnapshot(cloneName, p.dbMark.DataStateAt); err != nil {
 		return errors.Wrap(err, "failed to create a snapshot")
@@ -380,6 +444,7 @@ func (p *PhysicalInitial) run(ctx context.Context) (err error) {
 	p.updateDataStateAt()
 
 	p.tm.SendEvent(ctx, telemetry.SnapshotCreatedEvent, telemetry.SnapshotCreated{})
+	notifySnapshotCreated(p.listener)
 
 	return nil
 }
@@ -456,6 +521,7 @@ func (p *PhysicalInitial) runAutoSnapshot(ctx context.Context) func() {
 	return func() {
 		if err := p.run(ctx); err != nil {
 			log.Err(errors.Wrap(err, "failed to take a snapshot automatically"))
+			reportSnapshotFailure(ctx, p.alerter, err)
 		}
 	}
 }
@@ -472,7 +538,9 @@ func (p *PhysicalInitial) promoteContainerName() string {
 	return promoteContainerPrefix + p.engineProps.InstanceID
 }
 
-func (p *PhysicalInitial) promoteInstance(ctx context.Context, clonePath string, syState syncState) (err error) {
+// promoteInstance promotes the Postgres instance of the clone.
+// If targetTime is set, WAL is replayed up to the target time, which becomes the dataStateAt of the promoted data.
+func (p *PhysicalInitial) promoteInstance(ctx context.Context, clonePath string, syState syncState, targetTime time.Time) (err error) {
 	p.promotionMutex.Lock()
 	defer p.promotionMutex.Unlock()
 
@@ -502,10 +570,14 @@ func (p *PhysicalInitial) promoteInstance(ctx context.Context, clonePath string,
 	recoveryConfig := make(map[string]string)
 
 	// Item 5. Remove a recovery file: https://gitlab.com/postgres-ai/database-lab/-/issues/236#note_513401256
-	if syState.Err != nil {
+	if syState.Err != nil || !targetTime.IsZero() {
 		recoveryConfig = buildRecoveryConfig(recoveryFileConfig, p.options.Promotion.Recovery)
 
-		if err := cfgManager.ApplyRecovery(recoveryFileConfig); err != nil {
+		if !targetTime.IsZero() {
+			setRecoveryTargetTime(recoveryConfig, targetTime)
+		}
+
+		if err := cfgManager.ApplyRecovery(recoveryConfig); err != nil {
 			return errors.Wrap(err, "failed to apply recovery configuration")
 		}
 	} else if err := cfgManager.RemoveRecoveryConfig(); err != nil {
@@ -562,11 +634,14 @@ func (p *PhysicalInitial) promoteInstance(ctx context.Context, clonePath string,
 
 	log.Msg(fmt.Sprintf("Running container: %s. ID: %v", p.promoteContainerName(), promoteCont.ID))
 
+	// Log files are compared with the start time with a second precision.
+	startedAt := time.Now().Truncate(time.Second)
+
 	if err := p.dockerClient.ContainerStart(ctx, promoteCont.ID, types.ContainerStartOptions{}); err != nil {
 		return errors.Wrap(err, "failed to start container")
 	}
 
-	if syState.DSA == "" {
+	if syState.DSA == "" && targetTime.IsZero() {
 		dsa, err := p.getDSAFromWAL(ctx, cfgManager.GetPgVersion(), promoteCont.ID, clonePath)
 		if err != nil {
 			log.Dbg("cannot extract DSA form WAL files: ", err)
@@ -610,8 +685,12 @@ func (p *PhysicalInitial) promoteInstance(ctx context.Context, clonePath string,
 		}
 	}
 
-	if err := p.markDSA(ctx, syState.DSA, promoteCont.ID, clonePath, cfgManager.GetPgVersion()); err != nil {
-		return errors.Wrap(err, "failed to mark dataStateAt")
+	if targetTime.IsZero() {
+		if err := p.markDSA(ctx, syState.DSA, promoteCont.ID, clonePath, cfgManager.GetPgVersion()); err != nil {
+			return errors.Wrap(err, "failed to mark dataStateAt")
+		}
+	} else if err := checkRecoveryTargetReached(path.Join(clonePath, "log"), startedAt); err != nil {
+		return err
 	}
 
 	if p.queryProcessor != nil {
@@ -650,6 +729,76 @@ func (p *PhysicalInitial) promoteInstance(ctx context.Context, clonePath string,
 	return nil
 }
 
+// validateClone starts the promoted data of the clone and validates them.
+func (p *PhysicalInitial) validateClone(ctx context.Context, clonePath string) (err error) {
+	p.promotionMutex.Lock()
+	defer p.promotionMutex.Unlock()
+
+	pgVersion, err := tools.DetectPGVersion(clonePath)
+	if err != nil {
+		return errors.Wrap(err, "failed to detect the Postgres version")
+	}
+
+	hostConfig, err := p.buildHostConfig(ctx, clonePath)
+	if err != nil {
+		return errors.Wrap(err, "failed to build container host config")
+	}
+
+	validationImage := p.options.Promotion.DockerImage
+	if validationImage == "" {
+		validationImage = fmt.Sprintf("postgresai/extended-postgres:%g", pgVersion)
+	}
+
+	if err := tools.PullImage(ctx, p.dockerClient, validationImage); err != nil {
+		return errors.Wrap(err, "failed to scan image pulling response")
+	}
+
+	pwd, err := tools.GeneratePassword()
+	if err != nil {
+		return errors.Wrap(err, "failed to generate PostgreSQL password")
+	}
+
+	validationCont, err := p.dockerClient.ContainerCreate(ctx,
+		p.buildContainerConfig(clonePath, validationImage, pwd, ""),
+		hostConfig,
+		&network.NetworkingConfig{},
+		nil,
+		p.promoteContainerName(),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create container")
+	}
+
+	defer tools.RemoveContainer(ctx, p.dockerClient, validationCont.ID, cont.StopPhysicalTimeout)
+
+	defer func() {
+		if err != nil {
+			tools.PrintContainerLogs(ctx, p.dockerClient, p.promoteContainerName())
+		}
+	}()
+
+	log.Msg(fmt.Sprintf("Running container: %s. ID: %v", p.promoteContainerName(), validationCont.ID))
+
+	if err := p.dockerClient.ContainerStart(ctx, validationCont.ID, types.ContainerStartOptions{}); err != nil {
+		return errors.Wrap(err, "failed to start container")
+	}
+
+	if err := tools.CheckContainerReadiness(ctx, p.dockerClient, validationCont.ID); err != nil {
+		return errors.Wrap(err, "failed to readiness check")
+	}
+
+	if err := p.validator.validateData(ctx, validationCont.ID); err != nil {
+		return err
+	}
+
+	if err := tools.StopPostgres(ctx, p.dockerClient, validationCont.ID, clonePath, tools.DefaultStopTimeout); err != nil {
+		log.Msg("Failed to stop Postgres", err)
+		tools.PrintContainerLogs(ctx, p.dockerClient, validationCont.ID)
+	}
+
+	return nil
+}
+
 func (p *PhysicalInitial) getDSAFromWAL(ctx context.Context, pgVersion float64, containerID, cloneDir string) (string, error) {
 	log.Dbg(cloneDir)
 
@@ -755,9 +904,14 @@ func parseWALLine(line string) string {
 	return parsedDate.Format(tools.DataStateAtFormat)
 }
 
+// buildRecoveryConfig builds a recovery config of the promote instance.
+// Replication options of the sync instance are dropped because the replication slot on the source belongs to the sync instance.
 func buildRecoveryConfig(fileConfig, userRecoveryConfig map[string]string) map[string]string {
 	recoveryConf := fileConfig
 
+	delete(recoveryConf, primaryConnInfoOption)
+	delete(recoveryConf, primarySlotNameOption)
+
 	if rc, ok := fileConfig[restoreCommandOption]; ok || rc != "" {
 		for k, v := range defaultRecoveryCfg {
 			recoveryConf[k] = v
@@ -164,3 +164,20 @@ func TestParsingWalLine(t *testing.T) {
 		assert.EqualValues(t, tc.expectedDataStateAt, dsa)
 	}
 }
+
+func TestBuildRecoveryConfig(t *testing.T) {
+	fileConfig := map[string]string{
+		"primary_conninfo":  "host=source user=replicator password=secret",
+		"primary_slot_name": "dblab_slot",
+		"restore_command":   "wal-g wal-fetch %f %p",
+	}
+
+	recoveryConfig := buildRecoveryConfig(fileConfig, map[string]string{"recovery_target_timeline": "latest"})
+
+	assert.Equal(t, map[string]string{
+		"restore_command":          "wal-g wal-fetch %f %p",
+		"recovery_target":          "immediate",
+		"recovery_target_action":   "promote",
+		"recovery_target_timeline": "latest",
+	}, recoveryConfig)
+}
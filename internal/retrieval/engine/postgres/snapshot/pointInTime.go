@@ -0,0 +1,215 @@
+/*
+2022 © Postgres.ai
+*/
+
+package snapshot
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/telemetry"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/util"
+)
+
+const (
+	pointInTimeClonePrefix = "clone_pitr_"
+
+	recoveryTargetTimeOption = "recovery_target_time"
+	recoveryTargetTimeLayout = "2006-01-02 15:04:05.999999-07:00"
+
+	// recoveryStopMarker starts the message that Postgres logs when recovery stops at the recovery target.
+	recoveryStopMarker = "recovery stopping "
+)
+
+// recoveryTargetOptions lists mutually exclusive options that define a recovery target.
+var recoveryTargetOptions = []string{
+	"recovery_target",
+	"recovery_target_lsn",
+	"recovery_target_name",
+	"recovery_target_time",
+	"recovery_target_xid",
+}
+
+// preSnapshotLister describes a thin-clone manager that provides pre-snapshots.
+type preSnapshotLister interface {
+	GetPreSnapshots() ([]resources.Snapshot, error)
+}
+
+// CreatePointInTimeSnapshot creates a snapshot with data as of the target time.
+//
+// Published snapshots are promoted and cannot replay WAL anymore, so the nearest earlier pre-snapshot
+// is cloned, WAL is replayed up to the target time and the instance is promoted.
+// It fails if the available WAL ends before the target time.
+func (p *PhysicalInitial) CreatePointInTimeSnapshot(ctx context.Context, targetTime time.Time) (snapshotName string, err error) {
+	if !p.options.Promotion.Enabled {
+		return "", errors.New("promotion must be enabled to create point-in-time snapshots")
+	}
+
+	if targetTime.After(time.Now()) {
+		return "", errors.Errorf("target time %s is in the future", targetTime.Format(time.RFC3339))
+	}
+
+	lister, ok := p.cloneManager.(preSnapshotLister)
+	if !ok {
+		return "", errors.New("point-in-time snapshots are not supported by the thin-clone manager")
+	}
+
+	preSnapshots, err := lister.GetPreSnapshots()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get pre-snapshots")
+	}
+
+	baseSnapshot, err := findBaseSnapshot(preSnapshots, targetTime)
+	if err != nil {
+		return "", err
+	}
+
+	dataStateAt := targetTime.UTC().Format(util.DataStateAtFormat)
+	cloneName := pointInTimeClonePrefix + dataStateAt
+
+	log.Msg(fmt.Sprintf("Create a point-in-time snapshot as of %s from the %q pre-snapshot", dataStateAt, baseSnapshot.ID))
+
+	if err := p.cloneManager.CreateClone(cloneName, baseSnapshot.ID); err != nil {
+		return "", errors.Wrapf(err, "failed to create clone %s", cloneName)
+	}
+
+	defer func() {
+		if err != nil {
+			if errDestroy := p.cloneManager.DestroyClone(cloneName); errDestroy != nil {
+				log.Err(fmt.Sprintf("Failed to destroy clone %q: %v", cloneName, errDestroy))
+			}
+		}
+	}()
+
+	clonePath := path.Join(p.fsPool.ClonesDir(), cloneName, p.fsPool.DataSubDir)
+
+	if err := p.promoteInstance(ctx, clonePath, syncState{DSA: dataStateAt}, targetTime); err != nil {
+		return "", errors.Wrap(err, "failed to promote instance")
+	}
+
+	if p.options.PreprocessingScript != "" {
+		if err := runPreprocessingScript(p.options.PreprocessingScript); err != nil {
+			return "", err
+		}
+	}
+
+	if p.validator != nil {
+		if err := p.validateClone(ctx, clonePath); err != nil {
+			return "", err
+		}
+	}
+
+	snapshotName, err = p.cloneManager.CreateSnapshot(cloneName, dataStateAt)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create a snapshot")
+	}
+
+	p.tm.SendEvent(ctx, telemetry.SnapshotCreatedEvent, telemetry.SnapshotCreated{})
+	notifySnapshotCreated(p.listener)
+
+	log.Msg("Point-in-time snapshot has been created: ", snapshotName)
+
+	return snapshotName, nil
+}
+
+// findBaseSnapshot finds the latest pre-snapshot with the data state not later than the target time.
+func findBaseSnapshot(preSnapshots []resources.Snapshot, targetTime time.Time) (resources.Snapshot, error) {
+	var (
+		baseSnapshot resources.Snapshot
+		found        bool
+	)
+
+	for _, snapshot := range preSnapshots {
+		if snapshot.DataStateAt.IsZero() || snapshot.DataStateAt.After(targetTime) {
+			continue
+		}
+
+		if !found || snapshot.DataStateAt.After(baseSnapshot.DataStateAt) {
+			baseSnapshot = snapshot
+			found = true
+		}
+	}
+
+	if !found {
+		return resources.Snapshot{}, errors.Errorf("no pre-snapshots found earlier than %s", targetTime.Format(time.RFC3339))
+	}
+
+	return baseSnapshot, nil
+}
+
+// setRecoveryTargetTime replaces the recovery target with the target time and makes the instance promote once it is reached.
+func setRecoveryTargetTime(recoveryConfig map[string]string, targetTime time.Time) {
+	for _, option := range recoveryTargetOptions {
+		delete(recoveryConfig, option)
+	}
+
+	recoveryConfig[recoveryTargetTimeOption] = targetTime.UTC().Format(recoveryTargetTimeLayout)
+	recoveryConfig[targetActionOption] = promoteTargetAction
+}
+
+// checkRecoveryTargetReached makes sure that recovery stopped at the recovery target rather than at the end of available WAL.
+// Postgres 12 and older promote the instance in both cases, so the recovery stop is looked up in the logs written since the start.
+func checkRecoveryTargetReached(logDir string, startedAt time.Time) error {
+	logFiles, err := filepath.Glob(filepath.Join(logDir, "*.csv"))
+	if err != nil {
+		return errors.Wrap(err, "failed to list Postgres logs")
+	}
+
+	for _, logFile := range logFiles {
+		info, err := os.Stat(logFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to get info of a Postgres log")
+		}
+
+		if info.ModTime().Before(startedAt) {
+			continue
+		}
+
+		found, err := containsRecoveryStop(logFile)
+		if err != nil {
+			return err
+		}
+
+		if found {
+			return nil
+		}
+	}
+
+	return errors.New("recovery has not reached the target time: WAL up to the target is not available")
+}
+
+func containsRecoveryStop(logFile string) (bool, error) {
+	f, err := os.Open(logFile)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to open a Postgres log")
+	}
+
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1024*1024)
+
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), recoveryStopMarker) {
+			return true, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return false, errors.Wrap(err, "failed to read a Postgres log")
+	}
+
+	return false, nil
+}
@@ -0,0 +1,94 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/resources"
+)
+
+func TestFindBaseSnapshot(t *testing.T) {
+	preSnapshot := func(id string, dataStateAt time.Time) resources.Snapshot {
+		// Snapshots are created later than their data state.
+		return resources.Snapshot{ID: id, CreatedAt: dataStateAt.Add(40 * time.Minute), DataStateAt: dataStateAt}
+	}
+
+	preSnapshots := []resources.Snapshot{
+		preSnapshot("dblab_pool@snapshot_20220501100000_pre", time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)),
+		preSnapshot("dblab_pool@snapshot_20220501140000_pre", time.Date(2022, 5, 1, 14, 0, 0, 0, time.UTC)),
+		preSnapshot("dblab_pool@snapshot_20220501120000_pre", time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)),
+		preSnapshot("dblab_pool@snapshot_20220501150000_pre", time.Date(2022, 5, 1, 15, 0, 0, 0, time.UTC)),
+		{ID: "dblab_pool@snapshot_unknown_pre", CreatedAt: time.Date(2022, 5, 1, 11, 0, 0, 0, time.UTC)},
+	}
+
+	testCases := []struct {
+		targetTime time.Time
+		expectedID string
+	}{
+		{
+			targetTime: time.Date(2022, 5, 1, 14, 32, 10, 0, time.UTC),
+			expectedID: "dblab_pool@snapshot_20220501140000_pre",
+		},
+		{
+			targetTime: time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC),
+			expectedID: "dblab_pool@snapshot_20220501120000_pre",
+		},
+		{
+			targetTime: time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC),
+			expectedID: "dblab_pool@snapshot_20220501150000_pre",
+		},
+	}
+
+	for _, tc := range testCases {
+		snapshot, err := findBaseSnapshot(preSnapshots, tc.targetTime)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expectedID, snapshot.ID)
+	}
+
+	_, err := findBaseSnapshot(preSnapshots, time.Date(2022, 5, 1, 9, 0, 0, 0, time.UTC))
+	assert.Error(t, err)
+
+	_, err = findBaseSnapshot(nil, time.Date(2022, 5, 1, 9, 0, 0, 0, time.UTC))
+	assert.Error(t, err)
+}
+
+func TestSetRecoveryTargetTime(t *testing.T) {
+	recoveryConfig := map[string]string{
+		"restore_command":        "wal-g wal-fetch %f %p",
+		"recovery_target":        "immediate",
+		"recovery_target_action": "pause",
+		"recovery_target_lsn":    "0/3000000",
+	}
+
+	setRecoveryTargetTime(recoveryConfig, time.Date(2022, 5, 1, 16, 32, 10, 500000000, time.FixedZone("CEST", 2*60*60)))
+
+	assert.Equal(t, map[string]string{
+		"restore_command":        "wal-g wal-fetch %f %p",
+		"recovery_target_time":   "2022-05-01 14:32:10.5+00:00",
+		"recovery_target_action": "promote",
+	}, recoveryConfig)
+}
+
+func TestCheckRecoveryTargetReached(t *testing.T) {
+	logDir := t.TempDir()
+	startedAt := time.Now().Add(-time.Minute)
+
+	oldLog := filepath.Join(logDir, "postgresql-2022-05-01_100000.csv")
+	require.NoError(t, os.WriteFile(oldLog, []byte(`2022-05-01 10:00:00 UTC,,,LOG,"recovery stopping before commit of transaction 1"`), 0600))
+	require.NoError(t, os.Chtimes(oldLog, startedAt.Add(-time.Hour), startedAt.Add(-time.Hour)))
+
+	newLog := filepath.Join(logDir, "postgresql-2022-05-02_100000.csv")
+	require.NoError(t, os.WriteFile(newLog, []byte(`2022-05-02 10:00:00 UTC,,,LOG,"redo done at 0/3000148"`), 0600))
+
+	assert.Error(t, checkRecoveryTargetReached(logDir, startedAt))
+
+	require.NoError(t, os.WriteFile(newLog, []byte(`2022-05-02 10:00:00 UTC,,,LOG,"redo done at 0/3000148"
+2022-05-02 10:00:01 UTC,,,LOG,"recovery stopping before commit of transaction 736, time 2022-05-01 14:32:11.02+00"`), 0600))
+
+	assert.NoError(t, checkRecoveryTargetReached(logDir, startedAt))
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
 
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools"
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
@@ -26,17 +27,37 @@ type queryProcessor struct {
 	username   string
 	dirname    string
 	maxWorkers int
+	masking    Masking
 }
 
-func newQueryProcessor(docker *client.Client, dbName, username, scriptDir string, maxWorkers int) *queryProcessor {
+func newQueryProcessor(docker *client.Client, dbName, username string, preprocessing QueryPreprocessing) *queryProcessor {
+	maxWorkers := preprocessing.MaxParallelWorkers
 	if maxWorkers == 0 {
 		maxWorkers = defaultWorkerCount
 	}
 
-	return &queryProcessor{docker: docker, dbName: dbName, username: username, dirname: scriptDir, maxWorkers: maxWorkers}
+	return &queryProcessor{docker: docker, dbName: dbName, username: username, dirname: preprocessing.QueryPath,
+		maxWorkers: maxWorkers, masking: preprocessing.Masking}
 }
 
+// applyPreprocessingQueries runs preprocessing queries and masks data.
 func (q *queryProcessor) applyPreprocessingQueries(ctx context.Context, containerID string) error {
+	if q.dirname != "" {
+		if err := q.runQueryFiles(ctx, containerID); err != nil {
+			return err
+		}
+	}
+
+	if q.masking.isEnabled() {
+		if err := q.applyMasking(ctx, containerID); err != nil {
+			return errors.Wrap(err, "failed to mask data")
+		}
+	}
+
+	return nil
+}
+
+func (q *queryProcessor) runQueryFiles(ctx context.Context, containerID string) error {
 	infos, err := os.ReadDir(q.dirname)
 	if err != nil {
 		return err
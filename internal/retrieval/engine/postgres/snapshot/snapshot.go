@@ -9,6 +9,7 @@ import (
 	"github.com/pkg/errors"
 
 	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/runners"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/config"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/dbmarker"
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
 )
@@ -33,3 +34,10 @@ func runPreprocessingScript(preprocessingScript string) error {
 
 	return nil
 }
+
+// notifySnapshotCreated lets the listener know about the new snapshot.
+func notifySnapshotCreated(listener config.SnapshotListener) {
+	if listener != nil {
+		listener.SnapshotCreated()
+	}
+}
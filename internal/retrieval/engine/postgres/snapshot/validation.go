@@ -0,0 +1,294 @@
+/*
+2022 © Postgres.ai
+*/
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/config"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/telemetry"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+// btreeIndexesQuery lists valid B-tree indexes of user tables that can be verified by amcheck.
+const btreeIndexesQuery = `select quote_ident(n.nspname) || '.' || quote_ident(c.relname)
+from pg_index i
+join pg_class c on c.oid = i.indexrelid
+join pg_am a on a.oid = c.relam
+join pg_namespace n on n.oid = c.relnamespace
+where a.amname = 'btree'
+  and c.relkind = 'i'
+  and c.relpersistence <> 't'
+  and i.indisvalid
+  and i.indisready
+  and n.nspname not in ('pg_catalog', 'information_schema')
+  and n.nspname !~ '^pg_toast'
+order by 1`
+
+// Validation describes checks of the prepared data. A snapshot is created only if all checks pass.
+type Validation struct {
+	Queries []ValidationQuery `yaml:"queries"`
+	MinRows []MinRowsCheck    `yaml:"minRows"`
+	Amcheck AmcheckOptions    `yaml:"amcheck"`
+}
+
+// ValidationQuery defines an SQL assertion. The query must return a single boolean value that is true for valid data.
+type ValidationQuery struct {
+	Name     string `yaml:"name"`
+	Database string `yaml:"database"`
+	Query    string `yaml:"query"`
+}
+
+// MinRowsCheck defines the minimum number of rows of a table.
+type MinRowsCheck struct {
+	Database string `yaml:"database"`
+	Table    string `yaml:"table"`
+	Rows     int64  `yaml:"rows"`
+}
+
+// AmcheckOptions defines options of B-tree index verification by the amcheck extension.
+type AmcheckOptions struct {
+	Enabled        bool     `yaml:"enabled"`
+	Databases      []string `yaml:"databases"`
+	HeapAllIndexed bool     `yaml:"heapAllIndexed"`
+}
+
+// ValidationError describes a failed check of the prepared data.
+type ValidationError struct {
+	Check  string
+	Reason string
+}
+
+// Error returns error message.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("snapshot validation failed: %s: %s", e.Check, e.Reason)
+}
+
+// isEnabled reports whether the prepared data have to be validated.
+func (v Validation) isEnabled() bool {
+	return len(v.Queries) > 0 || len(v.MinRows) > 0 || v.Amcheck.Enabled
+}
+
+func (v Validation) validate() error {
+	for i, query := range v.Queries {
+		if strings.TrimSpace(query.Query) == "" {
+			return errors.Errorf("validation query #%d is empty", i+1)
+		}
+	}
+
+	for i, check := range v.MinRows {
+		if _, _, ok := splitTableName(check.Table); !ok {
+			return errors.Errorf("minRows check #%d: table %q must be schema-qualified", i+1, check.Table)
+		}
+
+		if check.Rows <= 0 {
+			return errors.Errorf("minRows check #%d: the number of rows must be positive", i+1)
+		}
+	}
+
+	return nil
+}
+
+func (q ValidationQuery) checkName() string {
+	if q.Name != "" {
+		return q.Name
+	}
+
+	return q.Query
+}
+
+func (c MinRowsCheck) buildQuery() string {
+	schemaName, tableName, _ := splitTableName(c.Table)
+
+	return fmt.Sprintf("select count(*) from %s", pgx.Identifier{schemaName, tableName}.Sanitize())
+}
+
+// dataValidator runs validation checks against a running instance of the prepared data.
+type dataValidator struct {
+	docker     *client.Client
+	dbName     string
+	username   string
+	validation Validation
+}
+
+func newDataValidator(docker *client.Client, dbName, username string, validation Validation) *dataValidator {
+	return &dataValidator{docker: docker, dbName: dbName, username: username, validation: validation}
+}
+
+// validateData runs all checks and returns ValidationError describing the first failed one.
+func (v *dataValidator) validateData(ctx context.Context, containerID string) error {
+	log.Msg("Validating the prepared data")
+
+	for _, query := range v.validation.Queries {
+		if err := v.checkQuery(ctx, containerID, query); err != nil {
+			return err
+		}
+	}
+
+	for _, check := range v.validation.MinRows {
+		if err := v.checkMinRows(ctx, containerID, check); err != nil {
+			return err
+		}
+	}
+
+	if v.validation.Amcheck.Enabled {
+		for _, dbName := range v.amcheckDatabases() {
+			if err := v.checkIndexes(ctx, containerID, dbName); err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Msg("The prepared data have passed validation")
+
+	return nil
+}
+
+func (v *dataValidator) checkQuery(ctx context.Context, containerID string, query ValidationQuery) error {
+	check := "query " + query.checkName()
+
+	output, err := v.runQuery(ctx, containerID, v.database(query.Database), query.Query)
+	if err != nil {
+		return &ValidationError{Check: check, Reason: failureReason(output, err)}
+	}
+
+	if result := strings.TrimSpace(output); result != "t" {
+		return &ValidationError{Check: check, Reason: fmt.Sprintf("expected true, got %q", result)}
+	}
+
+	log.Dbg("Validation check passed:", check)
+
+	return nil
+}
+
+func (v *dataValidator) checkMinRows(ctx context.Context, containerID string, check MinRowsCheck) error {
+	checkName := fmt.Sprintf("minRows %s", check.Table)
+
+	output, err := v.runQuery(ctx, containerID, v.database(check.Database), check.buildQuery())
+	if err != nil {
+		return &ValidationError{Check: checkName, Reason: failureReason(output, err)}
+	}
+
+	rows, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return &ValidationError{Check: checkName, Reason: fmt.Sprintf("failed to parse the number of rows %q", output)}
+	}
+
+	if rows < check.Rows {
+		return &ValidationError{Check: checkName, Reason: fmt.Sprintf("found %d rows, expected at least %d", rows, check.Rows)}
+	}
+
+	log.Dbg(fmt.Sprintf("Validation check passed: %s has %d rows", check.Table, rows))
+
+	return nil
+}
+
+// checkIndexes verifies B-tree indexes of the database. The amcheck extension is dropped afterwards if it has not been installed before.
+func (v *dataValidator) checkIndexes(ctx context.Context, containerID, dbName string) error {
+	checkName := "amcheck " + dbName
+
+	installed, err := v.runQuery(ctx, containerID, dbName, "select count(*) from pg_extension where extname = 'amcheck'")
+	if err != nil {
+		return errors.Wrapf(err, "failed to check the amcheck extension in the database %s", dbName)
+	}
+
+	if strings.TrimSpace(installed) == "0" {
+		if output, err := v.runQuery(ctx, containerID, dbName, "create extension amcheck"); err != nil {
+			return &ValidationError{Check: checkName, Reason: "failed to create the amcheck extension: " + failureReason(output, err)}
+		}
+
+		defer func() {
+			if _, err := v.runQuery(ctx, containerID, dbName, "drop extension amcheck"); err != nil {
+				log.Err(fmt.Sprintf("Failed to drop the amcheck extension in the database %s: %v", dbName, err))
+			}
+		}()
+	}
+
+	output, err := v.runQuery(ctx, containerID, dbName, btreeIndexesQuery)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list indexes of the database %s", dbName)
+	}
+
+	indexes := []string{}
+
+	for _, line := range strings.Split(output, "\n") {
+		if index := strings.TrimSpace(line); index != "" {
+			indexes = append(indexes, index)
+		}
+	}
+
+	log.Msg(fmt.Sprintf("Verifying %d B-tree indexes in the database %s", len(indexes), dbName))
+
+	for _, index := range indexes {
+		query := fmt.Sprintf("select bt_index_check(%s::regclass)", quoteLiteral(index))
+
+		if v.validation.Amcheck.HeapAllIndexed {
+			query = fmt.Sprintf("select bt_index_check(%s::regclass, true)", quoteLiteral(index))
+		}
+
+		if output, err := v.runQuery(ctx, containerID, dbName, query); err != nil {
+			return &ValidationError{Check: fmt.Sprintf("%s: index %s", checkName, index), Reason: failureReason(output, err)}
+		}
+	}
+
+	return nil
+}
+
+func (v *dataValidator) amcheckDatabases() []string {
+	if len(v.validation.Amcheck.Databases) == 0 {
+		return []string{v.dbName}
+	}
+
+	return v.validation.Amcheck.Databases
+}
+
+func (v *dataValidator) database(dbName string) string {
+	if dbName == "" {
+		return v.dbName
+	}
+
+	return dbName
+}
+
+func (v *dataValidator) runQuery(ctx context.Context, containerID, dbName, query string) (string, error) {
+	return tools.ExecCommandWithOutput(ctx, v.docker, containerID, types.ExecConfig{
+		Cmd: []string{"psql", "-U", v.username, "-d", dbName, "-XAt", "-v", "ON_ERROR_STOP=1", "-c", query},
+	})
+}
+
+// reportSnapshotFailure raises the RefreshFailed alert in the retrieval state if a scheduled snapshot has failed.
+func reportSnapshotFailure(ctx context.Context, alerter config.Alerter, err error) {
+	if alerter == nil {
+		return
+	}
+
+	message := "Failed to take a snapshot automatically"
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		message += ". " + validationErr.Error()
+	}
+
+	alerter.ReportAlert(ctx, telemetry.Alert{Level: models.RefreshFailed, Message: message})
+}
+
+// failureReason prefers the psql output that explains the error.
+func failureReason(output string, err error) string {
+	if reason := strings.TrimSpace(output); reason != "" {
+		return reason
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,75 @@
+package snapshot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/telemetry"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+func TestValidationConfig(t *testing.T) {
+	valid := Validation{
+		Queries: []ValidationQuery{{Name: "users exist", Query: "select exists (select from public.users)"}},
+		MinRows: []MinRowsCheck{{Table: "public.users", Rows: 1000}},
+		Amcheck: AmcheckOptions{Enabled: true},
+	}
+
+	assert.NoError(t, valid.validate())
+	assert.True(t, valid.isEnabled())
+	assert.False(t, Validation{}.isEnabled())
+	assert.True(t, Validation{Amcheck: AmcheckOptions{Enabled: true}}.isEnabled())
+
+	invalidValidations := []Validation{
+		{Queries: []ValidationQuery{{Name: "empty", Query: " "}}},
+		{MinRows: []MinRowsCheck{{Table: "users", Rows: 1}}},
+		{MinRows: []MinRowsCheck{{Table: "public.users"}}},
+	}
+
+	for _, validation := range invalidValidations {
+		assert.Error(t, validation.validate())
+	}
+}
+
+func TestValidationChecks(t *testing.T) {
+	assert.Equal(t, `select count(*) from "billing"."Invoices"`, MinRowsCheck{Table: "billing.Invoices", Rows: 1}.buildQuery())
+
+	assert.Equal(t, "users exist", ValidationQuery{Name: "users exist", Query: "select true"}.checkName())
+	assert.Equal(t, "select true", ValidationQuery{Query: "select true"}.checkName())
+
+	validator := newDataValidator(nil, "test", "postgres", Validation{Amcheck: AmcheckOptions{Enabled: true}})
+	assert.Equal(t, []string{"test"}, validator.amcheckDatabases())
+	assert.Equal(t, "test", validator.database(""))
+	assert.Equal(t, "billing", validator.database("billing"))
+
+	err := &ValidationError{Check: "amcheck test: index public.users_pkey", Reason: "index is corrupted"}
+	assert.Equal(t, "snapshot validation failed: amcheck test: index public.users_pkey: index is corrupted", err.Error())
+}
+
+type testAlerter struct {
+	alerts []telemetry.Alert
+}
+
+func (a *testAlerter) ReportAlert(_ context.Context, alert telemetry.Alert) {
+	a.alerts = append(a.alerts, alert)
+}
+
+func (a *testAlerter) ResolveAlert(models.AlertType) {}
+
+func TestReportSnapshotFailure(t *testing.T) {
+	alerter := &testAlerter{}
+
+	reportSnapshotFailure(context.Background(), alerter, errors.New("failed to create a snapshot"))
+	reportSnapshotFailure(context.Background(), alerter,
+		errors.Wrap(&ValidationError{Check: "minRows public.users", Reason: "found 0 rows, expected at least 1"}, "failed to prepare data"))
+	reportSnapshotFailure(context.Background(), nil, errors.New("failed to create a snapshot"))
+
+	assert.Equal(t, []telemetry.Alert{
+		{Level: models.RefreshFailed, Message: "Failed to take a snapshot automatically"},
+		{Level: models.RefreshFailed, Message: "Failed to take a snapshot automatically. " +
+			"snapshot validation failed: minRows public.users: found 0 rows, expected at least 1"},
+	}, alerter.alerts)
+}
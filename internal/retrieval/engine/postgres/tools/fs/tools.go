@@ -72,3 +72,22 @@ func AppendFile(file string, data []byte) error {
 
 	return nil
 }
+
+// DirSize returns the total size of regular files in the directory or the size of the file.
+func DirSize(path string) (uint64, error) {
+	var size uint64
+
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			size += uint64(info.Size())
+		}
+
+		return nil
+	})
+
+	return size, err
+}
@@ -0,0 +1,29 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "first"), make([]byte, 100), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "second"), make([]byte, 50), 0600))
+
+	size, err := DirSize(dir)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(150), size)
+
+	size, err = DirSize(filepath.Join(dir, "first"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), size)
+
+	_, err = DirSize(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
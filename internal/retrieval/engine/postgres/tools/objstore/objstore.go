@@ -0,0 +1,302 @@
+/*
+2022 © Postgres.ai
+*/
+
+// Package objstore provides access to S3-compatible object storage.
+package objstore
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultRegion = "us-east-1"
+
+	// keyDelimiter separates parts of object keys.
+	keyDelimiter = "/"
+)
+
+// Config defines connection options of an object storage.
+type Config struct {
+	// Endpoint defines a custom endpoint of S3-compatible storage, for example, MinIO. Leave empty to use AWS S3.
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	Prefix          string `yaml:"prefix"`
+	AccessKeyID     string `yaml:"accessKeyID"`
+	SecretAccessKey string `yaml:"secretAccessKey"`
+	ForcePathStyle  bool   `yaml:"forcePathStyle"`
+	DisableSSL      bool   `yaml:"disableSSL"`
+}
+
+// Storage provides operations on objects of a bucket. All keys are relative to the configured prefix.
+type Storage struct {
+	bucket     string
+	prefix     string
+	client     *s3.S3
+	uploader   *s3manager.Uploader
+	downloader *s3manager.Downloader
+}
+
+// New creates a new object storage client.
+// If credentials are not configured, they are taken from the environment as for any AWS client.
+func New(cfg Config) (*Storage, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("bucket of the object storage is not specified")
+	}
+
+	awsCfg := aws.NewConfig().
+		WithRegion(cfg.Region).
+		WithS3ForcePathStyle(cfg.ForcePathStyle).
+		WithDisableSSL(cfg.DisableSSL)
+
+	if cfg.Region == "" {
+		awsCfg = awsCfg.WithRegion(defaultRegion)
+	}
+
+	if cfg.Endpoint != "" {
+		awsCfg = awsCfg.WithEndpoint(cfg.Endpoint)
+	}
+
+	if cfg.AccessKeyID != "" {
+		awsCfg = awsCfg.WithCredentials(credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, ""))
+	}
+
+	awsSession, err := session.NewSession(awsCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start AWS session")
+	}
+
+	client := s3.New(awsSession)
+
+	return &Storage{
+		bucket:     cfg.Bucket,
+		prefix:     strings.Trim(cfg.Prefix, keyDelimiter),
+		client:     client,
+		uploader:   s3manager.NewUploaderWithClient(client),
+		downloader: s3manager.NewDownloaderWithClient(client),
+	}, nil
+}
+
+// Upload streams the body to the object. Large bodies are uploaded in parts, so the body is not buffered entirely.
+func (s *Storage) Upload(ctx context.Context, key string, body io.Reader) error {
+	if _, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+		Body:   body,
+	}); err != nil {
+		return errors.Wrapf(err, "failed to upload object %q", key)
+	}
+
+	return nil
+}
+
+// UploadDir uploads files of the directory recursively, keeping their relative paths under the key prefix.
+func (s *Storage) UploadDir(ctx context.Context, dir, keyPrefix string) error {
+	return filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		return s.uploadFile(ctx, filePath, path.Join(keyPrefix, filepath.ToSlash(relPath)))
+	})
+}
+
+func (s *Storage) uploadFile(ctx context.Context, filePath, key string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return errors.Wrap(err, "failed to open file to upload")
+	}
+
+	defer func() { _ = f.Close() }()
+
+	return s.Upload(ctx, key, f)
+}
+
+// Read returns the content of the object.
+func (s *Storage) Read(ctx context.Context, key string) ([]byte, error) {
+	body, err := s.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = body.Close() }()
+
+	return io.ReadAll(body)
+}
+
+// Open returns a reader streaming the content of the object. The reader must be closed.
+func (s *Storage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	output, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get object %q", key)
+	}
+
+	return output.Body, nil
+}
+
+// Exists checks if the object exists.
+func (s *Storage) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		var awsErr awserr.RequestFailure
+		if errors.As(err, &awsErr) && awsErr.StatusCode() == 404 {
+			return false, nil
+		}
+
+		return false, errors.Wrapf(err, "failed to check object %q", key)
+	}
+
+	return true, nil
+}
+
+// List returns keys of objects under the key prefix. The keys are relative to the key prefix.
+func (s *Storage) List(ctx context.Context, keyPrefix string) ([]string, error) {
+	keys := []string{}
+	listPrefix := s.listPrefix(keyPrefix)
+
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(listPrefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(aws.StringValue(object.Key), listPrefix))
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list objects under %q", keyPrefix)
+	}
+
+	return keys, nil
+}
+
+// ListDirs returns names of the nearest "directories" under the key prefix.
+func (s *Storage) ListDirs(ctx context.Context, keyPrefix string) ([]string, error) {
+	dirs := []string{}
+	listPrefix := s.listPrefix(keyPrefix)
+
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(s.bucket),
+		Prefix:    aws.String(listPrefix),
+		Delimiter: aws.String(keyDelimiter),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, commonPrefix := range page.CommonPrefixes {
+			dir := strings.TrimPrefix(aws.StringValue(commonPrefix.Prefix), listPrefix)
+			dirs = append(dirs, strings.TrimSuffix(dir, keyDelimiter))
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list directories under %q", keyPrefix)
+	}
+
+	return dirs, nil
+}
+
+// Download downloads objects under the key prefix to the directory, keeping their relative paths.
+// It skips objects for which the skip function returns true.
+func (s *Storage) Download(ctx context.Context, keyPrefix, dir string, skip func(key string) bool) error {
+	keys, err := s.List(ctx, keyPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if skip != nil && skip(key) {
+			continue
+		}
+
+		if err := s.downloadFile(ctx, path.Join(keyPrefix, key), filepath.Join(dir, filepath.FromSlash(key))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) downloadFile(ctx context.Context, key, filePath string) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return errors.Wrap(err, "failed to create a download directory")
+	}
+
+	f, err := os.Create(filePath)
+	if err != nil {
+		return errors.Wrap(err, "failed to create a downloaded file")
+	}
+
+	defer func() { _ = f.Close() }()
+
+	if _, err := s.downloader.DownloadWithContext(ctx, f, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	}); err != nil {
+		return errors.Wrapf(err, "failed to download object %q", key)
+	}
+
+	return nil
+}
+
+// Remove removes all objects under the key prefix.
+func (s *Storage) Remove(ctx context.Context, keyPrefix string) error {
+	keys, err := s.List(ctx, keyPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if _, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(s.objectKey(path.Join(keyPrefix, key))),
+		}); err != nil {
+			return errors.Wrapf(err, "failed to remove object %q", key)
+		}
+	}
+
+	return nil
+}
+
+// objectKey returns the full key of the object.
+func (s *Storage) objectKey(key string) string {
+	return path.Join(s.prefix, key)
+}
+
+// listPrefix returns the full key prefix to list objects of a "directory".
+func (s *Storage) listPrefix(keyPrefix string) string {
+	listPrefix := s.objectKey(keyPrefix)
+	if listPrefix == "" {
+		return ""
+	}
+
+	return listPrefix + keyDelimiter
+}
@@ -0,0 +1,117 @@
+//go:build integration
+// +build integration
+
+/*
+2022 © Postgres.ai
+*/
+
+package objstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	minioPort      = "9000/tcp"
+	minioAccessKey = "minioadmin"
+	minioSecretKey = "minioadmin"
+	testBucket     = "dumps"
+)
+
+func TestStorageWithMinIO(t *testing.T) {
+	ctx := context.Background()
+
+	minioContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "minio/minio:latest",
+			Cmd:          []string{"server", "/data"},
+			ExposedPorts: []string{minioPort},
+			Env: map[string]string{
+				"MINIO_ROOT_USER":     minioAccessKey,
+				"MINIO_ROOT_PASSWORD": minioSecretKey,
+			},
+			WaitingFor: wait.ForHTTP("/minio/health/live").WithPort(minioPort),
+		},
+		Started: true,
+	})
+	require.Nil(t, err)
+
+	defer func() { _ = minioContainer.Terminate(ctx) }()
+
+	endpoint, err := minioContainer.Endpoint(ctx, "http")
+	require.Nil(t, err)
+
+	storage, err := New(Config{
+		Endpoint:        endpoint,
+		Bucket:          testBucket,
+		Prefix:          "/archive/",
+		AccessKeyID:     minioAccessKey,
+		SecretAccessKey: minioSecretKey,
+		ForcePathStyle:  true,
+		DisableSSL:      true,
+	})
+	require.Nil(t, err)
+
+	_, err = storage.client.CreateBucketWithContext(ctx, &s3.CreateBucketInput{Bucket: aws.String(testBucket)})
+	require.Nil(t, err)
+
+	// Upload a streamed object and a directory.
+	require.Nil(t, storage.Upload(ctx, "20220601000000/db1.sql", strings.NewReader("select 1;")))
+
+	dumpDir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(dumpDir, "nested"), 0755))
+	require.Nil(t, os.WriteFile(filepath.Join(dumpDir, "toc.dat"), []byte("toc"), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(dumpDir, "nested", "1.dat"), []byte("data"), 0644))
+	require.Nil(t, storage.UploadDir(ctx, dumpDir, "20220602000000/db2"))
+
+	dirs, err := storage.ListDirs(ctx, "")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"20220601000000", "20220602000000"}, dirs)
+
+	keys, err := storage.List(ctx, "20220602000000")
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"db2/toc.dat", "db2/nested/1.dat"}, keys)
+
+	exists, err := storage.Exists(ctx, "20220601000000/db1.sql")
+	require.Nil(t, err)
+	assert.True(t, exists)
+
+	exists, err = storage.Exists(ctx, "20220601000000/missing")
+	require.Nil(t, err)
+	assert.False(t, exists)
+
+	content, err := storage.Read(ctx, "20220601000000/db1.sql")
+	require.Nil(t, err)
+	assert.Equal(t, "select 1;", string(content))
+
+	// Download a directory skipping some objects.
+	downloadDir := t.TempDir()
+	require.Nil(t, storage.Download(ctx, "20220602000000", downloadDir, func(key string) bool {
+		return key == "db2/toc.dat"
+	}))
+
+	data, err := os.ReadFile(filepath.Join(downloadDir, "db2", "nested", "1.dat"))
+	require.Nil(t, err)
+	assert.Equal(t, "data", string(data))
+
+	_, err = os.Stat(filepath.Join(downloadDir, "db2", "toc.dat"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Remove a generation.
+	require.Nil(t, storage.Remove(ctx, "20220601000000"))
+
+	dirs, err = storage.ListDirs(ctx, "")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"20220602000000"}, dirs)
+}
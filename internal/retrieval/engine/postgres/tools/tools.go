@@ -8,6 +8,7 @@
 package tools
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -319,7 +320,7 @@ func CheckContainerReadiness(ctx context.Context, dockerClient *client.Client, c
 	}
 }
 
-// PrintContainerLogs prints container output.
+// PrintContainerLogs prints container output and passes it to the handler of the context if there is one.
 func PrintContainerLogs(ctx context.Context, dockerClient *client.Client, containerID string) {
 	logs, err := dockerClient.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{
 		Since:      essentialLogsInterval,
@@ -341,6 +342,20 @@ func PrintContainerLogs(ctx context.Context, dockerClient *client.Client, contai
 	}
 
 	log.Msg("Container logs:\n", wb.String())
+
+	if handler, ok := ctx.Value(containerLogsKey{}).(ContainerLogsHandler); ok {
+		handler(containerID, wb.Bytes())
+	}
+}
+
+type containerLogsKey struct{}
+
+// ContainerLogsHandler receives container logs printed by PrintContainerLogs.
+type ContainerLogsHandler func(containerID string, logs []byte)
+
+// WithContainerLogsHandler returns a copy of the context that makes PrintContainerLogs pass container logs to the handler.
+func WithContainerLogsHandler(ctx context.Context, handler ContainerLogsHandler) context.Context {
+	return context.WithValue(ctx, containerLogsKey{}, handler)
 }
 
 // PrintLastPostgresLogs prints Postgres container logs.
@@ -490,6 +505,171 @@ func ExecCommandWithOutput(ctx context.Context, dockerClient *client.Client, con
 	return string(output), err
 }
 
+const (
+	// outputTailLines defines how many of the last output lines are kept to describe a failed command.
+	outputTailLines = 20
+
+	// maxOutputLineSize defines the maximum size of an output line passed to the handler.
+	maxOutputLineSize = 1024 * 1024
+)
+
+// ExecCommandWithLineHandler runs command in Docker container and passes each line of stdout and stderr to the handler.
+// The output is not collected, so the command may produce a lot of it. The last lines are included in the error.
+func ExecCommandWithLineHandler(ctx context.Context, dockerClient *client.Client, containerID string, execCfg types.ExecConfig,
+	handler func(line string)) error {
+	return ExecCommandWithStdout(ctx, dockerClient, containerID, execCfg, nil, handler)
+}
+
+// ExecCommandWithStdout runs command in Docker container, writes its stdout to the writer and passes stderr lines to the handler.
+// If the writer is nil, stdout lines are passed to the handler as well.
+func ExecCommandWithStdout(ctx context.Context, dockerClient *client.Client, containerID string, execCfg types.ExecConfig,
+	stdout io.Writer, handler func(line string)) error {
+	return execCommandWithStreams(ctx, dockerClient, containerID, execCfg, nil, stdout, handler)
+}
+
+// ExecCommandWithStdin runs command in Docker container and writes the content of the reader to its stdin.
+// The command may stop reading stdin early, but a failure to read the content fails the command.
+// Output is processed as in ExecCommandWithStdout.
+func ExecCommandWithStdin(ctx context.Context, dockerClient *client.Client, containerID string, execCfg types.ExecConfig,
+	stdin io.Reader, stdout io.Writer, handler func(line string)) error {
+	return execCommandWithStreams(ctx, dockerClient, containerID, execCfg, stdin, stdout, handler)
+}
+
+func execCommandWithStreams(ctx context.Context, dockerClient *client.Client, containerID string, execCfg types.ExecConfig,
+	stdin io.Reader, stdout io.Writer, handler func(line string)) error {
+	execCfg.AttachStdin = stdin != nil
+	execCfg.AttachStdout = true
+	execCfg.AttachStderr = true
+	execCfg.Tty = false
+
+	execCommand, err := dockerClient.ContainerExecCreate(ctx, containerID, execCfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to create an exec command")
+	}
+
+	attachResponse, err := dockerClient.ContainerExecAttach(ctx, execCommand.ID, types.ExecStartCheck{})
+	if err != nil {
+		return errors.Wrap(err, "failed to attach to exec command")
+	}
+
+	defer attachResponse.Close()
+
+	pr, pw := io.Pipe()
+	defer func() { _ = pr.Close() }()
+
+	if stdout == nil {
+		stdout = pw
+	}
+
+	go func() {
+		// StdCopy de-multiplexes the stream.
+		_, err := stdcopy.StdCopy(stdout, pw, attachResponse.Reader)
+		_ = pw.CloseWithError(err)
+	}()
+
+	input := &inputReader{reader: stdin}
+	inputDone := make(chan struct{})
+
+	if stdin != nil {
+		go func() {
+			defer close(inputDone)
+
+			// Write errors are ignored because the command may exit without reading the whole input.
+			_, _ = io.Copy(attachResponse.Conn, input)
+			_ = attachResponse.CloseWrite()
+		}()
+	} else {
+		close(inputDone)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		// Unblock reading if the context is canceled.
+		select {
+		case <-ctx.Done():
+			attachResponse.Close()
+
+		case <-done:
+		}
+	}()
+
+	tail, err := readOutputLines(pr, handler)
+
+	// The output is closed when the command exits, so the rest of the input is not needed anymore.
+	attachResponse.Close()
+	<-inputDone
+
+	if err != nil {
+		return errors.Wrap(err, "failed to read output of exec command")
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if input.err != nil {
+		return errors.Wrap(input.err, "failed to read input of exec command")
+	}
+
+	inspection, err := dockerClient.ContainerExecInspect(ctx, execCommand.ID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect an exec process: %w", err)
+	}
+
+	if inspection.ExitCode != 0 {
+		return fmt.Errorf("exit code: %d. Output:\n%s", inspection.ExitCode, strings.Join(tail, "\n"))
+	}
+
+	return nil
+}
+
+// inputReader keeps an error of reading the input, so it is not confused with the command closing its stdin.
+type inputReader struct {
+	reader io.Reader
+	err    error
+}
+
+func (r *inputReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+
+	return n, err
+}
+
+// readOutputLines passes each line of the output to the handler and returns the last lines.
+// The reader is closed on return, so the writer is never blocked if reading stops early, e.g. on a too long line.
+func readOutputLines(pr *io.PipeReader, handler func(line string)) ([]string, error) {
+	tail := make([]string, 0, outputTailLines)
+	scanner := bufio.NewScanner(pr)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxOutputLineSize)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if len(tail) == outputTailLines {
+			tail = tail[1:]
+		}
+
+		tail = append(tail, line)
+
+		handler(line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		_ = pr.CloseWithError(err)
+
+		return nil, err
+	}
+
+	_ = pr.Close()
+
+	return tail, nil
+}
+
 // processAttachResponse reads and processes the cmd output.
 func processAttachResponse(ctx context.Context, reader io.Reader) ([]byte, error) {
 	var outBuf, errBuf bytes.Buffer
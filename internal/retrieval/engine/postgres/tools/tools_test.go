@@ -5,8 +5,12 @@
 package tools
 
 import (
+	"bufio"
+	"io"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/mount"
@@ -79,3 +83,39 @@ func TestGetMountsFromMountPoints(t *testing.T) {
 		assert.Equal(t, tc.expectedPoints, mounts)
 	}
 }
+
+func TestReadOutputLines(t *testing.T) {
+	pr, pw := io.Pipe()
+
+	go func() {
+		_, err := io.WriteString(pw, "first\nsecond\n")
+		_ = pw.CloseWithError(err)
+	}()
+
+	lines := []string{}
+
+	tail, err := readOutputLines(pr, func(line string) { lines = append(lines, line) })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, lines)
+	assert.Equal(t, []string{"first", "second"}, tail)
+}
+
+func TestReadOutputLinesTooLong(t *testing.T) {
+	pr, pw := io.Pipe()
+	writeErr := make(chan error, 1)
+
+	go func() {
+		_, err := io.WriteString(pw, "first\n"+strings.Repeat("x", 2*maxOutputLineSize)+"\nlast\n")
+		writeErr <- err
+	}()
+
+	_, err := readOutputLines(pr, func(string) {})
+	assert.ErrorIs(t, err, bufio.ErrTooLong)
+
+	select {
+	case err := <-writeErr:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the writer is blocked after reading has stopped")
+	}
+}
@@ -52,8 +52,23 @@ type Retrieval struct {
 	runner        runners.Runner
 	jobs          []components.JobRunner
 	retrieveMutex sync.Mutex
+	schedulerMu   sync.Mutex
+	ctx           context.Context
 	ctxCancel     context.CancelFunc
 	jobSpecs      map[string]config.JobSpec
+
+	// syncMonitoring keeps a copy of monitoring settings because the monitor runs concurrently with configuration reloads.
+	syncMonitoring   config.SyncMonitoring
+	syncMonitoringMu sync.Mutex
+
+	warmPool WarmPool
+}
+
+// WarmPool manages pre-started clones that follow the latest snapshot.
+type WarmPool interface {
+	SuspendWarmPool(poolName string)
+	ResumeWarmPool()
+	RefillWarmPool()
 }
 
 // Scheduler defines a refresh scheduler.
@@ -66,14 +81,15 @@ type Scheduler struct {
 func New(cfg *dblabCfg.Config, engineProps global.EngineProps, docker *client.Client, pm *pool.Manager, tm *telemetry.Agent,
 	runner runners.Runner) *Retrieval {
 	r := &Retrieval{
-		cfg:         &cfg.Retrieval,
-		global:      &cfg.Global,
-		engineProps: engineProps,
-		docker:      docker,
-		poolManager: pm,
-		tm:          tm,
-		runner:      runner,
-		jobSpecs:    make(map[string]config.JobSpec, len(cfg.Retrieval.Jobs)),
+		cfg:            &cfg.Retrieval,
+		global:         &cfg.Global,
+		engineProps:    engineProps,
+		docker:         docker,
+		poolManager:    pm,
+		tm:             tm,
+		runner:         runner,
+		jobSpecs:       make(map[string]config.JobSpec, len(cfg.Retrieval.Jobs)),
+		syncMonitoring: cfg.Retrieval.SyncMonitoring,
 		State: State{
 			Status: models.Inactive,
 			alerts: make(map[models.AlertType]models.Alert),
@@ -90,6 +106,10 @@ func New(cfg *dblabCfg.Config, engineProps global.EngineProps, docker *client.Cl
 func (r *Retrieval) Reload(ctx context.Context, cfg *dblabCfg.Config) {
 	*r.cfg = cfg.Retrieval
 
+	r.syncMonitoringMu.Lock()
+	r.syncMonitoring = cfg.Retrieval.SyncMonitoring
+	r.syncMonitoringMu.Unlock()
+
 	r.formatJobsSpec()
 
 	for _, job := range r.jobs {
@@ -104,10 +124,32 @@ func (r *Retrieval) Reload(ctx context.Context, cfg *dblabCfg.Config) {
 		}
 	}
 
-	r.stopScheduler()
 	r.setupScheduler(ctx)
 }
 
+// ReportAlert registers an alert raised by other subsystems and sends it to telemetry.
+func (r *Retrieval) ReportAlert(ctx context.Context, alert telemetry.Alert) {
+	r.State.addAlert(alert)
+	r.tm.SendEvent(ctx, telemetry.AlertEvent, alert)
+}
+
+// SetWarmPool sets the pool of pre-started clones. It must be called before the retrieval is started.
+func (r *Retrieval) SetWarmPool(warmPool WarmPool) {
+	r.warmPool = warmPool
+}
+
+// SnapshotCreated refills pre-started clones from the new snapshot.
+func (r *Retrieval) SnapshotCreated() {
+	if r.warmPool != nil {
+		r.warmPool.RefillWarmPool()
+	}
+}
+
+// ResolveAlert removes the alert of the specified type.
+func (r *Retrieval) ResolveAlert(alertType models.AlertType) {
+	r.State.removeAlert(alertType)
+}
+
 func (r *Retrieval) formatJobsSpec() {
 	for _, jobName := range r.cfg.Jobs {
 		jobSpec, ok := r.cfg.JobsSpec[jobName]
@@ -122,6 +164,11 @@ func (r *Retrieval) formatJobsSpec() {
 
 // Run start retrieving process.
 func (r *Retrieval) Run(ctx context.Context) error {
+	r.ctx = ctx
+	r.restoreRuns()
+
+	go r.monitorSync(ctx)
+
 	runCtx, cancel := context.WithCancel(ctx)
 	r.ctxCancel = cancel
 
@@ -134,6 +181,7 @@ func (r *Retrieval) Run(ctx context.Context) error {
 			r.State.Status = models.Finished
 
 			log.Msg("Continue without performing a full refresh:", skipError.Error())
+			r.resumeJobs(runCtx, r.poolManager.First())
 			r.setupScheduler(ctx)
 
 			return nil
@@ -152,9 +200,14 @@ func (r *Retrieval) Run(ctx context.Context) error {
 
 	log.Msg("Pool to perform data retrieving: ", fsManager.Pool().Name)
 
-	if err := r.run(runCtx, fsManager); err != nil {
+	r.State.beginRun(models.TriggerStartup, "", r.cfg.Jobs)
+
+	err = r.run(runCtx, fsManager)
+	r.finishRun(err)
+
+	if err != nil {
 		alert := telemetry.Alert{Level: models.RefreshFailed,
-			Message: fmt.Sprintf("Failed to perform initial data retrieving: %s", r.State.Mode)}
+			Message: refreshFailedMessage(fmt.Sprintf("Failed to perform initial data retrieving: %s", r.State.Mode), err)}
 		r.State.addAlert(alert)
 		r.tm.SendEvent(ctx, telemetry.AlertEvent, alert)
 
@@ -239,18 +292,89 @@ func (r *Retrieval) run(ctx context.Context, fsm pool.FSManager) (err error) {
 		}()
 
 		for _, j := range r.jobs {
-			if err := j.Run(ctx); err != nil {
+			r.State.startJob(j.Name())
+			r.State.trackProgress(j)
+
+			err := j.Run(r.withLogCapture(ctx, j.Name()))
+			r.State.finishJob(j.Name(), err)
+
+			if err != nil {
 				return err
 			}
+
+			r.recordDataSize(j)
 		}
 	}
 
+	r.recordSnapshot(fsm)
 	r.poolManager.MakeActive(poolByName)
 	r.State.cleanAlerts()
+	r.watchRefreshRequests(ctx)
 
 	return nil
 }
 
+// resumeJobs resumes background work of jobs on the active pool when the engine starts without a data refresh.
+func (r *Retrieval) resumeJobs(ctx context.Context, fsm pool.FSManager) {
+	if err := r.configure(fsm); err != nil {
+		log.Err("Failed to configure retrieval jobs to resume them:", err)
+		return
+	}
+
+	for _, job := range r.jobs {
+		resumer, ok := job.(components.Resumer)
+		if !ok {
+			continue
+		}
+
+		log.Msg("Resume the retrieval job: ", job.Name())
+
+		if err := resumer.Resume(ctx); err != nil {
+			log.Err("Failed to resume the retrieval job", job.Name(), err)
+		}
+	}
+
+	r.watchRefreshRequests(ctx)
+}
+
+// watchRefreshRequests performs full refreshes requested by jobs until the context is canceled.
+func (r *Retrieval) watchRefreshRequests(ctx context.Context) {
+	for _, job := range r.jobs {
+		requester, ok := job.(components.RefreshRequester)
+		if !ok {
+			continue
+		}
+
+		go func() {
+			for {
+				select {
+				case reason := <-requester.RefreshRequests():
+					r.requestFullRefresh(reason)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
+// requestFullRefresh starts a full refresh requested by a retrieval job.
+func (r *Retrieval) requestFullRefresh(reason string) {
+	if r.State.IsPaused() {
+		log.Msg("Data retrieval is paused. Skip a data refresh requested by the job:", reason)
+		return
+	}
+
+	if !r.State.beginRun(models.TriggerJob, reason, r.cfg.Jobs) {
+		log.Msg("The data refresh is currently in progress. Skip a data refresh requested by the job:", reason)
+		return
+	}
+
+	log.Msg("Full refresh has been requested by the job:", reason)
+
+	go r.runFullRefresh(r.ctx)
+}
+
 // configure configures retrieval service.
 func (r *Retrieval) configure(fsm pool.FSManager) error {
 	if len(r.cfg.Jobs) == 0 {
@@ -286,10 +410,13 @@ func (r *Retrieval) parseJobs(fsm pool.FSManager) error {
 		}
 
 		jobCfg := config.JobConfig{
-			Spec:   jobSpec,
-			Docker: r.docker,
-			Marker: dbMarker,
-			FSPool: fsm.Pool(),
+			Spec:    jobSpec,
+			Docker:  r.docker,
+			Marker:  dbMarker,
+			FSPool:  fsm.Pool(),
+			Alerter: r,
+
+			SnapshotListener: r,
 		}
 
 		job, err := retrievalRunner.BuildJob(jobCfg)
@@ -329,6 +456,10 @@ func (r *Retrieval) hasLogicalJob() bool {
 		return true
 	}
 
+	if _, hasLogicalReplication := r.jobSpecs[logical.ReplicationJobType]; hasLogicalReplication {
+		return true
+	}
+
 	if _, hasLogicalSnapshot := r.jobSpecs[snapshot.LogicalSnapshotType]; hasLogicalSnapshot {
 		return true
 	}
@@ -383,9 +514,12 @@ func (r *Retrieval) prepareEnvironment(fsm pool.FSManager) error {
 }
 
 func (r *Retrieval) setupScheduler(ctx context.Context) {
+	r.schedulerMu.Lock()
+	defer r.schedulerMu.Unlock()
+
 	r.stopScheduler()
 
-	if r.cfg.Refresh.Timetable == "" {
+	if r.cfg.Refresh.Timetable == "" || r.State.IsPaused() {
 		return
 	}
 
@@ -405,29 +539,130 @@ func (r *Retrieval) setupScheduler(ctx context.Context) {
 
 func (r *Retrieval) refreshFunc(ctx context.Context) func() {
 	return func() {
-		if err := r.fullRefresh(ctx); err != nil {
-			alert := telemetry.Alert{Level: models.RefreshFailed, Message: "Failed to run full-refresh"}
+		if r.State.IsPaused() {
+			log.Msg("Data retrieval is paused. Skip a scheduled data refresh")
+			return
+		}
+
+		if !r.State.beginRun(models.TriggerTimetable, "", r.cfg.Jobs) {
+			alert := telemetry.Alert{
+				Level:   models.RefreshSkipped,
+				Message: "The data refresh is currently in progress. Skip a new data refresh iteration",
+			}
 			r.State.addAlert(alert)
 			r.tm.SendEvent(ctx, telemetry.AlertEvent, alert)
-			log.Err(alert.Message, err)
+			log.Msg(alert.Message)
+
+			return
 		}
+
+		r.runFullRefresh(ctx)
 	}
 }
 
-// fullRefresh performs full refresh for an unused storage pool and makes it active.
-func (r *Retrieval) fullRefresh(ctx context.Context) error {
-	if r.State.Status == models.Refreshing {
-		alert := telemetry.Alert{
-			Level:   models.RefreshSkipped,
-			Message: "The data refresh is currently in progress. Skip a new data refresh iteration",
+// FullRefresh starts a full refresh in the background on request. The refresh must not overlap with a scheduled one.
+func (r *Retrieval) FullRefresh(initiator string) error {
+	if r.ctx == nil {
+		return models.New(models.ErrCodeBadRequest, "data retrieval has not been started yet")
+	}
+
+	if r.State.IsPaused() {
+		return models.New(models.ErrCodeBadRequest, "data retrieval is paused, resume it to perform a data refresh")
+	}
+
+	if !r.State.beginRun(models.TriggerManual, initiator, r.cfg.Jobs) {
+		return models.New(models.ErrCodeBadRequest, "data refresh is already in progress")
+	}
+
+	log.Msg("Full refresh has been requested", initiator)
+
+	go r.runFullRefresh(r.ctx)
+
+	return nil
+}
+
+// Pause stops scheduled data refreshes and rejects manual ones. A refresh in progress is not interrupted.
+func (r *Retrieval) Pause() {
+	r.State.setPaused(true)
+
+	r.schedulerMu.Lock()
+	r.stopScheduler()
+	r.schedulerMu.Unlock()
+
+	log.Msg("Data retrieval has been paused")
+}
+
+// Resume restores scheduled data refreshes.
+func (r *Retrieval) Resume() {
+	r.State.setPaused(false)
+
+	if r.ctx != nil {
+		r.setupScheduler(r.ctx)
+	}
+
+	log.Msg("Data retrieval has been resumed")
+}
+
+// pointInTimeSnapshotter describes a job able to create snapshots as of the requested time.
+type pointInTimeSnapshotter interface {
+	CreatePointInTimeSnapshot(ctx context.Context, targetTime time.Time) (string, error)
+}
+
+// CreatePointInTimeSnapshot creates a snapshot with data as of the target time and returns its ID.
+// It blocks until the snapshot is ready.
+func (r *Retrieval) CreatePointInTimeSnapshot(targetTime time.Time) (string, error) {
+	if r.ctx == nil {
+		return "", models.New(models.ErrCodeBadRequest, "data retrieval has not been started yet")
+	}
+
+	if r.State.isRefreshing() {
+		return "", models.New(models.ErrCodeBadRequest, "data refresh is in progress, try again after it finishes")
+	}
+
+	for _, job := range r.jobs {
+		snapshotter, ok := job.(pointInTimeSnapshotter)
+		if !ok {
+			continue
+		}
+
+		log.Msg("Point-in-time snapshot has been requested: ", targetTime.Format(time.RFC3339))
+
+		snapshotID, err := snapshotter.CreatePointInTimeSnapshot(r.ctx, targetTime)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to create a point-in-time snapshot")
 		}
+
+		return snapshotID, nil
+	}
+
+	return "", models.New(models.ErrCodeBadRequest, "point-in-time snapshots require the physicalSnapshot job")
+}
+
+// runFullRefresh performs a full refresh registered with State.beginRun.
+func (r *Retrieval) runFullRefresh(ctx context.Context) {
+	err := r.fullRefresh(ctx)
+	r.finishRun(err)
+
+	if err != nil {
+		alert := telemetry.Alert{Level: models.RefreshFailed, Message: refreshFailedMessage("Failed to run full-refresh", err)}
 		r.State.addAlert(alert)
 		r.tm.SendEvent(ctx, telemetry.AlertEvent, alert)
-		log.Msg(alert.Message)
+		log.Err(alert.Message, err)
+	}
+}
 
-		return nil
+// refreshFailedMessage adds the failed check to the alert message if the snapshot has been rejected by validation.
+func refreshFailedMessage(message string, err error) string {
+	var validationErr *snapshot.ValidationError
+	if errors.As(err, &validationErr) {
+		return message + ". " + validationErr.Error()
 	}
 
+	return message
+}
+
+// fullRefresh performs full refresh for an unused storage pool and makes it active.
+func (r *Retrieval) fullRefresh(ctx context.Context) error {
 	// Stop previous runs and snapshot schedulers.
 	if r.ctxCancel != nil {
 		r.ctxCancel()
@@ -456,6 +691,12 @@ func (r *Retrieval) fullRefresh(ctx context.Context) error {
 
 	log.Msg("Pool to a full refresh: ", poolToUpdate.Pool())
 
+	if r.warmPool != nil {
+		// Pre-started clones are not used by anyone, so they must not block the refresh.
+		r.warmPool.SuspendWarmPool(poolToUpdate.Pool().Name)
+		defer r.warmPool.ResumeWarmPool()
+	}
+
 	if err := preparePoolToRefresh(poolToUpdate); err != nil {
 		return errors.Wrap(err, "failed to prepare the pool to a full refresh")
 	}
@@ -479,9 +720,13 @@ func (r *Retrieval) fullRefresh(ctx context.Context) error {
 
 // Stop stops a retrieval service.
 func (r *Retrieval) Stop() {
+	r.schedulerMu.Lock()
+	defer r.schedulerMu.Unlock()
+
 	r.stopScheduler()
 }
 
+// stopScheduler stops the refresh scheduler. It's not safe to invoke without the scheduler mutex locking.
 func (r *Retrieval) stopScheduler() {
 	if r.Scheduler.Cron != nil {
 		r.Scheduler.Cron.Stop()
@@ -3,9 +3,11 @@ package retrieval
 import (
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 
 	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/config"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/snapshot"
 )
 
 func TestParallelJobSpecs(t *testing.T) {
@@ -119,6 +121,10 @@ func TestLogicalJobs(t *testing.T) {
 			spec:       map[string]config.JobSpec{"logicalDump": {}},
 			hasLogical: true,
 		},
+		{
+			spec:       map[string]config.JobSpec{"logicalReplication": {}},
+			hasLogical: true,
+		},
 		{
 			spec: map[string]config.JobSpec{
 				"logicalDump":     {},
@@ -150,3 +156,37 @@ func TestLogicalJobs(t *testing.T) {
 		assert.Equal(t, tc.hasLogical, hasLogicalJob)
 	}
 }
+
+func TestRefreshFailedMessage(t *testing.T) {
+	assert.Equal(t, "Failed to run full-refresh",
+		refreshFailedMessage("Failed to run full-refresh", errors.New("failed to create a snapshot")))
+
+	validationErr := errors.Wrap(&snapshot.ValidationError{Check: "minRows public.users", Reason: "found 0 rows, expected at least 1000"},
+		"failed to promote instance")
+
+	assert.Equal(t, "Failed to run full-refresh. snapshot validation failed: minRows public.users: found 0 rows, expected at least 1000",
+		refreshFailedMessage("Failed to run full-refresh", validationErr))
+}
+
+type testWarmPool struct {
+	refills int
+}
+
+func (wp *testWarmPool) SuspendWarmPool(string) {}
+
+func (wp *testWarmPool) ResumeWarmPool() {}
+
+func (wp *testWarmPool) RefillWarmPool() {
+	wp.refills++
+}
+
+func TestSnapshotCreated(t *testing.T) {
+	r := &Retrieval{}
+	r.SnapshotCreated()
+
+	warmPool := &testWarmPool{}
+	r.SetWarmPool(warmPool)
+	r.SnapshotCreated()
+
+	assert.Equal(t, 1, warmPool.refills)
+}
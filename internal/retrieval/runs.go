@@ -0,0 +1,219 @@
+/*
+2022 © Postgres.ai
+*/
+
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/provision/pool"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/components"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/util"
+)
+
+const (
+	runsFilename = "retrieval_runs.json"
+	runLogsDir   = "retrieval_logs"
+
+	// maxRecordedRuns defines how many of the latest refresh runs are kept with their logs.
+	maxRecordedRuns = 100
+)
+
+// restoreRuns loads the history of refresh runs.
+func (r *Retrieval) restoreRuns() {
+	runsPath, err := util.GetMetaPath(runsFilename)
+	if err != nil {
+		log.Err("Failed to get path of a retrieval runs file", err)
+		return
+	}
+
+	data, err := os.ReadFile(runsPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Err("Failed to read retrieval runs", err)
+		}
+
+		return
+	}
+
+	var runs []*models.RetrievalRun
+
+	if err := json.Unmarshal(data, &runs); err != nil {
+		log.Err("Failed to decode retrieval runs", err)
+		return
+	}
+
+	r.State.restoreRuns(runs, time.Now())
+}
+
+// finishRun completes the current refresh run and saves the history of runs.
+func (r *Retrieval) finishRun(err error) {
+	r.State.finishRun(err)
+
+	for _, runID := range r.State.trimRuns(maxRecordedRuns) {
+		logsPath, err := runLogsPath(runID)
+		if err != nil {
+			log.Err("Failed to get path of retrieval logs", err)
+			continue
+		}
+
+		if err := os.RemoveAll(logsPath); err != nil {
+			log.Err("Failed to remove logs of the retrieval run", runID, err)
+		}
+	}
+
+	runsPath, err := util.GetMetaPath(runsFilename)
+	if err != nil {
+		log.Err("Failed to get path of a retrieval runs file", err)
+		return
+	}
+
+	data, err := json.Marshal(r.State.Runs())
+	if err != nil {
+		log.Err("Failed to encode retrieval runs", err)
+		return
+	}
+
+	if err := os.WriteFile(runsPath, data, 0600); err != nil {
+		log.Err("Failed to save retrieval runs", err)
+	}
+}
+
+// withLogCapture makes the job save logs of its containers to files linked to the current run.
+func (r *Retrieval) withLogCapture(ctx context.Context, jobName string) context.Context {
+	run := r.State.LastRun()
+	if run == nil || run.FinishedAt != nil {
+		return ctx
+	}
+
+	return tools.WithContainerLogsHandler(ctx, func(containerID string, logs []byte) {
+		filename := fmt.Sprintf("%s_%s.log", jobName, strings.ReplaceAll(containerID, string(filepath.Separator), "_"))
+		logsPath, err := runLogsPath(run.ID)
+		if err != nil {
+			log.Err("Failed to get path of retrieval logs", err)
+			return
+		}
+
+		if err := os.MkdirAll(logsPath, 0700); err != nil {
+			log.Err("Failed to create a directory for container logs", err)
+			return
+		}
+
+		filePath := filepath.Join(logsPath, filename)
+		_, statErr := os.Stat(filePath)
+
+		logFile, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			log.Err("Failed to open a container logs file", err)
+			return
+		}
+
+		defer func() { _ = logFile.Close() }()
+
+		if _, err := logFile.Write(logs); err != nil {
+			log.Err("Failed to save container logs", err)
+			return
+		}
+
+		if os.IsNotExist(statErr) {
+			r.State.addJobLogs(jobName, runLogLink(run.ID, filename))
+		}
+	})
+}
+
+// recordDataSize saves the number of bytes dumped or restored by the job.
+func (r *Retrieval) recordDataSize(job components.JobRunner) {
+	sizer, ok := job.(components.DataSizer)
+	if !ok {
+		return
+	}
+
+	dataSize, err := sizer.DataSize()
+	if err != nil {
+		log.Err("Failed to get the data size of the retrieval job", job.Name(), err)
+		return
+	}
+
+	r.State.setJobDataSize(job.Name(), dataSize)
+}
+
+// recordSnapshot saves ID of the latest snapshot created during the current run.
+func (r *Retrieval) recordSnapshot(fsm pool.FSManager) {
+	run := r.State.LastRun()
+	if run == nil || run.FinishedAt != nil {
+		return
+	}
+
+	snapshots, err := fsm.GetSnapshots()
+	if err != nil {
+		log.Err("Failed to get snapshots of the retrieval run", err)
+		return
+	}
+
+	var snapshotID string
+
+	latest := run.StartedAt
+
+	for _, snapshot := range snapshots {
+		if !snapshot.CreatedAt.Before(latest) {
+			latest = snapshot.CreatedAt
+			snapshotID = snapshot.ID
+		}
+	}
+
+	if snapshotID != "" {
+		r.State.setSnapshotID(snapshotID)
+	}
+}
+
+// RunLogsPath returns the path of a captured container logs file of the refresh run.
+func (r *Retrieval) RunLogsPath(runID int, filename string) (string, error) {
+	link := runLogLink(runID, filename)
+
+	for _, run := range r.State.Runs() {
+		if run.ID != runID {
+			continue
+		}
+
+		for _, job := range run.Jobs {
+			for _, jobLink := range job.Logs {
+				if jobLink != link {
+					continue
+				}
+
+				logsPath, err := runLogsPath(runID)
+				if err != nil {
+					return "", err
+				}
+
+				return filepath.Join(logsPath, filename), nil
+			}
+		}
+	}
+
+	return "", models.New(models.ErrCodeNotFound, "logs not found")
+}
+
+func runLogsPath(runID int) (string, error) {
+	logsDir, err := util.GetMetaPath(runLogsDir)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(logsDir, strconv.Itoa(runID)), nil
+}
+
+func runLogLink(runID int, filename string) string {
+	return fmt.Sprintf("/admin/retrieval/runs/%d/logs/%s", runID, filename)
+}
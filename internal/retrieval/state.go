@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/components"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/telemetry"
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
 )
@@ -19,6 +20,10 @@ type State struct {
 	LastRefresh *time.Time
 	mu          sync.Mutex
 	alerts      map[models.AlertType]models.Alert
+	paused      bool
+	runs        []*models.RetrievalRun
+	reporters   []components.ProgressReporter
+	syncStatus  *models.SyncStatus
 }
 
 // Alerts returns all registered retrieval alerts.
@@ -55,8 +60,287 @@ func (s *State) addAlert(telemetryAlert telemetry.Alert) {
 	s.alerts[telemetryAlert.Level] = alert
 }
 
+func (s *State) hasAlert(alertType models.AlertType) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.alerts[alertType]
+
+	return ok
+}
+
+func (s *State) removeAlert(alertType models.AlertType) {
+	s.mu.Lock()
+	delete(s.alerts, alertType)
+	s.mu.Unlock()
+}
+
 func (s *State) cleanAlerts() {
 	s.mu.Lock()
 	s.alerts = make(map[models.AlertType]models.Alert)
 	s.mu.Unlock()
 }
+
+// Progress returns progress of processing databases by jobs of the current or the last run.
+func (s *State) Progress() []models.DatabaseProgress {
+	s.mu.Lock()
+	reporters := s.reporters
+	s.mu.Unlock()
+
+	var progress []models.DatabaseProgress
+
+	for _, reporter := range reporters {
+		progress = append(progress, reporter.Progress()...)
+	}
+
+	return progress
+}
+
+// SyncStatus returns a copy of the last observed state of the sync instance.
+func (s *State) SyncStatus() *models.SyncStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.syncStatus == nil {
+		return nil
+	}
+
+	syncStatus := *s.syncStatus
+
+	return &syncStatus
+}
+
+func (s *State) setSyncStatus(syncStatus *models.SyncStatus) {
+	s.mu.Lock()
+	s.syncStatus = syncStatus
+	s.mu.Unlock()
+}
+
+// IsPaused reports whether scheduled and manual data refreshes are paused.
+func (s *State) IsPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.paused
+}
+
+// isRefreshing reports whether a refresh run is in progress.
+func (s *State) isRefreshing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.currentRun() != nil
+}
+
+func (s *State) setPaused(paused bool) {
+	s.mu.Lock()
+	s.paused = paused
+	s.mu.Unlock()
+}
+
+// LastRun returns a copy of the current or the last finished refresh run.
+func (s *State) LastRun() *models.RetrievalRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.runs) == 0 {
+		return nil
+	}
+
+	return copyRun(s.runs[len(s.runs)-1])
+}
+
+// Runs returns copies of recorded refresh runs starting from the latest one.
+func (s *State) Runs() []*models.RetrievalRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := make([]*models.RetrievalRun, 0, len(s.runs))
+
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		runs = append(runs, copyRun(s.runs[i]))
+	}
+
+	return runs
+}
+
+func copyRun(run *models.RetrievalRun) *models.RetrievalRun {
+	runCopy := *run
+	runCopy.Jobs = make([]models.JobProgress, len(run.Jobs))
+
+	for i, job := range run.Jobs {
+		runCopy.Jobs[i] = job
+		runCopy.Jobs[i].Logs = append([]string(nil), job.Logs...)
+	}
+
+	return &runCopy
+}
+
+// currentRun returns the run in progress.
+// It's not safe to invoke without the state mutex locking.
+func (s *State) currentRun() *models.RetrievalRun {
+	if len(s.runs) == 0 || s.runs[len(s.runs)-1].FinishedAt != nil {
+		return nil
+	}
+
+	return s.runs[len(s.runs)-1]
+}
+
+// beginRun registers a new refresh run. It returns false if another run is in progress.
+func (s *State) beginRun(trigger models.RefreshTrigger, initiator string, jobs []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.currentRun() != nil {
+		return false
+	}
+
+	run := &models.RetrievalRun{
+		ID:          1,
+		TriggeredBy: trigger,
+		Initiator:   initiator,
+		StartedAt:   time.Now().Truncate(time.Second),
+		Status:      models.Refreshing,
+		Jobs:        make([]models.JobProgress, 0, len(jobs)),
+	}
+
+	if len(s.runs) > 0 {
+		run.ID = s.runs[len(s.runs)-1].ID + 1
+	}
+
+	for _, job := range jobs {
+		run.Jobs = append(run.Jobs, models.JobProgress{Name: job, Status: models.JobPending})
+	}
+
+	s.runs = append(s.runs, run)
+	s.reporters = nil
+
+	return true
+}
+
+// trackProgress makes the state report progress of the job if the job supports it.
+func (s *State) trackProgress(job components.JobRunner) {
+	reporter, ok := job.(components.ProgressReporter)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	s.reporters = append(s.reporters, reporter)
+	s.mu.Unlock()
+}
+
+func (s *State) startJob(name string) {
+	s.updateJob(name, func(job *models.JobProgress, now time.Time) {
+		job.Status = models.JobRunning
+		job.StartedAt = &now
+	})
+}
+
+func (s *State) finishJob(name string, err error) {
+	s.updateJob(name, func(job *models.JobProgress, now time.Time) {
+		job.Status = models.JobFinished
+		if err != nil {
+			job.Status = models.JobFailed
+			job.Error = err.Error()
+		}
+
+		job.FinishedAt = &now
+	})
+}
+
+func (s *State) setJobDataSize(name string, dataSize uint64) {
+	s.updateJob(name, func(job *models.JobProgress, _ time.Time) {
+		job.DataSize = dataSize
+	})
+}
+
+func (s *State) addJobLogs(name, link string) {
+	s.updateJob(name, func(job *models.JobProgress, _ time.Time) {
+		job.Logs = append(job.Logs, link)
+	})
+}
+
+func (s *State) updateJob(name string, update func(job *models.JobProgress, now time.Time)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := s.currentRun()
+	if run == nil {
+		return
+	}
+
+	for i := range run.Jobs {
+		if run.Jobs[i].Name == name {
+			update(&run.Jobs[i], time.Now().Truncate(time.Second))
+			return
+		}
+	}
+}
+
+func (s *State) setSnapshotID(snapshotID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if run := s.currentRun(); run != nil {
+		run.SnapshotID = snapshotID
+	}
+}
+
+func (s *State) finishRun(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := s.currentRun()
+	if run == nil {
+		return
+	}
+
+	finishedAt := time.Now().Truncate(time.Second)
+	run.FinishedAt = &finishedAt
+	run.Status = models.Finished
+
+	if err != nil {
+		run.Status = models.Failed
+		run.Error = err.Error()
+	}
+}
+
+// restoreRuns sets recorded runs. Runs interrupted by the engine stop are marked as failed.
+func (s *State) restoreRuns(runs []*models.RetrievalRun, stoppedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, run := range runs {
+		if run.FinishedAt != nil {
+			continue
+		}
+
+		finishedAt := stoppedAt.Truncate(time.Second)
+		run.FinishedAt = &finishedAt
+		run.Status = models.Failed
+		run.Error = "interrupted by the engine stop"
+	}
+
+	s.runs = runs
+}
+
+// trimRuns keeps only the specified number of the latest runs and returns IDs of the removed ones.
+func (s *State) trimRuns(limit int) []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.runs) <= limit {
+		return nil
+	}
+
+	removed := make([]int, 0, len(s.runs)-limit)
+
+	for _, run := range s.runs[:len(s.runs)-limit] {
+		removed = append(removed, run.ID)
+	}
+
+	s.runs = append([]*models.RetrievalRun(nil), s.runs[len(s.runs)-limit:]...)
+
+	return removed
+}
@@ -5,6 +5,7 @@
 package retrieval
 
 import (
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -44,3 +45,71 @@ func TestState(t *testing.T) {
 
 	assert.Equal(t, 0, len(state.alerts))
 }
+
+func TestStateRun(t *testing.T) {
+	state := State{alerts: make(map[models.AlertType]models.Alert)}
+
+	assert.Nil(t, state.LastRun())
+	assert.True(t, state.beginRun(models.TriggerManual, "john", []string{"logicalDump", "logicalRestore"}))
+	assert.False(t, state.beginRun(models.TriggerTimetable, "", []string{"logicalDump", "logicalRestore"}))
+
+	state.startJob("logicalDump")
+	state.finishJob("logicalDump", nil)
+	state.startJob("logicalRestore")
+
+	run := state.LastRun()
+	assert.Equal(t, models.TriggerManual, run.TriggeredBy)
+	assert.Equal(t, "john", run.Initiator)
+	assert.Equal(t, models.Refreshing, run.Status)
+	assert.Equal(t, models.JobFinished, run.Jobs[0].Status)
+	assert.NotNil(t, run.Jobs[0].FinishedAt)
+	assert.Equal(t, models.JobRunning, run.Jobs[1].Status)
+	assert.Nil(t, run.Jobs[1].FinishedAt)
+
+	state.finishJob("logicalRestore", errors.New("restore failed"))
+	state.finishRun(errors.New("restore failed"))
+
+	run = state.LastRun()
+	assert.Equal(t, models.Failed, run.Status)
+	assert.Equal(t, "restore failed", run.Error)
+	assert.Equal(t, models.JobFailed, run.Jobs[1].Status)
+	assert.NotNil(t, run.FinishedAt)
+
+	// The returned run is a copy.
+	run.Jobs[0].Status = models.JobPending
+	assert.Equal(t, models.JobFinished, state.LastRun().Jobs[0].Status)
+
+	assert.True(t, state.beginRun(models.TriggerTimetable, "", nil))
+}
+
+func TestStateRunHistory(t *testing.T) {
+	state := State{alerts: make(map[models.AlertType]models.Alert)}
+	stoppedAt := time.Now()
+
+	state.restoreRuns([]*models.RetrievalRun{
+		{ID: 1, Status: models.Finished, FinishedAt: &stoppedAt},
+		{ID: 2, Status: models.Refreshing},
+	}, stoppedAt)
+
+	run := state.LastRun()
+	assert.Equal(t, 2, run.ID)
+	assert.Equal(t, models.Failed, run.Status)
+	assert.Equal(t, "interrupted by the engine stop", run.Error)
+	assert.NotNil(t, run.FinishedAt)
+
+	assert.True(t, state.beginRun(models.TriggerManual, "", []string{"logicalDump"}))
+	state.addJobLogs("logicalDump", "/admin/retrieval/runs/3/logs/logicalDump_dblab_ld.log")
+	state.setJobDataSize("logicalDump", 1024)
+	state.setSnapshotID("dblab_pool@snapshot_20220501000000")
+	state.finishRun(nil)
+
+	runs := state.Runs()
+	assert.Equal(t, []int{3, 2, 1}, []int{runs[0].ID, runs[1].ID, runs[2].ID})
+	assert.Equal(t, "dblab_pool@snapshot_20220501000000", runs[0].SnapshotID)
+	assert.Equal(t, uint64(1024), runs[0].Jobs[0].DataSize)
+	assert.Equal(t, []string{"/admin/retrieval/runs/3/logs/logicalDump_dblab_ld.log"}, runs[0].Jobs[0].Logs)
+
+	assert.Equal(t, []int{1, 2}, state.trimRuns(1))
+	assert.Nil(t, state.trimRuns(1))
+	assert.Len(t, state.Runs(), 1)
+}
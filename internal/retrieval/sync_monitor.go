@@ -0,0 +1,272 @@
+/*
+2022 © Postgres.ai
+*/
+
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/config"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/cont"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/engine/postgres/tools/defaults"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/telemetry"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/log"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+const (
+	// syncMonitoringPause defines how often the disabled monitoring checks whether it has been enabled.
+	syncMonitoringPause = time.Minute
+
+	syncStatusFields       = 5
+	streamingReceiverState = "streaming"
+	pgVersionNum10         = 100000
+)
+
+// walWaitEvents lists wait events of the startup process that mean it has replayed all available WAL and waits for more.
+var walWaitEvents = map[string]struct{}{
+	"RecoveryRetrieveRetryInterval": {},
+	"RecoveryWalAll":                {},
+	"RecoveryWalStream":             {},
+}
+
+// monitorSync periodically checks the sync instance and raises alerts if it does not keep up with the source.
+func (r *Retrieval) monitorSync(ctx context.Context) {
+	for {
+		select {
+		case <-time.After(r.syncMonitoringInterval()):
+		case <-ctx.Done():
+			return
+		}
+
+		if !r.isSyncMonitoringEnabled() {
+			r.State.setSyncStatus(nil)
+			r.ResolveAlert(models.SyncUnhealthy)
+
+			continue
+		}
+
+		r.checkSync(ctx)
+	}
+}
+
+func (r *Retrieval) syncMonitoringConfig() config.SyncMonitoring {
+	r.syncMonitoringMu.Lock()
+	defer r.syncMonitoringMu.Unlock()
+
+	return r.syncMonitoring
+}
+
+func (r *Retrieval) isSyncMonitoringEnabled() bool {
+	return r.syncMonitoringConfig().Interval > 0 && r.State.Mode == models.Physical
+}
+
+func (r *Retrieval) syncMonitoringInterval() time.Duration {
+	if !r.isSyncMonitoringEnabled() {
+		return syncMonitoringPause
+	}
+
+	return time.Duration(r.syncMonitoringConfig().Interval) * time.Second
+}
+
+func (r *Retrieval) checkSync(ctx context.Context) {
+	// The sync instance is recreated during a refresh.
+	if r.State.isRefreshing() {
+		return
+	}
+
+	syncStatus := r.getSyncStatus(ctx, time.Now())
+	r.State.setSyncStatus(syncStatus)
+
+	problems := syncProblems(syncStatus, r.syncMonitoringConfig())
+	if len(problems) == 0 {
+		r.ResolveAlert(models.SyncUnhealthy)
+		return
+	}
+
+	alert := telemetry.Alert{
+		Level:   models.SyncUnhealthy,
+		Message: "Sync instance is unhealthy: " + strings.Join(problems, "; "),
+	}
+
+	isRaised := r.State.hasAlert(models.SyncUnhealthy)
+
+	r.State.addAlert(alert)
+
+	if !isRaised {
+		log.Warn(alert.Message)
+		r.tm.SendEvent(ctx, telemetry.AlertEvent, alert)
+	}
+}
+
+func (r *Retrieval) getSyncStatus(ctx context.Context, now time.Time) *models.SyncStatus {
+	syncStatus := &models.SyncStatus{CheckedAt: now.Truncate(time.Second)}
+	containerName := cont.SyncInstanceContainerPrefix + r.engineProps.InstanceID
+
+	syncContainer, err := r.docker.ContainerInspect(ctx, containerName)
+	if err != nil {
+		syncStatus.Error = "sync instance is not found"
+		log.Dbg("Failed to inspect the sync instance:", err)
+
+		return syncStatus
+	}
+
+	if syncContainer.State == nil || !syncContainer.State.Running {
+		syncStatus.Error = "sync instance is not running"
+		return syncStatus
+	}
+
+	pgVersionNum, err := r.querySyncInstance(ctx, syncContainer.ID, "show server_version_num")
+	if err != nil {
+		syncStatus.Error = err.Error()
+		return syncStatus
+	}
+
+	versionNum, err := strconv.Atoi(pgVersionNum)
+	if err != nil {
+		syncStatus.Error = fmt.Sprintf("invalid Postgres version number: %q", pgVersionNum)
+		return syncStatus
+	}
+
+	output, err := r.querySyncInstance(ctx, syncContainer.ID, buildSyncStatusQuery(versionNum))
+	if err != nil {
+		syncStatus.Error = err.Error()
+		return syncStatus
+	}
+
+	if err := parseSyncStatus(output, now, syncStatus); err != nil {
+		syncStatus.Error = err.Error()
+	}
+
+	return syncStatus
+}
+
+func (r *Retrieval) querySyncInstance(ctx context.Context, containerID, query string) (string, error) {
+	output, err := tools.ExecCommandWithOutput(ctx, r.docker, containerID, types.ExecConfig{
+		Cmd:  []string{"psql", "-U", r.global.Database.User(), "-d", r.global.Database.Name(), "-XAtc", query},
+		User: defaults.Username,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to query the sync instance")
+	}
+
+	return output, nil
+}
+
+// buildSyncStatusQuery builds a query returning the recovery flag, the commit time of the last replayed transaction,
+// the amount of received but not replayed WAL, the status of the WAL receiver, and the wait event of the startup process.
+func buildSyncStatusQuery(pgVersionNum int) string {
+	diffFunc, receiveFunc, replayFunc := "pg_wal_lsn_diff", "pg_last_wal_receive_lsn", "pg_last_wal_replay_lsn"
+	startupWaitEvent := "coalesce((select wait_event from pg_stat_activity where backend_type = 'startup' limit 1), '')"
+
+	if pgVersionNum < pgVersionNum10 {
+		diffFunc, receiveFunc, replayFunc = "pg_xlog_location_diff", "pg_last_xlog_receive_location", "pg_last_xlog_replay_location"
+		startupWaitEvent = "''"
+	}
+
+	return fmt.Sprintf("select pg_is_in_recovery(), "+
+		"coalesce(extract(epoch from pg_last_xact_replay_timestamp())::bigint::text, ''), "+
+		"coalesce(%s(%s(), %s())::bigint::text, ''), "+
+		"coalesce((select status from pg_stat_wal_receiver limit 1), ''), "+
+		"%s",
+		diffFunc, receiveFunc, replayFunc, startupWaitEvent)
+}
+
+func parseSyncStatus(output string, now time.Time, syncStatus *models.SyncStatus) error {
+	fields := strings.Split(strings.TrimSpace(output), "|")
+	if len(fields) != syncStatusFields {
+		return errors.Errorf("unexpected output of the sync status query: %q", output)
+	}
+
+	syncStatus.InRecovery = fields[0] == "t"
+	syncStatus.ReceiverStatus = fields[3]
+
+	if fields[2] != "" {
+		lagBytes, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid replay lag in bytes: %q", fields[2])
+		}
+
+		syncStatus.ReplayLagBytes = &lagBytes
+	}
+
+	if fields[1] == "" {
+		return nil
+	}
+
+	replayEpoch, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "invalid last replay timestamp: %q", fields[1])
+	}
+
+	lastReplayAt := time.Unix(replayEpoch, 0).UTC()
+	syncStatus.LastReplayAt = &lastReplayAt
+
+	replayLag := int64(now.Sub(lastReplayAt).Seconds())
+
+	// No new transactions on the source do not mean a lag if there is no WAL to replay: either all streamed WAL is replayed,
+	// or the instance restores WAL from an archive and waits for the next segment.
+	if replayLag < 0 || isStreamReplayed(syncStatus) || isArchiveReplayed(syncStatus, fields[4]) {
+		replayLag = 0
+	}
+
+	syncStatus.ReplayLag = &replayLag
+
+	return nil
+}
+
+func isStreamReplayed(syncStatus *models.SyncStatus) bool {
+	return syncStatus.ReceiverStatus == streamingReceiverState && syncStatus.ReplayLagBytes != nil && *syncStatus.ReplayLagBytes == 0
+}
+
+func isArchiveReplayed(syncStatus *models.SyncStatus, startupWaitEvent string) bool {
+	if syncStatus.ReceiverStatus != "" {
+		return false
+	}
+
+	_, ok := walWaitEvents[startupWaitEvent]
+
+	return ok
+}
+
+// syncProblems lists the sync instance problems according to the monitoring thresholds.
+func syncProblems(syncStatus *models.SyncStatus, cfg config.SyncMonitoring) []string {
+	if syncStatus.Error != "" {
+		return []string{syncStatus.Error}
+	}
+
+	problems := []string{}
+
+	if !syncStatus.InRecovery {
+		problems = append(problems, "sync instance is not in recovery mode")
+	}
+
+	if cfg.MaxReplayLag > 0 && syncStatus.ReplayLag != nil && *syncStatus.ReplayLag > cfg.MaxReplayLag {
+		problems = append(problems, fmt.Sprintf("replay lag is %d seconds (threshold: %d)", *syncStatus.ReplayLag, cfg.MaxReplayLag))
+	}
+
+	if cfg.MaxReplayLagBytes > 0 && syncStatus.ReplayLagBytes != nil && *syncStatus.ReplayLagBytes > cfg.MaxReplayLagBytes {
+		problems = append(problems,
+			fmt.Sprintf("%d bytes of WAL are not replayed (threshold: %d)", *syncStatus.ReplayLagBytes, cfg.MaxReplayLagBytes))
+	}
+
+	if cfg.RequireStreaming && syncStatus.ReceiverStatus != streamingReceiverState {
+		receiverStatus := syncStatus.ReceiverStatus
+		if receiverStatus == "" {
+			receiverStatus = "not running"
+		}
+
+		problems = append(problems, "WAL receiver is "+receiverStatus)
+	}
+
+	return problems
+}
@@ -0,0 +1,142 @@
+package retrieval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/retrieval/config"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+func TestBuildSyncStatusQuery(t *testing.T) {
+	assert.Contains(t, buildSyncStatusQuery(140005), "pg_wal_lsn_diff(pg_last_wal_receive_lsn(), pg_last_wal_replay_lsn())")
+	assert.Contains(t, buildSyncStatusQuery(96020), "pg_xlog_location_diff(pg_last_xlog_receive_location(), pg_last_xlog_replay_location())")
+}
+
+func TestParseSyncStatus(t *testing.T) {
+	now := time.Date(2022, 5, 1, 14, 0, 0, 0, time.UTC)
+	lastReplayAt := time.Date(2022, 5, 1, 13, 50, 0, 0, time.UTC)
+	replayEpoch := "1651413000"
+
+	testCases := []struct {
+		output   string
+		expected models.SyncStatus
+	}{
+		{
+			output: "t|" + replayEpoch + "|0|streaming|",
+			expected: models.SyncStatus{
+				InRecovery:     true,
+				LastReplayAt:   &lastReplayAt,
+				ReplayLag:      pointer.ToInt64(0),
+				ReplayLagBytes: pointer.ToInt64(0),
+				ReceiverStatus: "streaming",
+			},
+		},
+		{
+			output: "t|" + replayEpoch + "|1024|streaming|",
+			expected: models.SyncStatus{
+				InRecovery:     true,
+				LastReplayAt:   &lastReplayAt,
+				ReplayLag:      pointer.ToInt64(600),
+				ReplayLagBytes: pointer.ToInt64(1024),
+				ReceiverStatus: "streaming",
+			},
+		},
+		{
+			output: "t|" + replayEpoch + "|||",
+			expected: models.SyncStatus{
+				InRecovery:   true,
+				LastReplayAt: &lastReplayAt,
+				ReplayLag:    pointer.ToInt64(600),
+			},
+		},
+		{
+			output: "t|" + replayEpoch + "|||RecoveryRetrieveRetryInterval",
+			expected: models.SyncStatus{
+				InRecovery:   true,
+				LastReplayAt: &lastReplayAt,
+				ReplayLag:    pointer.ToInt64(0),
+			},
+		},
+		{
+			output: "t|" + replayEpoch + "|1024|streaming|RecoveryWalStream",
+			expected: models.SyncStatus{
+				InRecovery:     true,
+				LastReplayAt:   &lastReplayAt,
+				ReplayLag:      pointer.ToInt64(600),
+				ReplayLagBytes: pointer.ToInt64(1024),
+				ReceiverStatus: "streaming",
+			},
+		},
+		{
+			output:   "f||||",
+			expected: models.SyncStatus{},
+		},
+	}
+
+	for _, tc := range testCases {
+		syncStatus := models.SyncStatus{}
+
+		require.NoError(t, parseSyncStatus(tc.output, now, &syncStatus))
+		assert.Equal(t, tc.expected, syncStatus)
+	}
+
+	assert.Error(t, parseSyncStatus("t|", now, &models.SyncStatus{}))
+	assert.Error(t, parseSyncStatus("t|invalid|||", now, &models.SyncStatus{}))
+}
+
+func TestSyncProblems(t *testing.T) {
+	cfg := config.SyncMonitoring{
+		Interval:          60,
+		MaxReplayLag:      300,
+		MaxReplayLagBytes: 1024,
+		RequireStreaming:  true,
+	}
+
+	testCases := []struct {
+		syncStatus models.SyncStatus
+		expected   []string
+	}{
+		{
+			syncStatus: models.SyncStatus{
+				InRecovery:     true,
+				ReplayLag:      pointer.ToInt64(10),
+				ReplayLagBytes: pointer.ToInt64(0),
+				ReceiverStatus: "streaming",
+			},
+			expected: []string{},
+		},
+		{
+			syncStatus: models.SyncStatus{Error: "sync instance is not running"},
+			expected:   []string{"sync instance is not running"},
+		},
+		{
+			syncStatus: models.SyncStatus{
+				ReplayLag:      pointer.ToInt64(600),
+				ReplayLagBytes: pointer.ToInt64(2048),
+			},
+			expected: []string{
+				"sync instance is not in recovery mode",
+				"replay lag is 600 seconds (threshold: 300)",
+				"2048 bytes of WAL are not replayed (threshold: 1024)",
+				"WAL receiver is not running",
+			},
+		},
+		{
+			syncStatus: models.SyncStatus{InRecovery: true, ReceiverStatus: "stopping"},
+			expected:   []string{"WAL receiver is stopping"},
+		},
+	}
+
+	for _, tc := range testCases {
+		syncStatus := tc.syncStatus
+
+		assert.Equal(t, tc.expected, syncProblems(&syncStatus, cfg))
+	}
+
+	assert.Empty(t, syncProblems(&models.SyncStatus{InRecovery: true, ReplayLag: pointer.ToInt64(600)}, config.SyncMonitoring{}))
+}
@@ -41,6 +41,20 @@ func (a *Auth) Authorized(h http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// AdminAuthorized checks if the request is made with the verification token of the instance.
+// Personal tokens are not allowed to access admin handlers.
+func (a *Auth) AdminAuthorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(VerificationTokenHeader)
+		if !a.isAdminAccessAllowed(token) {
+			api.SendUnauthorizedError(w, r)
+			return
+		}
+
+		h(w, r)
+	}
+}
+
 func (a *Auth) isAccessAllowed(ctx context.Context, token string) bool {
 	if a.verificationToken == "" {
 		return true
@@ -57,3 +71,11 @@ func (a *Auth) isAccessAllowed(ctx context.Context, token string) bool {
 
 	return false
 }
+
+func (a *Auth) isAdminAccessAllowed(token string) bool {
+	if a.verificationToken == "" {
+		return true
+	}
+
+	return subtle.ConstantTimeCompare([]byte(a.verificationToken), []byte(token)) == 1
+}
@@ -58,3 +58,17 @@ func TestAccess(t *testing.T) {
 		assert.Equal(t, tc.result, isAllowed)
 	}
 }
+
+func TestAdminAccess(t *testing.T) {
+	mw := Auth{
+		verificationToken:     testVerificationToken,
+		personalTokenVerifier: MockPersonalTokenVerifier{isPersonalTokenEnabled: true},
+	}
+
+	assert.True(t, mw.isAdminAccessAllowed(testVerificationToken))
+	assert.False(t, mw.isAdminAccessAllowed(testPlatformAccessToken))
+	assert.False(t, mw.isAdminAccessAllowed(""))
+
+	mw.verificationToken = ""
+	assert.True(t, mw.isAdminAccessAllowed(""))
+}
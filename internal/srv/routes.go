@@ -2,11 +2,14 @@ package srv
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -14,6 +17,7 @@ import (
 	"github.com/jackc/pgtype/pgxtype"
 	"github.com/pkg/errors"
 
+	"gitlab.com/postgres-ai/database-lab/v3/internal/cloning"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/estimator"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/observer"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/srv/api"
@@ -47,6 +51,88 @@ func (s *Server) getSnapshots(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+const (
+	defaultClonesPageLimit = 100
+	maxClonesPageLimit     = 1000
+)
+
+// getClones provides a filtered page of clones.
+func (s *Server) getClones(w http.ResponseWriter, r *http.Request) {
+	filter, err := cloneFilterFromQuery(r)
+	if err != nil {
+		api.SendBadRequestError(w, r, err.Error())
+		return
+	}
+
+	offset, limit, err := pageFromQuery(r)
+	if err != nil {
+		api.SendBadRequestError(w, r, err.Error())
+		return
+	}
+
+	clones := s.Cloning.FilterClones(filter)
+	page := models.ClonesPage{
+		Clones: []*models.Clone{},
+		Total:  len(clones),
+		Offset: offset,
+		Limit:  limit,
+	}
+
+	if offset < len(clones) {
+		end := offset + limit
+		if end > len(clones) {
+			end = len(clones)
+		}
+
+		page.Clones = clones[offset:end]
+	}
+
+	if err := api.WriteJSON(w, http.StatusOK, page); err != nil {
+		api.SendError(w, r, err)
+		return
+	}
+}
+
+// cloneFilterFromQuery builds a clone filter from query parameters. Multiple "label" parameters are combined.
+func cloneFilterFromQuery(r *http.Request) (cloning.CloneFilter, error) {
+	query := r.URL.Query()
+
+	selector, err := cloning.ParseSelector(strings.Join(query["label"], ","))
+	if err != nil {
+		return cloning.CloneFilter{}, err
+	}
+
+	return cloning.CloneFilter{
+		Selector:   selector,
+		Status:     models.StatusCode(query.Get("status")),
+		SnapshotID: query.Get("snapshot"),
+	}, nil
+}
+
+func pageFromQuery(r *http.Request) (int, int, error) {
+	offset, limit := 0, defaultClonesPageLimit
+
+	if value := r.URL.Query().Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, errors.Errorf("invalid offset %q", value)
+		}
+
+		offset = parsed
+	}
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxClonesPageLimit {
+			return 0, 0, errors.Errorf("invalid limit %q: must be between 1 and %d", value, maxClonesPageLimit)
+		}
+
+		limit = parsed
+	}
+
+	return offset, limit, nil
+}
+
 func (s *Server) createClone(w http.ResponseWriter, r *http.Request) {
 	var cloneRequest *types.CloneCreateRequest
 	if err := api.ReadJSON(r, &cloneRequest); err != nil {
@@ -106,6 +192,45 @@ func (s *Server) destroyClone(w http.ResponseWriter, r *http.Request) {
 	log.Dbg(fmt.Sprintf("Clone ID=%s is being deleted", cloneID))
 }
 
+// destroyClones destroys clones matching the filter.
+func (s *Server) destroyClones(w http.ResponseWriter, r *http.Request) {
+	filter, err := cloneFilterFromQuery(r)
+	if err != nil {
+		api.SendBadRequestError(w, r, err.Error())
+		return
+	}
+
+	if filter.Empty() {
+		api.SendBadRequestError(w, r, "at least one filter is required to destroy clones")
+		return
+	}
+
+	result := models.ClonesDestroyResult{
+		Destroyed: []string{},
+		Failed:    make(map[string]string),
+	}
+
+	for _, clone := range s.Cloning.FilterClones(filter) {
+		if err := s.Cloning.DestroyClone(clone.ID); err != nil {
+			result.Failed[clone.ID] = err.Error()
+			continue
+		}
+
+		result.Destroyed = append(result.Destroyed, clone.ID)
+
+		s.tm.SendEvent(context.Background(), telemetry.CloneDestroyedEvent, telemetry.CloneDestroyed{
+			ID: util.HashID(clone.ID),
+		})
+	}
+
+	log.Dbg(fmt.Sprintf("Clones are being deleted: %v", result.Destroyed))
+
+	if err := api.WriteJSON(w, http.StatusOK, result); err != nil {
+		api.SendError(w, r, err)
+		return
+	}
+}
+
 func (s *Server) patchClone(w http.ResponseWriter, r *http.Request) {
 	cloneID := mux.Vars(r)["id"]
 
@@ -121,8 +246,24 @@ func (s *Server) patchClone(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.validator.ValidateLabels(patchClone.Labels); err != nil {
+		api.SendBadRequestError(w, r, err.Error())
+		return
+	}
+
+	if err := s.validator.ValidateAllowedCIDRs(patchClone.AllowedCIDRs); err != nil {
+		api.SendBadRequestError(w, r, err.Error())
+		return
+	}
+
 	updatedClone, err := s.Cloning.UpdateClone(cloneID, patchClone)
 	if err != nil {
+		var reqErr *models.Error
+		if errors.As(err, &reqErr) && reqErr.Code == models.ErrCodeBadRequest {
+			api.SendBadRequestError(w, r, reqErr.Error())
+			return
+		}
+
 		api.SendError(w, r, errors.Wrap(err, "failed to update clone"))
 		return
 	}
@@ -183,6 +324,294 @@ func (s *Server) resetClone(w http.ResponseWriter, r *http.Request) {
 	log.Dbg(fmt.Sprintf("Clone ID=%s is being reset", cloneID))
 }
 
+const (
+	queryFormatJSON = "json"
+	queryFormatCSV  = "csv"
+
+	defaultQueryTimeout  = 30 * time.Second
+	maxQueryTimeout      = 5 * time.Minute
+	defaultQueryRowLimit = 1000
+	maxQueryRowLimit     = 10000
+)
+
+// queryClone runs a query in a clone and returns its result.
+func (s *Server) queryClone(w http.ResponseWriter, r *http.Request) {
+	cloneID := mux.Vars(r)["id"]
+
+	if cloneID == "" {
+		api.SendBadRequestError(w, r, "ID must not be empty")
+		return
+	}
+
+	var queryRequest types.CloneQueryRequest
+	if err := api.ReadJSON(r, &queryRequest); err != nil {
+		api.SendBadRequestError(w, r, err.Error())
+		return
+	}
+
+	opts, err := queryOptions(queryRequest)
+	if err != nil {
+		api.SendBadRequestError(w, r, err.Error())
+		return
+	}
+
+	// The request context is canceled when the client disconnects, so the running query is canceled as well.
+	if queryRequest.Explain {
+		plan, err := s.Cloning.ExplainQuery(r.Context(), cloneID, opts)
+		if err != nil {
+			sendQueryError(w, r, err)
+			return
+		}
+
+		if err := api.WriteJSON(w, http.StatusOK, plan); err != nil {
+			api.SendError(w, r, err)
+		}
+
+		return
+	}
+
+	result, err := s.Cloning.RunQuery(r.Context(), cloneID, opts)
+	if err != nil {
+		sendQueryError(w, r, err)
+		return
+	}
+
+	if queryRequest.Format == queryFormatCSV {
+		if err := writeQueryCSV(w, result); err != nil {
+			log.Err("Failed to write query result:", err)
+		}
+
+		return
+	}
+
+	if err := api.WriteJSON(w, http.StatusOK, result); err != nil {
+		api.SendError(w, r, err)
+	}
+}
+
+func queryOptions(queryRequest types.CloneQueryRequest) (cloning.QueryOptions, error) {
+	opts := cloning.QueryOptions{
+		Query:            strings.TrimSpace(queryRequest.Query),
+		StatementTimeout: defaultQueryTimeout,
+		RowLimit:         defaultQueryRowLimit,
+	}
+
+	if opts.Query == "" {
+		return cloning.QueryOptions{}, errors.New("query must not be empty")
+	}
+
+	switch queryRequest.Format {
+	case "", queryFormatJSON:
+	case queryFormatCSV:
+		if queryRequest.Explain {
+			return cloning.QueryOptions{}, errors.New("explain mode supports only the json format")
+		}
+
+	default:
+		return cloning.QueryOptions{}, errors.Errorf("unsupported format %q", queryRequest.Format)
+	}
+
+	if queryRequest.StatementTimeout != "" {
+		timeout, err := time.ParseDuration(queryRequest.StatementTimeout)
+		if err != nil || timeout <= 0 || timeout > maxQueryTimeout {
+			return cloning.QueryOptions{}, errors.Errorf("invalid statement timeout %q: must be a positive duration up to %s",
+				queryRequest.StatementTimeout, maxQueryTimeout)
+		}
+
+		opts.StatementTimeout = timeout
+	}
+
+	if queryRequest.RowLimit != 0 {
+		if queryRequest.RowLimit < 0 || queryRequest.RowLimit > maxQueryRowLimit {
+			return cloning.QueryOptions{}, errors.Errorf("invalid row limit %d: must be between 1 and %d",
+				queryRequest.RowLimit, maxQueryRowLimit)
+		}
+
+		opts.RowLimit = queryRequest.RowLimit
+	}
+
+	return opts, nil
+}
+
+func sendQueryError(w http.ResponseWriter, r *http.Request, err error) {
+	var reqErr *models.Error
+	if !errors.As(err, &reqErr) {
+		api.SendError(w, r, errors.Wrap(err, "failed to run query"))
+		return
+	}
+
+	if reqErr.Code == models.ErrCodeNotFound {
+		api.SendNotFoundError(w, r)
+		return
+	}
+
+	api.SendBadRequestError(w, r, reqErr.Error())
+}
+
+// writeQueryCSV writes the query result in the CSV format. NULL values are written as empty strings.
+func writeQueryCSV(w http.ResponseWriter, result *models.QueryResult) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("X-Query-Truncated", strconv.FormatBool(result.Truncated))
+	w.WriteHeader(http.StatusOK)
+
+	csvWriter := csv.NewWriter(w)
+
+	header := make([]string, 0, len(result.Columns))
+	for _, column := range result.Columns {
+		header = append(header, column.Name)
+	}
+
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range result.Rows {
+		record := make([]string, 0, len(row))
+
+		for _, value := range row {
+			if value == nil {
+				record = append(record, "")
+				continue
+			}
+
+			record = append(record, *value)
+		}
+
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
+
+const defaultUsagePeriod = 30 * 24 * time.Hour
+
+// usageQuery defines parameters of a usage report request.
+type usageQuery struct {
+	from    time.Time
+	to      time.Time
+	groupBy models.UsageGroupBy
+	label   string
+	format  string
+}
+
+// getUsage provides the usage report of clones.
+func (s *Server) getUsage(w http.ResponseWriter, r *http.Request) {
+	query, err := usageQueryFromRequest(r, time.Now())
+	if err != nil {
+		api.SendBadRequestError(w, r, err.Error())
+		return
+	}
+
+	report := s.Cloning.UsageReport(query.from, query.to, query.groupBy, query.label)
+
+	if query.format == queryFormatCSV {
+		if err := writeUsageCSV(w, report); err != nil {
+			log.Err("Failed to write usage report:", err)
+		}
+
+		return
+	}
+
+	if err := api.WriteJSON(w, http.StatusOK, report); err != nil {
+		api.SendError(w, r, err)
+	}
+}
+
+// usageQueryFromRequest parses parameters of a usage report. The report covers the last 30 days by default.
+func usageQueryFromRequest(r *http.Request, now time.Time) (usageQuery, error) {
+	values := r.URL.Query()
+	query := usageQuery{
+		to:      now,
+		groupBy: models.UsageGroupByUser,
+		label:   values.Get("label"),
+		format:  values.Get("format"),
+	}
+
+	if value := values.Get("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return usageQuery{}, errors.Errorf("invalid \"to\" time %q: must be in the RFC 3339 format", value)
+		}
+
+		query.to = to
+	}
+
+	query.from = query.to.Add(-defaultUsagePeriod)
+
+	if value := values.Get("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return usageQuery{}, errors.Errorf("invalid \"from\" time %q: must be in the RFC 3339 format", value)
+		}
+
+		query.from = from
+	}
+
+	if !query.from.Before(query.to) {
+		return usageQuery{}, errors.New("the start of the period must be before its end")
+	}
+
+	switch groupBy := models.UsageGroupBy(values.Get("groupBy")); groupBy {
+	case "", models.UsageGroupByUser:
+	case models.UsageGroupByLabel:
+		if query.label == "" {
+			return usageQuery{}, errors.New("label must be specified to group usage by label")
+		}
+
+		query.groupBy = groupBy
+
+	default:
+		return usageQuery{}, errors.Errorf("unsupported groupBy %q", groupBy)
+	}
+
+	switch query.format {
+	case "", queryFormatJSON, queryFormatCSV:
+	default:
+		return usageQuery{}, errors.Errorf("unsupported format %q", query.format)
+	}
+
+	return query, nil
+}
+
+// writeUsageCSV writes the usage report in the CSV format.
+func writeUsageCSV(w http.ResponseWriter, report *models.UsageReport) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	csvWriter := csv.NewWriter(w)
+
+	header := []string{"key", "clones", "created", "resets", "destroyed", "clone_hours", "idle_hours", "peak_diff_size", "avg_diff_size"}
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	for _, group := range report.Groups {
+		record := []string{
+			group.Key,
+			strconv.Itoa(group.Clones),
+			strconv.Itoa(group.Created),
+			strconv.Itoa(group.Resets),
+			strconv.Itoa(group.Destroyed),
+			strconv.FormatFloat(group.CloneHours, 'f', -1, 64),
+			strconv.FormatFloat(group.IdleHours, 'f', -1, 64),
+			strconv.FormatUint(group.PeakDiffSize, 10),
+			strconv.FormatUint(group.AvgDiffSize, 10),
+		}
+
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
+
 func (s *Server) startEstimator(w http.ResponseWriter, r *http.Request) {
 	values := r.URL.Query()
 	cloneID := values.Get("clone_id")
@@ -529,6 +958,142 @@ func (s *Server) downloadArtifact(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, filePath)
 }
 
+// refreshRetrieval starts a full data refresh in the background.
+func (s *Server) refreshRetrieval(w http.ResponseWriter, r *http.Request) {
+	var refreshRequest types.RefreshRequest
+
+	// The request body is optional.
+	if err := json.NewDecoder(r.Body).Decode(&refreshRequest); err != nil && !errors.Is(err, io.EOF) {
+		api.SendBadRequestError(w, r, err.Error())
+		return
+	}
+
+	if err := s.Retrieval.FullRefresh(refreshRequest.Initiator); err != nil {
+		var reqErr *models.Error
+		if errors.As(err, &reqErr) {
+			api.SendBadRequestError(w, r, reqErr.Error())
+			return
+		}
+
+		api.SendError(w, r, err)
+
+		return
+	}
+
+	if err := api.WriteJSON(w, http.StatusAccepted, s.retrievingStatus()); err != nil {
+		api.SendError(w, r, err)
+	}
+}
+
+// createPointInTimeSnapshot creates a snapshot of data as of the requested point in time.
+func (s *Server) createPointInTimeSnapshot(w http.ResponseWriter, r *http.Request) {
+	var snapshotRequest types.PointInTimeSnapshotRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&snapshotRequest); err != nil {
+		api.SendBadRequestError(w, r, err.Error())
+		return
+	}
+
+	targetTime, err := time.Parse(time.RFC3339, snapshotRequest.TargetTime)
+	if err != nil {
+		api.SendBadRequestError(w, r, fmt.Sprintf("invalid target time %q: must be in the RFC 3339 format", snapshotRequest.TargetTime))
+		return
+	}
+
+	snapshotID, err := s.Retrieval.CreatePointInTimeSnapshot(targetTime)
+	if err != nil {
+		var reqErr *models.Error
+		if errors.As(err, &reqErr) {
+			api.SendBadRequestError(w, r, reqErr.Error())
+			return
+		}
+
+		api.SendError(w, r, err)
+
+		return
+	}
+
+	snapshots, err := s.Cloning.GetSnapshots()
+	if err != nil {
+		api.SendError(w, r, err)
+		return
+	}
+
+	for i := range snapshots {
+		if snapshots[i].ID == snapshotID {
+			if err := api.WriteJSON(w, http.StatusCreated, snapshots[i]); err != nil {
+				api.SendError(w, r, err)
+			}
+
+			return
+		}
+	}
+
+	api.SendError(w, r, errors.Errorf("snapshot %s has been created, but not found in the snapshot list", snapshotID))
+}
+
+// pauseRetrieval pauses scheduled data refreshes.
+func (s *Server) pauseRetrieval(w http.ResponseWriter, r *http.Request) {
+	s.Retrieval.Pause()
+
+	if err := api.WriteJSON(w, http.StatusOK, s.retrievingStatus()); err != nil {
+		api.SendError(w, r, err)
+	}
+}
+
+// resumeRetrieval resumes scheduled data refreshes.
+func (s *Server) resumeRetrieval(w http.ResponseWriter, r *http.Request) {
+	s.Retrieval.Resume()
+
+	if err := api.WriteJSON(w, http.StatusOK, s.retrievingStatus()); err != nil {
+		api.SendError(w, r, err)
+	}
+}
+
+// getRetrievalRuns provides the history of data retrieval runs.
+func (s *Server) getRetrievalRuns(w http.ResponseWriter, r *http.Request) {
+	if err := api.WriteJSON(w, http.StatusOK, s.Retrieval.State.Runs()); err != nil {
+		api.SendError(w, r, err)
+	}
+}
+
+// getRetrievalRunLogs provides logs of a data retrieval run.
+func (s *Server) getRetrievalRunLogs(w http.ResponseWriter, r *http.Request) {
+	runID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		api.SendBadRequestError(w, r, "invalid run ID")
+		return
+	}
+
+	logsPath, err := s.Retrieval.RunLogsPath(runID, mux.Vars(r)["filename"])
+	if err != nil {
+		var reqErr *models.Error
+		if errors.As(err, &reqErr) && reqErr.Code == models.ErrCodeNotFound {
+			api.SendNotFoundError(w, r)
+			return
+		}
+
+		api.SendError(w, r, err)
+
+		return
+	}
+
+	logFile, err := os.Open(logsPath)
+	if err != nil {
+		api.SendError(w, r, errors.Wrap(err, "failed to open logs"))
+		return
+	}
+
+	defer func() { _ = logFile.Close() }()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, logFile); err != nil {
+		log.Err("Failed to write retrieval logs:", err)
+	}
+}
+
 // healthCheck provides a health check handler.
 func (s *Server) healthCheck(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
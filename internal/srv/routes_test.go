@@ -0,0 +1,106 @@
+package srv
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/postgres-ai/database-lab/v3/internal/cloning"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/client/dblabapi/types"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+func TestQueryOptions(t *testing.T) {
+	opts, err := queryOptions(types.CloneQueryRequest{Query: " select 1 "})
+	require.NoError(t, err)
+	assert.Equal(t, cloning.QueryOptions{Query: "select 1", StatementTimeout: defaultQueryTimeout, RowLimit: defaultQueryRowLimit}, opts)
+
+	opts, err = queryOptions(types.CloneQueryRequest{Query: "select 1", Format: "csv", StatementTimeout: "5s", RowLimit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, cloning.QueryOptions{Query: "select 1", StatementTimeout: 5 * time.Second, RowLimit: 10}, opts)
+}
+
+func TestQueryOptionsErrors(t *testing.T) {
+	testCases := []struct {
+		request types.CloneQueryRequest
+		error   string
+	}{
+		{
+			request: types.CloneQueryRequest{},
+			error:   "query must not be empty",
+		},
+		{
+			request: types.CloneQueryRequest{Query: "select 1", Format: "xml"},
+			error:   `unsupported format "xml"`,
+		},
+		{
+			request: types.CloneQueryRequest{Query: "select 1", Format: "csv", Explain: true},
+			error:   "explain mode supports only the json format",
+		},
+		{
+			request: types.CloneQueryRequest{Query: "select 1", StatementTimeout: "1h"},
+			error:   `invalid statement timeout "1h": must be a positive duration up to 5m0s`,
+		},
+		{
+			request: types.CloneQueryRequest{Query: "select 1", RowLimit: 100000},
+			error:   "invalid row limit 100000: must be between 1 and 10000",
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := queryOptions(tc.request)
+		assert.EqualError(t, err, tc.error)
+	}
+}
+
+func TestUsageQueryFromRequest(t *testing.T) {
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	query, err := usageQueryFromRequest(httptest.NewRequest(http.MethodGet, "/usage", nil), now)
+	require.NoError(t, err)
+	assert.Equal(t, usageQuery{from: now.Add(-defaultUsagePeriod), to: now, groupBy: models.UsageGroupByUser}, query)
+
+	query, err = usageQueryFromRequest(httptest.NewRequest(http.MethodGet,
+		"/usage?from=2022-05-01T00:00:00Z&to=2022-05-02T00:00:00Z&groupBy=label&label=team&format=csv", nil), now)
+	require.NoError(t, err)
+	assert.Equal(t, usageQuery{
+		from:    time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+		to:      time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC),
+		groupBy: models.UsageGroupByLabel,
+		label:   "team",
+		format:  "csv",
+	}, query)
+
+	testCases := []struct {
+		url   string
+		error string
+	}{
+		{url: "/usage?from=yesterday", error: `invalid "from" time "yesterday": must be in the RFC 3339 format`},
+		{url: "/usage?from=2022-05-02T00:00:00Z&to=2022-05-01T00:00:00Z", error: "the start of the period must be before its end"},
+		{url: "/usage?groupBy=label", error: "label must be specified to group usage by label"},
+		{url: "/usage?groupBy=team", error: `unsupported groupBy "team"`},
+		{url: "/usage?format=xml", error: `unsupported format "xml"`},
+	}
+
+	for _, tc := range testCases {
+		_, err := usageQueryFromRequest(httptest.NewRequest(http.MethodGet, tc.url, nil), now)
+		assert.EqualError(t, err, tc.error)
+	}
+}
+
+func TestWriteUsageCSV(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	err := writeUsageCSV(recorder, &models.UsageReport{Groups: []models.UsageGroup{
+		{Key: "alice", Clones: 2, Created: 1, CloneHours: 1.5, PeakDiffSize: 300, AvgDiffSize: 200},
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "key,clones,created,resets,destroyed,clone_hours,idle_hours,peak_diff_size,avg_diff_size\n"+
+		"alice,2,1,0,0,1.5,0,300,200\n", recorder.Body.String())
+}
@@ -19,7 +19,9 @@ import (
 	"github.com/pkg/errors"
 
 	"gitlab.com/postgres-ai/database-lab/v3/internal/cloning"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/diskpressure"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/estimator"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/freshness"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/observer"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/platform"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/provision"
@@ -39,22 +41,24 @@ import (
 
 // Server defines an HTTP server of the Database Lab.
 type Server struct {
-	validator   validator.Service
-	Cloning     *cloning.Base
-	provisioner *provision.Provisioner
-	Config      *srvCfg.Config
-	Global      *global.Config
-	engProps    global.EngineProps
-	Retrieval   *retrieval.Retrieval
-	Platform    *platform.Service
-	Observer    *observer.Observer
-	Estimator   *estimator.Estimator
-	upgrader    websocket.Upgrader
-	httpSrv     *http.Server
-	docker      *client.Client
-	pm          *pool.Manager
-	tm          *telemetry.Agent
-	startedAt   *time.Time
+	validator     validator.Service
+	Cloning       *cloning.Base
+	provisioner   *provision.Provisioner
+	Config        *srvCfg.Config
+	Global        *global.Config
+	engProps      global.EngineProps
+	Retrieval     *retrieval.Retrieval
+	Platform      *platform.Service
+	Observer      *observer.Observer
+	Estimator     *estimator.Estimator
+	DiskPressure  *diskpressure.Controller
+	DataFreshness *freshness.Controller
+	upgrader      websocket.Upgrader
+	httpSrv       *http.Server
+	docker        *client.Client
+	pm            *pool.Manager
+	tm            *telemetry.Agent
+	startedAt     *time.Time
 }
 
 // NewServer initializes a new Server instance with provided configuration.
@@ -67,23 +71,27 @@ func NewServer(cfg *srvCfg.Config, globalCfg *global.Config,
 	platform *platform.Service,
 	observer *observer.Observer,
 	estimator *estimator.Estimator,
+	diskPressure *diskpressure.Controller,
+	dataFreshness *freshness.Controller,
 	pm *pool.Manager,
 	tm *telemetry.Agent) *Server {
 	server := &Server{
-		Config:      cfg,
-		Global:      globalCfg,
-		engProps:    engineProps,
-		Cloning:     cloning,
-		provisioner: provisioner,
-		Retrieval:   retrievalSvc,
-		Platform:    platform,
-		Observer:    observer,
-		Estimator:   estimator,
-		upgrader:    websocket.Upgrader{},
-		docker:      dockerClient,
-		pm:          pm,
-		tm:          tm,
-		startedAt:   pointer.ToTimeOrNil(time.Now().Truncate(time.Second)),
+		Config:        cfg,
+		Global:        globalCfg,
+		engProps:      engineProps,
+		Cloning:       cloning,
+		provisioner:   provisioner,
+		Retrieval:     retrievalSvc,
+		Platform:      platform,
+		Observer:      observer,
+		Estimator:     estimator,
+		DiskPressure:  diskPressure,
+		DataFreshness: dataFreshness,
+		upgrader:      websocket.Upgrader{},
+		docker:        dockerClient,
+		pm:            pm,
+		tm:            tm,
+		startedAt:     pointer.ToTimeOrNil(time.Now().Truncate(time.Second)),
 	}
 
 	return server
@@ -100,19 +108,12 @@ func (s *Server) instanceStatus() *models.InstanceStatus {
 			StartedAt: s.startedAt,
 			Telemetry: pointer.ToBool(s.tm.IsEnabled()),
 		},
-		Pools:       s.provisioner.GetPoolEntryList(),
-		Cloning:     s.Cloning.GetCloningState(),
-		Provisioner: s.provisioner.ContainerOptions(),
-		Retrieving: models.Retrieving{
-			Mode:        s.Retrieval.State.Mode,
-			Status:      s.Retrieval.State.Status,
-			Alerts:      s.Retrieval.State.Alerts(),
-			LastRefresh: s.Retrieval.State.LastRefresh,
-		},
-	}
-
-	if s.Retrieval.Scheduler.Spec != nil {
-		instanceStatus.Retrieving.NextRefresh = pointer.ToTimeOrNil(s.Retrieval.Scheduler.Spec.Next(time.Now()))
+		Pools:         s.provisioner.GetPoolEntryList(),
+		Cloning:       s.Cloning.GetCloningState(),
+		Provisioner:   s.provisioner.ContainerOptions(),
+		Retrieving:    s.retrievingStatus(),
+		DiskPressure:  s.DiskPressure.Status(),
+		DataFreshness: s.DataFreshness.Status(),
 	}
 
 	s.summarizeStatus(instanceStatus)
@@ -120,12 +121,39 @@ func (s *Server) instanceStatus() *models.InstanceStatus {
 	return instanceStatus
 }
 
+func (s *Server) retrievingStatus() models.Retrieving {
+	retrieving := models.Retrieving{
+		Mode:        s.Retrieval.State.Mode,
+		Status:      s.Retrieval.State.Status,
+		Alerts:      s.Retrieval.State.Alerts(),
+		LastRefresh: s.Retrieval.State.LastRefresh,
+		Paused:      s.Retrieval.State.IsPaused(),
+		LastRun:     s.Retrieval.State.LastRun(),
+		Progress:    s.Retrieval.State.Progress(),
+		Sync:        s.Retrieval.State.SyncStatus(),
+	}
+
+	if s.Retrieval.Scheduler.Spec != nil {
+		retrieving.NextRefresh = pointer.ToTimeOrNil(s.Retrieval.Scheduler.Spec.Next(time.Now()))
+	}
+
+	return retrieving
+}
+
 func (s *Server) summarizeStatus(instance *models.InstanceStatus) {
 	subsystems := []string{}
 	if instance.Retrieving.Status == models.Failed {
 		subsystems = append(subsystems, "retrieving")
 	}
 
+	if instance.DiskPressure != nil && instance.DiskPressure.Level != models.DiskPressureOK {
+		subsystems = append(subsystems, "disk pressure")
+	}
+
+	if instance.DataFreshness != nil && instance.DataFreshness.Stale {
+		subsystems = append(subsystems, "data freshness")
+	}
+
 	if len(subsystems) > 0 {
 		instance.Status = &models.Status{
 			Code:    models.StatusWarning,
@@ -171,18 +199,30 @@ func (s *Server) InitHandlers() {
 
 	r.HandleFunc("/status", authMW.Authorized(s.getInstanceStatus)).Methods(http.MethodGet)
 	r.HandleFunc("/snapshots", authMW.Authorized(s.getSnapshots)).Methods(http.MethodGet)
+	r.HandleFunc("/clones", authMW.Authorized(s.getClones)).Methods(http.MethodGet)
 	r.HandleFunc("/clone", authMW.Authorized(s.createClone)).Methods(http.MethodPost)
 	r.HandleFunc("/clone/{id}", authMW.Authorized(s.destroyClone)).Methods(http.MethodDelete)
 	r.HandleFunc("/clone/{id}", authMW.Authorized(s.patchClone)).Methods(http.MethodPatch)
 	r.HandleFunc("/clone/{id}", authMW.Authorized(s.getClone)).Methods(http.MethodGet)
 	r.HandleFunc("/clone/{id}/reset", authMW.Authorized(s.resetClone)).Methods(http.MethodPost)
+	r.HandleFunc("/clone/{id}/query", authMW.Authorized(s.queryClone)).Methods(http.MethodPost)
 	r.HandleFunc("/clone/{id}", authMW.Authorized(s.getClone)).Methods(http.MethodGet)
 	r.HandleFunc("/observation/start", authMW.Authorized(s.startObservation)).Methods(http.MethodPost)
 	r.HandleFunc("/observation/stop", authMW.Authorized(s.stopObservation)).Methods(http.MethodPost)
 	r.HandleFunc("/observation/summary/{clone_id}/{session_id}", authMW.Authorized(s.sessionSummaryObservation)).Methods(http.MethodGet)
 	r.HandleFunc("/observation/download", authMW.Authorized(s.downloadArtifact)).Methods(http.MethodGet)
+	r.HandleFunc("/usage", authMW.Authorized(s.getUsage)).Methods(http.MethodGet)
 	r.HandleFunc("/estimate", s.startEstimator).Methods(http.MethodGet)
 
+	// Admin handlers.
+	r.HandleFunc("/admin/clones", authMW.AdminAuthorized(s.destroyClones)).Methods(http.MethodDelete)
+	r.HandleFunc("/admin/retrieval/refresh", authMW.AdminAuthorized(s.refreshRetrieval)).Methods(http.MethodPost)
+	r.HandleFunc("/admin/retrieval/pause", authMW.AdminAuthorized(s.pauseRetrieval)).Methods(http.MethodPost)
+	r.HandleFunc("/admin/retrieval/resume", authMW.AdminAuthorized(s.resumeRetrieval)).Methods(http.MethodPost)
+	r.HandleFunc("/admin/retrieval/runs", authMW.AdminAuthorized(s.getRetrievalRuns)).Methods(http.MethodGet)
+	r.HandleFunc("/admin/retrieval/runs/{id}/logs/{filename}", authMW.AdminAuthorized(s.getRetrievalRunLogs)).Methods(http.MethodGet)
+	r.HandleFunc("/admin/snapshot/point-in-time", authMW.AdminAuthorized(s.createPointInTimeSnapshot)).Methods(http.MethodPost)
+
 	// Health check.
 	r.HandleFunc("/healthz", s.healthCheck).Methods(http.MethodGet)
 
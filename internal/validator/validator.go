@@ -6,11 +6,20 @@
 package validator
 
 import (
+	"net"
+	"regexp"
+
 	"github.com/pkg/errors"
 
 	"gitlab.com/postgres-ai/database-lab/v3/pkg/client/dblabapi/types"
 )
 
+var (
+	initBundleNameRegexp = regexp.MustCompile(`^[\w.-]+$`)
+	labelKeyRegexp       = regexp.MustCompile(`^[A-Za-z0-9]([\w./-]{0,61}[A-Za-z0-9])?$`)
+	labelValueRegexp     = regexp.MustCompile(`^[\w.-]{0,63}$`)
+)
+
 // Service provides a validation service.
 type Service struct {
 }
@@ -29,5 +38,47 @@ func (v Service) ValidateCloneRequest(cloneRequest *types.CloneCreateRequest) er
 		return errors.New("missing DB password")
 	}
 
+	if cloneRequest.Init != nil {
+		for _, bundle := range cloneRequest.Init.Bundles {
+			if !initBundleNameRegexp.MatchString(bundle) || bundle == "." || bundle == ".." {
+				return errors.Errorf("invalid init bundle name %q", bundle)
+			}
+		}
+	}
+
+	if err := v.ValidateLabels(cloneRequest.Labels); err != nil {
+		return err
+	}
+
+	if err := v.ValidateAllowedCIDRs(cloneRequest.AllowedCIDRs); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidateLabels validates clone labels.
+func (v Service) ValidateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if !labelKeyRegexp.MatchString(key) {
+			return errors.Errorf("invalid label key %q", key)
+		}
+
+		if !labelValueRegexp.MatchString(value) {
+			return errors.Errorf("invalid value of label %q", key)
+		}
+	}
+
+	return nil
+}
+
+// ValidateAllowedCIDRs validates networks allowed to connect to a clone.
+func (v Service) ValidateAllowedCIDRs(allowedCIDRs []string) error {
+	for _, cidr := range allowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return errors.Errorf("invalid CIDR %q", cidr)
+		}
+	}
+
 	return nil
 }
@@ -19,7 +19,10 @@ func TestValidationCloneRequest(t *testing.T) {
 			DB: &types.DatabaseRequest{
 				Username: "username",
 				Password: "password",
-			}})
+			},
+			Labels:       map[string]string{"team": "payments", "ci.example.com/pipeline": "1234"},
+			AllowedCIDRs: []string{"10.0.0.0/8", "2001:db8::/32"},
+		})
 
 	assert.Nil(t, err)
 }
@@ -43,6 +46,41 @@ func TestValidationCloneRequestErrors(t *testing.T) {
 			createRequest: types.CloneCreateRequest{DB: &types.DatabaseRequest{Password: "password"}},
 			error:         "missing DB username",
 		},
+		{
+			createRequest: types.CloneCreateRequest{
+				DB:   &types.DatabaseRequest{Username: "user", Password: "password"},
+				Init: &types.InitScriptsRequest{Bundles: []string{"../etc/passwd"}},
+			},
+			error: `invalid init bundle name "../etc/passwd"`,
+		},
+		{
+			createRequest: types.CloneCreateRequest{
+				DB:   &types.DatabaseRequest{Username: "user", Password: "password"},
+				Init: &types.InitScriptsRequest{Bundles: []string{".."}},
+			},
+			error: `invalid init bundle name ".."`,
+		},
+		{
+			createRequest: types.CloneCreateRequest{
+				DB:     &types.DatabaseRequest{Username: "user", Password: "password"},
+				Labels: map[string]string{"team name": "payments"},
+			},
+			error: `invalid label key "team name"`,
+		},
+		{
+			createRequest: types.CloneCreateRequest{
+				DB:     &types.DatabaseRequest{Username: "user", Password: "password"},
+				Labels: map[string]string{"team": "pay,ments"},
+			},
+			error: `invalid value of label "team"`,
+		},
+		{
+			createRequest: types.CloneCreateRequest{
+				DB:           &types.DatabaseRequest{Username: "user", Password: "password"},
+				AllowedCIDRs: []string{"10.0.0.0/8", "10.0.0.1"},
+			},
+			error: `invalid CIDR "10.0.0.1"`,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -12,6 +12,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -56,6 +57,68 @@ func (c *Client) ListClonesRaw(ctx context.Context) (io.ReadCloser, error) {
 	return response.Body, nil
 }
 
+// ListClonesByFilter provides a page of Database Lab clones matching the filter.
+func (c *Client) ListClonesByFilter(ctx context.Context, filter types.ClonesFilterRequest) (*models.ClonesPage, error) {
+	body, err := c.ListClonesByFilterRaw(ctx, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get response")
+	}
+
+	defer func() { _ = body.Close() }()
+
+	var page models.ClonesPage
+
+	if err := json.NewDecoder(body).Decode(&page); err != nil {
+		return nil, errors.Wrap(err, "failed to decode a response body")
+	}
+
+	return &page, nil
+}
+
+// ListClonesByFilterRaw provides a raw page of Database Lab clones matching the filter.
+func (c *Client) ListClonesByFilterRaw(ctx context.Context, filter types.ClonesFilterRequest) (io.ReadCloser, error) {
+	u := c.URL("/clones")
+	u.RawQuery = clonesFilterQuery(filter).Encode()
+
+	request, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make a request")
+	}
+
+	response, err := c.Do(ctx, request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get response")
+	}
+
+	return response.Body, nil
+}
+
+func clonesFilterQuery(filter types.ClonesFilterRequest) url.Values {
+	values := url.Values{}
+
+	if filter.Selector != "" {
+		values.Set("label", filter.Selector)
+	}
+
+	if filter.Status != "" {
+		values.Set("status", filter.Status)
+	}
+
+	if filter.SnapshotID != "" {
+		values.Set("snapshot", filter.SnapshotID)
+	}
+
+	if filter.Offset > 0 {
+		values.Set("offset", strconv.Itoa(filter.Offset))
+	}
+
+	if filter.Limit > 0 {
+		values.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	return values
+}
+
 // GetClone returns info about a Database Lab clone.
 func (c *Client) GetClone(ctx context.Context, cloneID string) (*models.Clone, error) {
 	body, err := c.GetCloneRaw(ctx, cloneID)
@@ -126,11 +189,11 @@ func (c *Client) CreateClone(ctx context.Context, cloneRequest types.CloneCreate
 		return clone, nil
 	}
 
-	if clone.Status.Code != models.StatusCreating {
+	if clone.Status.Code != models.StatusCreating && clone.Status.Code != models.StatusQueued {
 		return nil, errors.Errorf("unexpected clone status given: %v", clone.Status)
 	}
 
-	clone, err = c.watchCloneStatus(ctx, clone.ID, clone.Status.Code)
+	clone, err = c.watchCloneStatus(ctx, clone.ID, models.StatusQueued, models.StatusCreating)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to watch the clone status")
 	}
@@ -142,8 +205,8 @@ func (c *Client) CreateClone(ctx context.Context, cloneRequest types.CloneCreate
 	return clone, nil
 }
 
-// watchCloneStatus checks the clone status for changing.
-func (c *Client) watchCloneStatus(ctx context.Context, cloneID string, initialStatusCode models.StatusCode) (*models.Clone, error) {
+// watchCloneStatus waits until the clone status leaves the pending status codes.
+func (c *Client) watchCloneStatus(ctx context.Context, cloneID string, pendingStatusCodes ...models.StatusCode) (*models.Clone, error) {
 	pollingTimer := time.NewTimer(c.pollingInterval)
 	defer pollingTimer.Stop()
 
@@ -162,7 +225,7 @@ func (c *Client) watchCloneStatus(ctx context.Context, cloneID string, initialSt
 				return nil, errors.Wrap(err, "failed to get clone info")
 			}
 
-			if clone.Status.Code != initialStatusCode {
+			if !isPendingStatus(clone.Status.Code, pendingStatusCodes) {
 				return clone, nil
 			}
 
@@ -174,6 +237,16 @@ func (c *Client) watchCloneStatus(ctx context.Context, cloneID string, initialSt
 	}
 }
 
+func isPendingStatus(code models.StatusCode, pendingStatusCodes []models.StatusCode) bool {
+	for _, pendingCode := range pendingStatusCodes {
+		if code == pendingCode {
+			return true
+		}
+	}
+
+	return false
+}
+
 // CreateCloneAsync asynchronously creates a new Database Lab clone.
 func (c *Client) CreateCloneAsync(ctx context.Context, cloneRequest types.CloneCreateRequest) (*models.Clone, error) {
 	u := c.URL("/clone")
@@ -236,7 +309,7 @@ func (c *Client) ResetClone(ctx context.Context, cloneID string, params types.Re
 
 	defer func() { _ = response.Body.Close() }()
 
-	clone, err := c.watchCloneStatus(ctx, cloneID, models.StatusResetting)
+	clone, err := c.watchCloneStatus(ctx, cloneID, models.StatusQueued, models.StatusResetting)
 	if err != nil {
 		return errors.Wrap(err, "failed to watch the clone status")
 	}
@@ -288,7 +361,7 @@ func (c *Client) DestroyClone(ctx context.Context, cloneID string) error {
 
 	defer func() { _ = response.Body.Close() }()
 
-	clone, err := c.watchCloneStatus(ctx, cloneID, models.StatusDeleting)
+	clone, err := c.watchCloneStatus(ctx, cloneID, models.StatusQueued, models.StatusDeleting)
 	if err != nil {
 		if err, ok := errors.Cause(err).(models.Error); ok && err.Code == models.ErrCodeNotFound {
 			return nil
@@ -323,6 +396,32 @@ func (c *Client) DestroyCloneAsync(ctx context.Context, cloneID string) error {
 	return nil
 }
 
+// DestroyClones destroys all Database Lab clones matching the filter. It requires the verification token of the instance.
+func (c *Client) DestroyClones(ctx context.Context, filter types.ClonesFilterRequest) (*models.ClonesDestroyResult, error) {
+	u := c.URL("/admin/clones")
+	u.RawQuery = clonesFilterQuery(filter).Encode()
+
+	request, err := http.NewRequest(http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make a request")
+	}
+
+	response, err := c.Do(ctx, request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get response")
+	}
+
+	defer func() { _ = response.Body.Close() }()
+
+	var result models.ClonesDestroyResult
+
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, errors.Wrap(err, "failed to decode a response body")
+	}
+
+	return &result, nil
+}
+
 // StartObservation starts a new clone observation.
 func (c *Client) StartObservation(ctx context.Context, startRequest types.StartObservationRequest) (*observer.Session, error) {
 	u := c.URL("/observation/start")
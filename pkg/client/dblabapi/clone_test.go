@@ -65,6 +65,48 @@ func TestClientListClones(t *testing.T) {
 	assert.EqualValues(t, expectedClones, cloneList)
 }
 
+func TestClientListClonesByFilter(t *testing.T) {
+	expectedPage := &models.ClonesPage{
+		Clones: []*models.Clone{{
+			ID:     "testCloneID",
+			Labels: map[string]string{"team": "payments"},
+		}},
+		Total:  1,
+		Offset: 0,
+		Limit:  100,
+	}
+
+	mockClient := NewTestClient(func(req *http.Request) *http.Response {
+		assert.Equal(t, "https://example.com/clones?label=team%3Dpayments&snapshot=snapshot1&status=OK", req.URL.String())
+
+		body, err := json.Marshal(expectedPage)
+		require.NoError(t, err)
+
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewBuffer(body)),
+			Header:     make(http.Header),
+		}
+	})
+
+	c, err := NewClient(Options{
+		Host:              "https://example.com/",
+		VerificationToken: "token",
+	})
+	require.NoError(t, err)
+
+	c.client = mockClient
+
+	page, err := c.ListClonesByFilter(context.Background(), types.ClonesFilterRequest{
+		Selector:   "team=payments",
+		Status:     "OK",
+		SnapshotID: "snapshot1",
+	})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, expectedPage, page)
+}
+
 func TestClientListClonesWithFailedRequest(t *testing.T) {
 	mockClient := NewTestClient(func(r *http.Request) *http.Response {
 		return &http.Response{
@@ -356,7 +398,8 @@ func TestClientUpdateClone(t *testing.T) {
 		err = json.Unmarshal(requestBody, &updateRequest)
 		require.NoError(t, err)
 
-		cloneModel.Protected = updateRequest.Protected
+		require.NotNil(t, updateRequest.Protected)
+		cloneModel.Protected = *updateRequest.Protected
 
 		// Prepare response.
 		responseBody, err := json.Marshal(cloneModel)
@@ -378,8 +421,10 @@ func TestClientUpdateClone(t *testing.T) {
 	c.client = mockClient
 
 	// Send a request.
+	protected := false
+
 	newClone, err := c.UpdateClone(context.Background(), cloneModel.ID, types.CloneUpdateRequest{
-		Protected: false,
+		Protected: &protected,
 	})
 	require.NoError(t, err)
 
@@ -12,11 +12,29 @@ type CloneCreateRequest struct {
 	DB        *DatabaseRequest           `json:"db"`
 	Snapshot  *SnapshotCloneFieldRequest `json:"snapshot"`
 	ExtraConf map[string]string          `json:"extra_conf"`
+	Init      *InitScriptsRequest        `json:"init"`
+	Labels    map[string]string          `json:"labels"`
+	// AllowedCIDRs limits networks allowed to connect to the clone. The default list of the instance is used if it is empty.
+	AllowedCIDRs []string `json:"allowed_cidrs"`
+	// Pooler starts a PgBouncer sidecar in front of the clone.
+	Pooler bool `json:"pooler"`
+}
+
+// InitScriptsRequest represents SQL scripts to run in a new clone after the user is created.
+type InitScriptsRequest struct {
+	Bundles []string `json:"bundles"`
+	SQL     string   `json:"sql"`
 }
 
 // CloneUpdateRequest represents params of an update request.
 type CloneUpdateRequest struct {
-	Protected bool `json:"protected"`
+	// Protected changes the protection of the clone if it is set.
+	Protected *bool `json:"protected,omitempty"`
+	// Labels replaces clone labels if it is set. An empty map removes all labels.
+	Labels map[string]string `json:"labels"`
+	// AllowedCIDRs replaces networks allowed to connect to the clone if it is set.
+	// An empty list restores the default list of the instance.
+	AllowedCIDRs []string `json:"allowed_cidrs"`
 }
 
 // DatabaseRequest represents database params of a clone request.
@@ -37,3 +55,21 @@ type ResetCloneRequest struct {
 	SnapshotID string `json:"snapshotID"`
 	Latest     bool   `json:"latest"`
 }
+
+// ClonesFilterRequest represents filters of a clone list request.
+type ClonesFilterRequest struct {
+	Selector   string
+	Status     string
+	SnapshotID string
+	Offset     int
+	Limit      int
+}
+
+// CloneQueryRequest represents params of an ad-hoc query request.
+type CloneQueryRequest struct {
+	Query            string `json:"query"`
+	Format           string `json:"format"`
+	StatementTimeout string `json:"statement_timeout"`
+	RowLimit         int    `json:"row_limit"`
+	Explain          bool   `json:"explain"`
+}
@@ -0,0 +1,17 @@
+/*
+2022 © Postgres.ai
+*/
+
+package types
+
+// RefreshRequest represents params of a manual data refresh request.
+type RefreshRequest struct {
+	// Initiator describes who requests the refresh and why, for example, "john: incident 42".
+	Initiator string `json:"initiator"`
+}
+
+// PointInTimeSnapshotRequest represents params of a point-in-time snapshot request.
+type PointInTimeSnapshotRequest struct {
+	// TargetTime defines the moment the snapshot data must correspond to in the RFC 3339 format, for example, "2022-05-01T14:32:10Z".
+	TargetTime string `json:"targetTime"`
+}
@@ -0,0 +1,18 @@
+/*
+2022 © Postgres.ai
+*/
+
+package types
+
+import (
+	"time"
+)
+
+// UsageRequest represents params of a clone usage report request.
+type UsageRequest struct {
+	From    time.Time
+	To      time.Time
+	GroupBy string
+	Label   string
+	Format  string
+}
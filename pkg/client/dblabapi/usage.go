@@ -0,0 +1,83 @@
+/*
+2022 © Postgres.ai
+*/
+
+package dblabapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/client/dblabapi/types"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+// Usage provides a clone usage report.
+func (c *Client) Usage(ctx context.Context, usageRequest types.UsageRequest) (*models.UsageReport, error) {
+	usageRequest.Format = ""
+
+	body, err := c.UsageRaw(ctx, usageRequest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get response")
+	}
+
+	defer func() { _ = body.Close() }()
+
+	var report models.UsageReport
+
+	if err := json.NewDecoder(body).Decode(&report); err != nil {
+		return nil, errors.Wrap(err, "failed to decode a response body")
+	}
+
+	return &report, nil
+}
+
+// UsageRaw provides a raw clone usage report in the requested format.
+func (c *Client) UsageRaw(ctx context.Context, usageRequest types.UsageRequest) (io.ReadCloser, error) {
+	u := c.URL("/usage")
+	u.RawQuery = usageQuery(usageRequest).Encode()
+
+	request, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make a request")
+	}
+
+	response, err := c.Do(ctx, request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get response")
+	}
+
+	return response.Body, nil
+}
+
+func usageQuery(usageRequest types.UsageRequest) url.Values {
+	values := url.Values{}
+
+	if !usageRequest.From.IsZero() {
+		values.Set("from", usageRequest.From.Format(time.RFC3339))
+	}
+
+	if !usageRequest.To.IsZero() {
+		values.Set("to", usageRequest.To.Format(time.RFC3339))
+	}
+
+	if usageRequest.GroupBy != "" {
+		values.Set("groupBy", usageRequest.GroupBy)
+	}
+
+	if usageRequest.Label != "" {
+		values.Set("label", usageRequest.Label)
+	}
+
+	if usageRequest.Format != "" {
+		values.Set("format", usageRequest.Format)
+	}
+
+	return values
+}
@@ -0,0 +1,59 @@
+package dblabapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/client/dblabapi/types"
+	"gitlab.com/postgres-ai/database-lab/v3/pkg/models"
+)
+
+func TestClientUsage(t *testing.T) {
+	from := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	expectedReport := &models.UsageReport{
+		From:    from,
+		To:      to,
+		GroupBy: models.UsageGroupByLabel,
+		Label:   "team",
+		Groups: []models.UsageGroup{
+			{Key: "payments", Clones: 2, Created: 2, CloneHours: 12.5, PeakDiffSize: 1024, AvgDiffSize: 512},
+		},
+	}
+
+	mockClient := NewTestClient(func(req *http.Request) *http.Response {
+		assert.Equal(t, "https://example.com/usage?from=2022-05-01T00%3A00%3A00Z&groupBy=label&label=team&to=2022-06-01T00%3A00%3A00Z",
+			req.URL.String())
+
+		body, err := json.Marshal(expectedReport)
+		require.NoError(t, err)
+
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewBuffer(body)),
+			Header:     make(http.Header),
+		}
+	})
+
+	c, err := NewClient(Options{
+		Host:              "https://example.com/",
+		VerificationToken: "testVerify",
+	})
+	require.NoError(t, err)
+
+	c.client = mockClient
+
+	report, err := c.Usage(context.Background(), types.UsageRequest{From: from, To: to, GroupBy: "label", Label: "team", Format: "csv"})
+	require.NoError(t, err)
+
+	assert.EqualValues(t, expectedReport, report)
+}
@@ -15,8 +15,10 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"gitlab.com/postgres-ai/database-lab/v3/internal/cloning"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/diskpressure"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/embeddedui"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/estimator"
+	"gitlab.com/postgres-ai/database-lab/v3/internal/freshness"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/observer"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/platform"
 	"gitlab.com/postgres-ai/database-lab/v3/internal/provision"
@@ -35,16 +37,18 @@ const (
 
 // Config contains a common database-lab configuration.
 type Config struct {
-	Server      srvCfg.Config     `yaml:"server"`
-	Provision   provision.Config  `yaml:"provision"`
-	Cloning     cloning.Config    `yaml:"cloning"`
-	Platform    platform.Config   `yaml:"platform"`
-	Global      global.Config     `yaml:"global"`
-	Retrieval   retConfig.Config  `yaml:"retrieval"`
-	Observer    observer.Config   `yaml:"observer"`
-	Estimator   estimator.Config  `yaml:"estimator"`
-	PoolManager pool.Config       `yaml:"poolManager"`
-	EmbeddedUI  embeddedui.Config `yaml:"embeddedUI"`
+	Server        srvCfg.Config       `yaml:"server"`
+	Provision     provision.Config    `yaml:"provision"`
+	Cloning       cloning.Config      `yaml:"cloning"`
+	Platform      platform.Config     `yaml:"platform"`
+	Global        global.Config       `yaml:"global"`
+	Retrieval     retConfig.Config    `yaml:"retrieval"`
+	Observer      observer.Config     `yaml:"observer"`
+	Estimator     estimator.Config    `yaml:"estimator"`
+	PoolManager   pool.Config         `yaml:"poolManager"`
+	EmbeddedUI    embeddedui.Config   `yaml:"embeddedUI"`
+	DiskPressure  diskpressure.Config `yaml:"diskPressure"`
+	DataFreshness freshness.Config    `yaml:"dataFreshness"`
 }
 
 // LoadConfiguration instances a new application configuration.
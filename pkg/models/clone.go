@@ -6,14 +6,21 @@ package models
 
 // Clone defines a clone model.
 type Clone struct {
-	ID        string        `json:"id"`
-	Snapshot  *Snapshot     `json:"snapshot"`
-	Protected bool          `json:"protected"`
-	DeleteAt  string        `json:"deleteAt"`
-	CreatedAt string        `json:"createdAt"`
-	Status    Status        `json:"status"`
-	DB        Database      `json:"db"`
-	Metadata  CloneMetadata `json:"metadata"`
+	ID        string            `json:"id"`
+	Snapshot  *Snapshot         `json:"snapshot"`
+	Protected bool              `json:"protected"`
+	DeleteAt  string            `json:"deleteAt"`
+	CreatedAt string            `json:"createdAt"`
+	Status    Status            `json:"status"`
+	DB        Database          `json:"db"`
+	Metadata  CloneMetadata     `json:"metadata"`
+	Labels    map[string]string `json:"labels,omitempty"`
+
+	// AllowedCIDRs lists networks allowed to connect to the clone. An empty list allows connections from any address.
+	AllowedCIDRs []string `json:"allowedCIDRs,omitempty"`
+
+	// Warning describes a problem of the clone that does not prevent using it, for example, stale data.
+	Warning string `json:"warning,omitempty"`
 }
 
 // CloneMetadata contains fields describing a clone model.
@@ -37,3 +44,25 @@ type CloneMetadataView struct {
 	CloneDiffSize Size `json:"cloneDiffSize"`
 	LogicalSize   Size `json:"logicalSize"`
 }
+
+// ClonesPage represents a page of filtered clones.
+type ClonesPage struct {
+	Clones []*Clone `json:"clones"`
+	Total  int      `json:"total"`
+	Offset int      `json:"offset"`
+	Limit  int      `json:"limit"`
+}
+
+// ClonesPageView represents a view of a page of filtered clones.
+type ClonesPageView struct {
+	Clones []*CloneView `json:"clones"`
+	Total  int          `json:"total"`
+	Offset int          `json:"offset"`
+	Limit  int          `json:"limit"`
+}
+
+// ClonesDestroyResult represents the result of a bulk clone destruction.
+type ClonesDestroyResult struct {
+	Destroyed []string          `json:"destroyed"`
+	Failed    map[string]string `json:"failed"`
+}
@@ -0,0 +1,27 @@
+/*
+2022 © Postgres.ai
+*/
+
+package models
+
+import (
+	"time"
+)
+
+// DataFreshness represents the age of data in storage pools and in the latest snapshot.
+type DataFreshness struct {
+	Stale          bool      `json:"stale"`
+	LatestSnapshot *DataAge  `json:"latestSnapshot,omitempty"`
+	Pools          []DataAge `json:"pools"`
+}
+
+// DataAge describes the age of data in a storage pool or in a snapshot.
+type DataAge struct {
+	Name        string    `json:"name"`
+	DataStateAt time.Time `json:"dataStateAt"`
+	// Age contains the data age in seconds.
+	Age uint `json:"age"`
+	// MaxAge contains the maximum data age in seconds. Zero means that the age is not checked.
+	MaxAge uint `json:"maxAge"`
+	Stale  bool `json:"stale"`
+}
@@ -12,4 +12,14 @@ type Database struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	DBName   string `json:"dbName"`
+
+	// PooledConnStr defines a connection string of the connection pooler of the clone if it is requested.
+	PooledConnStr string `json:"pooledConnStr,omitempty"`
+
+	// CACert contains the PEM-encoded certificate of the CA that issues server certificates of clones if TLS is enabled.
+	CACert string `json:"caCert,omitempty"`
+
+	// VerifyFullConnStr defines a connection string to verify the server certificate and the host name of the clone.
+	// The CA certificate has to be saved to the file referenced by the "sslrootcert" parameter.
+	VerifyFullConnStr string `json:"verifyFullConnStr,omitempty"`
 }
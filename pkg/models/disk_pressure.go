@@ -0,0 +1,47 @@
+/*
+2022 © Postgres.ai
+*/
+
+package models
+
+import (
+	"time"
+)
+
+// DiskPressureLevel defines the level of disk pressure.
+type DiskPressureLevel string
+
+const (
+	// DiskPressureOK defines the level when there is enough free space.
+	DiskPressureOK DiskPressureLevel = "ok"
+	// DiskPressureWarning defines the level when the warning threshold is exceeded.
+	DiskPressureWarning DiskPressureLevel = "warning"
+	// DiskPressureCritical defines the level when the critical threshold is exceeded and space is being reclaimed.
+	DiskPressureCritical DiskPressureLevel = "critical"
+)
+
+// DiskPressureActionType defines the type of action taken by the disk pressure controller.
+type DiskPressureActionType string
+
+const (
+	// DiskPressureActionWarn defines a warning about low disk space.
+	DiskPressureActionWarn DiskPressureActionType = "warn"
+	// DiskPressureActionDestroyClone defines destruction of an idle clone.
+	DiskPressureActionDestroyClone DiskPressureActionType = "destroy_clone"
+	// DiskPressureActionPruneSnapshots defines removal of snapshots without clones.
+	DiskPressureActionPruneSnapshots DiskPressureActionType = "prune_snapshots"
+)
+
+// DiskPressure represents the state of the disk pressure controller.
+type DiskPressure struct {
+	Level   DiskPressureLevel    `json:"level"`
+	Actions []DiskPressureAction `json:"actions"`
+}
+
+// DiskPressureAction describes an action taken by the disk pressure controller.
+type DiskPressureAction struct {
+	Time    time.Time              `json:"time"`
+	Pool    string                 `json:"pool"`
+	Type    DiskPressureActionType `json:"type"`
+	Message string                 `json:"message"`
+}
@@ -12,12 +12,14 @@ import (
 
 // InstanceStatus represents status of a Database Lab Engine instance.
 type InstanceStatus struct {
-	Status      *Status          `json:"status"`
-	Engine      Engine           `json:"engine"`
-	Pools       []PoolEntry      `json:"pools"`
-	Cloning     Cloning          `json:"cloning"`
-	Retrieving  Retrieving       `json:"retrieving"`
-	Provisioner ContainerOptions `json:"provisioner"`
+	Status        *Status          `json:"status"`
+	Engine        Engine           `json:"engine"`
+	Pools         []PoolEntry      `json:"pools"`
+	Cloning       Cloning          `json:"cloning"`
+	Retrieving    Retrieving       `json:"retrieving"`
+	Provisioner   ContainerOptions `json:"provisioner"`
+	DiskPressure  *DiskPressure    `json:"diskPressure,omitempty"`
+	DataFreshness *DataFreshness   `json:"dataFreshness,omitempty"`
 }
 
 // PoolEntry represents a pool entry.
@@ -40,6 +42,8 @@ type ContainerOptions struct {
 type Cloning struct {
 	ExpectedCloningTime float64  `json:"expectedCloningTime"`
 	NumClones           uint64   `json:"numClones"`
+	NumWarmClones       uint64   `json:"numWarmClones"`
+	NumQueued           uint64   `json:"numQueued"`
 	Clones              []*Clone `json:"clones"`
 }
 
@@ -68,7 +72,8 @@ type CloningView struct {
 // InstanceStatusView represents view of a Database Lab Engine instance status.
 type InstanceStatusView struct {
 	*InstanceStatus
-	Pools []PoolEntryView `json:"pools"`
+	Pools      []PoolEntryView `json:"pools"`
+	Retrieving RetrievingView  `json:"retrieving"`
 }
 
 // PoolEntryView represents a pool entry view.
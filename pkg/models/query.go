@@ -0,0 +1,30 @@
+/*
+2022 © Postgres.ai
+*/
+
+package models
+
+import (
+	"encoding/json"
+)
+
+// QueryResult represents the result of an ad-hoc query run in a clone.
+type QueryResult struct {
+	Columns    []QueryColumn `json:"columns"`
+	Rows       [][]*string   `json:"rows"`
+	RowCount   int           `json:"rowCount"`
+	Truncated  bool          `json:"truncated"`
+	CommandTag string        `json:"commandTag"`
+	Duration   float64       `json:"duration"`
+}
+
+// QueryColumn describes a column of the query result.
+type QueryColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// QueryPlan represents the execution plan of a query in the JSON format.
+type QueryPlan struct {
+	Plan json.RawMessage `json:"plan"`
+}
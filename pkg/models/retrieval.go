@@ -55,6 +55,15 @@ const (
 
 	// RefreshSkipped describes alert when data refreshing is skipped.
 	RefreshSkipped AlertType = "refresh_skipped"
+
+	// LowDiskSpace describes alert when a storage pool is running out of space.
+	LowDiskSpace AlertType = "low_disk_space"
+
+	// SyncUnhealthy describes alert when the sync instance is down, does not replay WAL, or lags behind.
+	SyncUnhealthy AlertType = "sync_unhealthy"
+
+	// DataStale describes alert when data of a storage pool or of the latest snapshot is older than allowed.
+	DataStale AlertType = "data_stale"
 )
 
 // Retrieving represents state of retrieval subsystem.
@@ -64,6 +73,104 @@ type Retrieving struct {
 	LastRefresh *time.Time          `json:"lastRefresh"`
 	NextRefresh *time.Time          `json:"nextRefresh"`
 	Alerts      map[AlertType]Alert `json:"alerts"`
+	Paused      bool                `json:"paused"`
+	LastRun     *RetrievalRun       `json:"lastRun,omitempty"`
+	Progress    []DatabaseProgress  `json:"progress,omitempty"`
+	Sync        *SyncStatus         `json:"sync,omitempty"`
+}
+
+// SyncStatus describes the state of WAL replay on the sync instance.
+type SyncStatus struct {
+	CheckedAt  time.Time `json:"checkedAt"`
+	InRecovery bool      `json:"inRecovery"`
+	// LastReplayAt contains the commit time of the last replayed transaction.
+	LastReplayAt *time.Time `json:"lastReplayAt,omitempty"`
+	// ReplayLag contains the replay lag in seconds. It is zero when all received WAL is replayed.
+	ReplayLag *int64 `json:"replayLag,omitempty"`
+	// ReplayLagBytes contains the amount of received WAL that is not replayed yet.
+	ReplayLagBytes *int64 `json:"replayLagBytes,omitempty"`
+	// ReceiverStatus contains the status of the WAL receiver. It is empty when WAL is fetched from an archive.
+	ReceiverStatus string `json:"receiverStatus,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// RetrievingView represents a view of the retrieval subsystem state.
+type RetrievingView struct {
+	*Retrieving
+	Progress []DatabaseProgressView `json:"progress,omitempty"`
+}
+
+// DatabaseProgress describes progress of dumping or restoring a database.
+type DatabaseProgress struct {
+	Job         string `json:"job"`
+	Database    string `json:"database"`
+	TablesDone  int    `json:"tablesDone"`
+	TablesTotal int    `json:"tablesTotal"`
+	// BytesWritten contains the size of the dump in the dump location.
+	BytesWritten uint64     `json:"bytesWritten,omitempty"`
+	StartedAt    time.Time  `json:"startedAt"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+	ETA          *time.Time `json:"eta,omitempty"`
+}
+
+// DatabaseProgressView represents a view of dumping or restoring progress.
+type DatabaseProgressView struct {
+	*DatabaseProgress
+	BytesWritten Size `json:"bytesWritten,omitempty"`
+}
+
+// RefreshTrigger defines what has started a data refresh.
+type RefreshTrigger string
+
+const (
+	// TriggerStartup defines a data refresh performed at the engine startup.
+	TriggerStartup RefreshTrigger = "startup"
+	// TriggerTimetable defines a data refresh started by the refresh timetable.
+	TriggerTimetable RefreshTrigger = "timetable"
+	// TriggerManual defines a data refresh requested through the API.
+	TriggerManual RefreshTrigger = "manual"
+	// TriggerJob defines a data refresh requested by a retrieval job, for example, when replicated data diverges from the source.
+	TriggerJob RefreshTrigger = "job"
+)
+
+// JobStatus defines status of a retrieval job within a refresh run.
+type JobStatus string
+
+const (
+	// JobPending defines a job waiting for previous jobs.
+	JobPending JobStatus = "pending"
+	// JobRunning defines a running job.
+	JobRunning JobStatus = "running"
+	// JobFinished defines a successfully finished job.
+	JobFinished JobStatus = "finished"
+	// JobFailed defines a failed job.
+	JobFailed JobStatus = "failed"
+)
+
+// RetrievalRun describes a data refresh run.
+type RetrievalRun struct {
+	ID          int             `json:"id"`
+	TriggeredBy RefreshTrigger  `json:"triggeredBy"`
+	Initiator   string          `json:"initiator,omitempty"`
+	StartedAt   time.Time       `json:"startedAt"`
+	FinishedAt  *time.Time      `json:"finishedAt,omitempty"`
+	Status      RetrievalStatus `json:"status"`
+	Error       string          `json:"error,omitempty"`
+	SnapshotID  string          `json:"snapshotID,omitempty"`
+	Jobs        []JobProgress   `json:"jobs"`
+}
+
+// JobProgress describes progress of a retrieval job within a refresh run.
+type JobProgress struct {
+	Name       string     `json:"name"`
+	Status     JobStatus  `json:"status"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	// DataSize contains the number of bytes dumped or restored by the job.
+	DataSize uint64 `json:"dataSize,omitempty"`
+	// Logs contains links to captured logs of job containers.
+	Logs []string `json:"logs,omitempty"`
 }
 
 // Alert describes retrieval subsystem alert.
@@ -80,7 +187,7 @@ func AlertLevelByType(alertType AlertType) AlertLevel {
 	case RefreshFailed:
 		return ErrorLevel
 
-	case RefreshSkipped:
+	case RefreshSkipped, LowDiskSpace, SyncUnhealthy, DataStale:
 		return WarningLevel
 
 	default:
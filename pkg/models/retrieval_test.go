@@ -23,6 +23,14 @@ func TestLevelByAlertType(t *testing.T) {
 			alertType: "refresh_skipped",
 			level:     WarningLevel,
 		},
+		{
+			alertType: "sync_unhealthy",
+			level:     WarningLevel,
+		},
+		{
+			alertType: "data_stale",
+			level:     WarningLevel,
+		},
 		{
 			alertType: "unknown_fail",
 			level:     UnknownLevel,
@@ -16,6 +16,7 @@ type StatusCode string
 // Constants declares available status codes and messages.
 const (
 	StatusOK        StatusCode = "OK"
+	StatusQueued    StatusCode = "QUEUED"
 	StatusCreating  StatusCode = "CREATING"
 	StatusResetting StatusCode = "RESETTING"
 	StatusDeleting  StatusCode = "DELETING"
@@ -24,6 +25,7 @@ const (
 	StatusWarning   StatusCode = "WARNING"
 
 	CloneMessageOK        = "Clone is ready to accept Postgres connections."
+	CloneMessageQueued    = "Clone operation is queued. Position in the queue: %d."
 	CloneMessageCreating  = "Clone is being created."
 	CloneMessageResetting = "Clone is being reset."
 	CloneMessageDeleting  = "Clone is being deleted."
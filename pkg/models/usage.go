@@ -0,0 +1,43 @@
+/*
+2022 © Postgres.ai
+*/
+
+package models
+
+import (
+	"time"
+)
+
+// UsageGroupBy defines how clone usage is grouped in a report.
+type UsageGroupBy string
+
+const (
+	// UsageGroupByUser groups clone usage by the database username of clones.
+	UsageGroupByUser UsageGroupBy = "user"
+
+	// UsageGroupByLabel groups clone usage by the value of a clone label.
+	UsageGroupByLabel UsageGroupBy = "label"
+)
+
+// UsageReport represents clone usage for a period of time.
+type UsageReport struct {
+	From    time.Time    `json:"from"`
+	To      time.Time    `json:"to"`
+	GroupBy UsageGroupBy `json:"groupBy"`
+	Label   string       `json:"label,omitempty"`
+	Groups  []UsageGroup `json:"groups"`
+}
+
+// UsageGroup represents clone usage of a user or of a label value.
+type UsageGroup struct {
+	// Key contains the username or the label value. It is empty for clones without the label.
+	Key          string  `json:"key"`
+	Clones       int     `json:"clones"`
+	Created      int     `json:"created"`
+	Resets       int     `json:"resets"`
+	Destroyed    int     `json:"destroyed"`
+	CloneHours   float64 `json:"cloneHours"`
+	IdleHours    float64 `json:"idleHours"`
+	PeakDiffSize uint64  `json:"peakDiffSize"`
+	AvgDiffSize  uint64  `json:"avgDiffSize"`
+}